@@ -0,0 +1,369 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+    . "github.com/ing-bank/zkrp/util"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+/*
+AggregateBulletProof contains the elements that are necessary for the verification
+of a single range proof over m committed values, as described in Section 4.3 of the
+Bulletproofs paper. Its size grows logarithmically in m.N.
+*/
+type AggregateBulletProof struct {
+    V                 []*p256.P256
+    A                 *p256.P256
+    S                 *p256.P256
+    T1                *p256.P256
+    T2                *p256.P256
+    Taux              *big.Int
+    Mu                *big.Int
+    Tprime            *big.Int
+    InnerProductProof InnerProductProof
+}
+
+/*
+SetupAggregate computes the common parameters for proving that m values belong to
+[0, b). It is equal to Setup, except that it provides m.N generators Gg and Hh.
+The number of values m must be a power of 2.
+*/
+func SetupAggregate(b, m int64) (BulletProofSetupParams, error) {
+    if m <= 0 || !IsPowerOfTwo(m) {
+        return BulletProofSetupParams{}, errors.New("number of values is not a power of 2")
+    }
+    params, err := Setup(b)
+    if err != nil {
+        return BulletProofSetupParams{}, err
+    }
+    Gg, Hh, err := vectorGenerators(params.N, m*params.N)
+    if err != nil {
+        return BulletProofSetupParams{}, err
+    }
+    params.Gg = append(params.Gg, Gg...)
+    params.Hh = append(params.Hh, Hh...)
+    return params, nil
+}
+
+/*
+ProveAggregate computes a single ZK rangeproof for all the secrets. The number of
+secrets must be a power of 2 and params must provide enough generators for them,
+see SetupAggregate. The equation numbers refer to the single value protocol, which
+is generalized in Section 4.3 of https://eprint.iacr.org/2017/1066.pdf
+*/
+func ProveAggregate(secrets []*big.Int, params BulletProofSetupParams, opts ...ProverOption) (AggregateBulletProof, error) {
+    gammas, err := newProverConfig(opts).sampleBlindings(len(secrets))
+    if err != nil {
+        return AggregateBulletProof{}, err
+    }
+    return ProveAggregateWithCommitments(secrets, gammas, params, opts...)
+}
+
+/*
+ProveAggregateWithCommitments computes a single ZK rangeproof for the commitments
+V_j = g^secrets_j.h^gammas_j, where the blinding factors gammas are chosen by whoever
+issued the commitments.
+*/
+func ProveAggregateWithCommitments(secrets, gammas []*big.Int, params BulletProofSetupParams, opts ...ProverOption) (AggregateBulletProof, error) {
+    var (
+        proof AggregateBulletProof
+    )
+    if len(secrets) != len(gammas) {
+        return proof, errors.New("number of secrets and blinding factors must be equal")
+    }
+    for j := range secrets {
+        if secrets[j] == nil || gammas[j] == nil {
+            return proof, errors.New("secret and blinding factor must not be nil")
+        }
+    }
+    m := int64(len(secrets))
+    if m == 0 || !IsPowerOfTwo(m) {
+        return proof, errors.New("number of secrets is not a power of 2")
+    }
+    mn := m * params.N
+    if int64(len(params.Gg)) < mn || int64(len(params.Hh)) < mn {
+        return proof, errors.New("not enough generators for the number of secrets")
+    }
+    params.Gg = params.Gg[:mn]
+    params.Hh = params.Hh[:mn]
+    cfg := newProverConfig(opts)
+
+    // ////////////////////////////////////////////////////////////////////////////
+    // First phase
+    // ////////////////////////////////////////////////////////////////////////////
+
+    // commitments to v_j and gamma_j
+    gamma := make([]*big.Int, m)
+    V := make([]*p256.P256, m)
+    aL := make([]int64, 0, mn)
+    for j := int64(0); j < m; j++ {
+        gamma[j] = bn.Mod(gammas[j], ORDER)
+        V[j], _ = CommitG1(secrets[j], gamma[j], params.H)
+        bits, _ := Decompose(secrets[j], 2, params.N) // (41)
+        aL = append(aL, bits...)
+    }
+
+    t := transcript.New(RANGE_PROOF_LABEL)
+    rangeProofDomainSep(t, params, m)
+    for j := int64(0); j < m; j++ {
+        appendPoint(t, "V", V[j])
+    }
+
+    // alpha (43), rho (46), tau1 and tau2, followed by sL and sR (45)
+    rng, err := cfg.nonceReader(t, append(append([]*big.Int{}, secrets...), gamma...)...)
+    if err != nil {
+        return proof, err
+    }
+    nonces, err := sampleScalars(rng, 4)
+    if err != nil {
+        return proof, err
+    }
+    alpha, rho, tau1, tau2 := nonces[0], nonces[1], nonces[2], nonces[3]
+
+    // aL, aR and commitment: (A, alpha)
+    aR, _ := computeAR(aL)                                            // (42)
+    A := commitVector(aL, aR, alpha, params.H, params.Gg, params.Hh, mn, cfg.workers) // (44)
+
+    // sL, sR and commitment: (S, rho)                                     // (45)
+    sL, err := sampleRandomVector(rng, mn)
+    if err != nil {
+        return proof, err
+    }
+    sR, err := sampleRandomVector(rng, mn)
+    if err != nil {
+        return proof, err
+    }
+    S := commitVectorBig(sL, sR, rho, params.H, params.Gg, params.Hh, mn, cfg.workers) // (47)
+
+    // Fiat-Shamir heuristic to compute challenges y and z
+    appendPoint(t, "A", A)
+    appendPoint(t, "S", S)
+    y := challengeScalar(t, "y")
+    z := challengeScalar(t, "z")
+
+    // ////////////////////////////////////////////////////////////////////////////
+    // Second phase
+    // ////////////////////////////////////////////////////////////////////////////
+    vz, _ := VectorCopy(z, mn)
+    vy := powerOf(y, mn)
+
+    // l(X) = (aL - z.1^mn) + sL.X
+    naL, _ := VectorConvertToBig(aL, mn)
+    aLmvz, _ := VectorSub(naL, vz)
+
+    // r(X) = y^mn . (aR + z.1^mn + sR.X) + sum_j z^(1+j) . (0^((j-1)n) || 2^n || 0^((m-j)n))
+    naR, _ := VectorConvertToBig(aR, mn)
+    aRzn, _ := VectorAdd(naR, vz)
+    ynaRzn, _ := VectorMul(vy, aRzn)
+    zj22n := aggregatePowersOfTwo(z, params.N, m)
+    r0, _ := VectorAdd(ynaRzn, zj22n)
+    ynsR, _ := VectorMul(vy, sR)
+
+    // t1 = < aL - z.1^mn, y^mn . sR > + < sL, r0 >
+    sp1, _ := ScalarProduct(aLmvz, ynsR)
+    sp2, _ := ScalarProduct(sL, r0)
+    t1 := bn.Mod(bn.Add(sp1, sp2), ORDER)
+
+    // t2 = < sL, y^mn . sR >
+    t2, _ := ScalarProduct(sL, ynsR)
+    t2 = bn.Mod(t2, ORDER)
+
+    T1, _ := CommitG1(t1, tau1, params.H) // (53)
+    T2, _ := CommitG1(t2, tau2, params.H) // (53)
+
+    // Fiat-Shamir heuristic to compute 'random' challenge x
+    appendPoint(t, "T1", T1)
+    appendPoint(t, "T2", T2)
+    x := challengeScalar(t, "x")
+
+    // ////////////////////////////////////////////////////////////////////////////
+    // Third phase
+    // ////////////////////////////////////////////////////////////////////////////
+
+    // bl = aL - z.1^mn + sL.x                                             // (58)
+    sLx, _ := VectorScalarMul(sL, x)
+    bl, _ := VectorAdd(aLmvz, sLx)
+
+    // br = r0 + y^mn . sR.x                                               // (59)
+    ynsRx, _ := VectorScalarMul(ynsR, x)
+    br, _ := VectorAdd(r0, ynsRx)
+
+    // t' = < bl, br >                                                     // (60)
+    tprime, _ := ScalarProduct(bl, br)
+
+    // taux = tau2 . x^2 + tau1 . x + sum_j z^(1+j) . gamma_j              // (61)
+    taux := bn.Multiply(tau2, bn.Multiply(x, x))
+    taux = bn.Add(taux, bn.Multiply(tau1, x))
+    zj := bn.Mod(bn.Multiply(z, z), ORDER)
+    for j := int64(0); j < m; j++ {
+        taux = bn.Add(taux, bn.Multiply(zj, gamma[j]))
+        zj = bn.Mod(bn.Multiply(zj, z), ORDER)
+    }
+    taux = bn.Mod(taux, ORDER)
+
+    // mu = alpha + rho.x                                                  // (62)
+    mu := bn.Mod(bn.Add(alpha, bn.Multiply(rho, x)), ORDER)
+
+    // Inner Product over (g, h', P.h^-mu, tprime)
+    hprime := updateGenerators(params.Hh, y, mn, cfg.workers)
+    var setupErr error
+    params.InnerProductParams, setupErr = setupInnerProduct(params.H, params.Gg, hprime, tprime, mn)
+    if setupErr != nil {
+        return proof, setupErr
+    }
+    appendScalar(t, "taux", taux)
+    appendScalar(t, "mu", mu)
+    appendScalar(t, "t", tprime)
+    commit := commitInnerProduct(params.Gg, hprime, bl, br, cfg.workers)
+    proofip, _ := proveInnerProductWithTranscript(t, bl, br, commit, params.InnerProductParams, cfg.workers)
+
+    proof.V = V
+    proof.A = A
+    proof.S = S
+    proof.T1 = T1
+    proof.T2 = T2
+    proof.Taux = taux
+    proof.Mu = mu
+    proof.Tprime = tprime
+    proof.InnerProductProof = proofip
+
+    return proof, nil
+}
+
+/*
+Verify returns true if and only if the aggregated proof is valid, i.e. all the
+committed values belong to [0, 2^N). The params must be computed by the verifier,
+using SetupAggregate.
+*/
+func (proof *AggregateBulletProof) Verify(params BulletProofSetupParams) (bool, error) {
+    m := int64(len(proof.V))
+    if m == 0 || !IsPowerOfTwo(m) {
+        return false, errors.New("number of commitments is not a power of 2")
+    }
+    mn := m * params.N
+    if int64(len(params.Gg)) < mn || int64(len(params.Hh)) < mn {
+        return false, errors.New("not enough generators for the number of commitments")
+    }
+    if err := params.validate(m); err != nil {
+        return false, err
+    }
+    if err := proof.validate(params.N); err != nil {
+        return false, err
+    }
+    params.Gg = params.Gg[:mn]
+    params.Hh = params.Hh[:mn]
+
+    // Recover x, y, z using Fiat-Shamir heuristic
+    t := transcript.New(RANGE_PROOF_LABEL)
+    y, z, x := proof.challenges(t, params)
+
+    // Switch generators                                                   // (64)
+    hprime := updateGenerators(params.Hh, y, mn, 1)
+
+    // ////////////////////////////////////////////////////////////////////////////
+    // Check that tprime  = t(x) = t0 + t1x + t2x^2  ----------  Condition (65) //
+    // ////////////////////////////////////////////////////////////////////////////
+
+    // Compute left hand side
+    lhs, _ := CommitG1(proof.Tprime, proof.Taux, params.H)
+
+    // Compute right hand side: V^(z^2.z^m) . g^delta . T1^x . T2^(x^2)
+    zj := bn.Mod(bn.Multiply(z, z), ORDER)
+    zjs := make([]*big.Int, m)
+    for j := int64(0); j < m; j++ {
+        zjs[j] = zj
+        zj = bn.Mod(bn.Multiply(zj, z), ORDER)
+    }
+    x2 := bn.Mod(bn.Multiply(x, x), ORDER)
+    points := concatPoints(proof.V, []*p256.P256{params.G, proof.T1, proof.T2})
+    scalars := concatScalars(zjs, []*big.Int{params.aggregateDelta(y, z, m), x, x2})
+    rhs, _ := VectorExp(points, scalars)
+
+    // Subtract lhs and rhs and compare with point at infinity
+    lhs.Neg(lhs)
+    rhs.Multiply(rhs, lhs)
+    c65 := rhs.IsZero()
+
+    // Compute P.h^-mu  ################ Conditions (66) and (67) ###############
+    lP := innerProductCommitment(params, proof.A, proof.S, proof.Mu, hprime, x, y, z, m)
+
+    // Verify Inner Product Proof, i.e. that tprime = < l, r > ######### (68) ####
+    ipParams, errIP := setupInnerProduct(params.H, params.Gg, hprime, proof.Tprime, mn)
+    if errIP != nil {
+        return false, errIP
+    }
+    ipParams.P = lP
+    ok, _ := proof.InnerProductProof.verifyWithTranscript(t, ipParams)
+
+    return c65 && ok, nil
+}
+
+/*
+aggregatePowersOfTwo returns the vector of size m.n formed by the concatenation of
+z^(2+j) . 2^n, for j in [0, m).
+*/
+func aggregatePowersOfTwo(z *big.Int, n, m int64) []*big.Int {
+    result := make([]*big.Int, 0, m*n)
+    p2n := powerOf(new(big.Int).SetInt64(2), n)
+    zj := bn.Mod(bn.Multiply(z, z), ORDER)
+    for j := int64(0); j < m; j++ {
+        zj22n, _ := VectorScalarMul(p2n, zj)
+        result = append(result, zj22n...)
+        zj = bn.Mod(bn.Multiply(zj, z), ORDER)
+    }
+    return result
+}
+
+/*
+aggregateDelta computes delta(y,z) for m values:
+(z-z^2) . < 1^mn, y^mn > - sum_j z^(j+2) . < 1^n, 2^n >, for j in [1, m].
+*/
+func (params *BulletProofSetupParams) aggregateDelta(y, z *big.Int, m int64) *big.Int {
+    var (
+        result *big.Int
+    )
+    mn := m * params.N
+    z2 := bn.Mod(bn.Multiply(z, z), ORDER)
+
+    // < 1^mn, y^mn >
+    v1, _ := VectorCopy(new(big.Int).SetInt64(1), mn)
+    sp1y, _ := ScalarProduct(v1, powerOf(y, mn))
+
+    // < 1^n, 2^n >
+    sp12, _ := ScalarProduct(v1[:params.N], powerOf(new(big.Int).SetInt64(2), params.N))
+
+    result = bn.Sub(z, z2)
+    result = bn.Mod(result, ORDER)
+    result = bn.Multiply(result, sp1y)
+    result = bn.Mod(result, ORDER)
+
+    zj := bn.Mod(bn.Multiply(z2, z), ORDER)
+    for j := int64(0); j < m; j++ {
+        result = bn.Sub(result, bn.Multiply(zj, sp12))
+        result = bn.Mod(result, ORDER)
+        zj = bn.Mod(bn.Multiply(zj, z), ORDER)
+    }
+
+    return result
+}
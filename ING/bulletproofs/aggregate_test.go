@@ -0,0 +1,102 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "encoding/json"
+    "math/big"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestAggregateWithinRange(t *testing.T) {
+    secrets := []*big.Int{big.NewInt(18), big.NewInt(4200), big.NewInt(0), big.NewInt(MAX_RANGE_END - 1)}
+    if proveAndVerifyAggregate(t, secrets) != true {
+        t.Errorf("secrets within range should verify successfully")
+    }
+}
+
+func TestAggregateSingleValue(t *testing.T) {
+    if proveAndVerifyAggregate(t, []*big.Int{big.NewInt(3)}) != true {
+        t.Errorf("single secret within range should verify successfully")
+    }
+}
+
+func TestAggregateOneOutOfRange(t *testing.T) {
+    secrets := []*big.Int{big.NewInt(18), big.NewInt(MAX_RANGE_END)}
+    if proveAndVerifyAggregate(t, secrets) == true {
+        t.Errorf("secret equal to range end should not verify")
+    }
+}
+
+func TestAggregateNegativeValue(t *testing.T) {
+    secrets := []*big.Int{big.NewInt(-1), big.NewInt(18)}
+    if proveAndVerifyAggregate(t, secrets) == true {
+        t.Errorf("secret lower than range start should not verify")
+    }
+}
+
+func TestAggregateNotPowerOfTwo(t *testing.T) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 4)
+    _, err := ProveAggregate([]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}, params)
+    assert.Error(t, err, "3 secrets should be rejected")
+
+    _, err = SetupAggregate(MAX_RANGE_END, 3)
+    assert.Error(t, err, "3 values should be rejected")
+}
+
+func TestAggregateNotEnoughGenerators(t *testing.T) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 2)
+    _, err := ProveAggregate([]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)}, params)
+    assert.Error(t, err, "4 secrets should not fit in generators for 2")
+}
+
+func TestAggregateJsonEncodeDecode(t *testing.T) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 2)
+    proof, _ := ProveAggregate([]*big.Int{big.NewInt(18), big.NewInt(65)}, params)
+    jsonEncoded, err := json.Marshal(proof)
+    if err != nil {
+        t.Fatal("encode error:", err)
+    }
+
+    var decodedProof AggregateBulletProof
+    err = json.Unmarshal(jsonEncoded, &decodedProof)
+    if err != nil {
+        t.Fatal("decode error:", err)
+    }
+
+    ok, err := decodedProof.Verify(params)
+    if err != nil {
+        t.Fatal("verify error:", err)
+    }
+    assert.True(t, ok, "should verify")
+}
+
+func proveAndVerifyAggregate(t *testing.T, secrets []*big.Int) bool {
+    params, err := SetupAggregate(MAX_RANGE_END, int64(len(secrets)))
+    if err != nil {
+        t.Fatal("setup error:", err)
+    }
+    proof, err := ProveAggregate(secrets, params)
+    if err != nil {
+        t.Fatal("prove error:", err)
+    }
+    ok, _ := proof.Verify(params)
+    return ok
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "crypto/rand"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+/*
+VerifyBatch verifies many range proofs at once. Condition (65) and the inner product
+argument of every proof are combined using random weights into a single
+multi-exponentiation, which is equal to the point at infinity if and only if all
+the proofs are valid, except with negligible probability.
+It returns true if all the proofs are valid. Otherwise every proof is verified on
+its own and the indexes of the invalid ones are returned.
+*/
+func VerifyBatch(proofs []BulletProof, params BulletProofSetupParams) (bool, []int, error) {
+    n := params.N
+    if int64(len(params.Gg)) != n || int64(len(params.Hh)) != n {
+        return false, nil, errors.New("number of generators does not match the bit-length of the range")
+    }
+    ok, err := verifyBatch(proofs, params)
+    if err != nil {
+        return false, nil, err
+    }
+    if ok {
+        return true, nil, nil
+    }
+
+    // Fall back to the verification of each proof to find the invalid ones.
+    var invalid []int
+    for i := range proofs {
+        ok, _ := proofs[i].Verify(params)
+        if !ok {
+            invalid = append(invalid, i)
+        }
+    }
+    return len(invalid) == 0, invalid, nil
+}
+
+/*
+verifyBatch computes the weighted sum of the verification equations of all the
+proofs, in the form of a single multi-exponentiation. For each proof, with weights
+w and v, the following must be equal to the point at infinity:
+
+[g^t'.h^taux.V^-z^2.g^-delta.T1^-x.T2^-x^2]^w                           (65)
+[A.S^x.g^-z.h'^(z.y^n + z^2.2^n).h^-mu.u^(t'-a.b).L^x^2.R^x^-2.g^-a.s.h'^-b.s^-1]^v
+
+where s are the folding scalars of the inner product argument, see foldingScalars.
+The second line follows from conditions (66), (67) and (16).
+*/
+func verifyBatch(proofs []BulletProof, params BulletProofSetupParams) (bool, error) {
+    var (
+        points  []*p256.P256
+        scalars []*big.Int
+    )
+    n := params.N
+    logn := 0
+    for (int64(1) << uint(logn)) < n {
+        logn = logn + 1
+    }
+    ipParams, _ := setupInnerProduct(params.H, params.Gg, params.Hh, nil, n)
+    U := ipParams.Uu
+    p2n := powerOf(new(big.Int).SetInt64(2), n)
+
+    gScalar := new(big.Int)
+    hScalar := new(big.Int)
+    uScalar := new(big.Int)
+    ggScalars := make([]*big.Int, n)
+    hhScalars := make([]*big.Int, n)
+    for i := int64(0); i < n; i++ {
+        ggScalars[i] = new(big.Int)
+        hhScalars[i] = new(big.Int)
+    }
+
+    for _, proof := range proofs {
+        ipp := proof.InnerProductProof
+        if proof.validate(n) != nil {
+            return false, nil
+        }
+        w, errW := rand.Int(rand.Reader, ORDER)
+        if errW != nil {
+            return false, errW
+        }
+        v, errV := rand.Int(rand.Reader, ORDER)
+        if errV != nil {
+            return false, errV
+        }
+
+        // Recover the challenges using Fiat-Shamir heuristic
+        t := transcript.New(RANGE_PROOF_LABEL)
+        y, z, x := proof.challenges(t, params)
+        xip, xs := ipp.challenges(t, ipParams)
+        s := foldingScalars(xs, n)
+
+        z2 := bn.Mod(bn.Multiply(z, z), ORDER)
+        x2 := bn.Mod(bn.Multiply(x, x), ORDER)
+        delta := params.delta(y, z)
+
+        // Condition (65), weighted by w
+        gScalar.Add(gScalar, bn.Multiply(w, bn.Sub(proof.Tprime, delta)))
+        hScalar.Add(hScalar, bn.Multiply(w, proof.Taux))
+        points = append(points, proof.V, proof.T1, proof.T2)
+        scalars = append(scalars,
+            bn.Mod(bn.Multiply(w, bn.Sub(ORDER, z2)), ORDER),
+            bn.Mod(bn.Multiply(w, bn.Sub(ORDER, x)), ORDER),
+            bn.Mod(bn.Multiply(w, bn.Sub(ORDER, x2)), ORDER))
+
+        // Inner product argument, weighted by v
+        hScalar.Sub(hScalar, bn.Multiply(v, proof.Mu))
+        ab := bn.Multiply(ipp.A, ipp.B)
+        uScalar.Add(uScalar, bn.Multiply(bn.Multiply(v, xip), bn.Sub(proof.Tprime, ab)))
+        points = append(points, proof.A, proof.S)
+        scalars = append(scalars, v, bn.Mod(bn.Multiply(v, x), ORDER))
+        for k := 0; k < logn; k++ {
+            xk2 := bn.Mod(bn.Multiply(xs[k], xs[k]), ORDER)
+            points = append(points, ipp.Ls[k], ipp.Rs[k])
+            scalars = append(scalars,
+                bn.Mod(bn.Multiply(v, xk2), ORDER),
+                bn.Mod(bn.Multiply(v, bn.ModInverse(xk2, ORDER)), ORDER))
+        }
+        // h'_i = h_i^(y^-i) and s_i^-1 = s_(n-1-i)
+        yinv := bn.ModInverse(y, ORDER)
+        yinvi := new(big.Int).SetInt64(1)
+        for i := int64(0); i < n; i++ {
+            // g_i^(-z - a.s_i)
+            gi := bn.Sub(bn.Sub(ORDER, z), bn.Multiply(ipp.A, s[i]))
+            ggScalars[i] = bn.Mod(bn.Add(ggScalars[i], bn.Multiply(v, gi)), ORDER)
+            // h_i^(z + (z^2.2^i - b.s_i^-1).y^-i)
+            hi := bn.Sub(bn.Multiply(z2, p2n[i]), bn.Multiply(ipp.B, s[n-1-i]))
+            hi = bn.Add(z, bn.Multiply(hi, yinvi))
+            hhScalars[i] = bn.Mod(bn.Add(hhScalars[i], bn.Multiply(v, hi)), ORDER)
+            yinvi = bn.Mod(bn.Multiply(yinvi, yinv), ORDER)
+        }
+    }
+
+    points = append(points, params.G, params.H, U)
+    scalars = append(scalars, bn.Mod(gScalar, ORDER), bn.Mod(hScalar, ORDER), bn.Mod(uScalar, ORDER))
+    points = append(points, params.Gg...)
+    scalars = append(scalars, ggScalars...)
+    points = append(points, params.Hh...)
+    scalars = append(scalars, hhScalars...)
+
+    result, err := VectorExp(points, scalars)
+    if err != nil {
+        return false, err
+    }
+    return result.IsZero(), nil
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestVerifyBatch(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proofs := proveBatch(t, params, 18, 40, 0, MAX_RANGE_END-1)
+    ok, invalid, err := VerifyBatch(proofs, params)
+    assert.NoError(t, err)
+    assert.True(t, ok, "valid proofs should verify")
+    assert.Empty(t, invalid)
+}
+
+func TestVerifyBatchOutOfRange(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proofs := proveBatch(t, params, 18, MAX_RANGE_END, 40, -1)
+    ok, invalid, err := VerifyBatch(proofs, params)
+    assert.NoError(t, err)
+    assert.False(t, ok, "secrets out of range should not verify")
+    assert.Equal(t, []int{1, 3}, invalid)
+}
+
+func TestVerifyBatchTamperedInnerProduct(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proofs := proveBatch(t, params, 18, 40, 65)
+    proofs[2].InnerProductProof.A = new(big.Int).Add(proofs[2].InnerProductProof.A, big.NewInt(1))
+    ok, invalid, err := VerifyBatch(proofs, params)
+    assert.NoError(t, err)
+    assert.False(t, ok, "tampered proof should not verify")
+    assert.Equal(t, []int{2}, invalid)
+}
+
+func TestVerifyBatchTamperedTaux(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proofs := proveBatch(t, params, 18, 40)
+    proofs[0].Taux = new(big.Int).Add(proofs[0].Taux, big.NewInt(1))
+    ok, invalid, err := VerifyBatch(proofs, params)
+    assert.NoError(t, err)
+    assert.False(t, ok, "tampered proof should not verify")
+    assert.Equal(t, []int{0}, invalid)
+}
+
+func proveBatch(t *testing.T, params BulletProofSetupParams, secrets ...int64) []BulletProof {
+    proofs := make([]BulletProof, len(secrets))
+    for i, secret := range secrets {
+        var err error
+        proofs[i], err = Prove(new(big.Int).SetInt64(secret), params)
+        if err != nil {
+            t.Fatal("prove error:", err)
+        }
+    }
+    return proofs
+}
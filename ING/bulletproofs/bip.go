@@ -18,13 +18,12 @@
 package bulletproofs
 
 import (
-    "crypto/sha256"
     "errors"
     "math/big"
 
     "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/transcript"
     "github.com/ing-bank/zkrp/util/bn"
-    "github.com/ing-bank/zkrp/util/byteconversion"
 )
 
 var SEEDU = "BulletproofsDoesNotNeedTrustedSetupU"
@@ -45,18 +44,14 @@ type InnerProductParams struct {
 
 /*
 InnerProductProof contains the elements used to verify the Inner Product Proof.
+Only the prover messages are included, the generators and the commitment P are
+provided by the verifier through InnerProductParams.
 */
 type InnerProductProof struct {
-    N      int64
-    Ls     []*p256.P256
-    Rs     []*p256.P256
-    U      *p256.P256
-    P      *p256.P256
-    Gg     *p256.P256
-    Hh     *p256.P256
-    A      *big.Int
-    B      *big.Int
-    Params InnerProductParams
+    Ls []*p256.P256
+    Rs []*p256.P256
+    A  *big.Int
+    B  *big.Int
 }
 
 /*
@@ -72,28 +67,30 @@ func setupInnerProduct(H *p256.P256, g, h []*p256.P256, c *big.Int, N int64) (In
         params.N = N
     }
     if H == nil {
-        params.H, _ = p256.MapToGroup(SEEDH)
+        params.H, _ = generatorH()
     } else {
         params.H = H
     }
-    if g == nil {
-        params.Gg = make([]*p256.P256, params.N)
-        for i := int64(0); i < params.N; i++ {
-            params.Gg[i], _ = p256.MapToGroup(SEEDH + "g" + string(i))
+    params.Gg = g
+    params.Hh = h
+    if g == nil || h == nil {
+        Gg, Hh, err := vectorGenerators(0, params.N)
+        if err != nil {
+            return params, err
         }
-    } else {
-        params.Gg = g
-    }
-    if h == nil {
-        params.Hh = make([]*p256.P256, params.N)
-        for i := int64(0); i < params.N; i++ {
-            params.Hh[i], _ = p256.MapToGroup(SEEDH + "h" + string(i))
+        if g == nil {
+            params.Gg = Gg
+        }
+        if h == nil {
+            params.Hh = Hh
         }
-    } else {
-        params.Hh = h
     }
     params.Cc = c
-    params.Uu, _ = p256.MapToGroup(SEEDU)
+    var err error
+    params.Uu, err = generatorU()
+    if err != nil {
+        return params, err
+    }
     params.P = new(p256.P256).SetInfinity()
 
     return params, nil
@@ -103,6 +100,15 @@ func setupInnerProduct(H *p256.P256, g, h []*p256.P256, c *big.Int, N int64) (In
 proveInnerProduct calculates the Zero Knowledge Proof for the Inner Product argument.
 */
 func proveInnerProduct(a, b []*big.Int, P *p256.P256, params InnerProductParams) (InnerProductProof, error) {
+    return proveInnerProductWithTranscript(newInnerProductTranscript(params, P), a, b, P, params, 1)
+}
+
+/*
+proveInnerProductWithTranscript calculates the Inner Product argument, deriving the
+challenges from t. The transcript must already determine P and c. The work of each
+round is split between the given number of workers.
+*/
+func proveInnerProductWithTranscript(t *transcript.Transcript, a, b []*big.Int, P *p256.P256, params InnerProductParams, workers int) (InnerProductProof, error) {
     var (
         proof InnerProductProof
         n, m  int64
@@ -118,28 +124,27 @@ func proveInnerProduct(a, b []*big.Int, P *p256.P256, params InnerProductParams)
     }
 
     // Fiat-Shamir:
-    // x = Hash(g,h,P,c)
-    x, _ := hashIP(params.Gg, params.Hh, P, params.Cc, params.N)
-    // Pprime = P.u^(x.c)
-    ux := new(p256.P256).ScalarMult(params.Uu, x)
+    // w = Hash(transcript)
+    innerProductDomainSep(t, params)
+    w := challengeScalar(t, "w")
+    // Pprime = P.u^(w.c)
+    ux := new(p256.P256).ScalarMult(params.Uu, w)
     uxc := new(p256.P256).ScalarMult(ux, params.Cc)
     PP := new(p256.P256).Multiply(P, uxc)
     // Execute Protocol 2 recursively
-    proof = computeBipRecursive(a, b, params.Gg, params.Hh, ux, PP, n, Ls, Rs)
-    proof.Params = params
-    proof.Params.P = PP
+    proof = computeBipRecursive(t, a, b, params.Gg, params.Hh, ux, PP, n, Ls, Rs, workers)
     return proof, nil
 }
 
 /*
 computeBipRecursive is the main recursive function that will be used to compute the inner product argument.
 */
-func computeBipRecursive(a, b []*big.Int, g, h []*p256.P256, u, P *p256.P256, n int64, Ls, Rs []*p256.P256) InnerProductProof {
+func computeBipRecursive(t *transcript.Transcript, a, b []*big.Int, g, h []*p256.P256, u, P *p256.P256, n int64, Ls, Rs []*p256.P256, workers int) InnerProductProof {
     var (
         proof                            InnerProductProof
         cL, cR, x, xinv, x2, x2inv       *big.Int
-        L, R, Lh, Rh, Pprime             *p256.P256
-        gprime, hprime, gprime2, hprime2 []*p256.P256
+        L, R, Pprime                     *p256.P256
+        gprime, hprime                   []*p256.P256
         aprime, bprime, aprime2, bprime2 []*big.Int
     )
 
@@ -147,10 +152,6 @@ func computeBipRecursive(a, b []*big.Int, g, h []*p256.P256, u, P *p256.P256, n
         // recursion end
         proof.A = a[0]
         proof.B = b[0]
-        proof.Gg = g[0]
-        proof.Hh = h[0]
-        proof.P = P
-        proof.U = u
         proof.Ls = Ls
         proof.Rs = Rs
 
@@ -164,37 +165,31 @@ func computeBipRecursive(a, b []*big.Int, g, h []*p256.P256, u, P *p256.P256, n
         cL, _ = ScalarProduct(a[:nprime], b[nprime:])
         // Compute cR = < a[n':], b[:n'] >                                    // (22)
         cR, _ = ScalarProduct(a[nprime:], b[:nprime])
-        // Compute L = g[n':]^(a[:n']).h[:n']^(b[n':]).u^cL                   // (23)
-        L, _ = VectorExp(g[nprime:], a[:nprime])
-        Lh, _ = VectorExp(h[:nprime], b[nprime:])
-        L.Multiply(L, Lh)
-        L.Multiply(L, new(p256.P256).ScalarMult(u, cL))
-
-        // Compute R = g[:n']^(a[n':]).h[n':]^(b[:n']).u^cR                   // (24)
-        R, _ = VectorExp(g[:nprime], a[nprime:])
-        Rh, _ = VectorExp(h[nprime:], b[:nprime])
-        R.Multiply(R, Rh)
-        R.Multiply(R, new(p256.P256).ScalarMult(u, cR))
+        // L and R are computed concurrently, each with half of the workers
+        half := (workers + 1) / 2
+        parallelDo(workers, func() {
+            // Compute L = g[n':]^(a[:n']).h[:n']^(b[n':]).u^cL               // (23)
+            L, _ = parallelVectorExp(half, concatPoints(g[nprime:], h[:nprime], []*p256.P256{u}), concatScalars(a[:nprime], b[nprime:], []*big.Int{cL}))
+        }, func() {
+            // Compute R = g[:n']^(a[n':]).h[n':]^(b[:n']).u^cR               // (24)
+            R, _ = parallelVectorExp(half, concatPoints(g[:nprime], h[nprime:], []*p256.P256{u}), concatScalars(a[nprime:], b[:nprime], []*big.Int{cR}))
+        })
 
         // Fiat-Shamir:                                                       // (26)
-        x, _, _ = HashBP(L, R)
+        appendPoint(t, "L", L)
+        appendPoint(t, "R", R)
+        x = challengeScalar(t, "x")
         xinv = bn.ModInverse(x, ORDER)
 
         // Compute g' = g[:n']^(x^-1) * g[n':]^(x)                            // (29)
-        gprime = vectorScalarExp(g[:nprime], xinv)
-        gprime2 = vectorScalarExp(g[nprime:], x)
-        gprime, _ = VectorECAdd(gprime, gprime2)
+        gprime = foldGenerators(g[:nprime], g[nprime:], xinv, x, workers)
         // Compute h' = h[:n']^(x)    * h[n':]^(x^-1)                         // (30)
-        hprime = vectorScalarExp(h[:nprime], x)
-        hprime2 = vectorScalarExp(h[nprime:], xinv)
-        hprime, _ = VectorECAdd(hprime, hprime2)
+        hprime = foldGenerators(h[:nprime], h[nprime:], x, xinv, workers)
 
         // Compute P' = L^(x^2).P.R^(x^-2)                                    // (31)
         x2 = bn.Mod(bn.Multiply(x, x), ORDER)
         x2inv = bn.ModInverse(x2, ORDER)
-        Pprime = new(p256.P256).ScalarMult(L, x2)
-        Pprime.Multiply(Pprime, P)
-        Pprime.Multiply(Pprime, new(p256.P256).ScalarMult(R, x2inv))
+        Pprime, _ = VectorExp([]*p256.P256{L, P, R}, []*big.Int{x2, big.NewInt(1), x2inv})
 
         // Compute a' = a[:n'].x      + a[n':].x^(-1)                         // (33)
         aprime, _ = VectorScalarMul(a[:nprime], x)
@@ -208,109 +203,165 @@ func computeBipRecursive(a, b []*big.Int, g, h []*p256.P256, u, P *p256.P256, n
         Ls = append(Ls, L)
         Rs = append(Rs, R)
         // recursion computeBipRecursive(g',h',u,P'; a', b')                  // (35)
-        proof = computeBipRecursive(aprime, bprime, gprime, hprime, u, Pprime, nprime, Ls, Rs)
+        proof = computeBipRecursive(t, aprime, bprime, gprime, hprime, u, Pprime, nprime, Ls, Rs, workers)
     }
-    proof.N = n
     return proof
 }
 
 /*
-Verify is responsible for the verification of the Inner Product Proof.
+Verify is responsible for the verification of the Inner Product Proof. The params
+must be computed by the verifier, params.P being the commitment to the vectors a
+and b and params.Cc their inner product.
 */
-func (proof InnerProductProof) Verify() (bool, error) {
+func (proof InnerProductProof) Verify(params InnerProductParams) (bool, error) {
+    return proof.verifyWithTranscript(newInnerProductTranscript(params, params.P), params)
+}
 
+/*
+verifyWithTranscript verifies the Inner Product Proof, deriving the challenges from
+t. The transcript must already determine params.P and params.Cc. Instead of folding
+the generators in every round, the verifier computes the scalars s such that the
+folded generators are g^s and h^(s^-1), and checks (16) with a single multi-scalar
+multiplication:
+g^(a.s).h^(b.s^-1).u^(w.(a.b - c)) = P.prod_j L_j^(x_j^2).R_j^(x_j^-2)
+Neither the proof nor the params are modified, hence the verification can be
+repeated and run concurrently.
+*/
+func (proof InnerProductProof) verifyWithTranscript(t *transcript.Transcript, params InnerProductParams) (bool, error) {
     logn := len(proof.Ls)
-    var (
-        x, xinv, x2, x2inv                   *big.Int
-        ngprime, nhprime, ngprime2, nhprime2 []*p256.P256
-    )
+    n := params.N
+    if err := proof.validate(n); err != nil {
+        return false, err
+    }
+    if int64(len(params.Gg)) != n || int64(len(params.Hh)) != n {
+        return false, errors.New("number of generators does not match the size of the vectors")
+    }
+    if err := params.validate(); err != nil {
+        return false, err
+    }
 
-    gprime := proof.Params.Gg
-    hprime := proof.Params.Hh
-    Pprime := proof.Params.P
-    nprime := proof.N
-    for i := int64(0); i < int64(logn); i++ {
-        nprime = nprime / 2                        // (20)
-        x, _, _ = HashBP(proof.Ls[i], proof.Rs[i]) // (26)
-        xinv = bn.ModInverse(x, ORDER)
-        // Compute g' = g[:n']^(x^-1) * g[n':]^(x)                            // (29)
-        ngprime = vectorScalarExp(gprime[:nprime], xinv)
-        ngprime2 = vectorScalarExp(gprime[nprime:], x)
-        gprime, _ = VectorECAdd(ngprime, ngprime2)
-        // Compute h' = h[:n']^(x)    * h[n':]^(x^-1)                         // (30)
-        nhprime = vectorScalarExp(hprime[:nprime], x)
-        nhprime2 = vectorScalarExp(hprime[nprime:], xinv)
-        hprime, _ = VectorECAdd(nhprime, nhprime2)
-        // Compute P' = L^(x^2).P.R^(x^-2)                                    // (31)
-        x2 = bn.Mod(bn.Multiply(x, x), ORDER)
-        x2inv = bn.ModInverse(x2, ORDER)
-        Pprime.Multiply(Pprime, new(p256.P256).ScalarMult(proof.Ls[i], x2))
-        Pprime.Multiply(Pprime, new(p256.P256).ScalarMult(proof.Rs[i], x2inv))
+    // Fiat-Shamir:
+    // w = Hash(transcript), x = Hash(transcript, L, R) for each round
+    w, xs := proof.challenges(t, params)
+    s := foldingScalars(xs, n)
+
+    // u^(w.(a.b - c))
+    ab := bn.Mod(bn.Multiply(proof.A, proof.B), ORDER)
+    uExp := bn.Mod(bn.Multiply(w, bn.Sub(ab, params.Cc)), ORDER)
+
+    points := make([]*p256.P256, 0, 2*n+int64(2*logn)+2)
+    scalars := make([]*big.Int, 0, 2*n+int64(2*logn)+2)
+    // g^(a.s).h^(b.s^-1), where s_i^-1 = s_(n-1-i)
+    for i := int64(0); i < n; i++ {
+        points = append(points, params.Gg[i])
+        scalars = append(scalars, bn.Mod(bn.Multiply(proof.A, s[i]), ORDER))
+    }
+    for i := int64(0); i < n; i++ {
+        points = append(points, params.Hh[i])
+        scalars = append(scalars, bn.Mod(bn.Multiply(proof.B, s[n-1-i]), ORDER))
+    }
+    // (L^(x^2).R^(x^-2))^-1 for each round                                 // (31)
+    for j := 0; j < logn; j++ {
+        x2 := bn.Mod(bn.Multiply(xs[j], xs[j]), ORDER)
+        x2inv := bn.ModInverse(x2, ORDER)
+        points = append(points, proof.Ls[j], proof.Rs[j])
+        scalars = append(scalars, bn.Sub(ORDER, x2), bn.Sub(ORDER, x2inv))
     }
+    points = append(points, params.Uu, params.P)
+    scalars = append(scalars, uExp, bn.Sub(ORDER, big.NewInt(1)))
 
-    // c == a*b and checks if P = g^a.h^b.u^c                                     // (16)
-    ab := bn.Multiply(proof.A, proof.B)
-    ab = bn.Mod(ab, ORDER)
-    // Compute right hand side
-    rhs := new(p256.P256).ScalarMult(gprime[0], proof.A)
-    hb := new(p256.P256).ScalarMult(hprime[0], proof.B)
-    rhs.Multiply(rhs, hb)
-    rhs.Multiply(rhs, new(p256.P256).ScalarMult(proof.U, ab))
-    // Compute inverse of left hand side
-    nP := Pprime.Neg(Pprime)
-    nP.Multiply(nP, rhs)
-    // If both sides are equal then nP must be zero                               // (17)
-    c := nP.IsZero()
-
-    return c, nil
+    result, err := VectorExp(points, scalars)
+    if err != nil {
+        return false, err
+    }
+    // If both sides are equal then the result must be zero                    // (17)
+    return result.IsZero(), nil
 }
 
 /*
-hashIP is responsible for the computing a Zp element given elements from GT and G1.
+challenges recomputes the Fiat-Shamir challenge w, used for the generator u, and
+the challenge x of each round of the argument (26).
 */
-func hashIP(g, h []*p256.P256, P *p256.P256, c *big.Int, n int64) (*big.Int, error) {
-    digest := sha256.New()
-    digest.Write([]byte(P.String()))
-
-    for i := int64(0); i < n; i++ {
-        digest.Write([]byte(g[i].String()))
-        digest.Write([]byte(h[i].String()))
+func (proof InnerProductProof) challenges(t *transcript.Transcript, params InnerProductParams) (*big.Int, []*big.Int) {
+    innerProductDomainSep(t, params)
+    w := challengeScalar(t, "w")
+    result := make([]*big.Int, len(proof.Ls))
+    for i := range proof.Ls {
+        appendPoint(t, "L", proof.Ls[i])
+        appendPoint(t, "R", proof.Rs[i])
+        result[i] = challengeScalar(t, "x")
     }
+    return w, result
+}
 
-    digest.Write([]byte(c.String()))
-    output := digest.Sum(nil)
-    tmp := output[0:]
-    result, err := byteconversion.FromByteArray(tmp)
-
-    return result, err
+/*
+foldingScalars returns the vector s such that, after all the rounds, g' = g^s and
+h' = h^(s^-1). Namely s_i = prod_j x_j^b(i,j), where b(i,j) is 1 if the bit j of
+i, counting from the most significant one, is set and -1 otherwise.
+*/
+func foldingScalars(x []*big.Int, n int64) []*big.Int {
+    logn := len(x)
+    s := make([]*big.Int, n)
+    s[0] = new(big.Int).SetInt64(1)
+    for j := 0; j < logn; j++ {
+        s[0] = bn.Mod(bn.Multiply(s[0], bn.ModInverse(x[j], ORDER)), ORDER)
+    }
+    for i := int64(1); i < n; i++ {
+        // k is the position of the most significant bit of i
+        k := 0
+        for (i >> uint(k+1)) > 0 {
+            k = k + 1
+        }
+        x2 := bn.Mod(bn.Multiply(x[logn-1-k], x[logn-1-k]), ORDER)
+        s[i] = bn.Mod(bn.Multiply(s[i-(int64(1)<<uint(k))], x2), ORDER)
+    }
+    return s
 }
 
 /*
 commitInnerProduct is responsible for calculating g^a.h^b.
 */
-func commitInnerProduct(g, h []*p256.P256, a, b []*big.Int) *p256.P256 {
+func commitInnerProduct(g, h []*p256.P256, a, b []*big.Int, workers int) *p256.P256 {
     var (
         result *p256.P256
     )
 
-    ga, _ := VectorExp(g, a)
-    hb, _ := VectorExp(h, b)
-    result = new(p256.P256).Multiply(ga, hb)
+    result, _ = parallelVectorExp(workers, concatPoints(g, h), concatScalars(a, b))
     return result
 }
 
 /*
-VectorScalarExp computes a[i]^b for each i.
+foldGenerators computes g[i]^x.h[i]^y for each i, with one multi-scalar
+multiplication per element, split between the workers.
 */
-func vectorScalarExp(a []*p256.P256, b *big.Int) []*p256.P256 {
-    var (
-        result []*p256.P256
-        n      int64
-    )
-    n = int64(len(a))
-    result = make([]*p256.P256, n)
-    for i := int64(0); i < n; i++ {
-        result[i] = new(p256.P256).ScalarMult(a[i], b)
+func foldGenerators(g, h []*p256.P256, x, y *big.Int, workers int) []*p256.P256 {
+    result := make([]*p256.P256, len(g))
+    parallelRange(workers, len(g), func(from, to int) {
+        for i := from; i < to; i++ {
+            result[i], _ = VectorExp([]*p256.P256{g[i], h[i]}, []*big.Int{x, y})
+        }
+    })
+    return result
+}
+
+/*
+concatPoints returns the concatenation of the vectors of points.
+*/
+func concatPoints(vectors ...[]*p256.P256) []*p256.P256 {
+    var result []*p256.P256
+    for _, v := range vectors {
+        result = append(result, v...)
+    }
+    return result
+}
+
+/*
+concatScalars returns the concatenation of the vectors of scalars.
+*/
+func concatScalars(vectors ...[]*big.Int) []*big.Int {
+    var result []*big.Int
+    for _, v := range vectors {
+        result = append(result, v...)
     }
     return result
 }
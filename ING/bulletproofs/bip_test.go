@@ -18,7 +18,10 @@
 package bulletproofs
 
 import (
+    "bytes"
+    "crypto/rand"
     "math/big"
+    "sync"
     "testing"
 )
 
@@ -44,11 +47,54 @@ func TestInnerProduct(t *testing.T) {
     b[1] = new(big.Int).SetInt64(2)
     b[2] = new(big.Int).SetInt64(10)
     b[3] = new(big.Int).SetInt64(7)
-    commit := commitInnerProduct(innerProductParams.Gg, innerProductParams.Hh, a, b)
+    commit := commitInnerProduct(innerProductParams.Gg, innerProductParams.Hh, a, b, 1)
 
     proof, _ := proveInnerProduct(a, b, commit, innerProductParams)
-    ok, _ := proof.Verify()
+    innerProductParams.P = commit
+    ok, _ := proof.Verify(innerProductParams)
     if ok != true {
         t.Errorf("Assert failure: expected true, actual: %t", ok)
     }
 }
+
+/*
+Test that the verification does not modify the proof nor the params, so that it
+can be repeated and run concurrently.
+*/
+func TestInnerProductVerifyRepeatable(t *testing.T) {
+    n := int64(16)
+    a, _ := sampleRandomVector(rand.Reader, n)
+    b, _ := sampleRandomVector(rand.Reader, n)
+    c, _ := ScalarProduct(a, b)
+    params, _ := setupInnerProduct(nil, nil, nil, c, n)
+    commit := commitInnerProduct(params.Gg, params.Hh, a, b, 1)
+    proof, _ := proveInnerProduct(a, b, commit, params)
+    params.P = commit
+    encoded := commit.Bytes()
+
+    var wg sync.WaitGroup
+    results := make([]bool, 8)
+    for i := range results {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            results[i], _ = proof.Verify(params)
+        }(i)
+    }
+    wg.Wait()
+    for i, ok := range results {
+        if !ok {
+            t.Errorf("verification %d failed", i)
+        }
+    }
+    if !bytes.Equal(params.P.Bytes(), encoded) {
+        t.Errorf("verification should not modify the commitment")
+    }
+
+    // The proof does not hold for another inner product.
+    params.Cc = new(big.Int).Add(c, big.NewInt(1))
+    ok, _ := proof.Verify(params)
+    if ok {
+        t.Errorf("proof should not verify for another inner product")
+    }
+}
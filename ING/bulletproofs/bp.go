@@ -18,13 +18,16 @@
 package bulletproofs
 
 import (
-    "crypto/rand"
     "errors"
     "fmt"
+    "io"
     "math"
     "math/big"
 
+    "github.com/ing-bank/zkrp/crypto/group"
     "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
+    "github.com/ing-bank/zkrp/crypto/transcript"
     . "github.com/ing-bank/zkrp/util"
     "github.com/ing-bank/zkrp/util/bn"
 )
@@ -51,7 +54,8 @@ type BulletProofSetupParams struct {
 
 /*
 BulletProofs structure contains the elements that are necessary for the verification
-of the Zero Knowledge Proof.
+of the Zero Knowledge Proof. It only carries the prover messages, the generators
+are part of the verifier's BulletProofSetupParams.
 */
 type BulletProof struct {
     V                 *p256.P256
@@ -63,77 +67,167 @@ type BulletProof struct {
     Mu                *big.Int
     Tprime            *big.Int
     InnerProductProof InnerProductProof
-    Commit            *p256.P256
-    Params            BulletProofSetupParams
 }
 
 /*
-SetupInnerProduct is responsible for computing the common parameters.
-Only works for ranges to 0 to 2^n, where n is a power of 2 and n <= 32
-TODO: allow n > 32 (need uint64 for that).
+Setup is responsible for computing the common parameters for the range [0, b).
+Only works for ranges to 0 to 2^n, where n is a power of 2 and n <= 64. Since b is
+an int64, use SetupBits for n = 64.
 */
 func Setup(b int64) (BulletProofSetupParams, error) {
     if !IsPowerOfTwo(b) {
         return BulletProofSetupParams{}, errors.New("range end is not a power of 2")
     }
+    n := int64(math.Log2(float64(b)))
+    if !IsPowerOfTwo(n) {
+        return BulletProofSetupParams{}, fmt.Errorf("range end is a power of 2, but it's exponent should also be. Exponent: %d", n)
+    }
+    return SetupBits(n)
+}
 
-    params := BulletProofSetupParams{}
-    params.G = new(p256.P256).ScalarBaseMult(new(big.Int).SetInt64(1))
-    params.H, _ = p256.MapToGroup(SEEDH)
-    params.N = int64(math.Log2(float64(b)))
-    if !IsPowerOfTwo(params.N) {
-        return BulletProofSetupParams{}, fmt.Errorf("range end is a power of 2, but it's exponent should also be. Exponent: %d", params.N)
+/*
+SetupBits is responsible for computing the common parameters for the range [0, 2^n),
+where n is a power of 2 and n <= 64.
+*/
+func SetupBits(n int64) (BulletProofSetupParams, error) {
+    if n <= 0 || !IsPowerOfTwo(n) {
+        return BulletProofSetupParams{}, fmt.Errorf("bit-length of the range should be a power of 2: %d", n)
     }
-    if params.N > 32 {
-        return BulletProofSetupParams{}, errors.New("range end can not be greater than 2**32")
+    if n > MAX_RANGE_BITS {
+        return BulletProofSetupParams{}, errors.New("range end can not be greater than 2**64")
     }
-    params.Gg = make([]*p256.P256, params.N)
-    params.Hh = make([]*p256.P256, params.N)
-    for i := int64(0); i < params.N; i++ {
-        params.Gg[i], _ = p256.MapToGroup(SEEDH + "g" + string(i))
-        params.Hh[i], _ = p256.MapToGroup(SEEDH + "h" + string(i))
+
+    var err error
+    params := BulletProofSetupParams{}
+    params.G = generatorG()
+    params.H, err = generatorH()
+    if err != nil {
+        return BulletProofSetupParams{}, err
+    }
+    params.N = n
+    params.Gg, params.Hh, err = vectorGenerators(0, n)
+    if err != nil {
+        return BulletProofSetupParams{}, err
     }
     return params, nil
 }
 
+/*
+Pedersen returns the parameters of the commitments to the secrets, g^secret.h^gamma.
+*/
+func (params BulletProofSetupParams) Pedersen() pedersen.Params {
+    return pedersen.NewP256Params(params.H)
+}
+
+/*
+NewCommitment returns the commitment V to a secret, as carried by the proofs, which is
+computed with the generator H of the setup.
+*/
+func NewCommitment(V *p256.P256) pedersen.Commitment {
+    H, err := generatorH()
+    if err != nil {
+        return pedersen.Commitment{}
+    }
+    return pedersen.NewP256Params(H).NewCommitment(group.NewP256(V))
+}
+
 /*
 Prove computes the ZK rangeproof. The documentation and comments are based on
 eprint version of Bulletproofs papers:
 https://eprint.iacr.org/2017/1066.pdf
 */
-func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error) {
+func Prove(secret *big.Int, params BulletProofSetupParams, opts ...ProverOption) (BulletProof, error) {
+    if secret == nil {
+        return BulletProof{}, errors.New("secret must not be nil")
+    }
+    gammas, err := newProverConfig(opts).sampleBlindings(1)
+    if err != nil {
+        return BulletProof{}, err
+    }
+    return ProveWithCommitment(secret, gammas[0], params, opts...)
+}
+
+/*
+ProveWithCommitment computes the ZK rangeproof for the Pedersen commitment
+V = g^secret.h^gamma, computed with params.Pedersen(), where gamma is the blinding factor
+chosen by whoever issued the commitment. It allows the same committed value to be
+used in many range proofs, which are verified with VerifyCommitment.
+*/
+func ProveWithCommitment(secret, gamma *big.Int, params BulletProofSetupParams, opts ...ProverOption) (BulletProof, error) {
+    if secret == nil || gamma == nil {
+        return BulletProof{}, errors.New("secret and blinding factor must not be nil")
+    }
+    return proveWithTranscript(transcript.New(RANGE_PROOF_LABEL), secret, gamma, params, newProverConfig(opts))
+}
+
+/*
+ProveOpening computes the ZK rangeproof for the commitment opened by opening, which
+must have been computed with the Pedersen parameters of params.
+*/
+func ProveOpening(opening pedersen.Opening, params BulletProofSetupParams, opts ...ProverOption) (BulletProof, error) {
+    if opening.Group != pedersen.P256 {
+        return BulletProof{}, pedersen.ErrGroupMismatch
+    }
+    return ProveWithCommitment(opening.Value, opening.Blinding, params, opts...)
+}
+
+/*
+proveWithTranscript computes the ZK rangeproof for the commitment to secret with
+blinding factor gamma, deriving the challenges from t.
+*/
+func proveWithTranscript(t *transcript.Transcript, secret, gamma *big.Int, params BulletProofSetupParams, cfg proverConfig) (BulletProof, error) {
     var (
         proof BulletProof
     )
+    if int64(len(params.Gg)) != params.N || int64(len(params.Hh)) != params.N {
+        return proof, errors.New("number of generators does not match the bit-length of the range")
+    }
     // ////////////////////////////////////////////////////////////////////////////
     // First phase: page 19
     // ////////////////////////////////////////////////////////////////////////////
 
     // commitment to v and gamma
-    gamma, _ := rand.Int(rand.Reader, ORDER)
+    gamma = bn.Mod(gamma, ORDER)
     V, _ := CommitG1(secret, gamma, params.H)
+    rangeProofDomainSep(t, params, 1)
+    appendPoint(t, "V", V)
+
+    // alpha (43), rho (46), tau1 and tau2 (52), followed by sL and sR (45)
+    rng, err := cfg.nonceReader(t, secret, gamma)
+    if err != nil {
+        return proof, err
+    }
+    nonces, err := sampleScalars(rng, 4)
+    if err != nil {
+        return proof, err
+    }
+    alpha, rho, tau1, tau2 := nonces[0], nonces[1], nonces[2], nonces[3]
 
     // aL, aR and commitment: (A, alpha)
     aL, _ := Decompose(secret, 2, params.N)                                    // (41)
     aR, _ := computeAR(aL)                                                     // (42)
-    alpha, _ := rand.Int(rand.Reader, ORDER)                                   // (43)
-    A := commitVector(aL, aR, alpha, params.H, params.Gg, params.Hh, params.N) // (44)
+    A := commitVector(aL, aR, alpha, params.H, params.Gg, params.Hh, params.N, cfg.workers) // (44)
 
     // sL, sR and commitment: (S, rho)                                     // (45)
-    sL := sampleRandomVector(params.N)
-    sR := sampleRandomVector(params.N)
-    rho, _ := rand.Int(rand.Reader, ORDER)                                      // (46)
-    S := commitVectorBig(sL, sR, rho, params.H, params.Gg, params.Hh, params.N) // (47)
+    sL, err := sampleRandomVector(rng, params.N)
+    if err != nil {
+        return proof, err
+    }
+    sR, err := sampleRandomVector(rng, params.N)
+    if err != nil {
+        return proof, err
+    }
+    S := commitVectorBig(sL, sR, rho, params.H, params.Gg, params.Hh, params.N, cfg.workers) // (47)
 
     // Fiat-Shamir heuristic to compute challenges y and z, corresponds to    (49)
-    y, z, _ := HashBP(A, S)
+    appendPoint(t, "A", A)
+    appendPoint(t, "S", S)
+    y := challengeScalar(t, "y")
+    z := challengeScalar(t, "z")
 
     // ////////////////////////////////////////////////////////////////////////////
     // Second phase: page 20
     // ////////////////////////////////////////////////////////////////////////////
-    tau1, _ := rand.Int(rand.Reader, ORDER) // (52)
-    tau2, _ := rand.Int(rand.Reader, ORDER) // (52)
-
     /*
        The paper does not describe how to compute t1 and t2.
     */
@@ -179,7 +273,9 @@ func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error)
     T2, _ := CommitG1(t2, tau2, params.H) // (53)
 
     // Fiat-Shamir heuristic to compute 'random' challenge x
-    x, _, _ := HashBP(T1, T2)
+    appendPoint(t, "T1", T1)
+    appendPoint(t, "T2", T2)
+    x := challengeScalar(t, "x")
 
     // ////////////////////////////////////////////////////////////////////////////
     // Third phase                                                              //
@@ -212,7 +308,7 @@ func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error)
     mu = bn.Mod(mu, ORDER)
 
     // Inner Product over (g, h', P.h^-mu, tprime)
-    hprime := updateGenerators(params.Hh, y, params.N)
+    hprime := updateGenerators(params.Hh, y, params.N, cfg.workers)
 
     // SetupInnerProduct Inner Product (Section 4.2)
     var setupErr error
@@ -220,8 +316,11 @@ func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error)
     if setupErr != nil {
         return proof, setupErr
     }
-    commit := commitInnerProduct(params.Gg, hprime, bl, br)
-    proofip, _ := proveInnerProduct(bl, br, commit, params.InnerProductParams)
+    appendScalar(t, "taux", taux)
+    appendScalar(t, "mu", mu)
+    appendScalar(t, "t", tprime)
+    commit := commitInnerProduct(params.Gg, hprime, bl, br, cfg.workers)
+    proofip, _ := proveInnerProductWithTranscript(t, bl, br, commit, params.InnerProductParams, cfg.workers)
 
     proof.V = V
     proof.A = A
@@ -232,23 +331,62 @@ func Prove(secret *big.Int, params BulletProofSetupParams) (BulletProof, error)
     proof.Mu = mu
     proof.Tprime = tprime
     proof.InnerProductProof = proofip
-    proof.Commit = commit
-    proof.Params = params
 
     return proof, nil
 }
 
 /*
-Verify returns true if and only if the proof is valid.
+Verify returns true if and only if the proof is valid with respect to params. The
+params must be computed by the verifier, using Setup, and never taken from the prover.
+*/
+func (proof *BulletProof) Verify(params BulletProofSetupParams) (bool, error) {
+    return proof.verifyWithTranscript(transcript.New(RANGE_PROOF_LABEL), params)
+}
+
+/*
+VerifyCommitment returns true if and only if the proof is valid with respect to
+params and proves that the value committed in V belongs to the range. V is the
+commitment expected by the verifier, e.g. taken from a credential, and the
+commitment carried by the proof is ignored.
+*/
+func (proof *BulletProof) VerifyCommitment(C pedersen.Commitment, params BulletProofSetupParams) (bool, error) {
+    if !params.Pedersen().Owns(C) {
+        return false, pedersen.ErrGroupMismatch
+    }
+    V, err := C.P256()
+    if err != nil {
+        return false, err
+    }
+    expected := *proof
+    expected.V = V
+    return expected.Verify(params)
+}
+
+/*
+Commitment returns the commitment V to the secret.
 */
-func (proof *BulletProof) Verify() (bool, error) {
-    params := proof.Params
+func (proof *BulletProof) Commitment() pedersen.Commitment {
+    return NewCommitment(proof.V)
+}
+
+/*
+verifyWithTranscript verifies the proof, deriving the challenges from t.
+*/
+func (proof *BulletProof) verifyWithTranscript(t *transcript.Transcript, params BulletProofSetupParams) (bool, error) {
+    if int64(len(params.Gg)) != params.N || int64(len(params.Hh)) != params.N {
+        return false, errors.New("number of generators does not match the bit-length of the range")
+    }
+    if err := params.validate(1); err != nil {
+        return false, err
+    }
+    if err := proof.validate(params.N); err != nil {
+        return false, err
+    }
     // Recover x, y, z using Fiat-Shamir heuristic
-    x, _, _ := HashBP(proof.T1, proof.T2)
-    y, z, _ := HashBP(proof.A, proof.S)
+    y, z, x := proof.challenges(t, params)
 
     // Switch generators                                                   // (64)
-    hprime := updateGenerators(params.Hh, y, params.N)
+    hprime := updateGenerators(params.Hh, y, params.N, 1)
 
     // ////////////////////////////////////////////////////////////////////////////
     // Check that tprime  = t(x) = t0 + t1x + t2x^2  ----------  Condition (65) //
@@ -263,85 +401,66 @@ func (proof *BulletProof) Verify() (bool, error) {
     x2 := bn.Multiply(x, x)
     x2 = bn.Mod(x2, ORDER)
 
-    rhs := new(p256.P256).ScalarMult(proof.V, z2)
-
     delta := params.delta(y, z)
 
-    gdelta := new(p256.P256).ScalarBaseMult(delta)
-
-    rhs.Multiply(rhs, gdelta)
-
-    T1x := new(p256.P256).ScalarMult(proof.T1, x)
-    T2x2 := new(p256.P256).ScalarMult(proof.T2, x2)
-
-    rhs.Multiply(rhs, T1x)
-    rhs.Multiply(rhs, T2x2)
+    rhs, _ := VectorExp([]*p256.P256{proof.V, params.G, proof.T1, proof.T2}, []*big.Int{z2, delta, x, x2})
 
     // Subtract lhs and rhs and compare with poitn at infinity
     lhs.Neg(lhs)
     rhs.Multiply(rhs, lhs)
     c65 := rhs.IsZero() // Condition (65), page 20, from eprint version
 
-    // Compute P - lhs  #################### Condition (66) ######################
-
-    // S^x
-    Sx := new(p256.P256).ScalarMult(proof.S, x)
-    // A.S^x
-    ASx := new(p256.P256).Add(proof.A, Sx)
+    // Compute P.h^-mu  ################ Conditions (66) and (67) ###############
+    lP := innerProductCommitment(params, proof.A, proof.S, proof.Mu, hprime, x, y, z, 1)
 
-    // g^-z
-    mz := bn.Sub(ORDER, z)
-    vmz, _ := VectorCopy(mz, params.N)
-    gpmz, _ := VectorExp(params.Gg, vmz)
-
-    // z.y^n
-    vz, _ := VectorCopy(z, params.N)
-    vy := powerOf(y, params.N)
-    zyn, _ := VectorMul(vy, vz)
-
-    p2n := powerOf(new(big.Int).SetInt64(2), params.N)
-    zsquared := bn.Multiply(z, z)
-    z22n, _ := VectorScalarMul(p2n, zsquared)
-
-    // z.y^n + z^2.2^n
-    zynz22n, _ := VectorAdd(zyn, z22n)
-
-    lP := new(p256.P256)
-    lP.Add(ASx, gpmz)
+    // Verify Inner Product Proof, i.e. that tprime = < l, r > ######### (68) ####
+    ipParams, errIP := setupInnerProduct(params.H, params.Gg, hprime, proof.Tprime, params.N)
+    if errIP != nil {
+        return false, errIP
+    }
+    ipParams.P = lP
+    ok, _ := proof.InnerProductProof.verifyWithTranscript(t, ipParams)
 
-    // h'^(z.y^n + z^2.2^n)
-    hprimeexp, _ := VectorExp(hprime, zynz22n)
+    result := c65 && ok
 
-    lP.Add(lP, hprimeexp)
+    return result, nil
+}
 
-    // Compute P - rhs  #################### Condition (67) ######################
+/*
+innerProductCommitment computes P.h^-mu = A.S^x.g^-z.h'^(z.y^mn + sum_j z^(2+j).2^n_j).h^-mu
+for m aggregated values of n bits, where 2^n_j is 2^n at the positions [j.n, (j+1).n)
+and 0 elsewhere. With m = 1 it is A.S^x.g^-z.h'^(z.y^n + z^2.2^n).h^-mu. It must be a
+commitment to l and r with respect to g and h', as stated by conditions (66) and
+(67). The inner product argument then proves that t' = < l, r >.
+*/
+func innerProductCommitment(params BulletProofSetupParams, A, S *p256.P256, mu *big.Int, hprime []*p256.P256, x, y, z *big.Int, m int64) *p256.P256 {
+    mn := m * params.N
 
-    // h^mu
-    rP := new(p256.P256).ScalarMult(params.H, proof.Mu)
-    rP.Multiply(rP, proof.Commit)
+    // g^-z
+    vmz, _ := VectorCopy(bn.Sub(ORDER, z), mn)
 
-    // Subtract lhs and rhs and compare with poitn at infinity
-    lP = lP.Neg(lP)
-    rP.Add(rP, lP)
-    c67 := rP.IsZero()
+    // z.y^mn
+    vz, _ := VectorCopy(z, mn)
+    zyn, _ := VectorMul(powerOf(y, mn), vz)
 
-    // Verify Inner Product Proof ################################################
-    ok, _ := proof.InnerProductProof.Verify()
+    // z.y^mn + sum_j z^(2+j).2^n_j
+    exps, _ := VectorAdd(zyn, aggregatePowersOfTwo(z, params.N, m))
 
-    result := c65 && c67 && ok
+    // h^-mu
+    mmu := bn.Mod(bn.Sub(ORDER, mu), ORDER)
 
-    return result, nil
+    // A.S^x.g^-z.h'^exps.h^-mu
+    points := concatPoints([]*p256.P256{A, S, params.H}, params.Gg[:mn], hprime)
+    scalars := concatScalars([]*big.Int{big.NewInt(1), x, mmu}, vmz, exps)
+    lP, _ := VectorExp(points, scalars)
+    return lP
 }
 
 /*
-SampleRandomVector generates a vector composed by random big numbers.
+SampleRandomVector generates a vector composed by random big numbers read from r.
 */
-func sampleRandomVector(N int64) []*big.Int {
-    s := make([]*big.Int, N)
-    for i := int64(0); i < N; i++ {
-        s[i], _ = rand.Int(rand.Reader, ORDER)
-    }
-    return s
+func sampleRandomVector(r io.Reader, N int64) ([]*big.Int, error) {
+    return sampleScalars(r, int(N))
 }
 
 /*
@@ -349,24 +468,20 @@ updateGenerators is responsible for computing generators in the following format
 [h_1, h_2^(y^-1), ..., h_n^(y^(-n+1))], where [h_1, h_2, ..., h_n] is the original
 vector of generators. This method is used both by prover and verifier. After this
 update we have that A is a vector commitments to (aL, aR . y^n). Also S is a vector
-commitment to (sL, sR . y^n).
+commitment to (sL, sR . y^n). The exponentiations are split between the workers.
 */
-func updateGenerators(Hh []*p256.P256, y *big.Int, N int64) []*p256.P256 {
-    var (
-        i int64
-    )
+func updateGenerators(Hh []*p256.P256, y *big.Int, N int64, workers int) []*p256.P256 {
     // Compute h'                                                          // (64)
     hprime := make([]*p256.P256, N)
     // Switch generators
     yinv := bn.ModInverse(y, ORDER)
-    expy := yinv
-    hprime[0] = Hh[0]
-    i = 1
-    for i < N {
-        hprime[i] = new(p256.P256).ScalarMult(Hh[i], expy)
-        expy = bn.Multiply(expy, yinv)
-        i = i + 1
-    }
+    parallelRange(workers, int(N), func(from, to int) {
+        expy := new(big.Int).Exp(yinv, big.NewInt(int64(from)), ORDER)
+        for i := from; i < to; i++ {
+            hprime[i] = new(p256.P256).ScalarMult(Hh[i], expy)
+            expy = bn.Mod(bn.Multiply(expy, yinv), ORDER)
+        }
+    })
     return hprime
 }
 
@@ -387,59 +502,31 @@ func computeAR(x []int64) ([]int64, error) {
     return result, nil
 }
 
-func commitVectorBig(aL, aR []*big.Int, alpha *big.Int, H *p256.P256, g, h []*p256.P256, n int64) *p256.P256 {
+func commitVectorBig(aL, aR []*big.Int, alpha *big.Int, H *p256.P256, g, h []*p256.P256, n int64, workers int) *p256.P256 {
     // Compute h^alpha.vg^aL.vh^aR
-    R := new(p256.P256).ScalarMult(H, alpha)
-    for i := int64(0); i < n; i++ {
-        R.Multiply(R, new(p256.P256).ScalarMult(g[i], aL[i]))
-        R.Multiply(R, new(p256.P256).ScalarMult(h[i], aR[i]))
-    }
+    points := concatPoints([]*p256.P256{H}, g[:n], h[:n])
+    scalars := concatScalars([]*big.Int{alpha}, aL[:n], aR[:n])
+    R, _ := parallelVectorExp(workers, points, scalars)
     return R
 }
 
 /*
 Commitvector computes a commitment to the bit of the secret.
 */
-func commitVector(aL, aR []int64, alpha *big.Int, H *p256.P256, g, h []*p256.P256, n int64) *p256.P256 {
+func commitVector(aL, aR []int64, alpha *big.Int, H *p256.P256, g, h []*p256.P256, n int64, workers int) *p256.P256 {
     // Compute h^alpha.vg^aL.vh^aR
-    R := new(p256.P256).ScalarMult(H, alpha)
+    bL := make([]*big.Int, n)
+    bR := make([]*big.Int, n)
     for i := int64(0); i < n; i++ {
-        gaL := new(p256.P256).ScalarMult(g[i], new(big.Int).SetInt64(aL[i]))
-        haR := new(p256.P256).ScalarMult(h[i], new(big.Int).SetInt64(aR[i]))
-        R.Multiply(R, gaL)
-        R.Multiply(R, haR)
+        bL[i] = new(big.Int).SetInt64(aL[i])
+        bR[i] = new(big.Int).SetInt64(aR[i])
     }
-    return R
+    return commitVectorBig(bL, bR, alpha, H, g, h, n, workers)
 }
 
 /*
 delta(y,z) = (z-z^2) . < 1^n, y^n > - z^3 . < 1^n, 2^n >
 */
 func (params *BulletProofSetupParams) delta(y, z *big.Int) *big.Int {
-    var (
-        result *big.Int
-    )
-    // delta(y,z) = (z-z^2) . < 1^n, y^n > - z^3 . < 1^n, 2^n >
-    z2 := bn.Multiply(z, z)
-    z2 = bn.Mod(z2, ORDER)
-    z3 := bn.Multiply(z2, z)
-    z3 = bn.Mod(z3, ORDER)
-
-    // < 1^n, y^n >
-    v1, _ := VectorCopy(new(big.Int).SetInt64(1), params.N)
-    vy := powerOf(y, params.N)
-    sp1y, _ := ScalarProduct(v1, vy)
-
-    // < 1^n, 2^n >
-    p2n := powerOf(new(big.Int).SetInt64(2), params.N)
-    sp12, _ := ScalarProduct(v1, p2n)
-
-    result = bn.Sub(z, z2)
-    result = bn.Mod(result, ORDER)
-    result = bn.Multiply(result, sp1y)
-    result = bn.Mod(result, ORDER)
-    result = bn.Sub(result, bn.Multiply(z3, sp12))
-    result = bn.Mod(result, ORDER)
-
-    return result
+    return params.aggregateDelta(y, z, 1)
 }
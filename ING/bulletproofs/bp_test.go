@@ -23,6 +23,8 @@ import (
     "math/big"
     "testing"
 
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
     "github.com/stretchr/testify/assert"
 )
 
@@ -87,7 +89,7 @@ func setupRange(t *testing.T, rangeEnd int64) BulletProofSetupParams {
 
 func proveAndVerifyRange(x *big.Int, params BulletProofSetupParams) bool {
     proof, _ := Prove(x, params)
-    ok, _ := proof.Verify()
+    ok, _ := proof.Verify(params)
     return ok
 }
 
@@ -109,9 +111,106 @@ func TestJsonEncodeDecode(t *testing.T) {
 
     assert.Equal(t, proof, decodedProof, "should be equal")
 
-    ok, err := decodedProof.Verify()
+    ok, err := decodedProof.Verify(params)
     if err != nil {
         t.Fatal("verify error:", err)
     }
     assert.True(t, ok, "should verify")
 }
+
+func TestJsonDecodeRejectsParams(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    jsonEncoded, _ := json.Marshal(proof)
+
+    // A malicious prover appends its own generators to the proof.
+    var fields map[string]interface{}
+    _ = json.Unmarshal(jsonEncoded, &fields)
+    fields["Params"] = params
+    jsonEncoded, _ = json.Marshal(fields)
+
+    var decodedProof BulletProof
+    err := json.Unmarshal(jsonEncoded, &decodedProof)
+    assert.Error(t, err, "proof with embedded params should be rejected")
+}
+
+func TestVerifyWithOtherGenerators(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+
+    // The verifier uses its own generators, which differ from the prover's.
+    other, _ := Setup(MAX_RANGE_END)
+    other.H, _ = p256.MapToGroup("NotTheBulletproofsGenerator")
+    ok, _ := proof.Verify(other)
+    assert.False(t, ok, "proof should not verify with different generators")
+}
+
+func TestXWithin64BitRange(t *testing.T) {
+    params, err := SetupBits(64)
+    if err != nil {
+        t.Fatal("setup error:", err)
+    }
+    x := new(big.Int).Lsh(big.NewInt(1), 63)
+    x.Add(x, big.NewInt(5))
+    if proveAndVerifyRange(x, params) != true {
+        t.Errorf("x within 64-bit range should verify successfully")
+    }
+}
+
+func TestXEqualTo64BitRangeEnd(t *testing.T) {
+    params, _ := SetupBits(64)
+    x := new(big.Int).Lsh(big.NewInt(1), 64)
+    if proveAndVerifyRange(x, params) == true {
+        t.Errorf("x equal to range end should not verify")
+    }
+}
+
+func TestSetupBitsInvalid(t *testing.T) {
+    _, err := SetupBits(128)
+    assert.Error(t, err, "range greater than 2^64 should be rejected")
+    _, err = SetupBits(24)
+    assert.Error(t, err, "bit-length that is not a power of 2 should be rejected")
+}
+
+func TestProveWithCommitment(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    secret := new(big.Int).SetInt64(40)
+    gamma := new(big.Int).SetInt64(123456789)
+    // The commitment is issued beforehand, e.g. as part of a credential.
+    V, opening := params.Pedersen().Commit(secret, gamma)
+
+    // The same commitment backs several range proofs.
+    for i := 0; i < 2; i++ {
+        proof, err := ProveWithCommitment(secret, gamma, params)
+        assert.NoError(t, err)
+        ok, err := proof.VerifyCommitment(V, params)
+        assert.NoError(t, err)
+        assert.True(t, ok, "proof should verify against the issued commitment")
+    }
+
+    proof, err := ProveOpening(opening, params)
+    assert.NoError(t, err)
+    assert.True(t, V.Equal(proof.Commitment()))
+    ok, _ := proof.VerifyCommitment(V, params)
+    assert.True(t, ok, "proof of the opening should verify against the issued commitment")
+}
+
+func TestVerifyCommitmentWrongCommitment(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    secret := new(big.Int).SetInt64(40)
+    gamma := new(big.Int).SetInt64(123456789)
+    proof, _ := ProveWithCommitment(secret, gamma, params)
+
+    other, _ := params.Pedersen().Commit(new(big.Int).SetInt64(41), gamma)
+    ok, _ := proof.VerifyCommitment(other, params)
+    assert.False(t, ok, "proof should not verify against another commitment")
+
+    // A proof about a fresh commitment to the same value must be rejected as well.
+    V, _ := params.Pedersen().Commit(secret, gamma)
+    fresh, _ := Prove(secret, params)
+    ok, _ = fresh.VerifyCommitment(V, params)
+    assert.False(t, ok, "proof should not verify against a commitment with another blinding factor")
+
+    _, err := proof.VerifyCommitment(pedersen.Commitment{}, params)
+    assert.Error(t, err, "empty commitment should be rejected")
+}
@@ -18,24 +18,35 @@
 package bulletproofs
 
 import (
+    "errors"
     "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+    . "github.com/ing-bank/zkrp/util"
 )
 
 /*
 bprp structure contains 2 BulletProofs in order to allow computation of
-generic Range Proofs, for any interval [A, B).
+generic Range Proofs, for any interval [A, B) such that B - A <= 2^N.
 */
 type bprp struct {
-    A   int64
-    B   int64
+    A   *big.Int
+    B   *big.Int
+    N   int64
     BP1 BulletProofSetupParams
     BP2 BulletProofSetupParams
 }
 
 /*
-ProofBPRP stores the generic ZKRP.
+ProofBPRP stores the generic ZKRP. Both BulletProofs concern the value committed in
+C = g^secret.h^gamma: P1 proves that C.g^(2^N - B) commits to a value in [0, 2^N) and
+P2 that C.g^(-A) does. Their commitments V are not part of the proof, the verifier
+derives them from C.
 */
 type ProofBPRP struct {
+    C  *p256.P256
     P1 BulletProof
     P2 BulletProof
 }
@@ -45,15 +56,36 @@ SetupGeneric is responsible for calling the Setup algorithm for each
 BulletProof.
 */
 func SetupGeneric(a, b int64) (*bprp, error) {
+    return SetupGenericBig(new(big.Int).SetInt64(a), new(big.Int).SetInt64(b))
+}
+
+/*
+SetupGenericBig is equal to SetupGeneric, but accepts arbitrary bounds for the interval
+[a, b). The bit-length N of both BulletProofs is 32, or 64 when b - a is greater than
+2^32. Intervals wider than 2^64 are not supported.
+*/
+func SetupGenericBig(a, b *big.Int) (*bprp, error) {
+    width := new(big.Int).Sub(b, a)
+    if width.Sign() <= 0 {
+        return nil, errors.New("range start must be less than range end")
+    }
+    n := int64(MAX_RANGE_END_EXPONENT)
+    if width.Cmp(new(big.Int).SetInt64(MAX_RANGE_END)) > 0 {
+        n = MAX_RANGE_BITS
+    }
+    if width.Cmp(new(big.Int).Lsh(big.NewInt(1), uint(n))) > 0 {
+        return nil, errors.New("range width can not be greater than 2**64")
+    }
     params := new(bprp)
-    params.A = a
-    params.B = b
+    params.A = new(big.Int).Set(a)
+    params.B = new(big.Int).Set(b)
+    params.N = n
     var errBp1, errBp2 error
-    params.BP1, errBp1 = Setup(MAX_RANGE_END)
+    params.BP1, errBp1 = SetupBits(n)
     if errBp1 != nil {
         return nil, errBp1
     }
-    params.BP2, errBp2 = Setup(MAX_RANGE_END)
+    params.BP2, errBp2 = SetupBits(n)
     if errBp2 != nil {
         return nil, errBp2
     }
@@ -66,42 +98,138 @@ allow generic intervals in the format [A, B) it is necessary to use 2
 BulletProofs, as explained in Section 4.3 from the following paper:
 https://infoscience.epfl.ch/record/128718/files/CCS08.pdf
 */
-func ProveGeneric(secret *big.Int, params *bprp) (ProofBPRP, error) {
+func ProveGeneric(secret *big.Int, params *bprp, opts ...ProverOption) (ProofBPRP, error) {
+    if secret == nil {
+        return ProofBPRP{}, errors.New("secret must not be nil")
+    }
+    gammas, err := newProverConfig(opts).sampleBlindings(1)
+    if err != nil {
+        return ProofBPRP{}, err
+    }
+    return ProveGenericWithCommitment(secret, gammas[0], params, opts...)
+}
+
+/*
+ProveGenericOpening computes the generic ZKRP for the commitment opened by opening,
+which must have been computed with the Pedersen parameters of params.
+*/
+func ProveGenericOpening(opening pedersen.Opening, params *bprp, opts ...ProverOption) (ProofBPRP, error) {
+    if opening.Group != pedersen.P256 {
+        return ProofBPRP{}, pedersen.ErrGroupMismatch
+    }
+    return ProveGenericWithCommitment(opening.Value, opening.Blinding, params, opts...)
+}
+
+/*
+ProveGenericWithCommitment computes the generic ZKRP for the Pedersen commitment
+C = g^secret.h^gamma, computed with params.Pedersen(). It is verified with VerifyCommitment
+when C is issued to the verifier beforehand.
+*/
+func ProveGenericWithCommitment(secret, gamma *big.Int, params *bprp, opts ...ProverOption) (ProofBPRP, error) {
     var proof ProofBPRP
+    cfg := newProverConfig(opts)
+    if secret == nil || gamma == nil {
+        return proof, errors.New("secret and blinding factor must not be nil")
+    }
+    gamma = new(big.Int).Mod(gamma, ORDER)
+    C, _ := CommitG1(secret, gamma, params.BP1.H)
 
-    // x - b + 2^N
-    p2 := new(big.Int).SetInt64(MAX_RANGE_END)
-    xb := new(big.Int).Sub(secret, new(big.Int).SetInt64(params.B))
-    xb.Add(xb, p2)
+    // Both proofs share a transcript, which starts with the interval and the
+    // commitment, so that they can not be taken from different generic proofs.
+    t := transcript.New(GENERIC_RANGE_PROOF_LABEL)
+    genericDomainSep(t, params, C)
 
-    var err1 error
-    proof.P1, err1 = Prove(xb, params.BP1)
-    if err1 != nil {
-        return proof, err1
+    // x - b + 2^N, committed in C.g^(2^N - b) with the same blinding factor
+    p2 := new(big.Int).Lsh(big.NewInt(1), uint(params.N))
+    xb := new(big.Int).Sub(secret, params.B)
+    xb.Add(xb, p2)
+    var err error
+    proof.P1, err = proveWithTranscript(t, xb, gamma, params.BP1, cfg)
+    if err != nil {
+        return proof, err
     }
 
-    xa := new(big.Int).Sub(secret, new(big.Int).SetInt64(params.A))
-    var err2 error
-    proof.P2, err2 = Prove(xa, params.BP2)
-    if err2 != nil {
-        return proof, err2
+    // x - a, committed in C.g^(-a)
+    xa := new(big.Int).Sub(secret, params.A)
+    proof.P2, err = proveWithTranscript(t, xa, gamma, params.BP2, cfg)
+    if err != nil {
+        return proof, err
     }
 
+    proof.C = C
+    proof.P1.V = nil
+    proof.P2.V = nil
     return proof, nil
 }
 
 /*
-Verify call the Verification algorithm for each BulletProof argument.
+Verify call the Verification algorithm for each BulletProof argument, for the
+commitment C carried by the proof. The params must be computed by the verifier, using
+SetupGeneric, for the interval [A, B) the verifier expects.
 */
-func (proof ProofBPRP) Verify() (bool, error) {
-    ok1, err1 := proof.P1.Verify()
+func (proof ProofBPRP) Verify(params *bprp) (bool, error) {
+    if err := checkPoints(proof.C); err != nil {
+        return false, err
+    }
+    t := transcript.New(GENERIC_RANGE_PROOF_LABEL)
+    genericDomainSep(t, params, proof.C)
+
+    // The commitments of both BulletProofs are derived from C, which binds them
+    // to the same secret and to the interval.
+    p2 := new(big.Int).Lsh(big.NewInt(1), uint(params.N))
+    P1, P2 := proof.P1, proof.P2
+    P1.V = shiftCommitment(proof.C, new(big.Int).Sub(p2, params.B))
+    P2.V = shiftCommitment(proof.C, new(big.Int).Neg(params.A))
+
+    ok1, err1 := P1.verifyWithTranscript(t, params.BP1)
     if !ok1 {
         return false, err1
     }
-    ok2, err2 := proof.P2.Verify()
+    ok2, err2 := P2.verifyWithTranscript(t, params.BP2)
     if !ok2 {
         return false, err2
     }
 
     return ok1 && ok2, nil
 }
+
+/*
+VerifyCommitment returns true if and only if the proof is valid with respect to
+params and proves that the value committed in C belongs to [A, B). C is the
+commitment expected by the verifier, and the commitment carried by the proof is
+ignored.
+*/
+func (proof ProofBPRP) VerifyCommitment(C pedersen.Commitment, params *bprp) (bool, error) {
+    if !params.Pedersen().Owns(C) {
+        return false, pedersen.ErrGroupMismatch
+    }
+    point, err := C.P256()
+    if err != nil {
+        return false, err
+    }
+    proof.C = point
+    return proof.Verify(params)
+}
+
+/*
+Commitment returns the commitment C to the secret.
+*/
+func (proof ProofBPRP) Commitment() pedersen.Commitment {
+    return NewCommitment(proof.C)
+}
+
+/*
+Pedersen returns the parameters of the commitments to the secrets, g^secret.h^gamma.
+*/
+func (params *bprp) Pedersen() pedersen.Params {
+    return params.BP1.Pedersen()
+}
+
+/*
+shiftCommitment returns C.g^k, which commits to the value of C plus k with the same
+blinding factor.
+*/
+func shiftCommitment(C *p256.P256, k *big.Int) *p256.P256 {
+    gk := new(p256.P256).ScalarBaseMult(k)
+    return new(p256.P256).Multiply(C, gk)
+}
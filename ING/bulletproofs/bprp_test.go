@@ -22,6 +22,8 @@ import (
     "math/big"
     "testing"
 
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
     "github.com/stretchr/testify/assert"
 )
 
@@ -67,7 +69,7 @@ func setupProveVerify18To200(t *testing.T, secret int) bool {
         t.Errorf(errProve.Error())
         t.FailNow()
     }
-    ok, errVerify := proof.Verify()
+    ok, errVerify := proof.Verify(params)
     if errVerify != nil {
         t.Errorf(errVerify.Error())
         t.FailNow()
@@ -110,10 +112,100 @@ func TestJsonEncodeDecodeBPRP(t *testing.T) {
     assert.Equal(t, proof, decodedProof, "should be equal")
 
     // Verify the proof
-    ok, errVerify := proof.Verify()
+    ok, errVerify := decodedProof.Verify(params)
     if errVerify != nil {
         t.Errorf(errVerify.Error())
         t.FailNow()
     }
     assert.True(t, ok, "should verify")
 }
+
+func TestXWithinBigGenericRange(t *testing.T) {
+    // One ether and one ether plus 2^40 wei
+    a, _ := new(big.Int).SetString("1000000000000000000", 10)
+    b := new(big.Int).Add(a, new(big.Int).Lsh(big.NewInt(1), 40))
+    params, err := SetupGenericBig(a, b)
+    if err != nil {
+        t.Fatal("setup error:", err)
+    }
+    assert.Equal(t, int64(64), params.N, "wide intervals need 64 bits")
+
+    proof, _ := ProveGeneric(new(big.Int).Add(a, big.NewInt(123456789)), params)
+    ok, _ := proof.Verify(params)
+    assert.True(t, ok, "secret within range should verify successfully")
+
+    proof, _ = ProveGeneric(b, params)
+    ok, _ = proof.Verify(params)
+    assert.False(t, ok, "secret equal to range end should fail verification")
+}
+
+func TestSetupGenericBigInvalid(t *testing.T) {
+    _, err := SetupGenericBig(big.NewInt(200), big.NewInt(18))
+    assert.Error(t, err, "empty interval should be rejected")
+
+    tooWide := new(big.Int).Lsh(big.NewInt(1), 64)
+    tooWide.Add(tooWide, big.NewInt(1))
+    _, err = SetupGenericBig(big.NewInt(0), tooWide)
+    assert.Error(t, err, "interval wider than 2^64 should be rejected")
+}
+
+func TestVerifyGenericOtherInterval(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    proof, _ := ProveGeneric(new(big.Int).SetInt64(40), params)
+
+    // The verifier checks the interval it expects, not the one of the prover.
+    for _, bounds := range [][2]int64{{41, 200}, {18, 40}, {17, 200}, {18, 201}} {
+        other, _ := SetupGeneric(bounds[0], bounds[1])
+        ok, _ := proof.Verify(other)
+        assert.False(t, ok, "proof should not verify for the interval %v", bounds)
+    }
+}
+
+func TestProveGenericWithCommitment(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    secret, gamma := big.NewInt(40), big.NewInt(12345)
+    C, opening := params.Pedersen().Commit(secret, gamma)
+
+    proof, err := ProveGenericWithCommitment(secret, gamma, params)
+    assert.NoError(t, err)
+    assert.True(t, C.Equal(proof.Commitment()))
+    ok, _ := proof.VerifyCommitment(C, params)
+    assert.True(t, ok, "proof should verify for the issued commitment")
+
+    proof, err = ProveGenericOpening(opening, params)
+    assert.NoError(t, err)
+    ok, _ = proof.VerifyCommitment(C, params)
+    assert.True(t, ok, "proof of the opening should verify for the issued commitment")
+
+    // Commitments are homomorphic: C.g^1 commits to 41 with the same blinding factor.
+    one, oneOpening := params.Pedersen().Commit(big.NewInt(1), big.NewInt(0))
+    shifted, _ := C.Add(one)
+    shiftedOpening, _ := opening.Add(oneOpening)
+    proof, _ = ProveGenericOpening(shiftedOpening, params)
+    ok, _ = proof.VerifyCommitment(shifted, params)
+    assert.True(t, ok, "proof should verify for the sum of commitments")
+
+    other, _ := params.Pedersen().Commit(big.NewInt(41), big.NewInt(1))
+    ok, _ = proof.VerifyCommitment(other, params)
+    assert.False(t, ok, "proof should not verify for another commitment")
+
+    ok, err = proof.VerifyCommitment(pedersen.Commitment{}, params)
+    assert.Error(t, err)
+    assert.False(t, ok)
+}
+
+func TestVerifyGenericDifferentBlindingFactors(t *testing.T) {
+    // Halves committing to the same value with unrelated blinding factors do not
+    // open C.g^(2^N - B) and C.g^(-A), so they are rejected.
+    params, _ := SetupGeneric(18, 200)
+    proof, _ := ProveGenericWithCommitment(big.NewInt(40), big.NewInt(1), params)
+    other, _ := ProveGenericWithCommitment(big.NewInt(40), big.NewInt(2), params)
+    ok, _ := ProofBPRP{C: proof.C, P1: proof.P1, P2: other.P2}.Verify(params)
+    assert.False(t, ok, "halves with different blinding factors should not verify")
+
+    ok, err := ProofBPRP{C: nil, P1: proof.P1, P2: proof.P2}.Verify(params)
+    assert.Error(t, err)
+    assert.False(t, ok)
+    ok, _ = ProofBPRP{C: new(p256.P256).SetInfinity(), P1: proof.P1, P2: proof.P2}.Verify(params)
+    assert.False(t, ok)
+}
@@ -0,0 +1,790 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the canonical encodings of the proofs. The binary encoding starts
+with the version and the type of the encoded proof, followed by the prover messages:
+points use the 33 bytes compressed encoding and scalars 32 bytes in big-endian order.
+The JSON encoding contains the same elements, as hexadecimal strings.
+*/
+
+package bulletproofs
+
+import (
+    "bytes"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+// ENCODING_VERSION is the version of the binary and JSON encodings of the proofs.
+// Version 1 proofs derive their challenges without a transcript, and version 2 generic
+// proofs are not bound to a single commitment, so both are rejected.
+const ENCODING_VERSION = 3
+
+// SCALAR_SIZE is the size in bytes of an encoded scalar.
+const SCALAR_SIZE = 32
+
+// Type tags used in the header of the binary encoding.
+const (
+    tagBulletProof          = 1
+    tagInnerProductProof    = 2
+    tagProofBPRP            = 3
+    tagAggregateBulletProof = 4
+    tagBitCommitment        = 5
+    tagBitChallenge         = 6
+    tagPolyCommitment       = 7
+    tagPolyChallenge        = 8
+    tagProofShare           = 9
+)
+
+var (
+    ErrUnsupportedVersion = errors.New("unsupported proof encoding version")
+    ErrInvalidProofType   = errors.New("encoded data does not contain the expected proof type")
+    ErrInvalidScalar      = errors.New("scalar is not lower than the order of the curve")
+    ErrTrailingData       = errors.New("unexpected data after the encoded proof")
+    ErrTruncatedData      = errors.New("encoded proof is truncated")
+)
+
+/*
+MarshalBinary returns the canonical binary encoding of the proof.
+*/
+func (proof BulletProof) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    buffer.Write([]byte{ENCODING_VERSION, tagBulletProof})
+    err := proof.writeTo(&buffer)
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the canonical binary encoding of the proof, validating every
+point and scalar.
+*/
+func (proof *BulletProof) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagBulletProof)
+    decoded := d.bulletProof()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *proof = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the canonical binary encoding of the proof.
+*/
+func (proof InnerProductProof) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    buffer.Write([]byte{ENCODING_VERSION, tagInnerProductProof})
+    err := proof.writeTo(&buffer)
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the canonical binary encoding of the proof, validating every
+point and scalar.
+*/
+func (proof *InnerProductProof) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagInnerProductProof)
+    decoded := d.innerProductProof()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *proof = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the canonical binary encoding of the proof.
+*/
+func (proof ProofBPRP) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    buffer.Write([]byte{ENCODING_VERSION, tagProofBPRP})
+    writePoints(&buffer, proof.C)
+    err := proof.P1.writeMessagesTo(&buffer)
+    if err != nil {
+        return nil, err
+    }
+    err = proof.P2.writeMessagesTo(&buffer)
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the canonical binary encoding of the proof, validating every
+point and scalar.
+*/
+func (proof *ProofBPRP) UnmarshalBinary(data []byte) error {
+    var decoded ProofBPRP
+    d := newDecoder(data, tagProofBPRP)
+    decoded.C = d.point()
+    decoded.P1 = d.bulletProofMessages()
+    decoded.P2 = d.bulletProofMessages()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *proof = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the canonical binary encoding of the proof.
+*/
+func (proof AggregateBulletProof) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    if len(proof.V) == 0 || len(proof.V) > 255 {
+        return nil, errors.New("number of commitments can not be encoded")
+    }
+    buffer.Write([]byte{ENCODING_VERSION, tagAggregateBulletProof, byte(len(proof.V))})
+    for _, V := range proof.V {
+        buffer.Write(V.Bytes())
+    }
+    writePoints(&buffer, proof.A, proof.S, proof.T1, proof.T2)
+    writeScalars(&buffer, proof.Taux, proof.Mu, proof.Tprime)
+    err := proof.InnerProductProof.writeTo(&buffer)
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the canonical binary encoding of the proof, validating every
+point and scalar.
+*/
+func (proof *AggregateBulletProof) UnmarshalBinary(data []byte) error {
+    var decoded AggregateBulletProof
+    d := newDecoder(data, tagAggregateBulletProof)
+    m := d.readByte()
+    if d.err == nil && m == 0 {
+        return errors.New("number of commitments must be greater than zero")
+    }
+    decoded.V = make([]*p256.P256, m)
+    for i := range decoded.V {
+        decoded.V[i] = d.point()
+    }
+    decoded.A = d.point()
+    decoded.S = d.point()
+    decoded.T1 = d.point()
+    decoded.T2 = d.point()
+    decoded.Taux = d.scalar()
+    decoded.Mu = d.scalar()
+    decoded.Tprime = d.scalar()
+    decoded.InnerProductProof = d.innerProductProof()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *proof = decoded
+    return nil
+}
+
+/*
+innerProductProofJSON is the JSON encoding of InnerProductProof, with hexadecimal
+points and scalars.
+*/
+type innerProductProofJSON struct {
+    Ls []string
+    Rs []string
+    A  string
+    B  string
+}
+
+/*
+bulletProofJSON is the JSON encoding of BulletProof, with hexadecimal points and
+scalars.
+*/
+type bulletProofJSON struct {
+    V string
+    bulletProofMessagesJSON
+}
+
+/*
+bulletProofMessagesJSON is the JSON encoding of the messages of BulletProof that
+follow the commitment V, which is derived by the verifier in the generic proofs.
+*/
+type bulletProofMessagesJSON struct {
+    A                 string
+    S                 string
+    T1                string
+    T2                string
+    Taux              string
+    Mu                string
+    Tprime            string
+    InnerProductProof innerProductProofJSON
+}
+
+type aggregateBulletProofJSON struct {
+    V                 []string
+    A                 string
+    S                 string
+    T1                string
+    T2                string
+    Taux              string
+    Mu                string
+    Tprime            string
+    InnerProductProof innerProductProofJSON
+}
+
+/*
+MarshalJSON returns the compact JSON encoding of the proof.
+*/
+func (proof BulletProof) MarshalJSON() ([]byte, error) {
+    return json.Marshal(struct {
+        Version int
+        bulletProofJSON
+    }{ENCODING_VERSION, proof.toJSON()})
+}
+
+/*
+UnmarshalJSON decodes the compact JSON encoding of the proof, refusing any field that
+is not a prover message. In particular proofs that embed setup parameters are
+rejected, because the verifier must never use generators chosen by the prover.
+*/
+func (proof *BulletProof) UnmarshalJSON(data []byte) error {
+    var decoded struct {
+        Version int
+        bulletProofJSON
+    }
+    err := checkVersion(data)
+    if err != nil {
+        return err
+    }
+    err = decodeStrict(data, &decoded)
+    if err != nil {
+        return err
+    }
+    return proof.fromJSON(decoded.bulletProofJSON)
+}
+
+/*
+MarshalJSON returns the compact JSON encoding of the proof.
+*/
+func (proof InnerProductProof) MarshalJSON() ([]byte, error) {
+    return json.Marshal(struct {
+        Version int
+        innerProductProofJSON
+    }{ENCODING_VERSION, proof.toJSON()})
+}
+
+/*
+UnmarshalJSON decodes the compact JSON encoding of the proof, refusing any field that
+is not a prover message.
+*/
+func (proof *InnerProductProof) UnmarshalJSON(data []byte) error {
+    var decoded struct {
+        Version int
+        innerProductProofJSON
+    }
+    err := checkVersion(data)
+    if err != nil {
+        return err
+    }
+    err = decodeStrict(data, &decoded)
+    if err != nil {
+        return err
+    }
+    return proof.fromJSON(decoded.innerProductProofJSON)
+}
+
+/*
+MarshalJSON returns the compact JSON encoding of the proof.
+*/
+func (proof ProofBPRP) MarshalJSON() ([]byte, error) {
+    return json.Marshal(struct {
+        Version int
+        C       string
+        P1      bulletProofMessagesJSON
+        P2      bulletProofMessagesJSON
+    }{ENCODING_VERSION, encodePoint(proof.C), proof.P1.messagesToJSON(), proof.P2.messagesToJSON()})
+}
+
+/*
+UnmarshalJSON decodes the compact JSON encoding of the proof, refusing any field that
+is not a prover message.
+*/
+func (proof *ProofBPRP) UnmarshalJSON(data []byte) error {
+    var decoded struct {
+        Version int
+        C       string
+        P1      bulletProofMessagesJSON
+        P2      bulletProofMessagesJSON
+    }
+    err := checkVersion(data)
+    if err != nil {
+        return err
+    }
+    err = decodeStrict(data, &decoded)
+    if err != nil {
+        return err
+    }
+    var result ProofBPRP
+    result.C, err = decodePoint(decoded.C)
+    if err != nil {
+        return err
+    }
+    err = result.P1.messagesFromJSON(decoded.P1)
+    if err != nil {
+        return err
+    }
+    err = result.P2.messagesFromJSON(decoded.P2)
+    if err != nil {
+        return err
+    }
+    *proof = result
+    return nil
+}
+
+/*
+MarshalJSON returns the compact JSON encoding of the proof.
+*/
+func (proof AggregateBulletProof) MarshalJSON() ([]byte, error) {
+    encoded := aggregateBulletProofJSON{
+        V:                 make([]string, len(proof.V)),
+        A:                 encodePoint(proof.A),
+        S:                 encodePoint(proof.S),
+        T1:                encodePoint(proof.T1),
+        T2:                encodePoint(proof.T2),
+        Taux:              encodeScalar(proof.Taux),
+        Mu:                encodeScalar(proof.Mu),
+        Tprime:            encodeScalar(proof.Tprime),
+        InnerProductProof: proof.InnerProductProof.toJSON(),
+    }
+    for i, V := range proof.V {
+        encoded.V[i] = encodePoint(V)
+    }
+    return json.Marshal(struct {
+        Version int
+        aggregateBulletProofJSON
+    }{ENCODING_VERSION, encoded})
+}
+
+/*
+UnmarshalJSON decodes the compact JSON encoding of the proof, refusing any field that
+is not a prover message.
+*/
+func (proof *AggregateBulletProof) UnmarshalJSON(data []byte) error {
+    var (
+        decoded struct {
+            Version int
+            aggregateBulletProofJSON
+        }
+        result AggregateBulletProof
+    )
+    err := checkVersion(data)
+    if err != nil {
+        return err
+    }
+    err = decodeStrict(data, &decoded)
+    if err != nil {
+        return err
+    }
+    encoded := decoded.aggregateBulletProofJSON
+    if len(encoded.V) == 0 {
+        return errors.New("number of commitments must be greater than zero")
+    }
+    result.V = make([]*p256.P256, len(encoded.V))
+    for i := range encoded.V {
+        result.V[i], err = decodePoint(encoded.V[i])
+        if err != nil {
+            return err
+        }
+    }
+    points := []*string{&encoded.A, &encoded.S, &encoded.T1, &encoded.T2}
+    targets := []**p256.P256{&result.A, &result.S, &result.T1, &result.T2}
+    for i := range points {
+        *targets[i], err = decodePoint(*points[i])
+        if err != nil {
+            return err
+        }
+    }
+    result.Taux, err = decodeScalar(encoded.Taux)
+    if err != nil {
+        return err
+    }
+    result.Mu, err = decodeScalar(encoded.Mu)
+    if err != nil {
+        return err
+    }
+    result.Tprime, err = decodeScalar(encoded.Tprime)
+    if err != nil {
+        return err
+    }
+    err = result.InnerProductProof.fromJSON(encoded.InnerProductProof)
+    if err != nil {
+        return err
+    }
+    *proof = result
+    return nil
+}
+
+/*
+checkVersion reads the version of a JSON encoded proof before decoding it, so that
+proofs in older formats, including the ones without version, are reported as such.
+*/
+func checkVersion(data []byte) error {
+    var header struct {
+        Version int
+    }
+    err := json.Unmarshal(data, &header)
+    if err != nil {
+        return fmt.Errorf("invalid proof: %v", err)
+    }
+    if header.Version != ENCODING_VERSION {
+        return ErrUnsupportedVersion
+    }
+    return nil
+}
+
+func (proof BulletProof) writeTo(buffer *bytes.Buffer) error {
+    writePoints(buffer, proof.V)
+    return proof.writeMessagesTo(buffer)
+}
+
+func (proof BulletProof) writeMessagesTo(buffer *bytes.Buffer) error {
+    writePoints(buffer, proof.A, proof.S, proof.T1, proof.T2)
+    writeScalars(buffer, proof.Taux, proof.Mu, proof.Tprime)
+    return proof.InnerProductProof.writeTo(buffer)
+}
+
+func (proof InnerProductProof) writeTo(buffer *bytes.Buffer) error {
+    if len(proof.Ls) != len(proof.Rs) {
+        return errors.New("number of L and R elements must be equal")
+    }
+    if len(proof.Ls) > 255 {
+        return errors.New("number of rounds can not be encoded")
+    }
+    buffer.WriteByte(byte(len(proof.Ls)))
+    for i := range proof.Ls {
+        writePoints(buffer, proof.Ls[i], proof.Rs[i])
+    }
+    writeScalars(buffer, proof.A, proof.B)
+    return nil
+}
+
+func (proof BulletProof) toJSON() bulletProofJSON {
+    return bulletProofJSON{
+        V:                       encodePoint(proof.V),
+        bulletProofMessagesJSON: proof.messagesToJSON(),
+    }
+}
+
+func (proof BulletProof) messagesToJSON() bulletProofMessagesJSON {
+    return bulletProofMessagesJSON{
+        A:                 encodePoint(proof.A),
+        S:                 encodePoint(proof.S),
+        T1:                encodePoint(proof.T1),
+        T2:                encodePoint(proof.T2),
+        Taux:              encodeScalar(proof.Taux),
+        Mu:                encodeScalar(proof.Mu),
+        Tprime:            encodeScalar(proof.Tprime),
+        InnerProductProof: proof.InnerProductProof.toJSON(),
+    }
+}
+
+func (proof *BulletProof) fromJSON(encoded bulletProofJSON) error {
+    V, err := decodePoint(encoded.V)
+    if err != nil {
+        return err
+    }
+    err = proof.messagesFromJSON(encoded.bulletProofMessagesJSON)
+    if err != nil {
+        return err
+    }
+    proof.V = V
+    return nil
+}
+
+func (proof *BulletProof) messagesFromJSON(encoded bulletProofMessagesJSON) error {
+    var (
+        result BulletProof
+        err    error
+    )
+    points := []string{encoded.A, encoded.S, encoded.T1, encoded.T2}
+    targets := []**p256.P256{&result.A, &result.S, &result.T1, &result.T2}
+    for i := range points {
+        *targets[i], err = decodePoint(points[i])
+        if err != nil {
+            return err
+        }
+    }
+    scalars := []string{encoded.Taux, encoded.Mu, encoded.Tprime}
+    scalarTargets := []**big.Int{&result.Taux, &result.Mu, &result.Tprime}
+    for i := range scalars {
+        *scalarTargets[i], err = decodeScalar(scalars[i])
+        if err != nil {
+            return err
+        }
+    }
+    err = result.InnerProductProof.fromJSON(encoded.InnerProductProof)
+    if err != nil {
+        return err
+    }
+    *proof = result
+    return nil
+}
+
+func (proof InnerProductProof) toJSON() innerProductProofJSON {
+    encoded := innerProductProofJSON{
+        Ls: make([]string, len(proof.Ls)),
+        Rs: make([]string, len(proof.Rs)),
+        A:  encodeScalar(proof.A),
+        B:  encodeScalar(proof.B),
+    }
+    for i := range proof.Ls {
+        encoded.Ls[i] = encodePoint(proof.Ls[i])
+    }
+    for i := range proof.Rs {
+        encoded.Rs[i] = encodePoint(proof.Rs[i])
+    }
+    return encoded
+}
+
+func (proof *InnerProductProof) fromJSON(encoded innerProductProofJSON) error {
+    var (
+        result InnerProductProof
+        err    error
+    )
+    if len(encoded.Ls) != len(encoded.Rs) {
+        return errors.New("number of L and R elements must be equal")
+    }
+    result.Ls = make([]*p256.P256, len(encoded.Ls))
+    result.Rs = make([]*p256.P256, len(encoded.Rs))
+    for i := range encoded.Ls {
+        result.Ls[i], err = decodePoint(encoded.Ls[i])
+        if err != nil {
+            return err
+        }
+        result.Rs[i], err = decodePoint(encoded.Rs[i])
+        if err != nil {
+            return err
+        }
+    }
+    result.A, err = decodeScalar(encoded.A)
+    if err != nil {
+        return err
+    }
+    result.B, err = decodeScalar(encoded.B)
+    if err != nil {
+        return err
+    }
+    *proof = result
+    return nil
+}
+
+func writePoints(buffer *bytes.Buffer, points ...*p256.P256) {
+    for _, point := range points {
+        buffer.Write(pointBytes(point))
+    }
+}
+
+func writeScalars(buffer *bytes.Buffer, scalars ...*big.Int) {
+    for _, scalar := range scalars {
+        buffer.Write(scalarBytes(scalar))
+    }
+}
+
+/*
+pointBytes returns the compressed encoding of the point, nil being encoded as the
+point at infinity.
+*/
+func pointBytes(point *p256.P256) []byte {
+    if point == nil {
+        return new(p256.P256).SetInfinity().Bytes()
+    }
+    return point.Bytes()
+}
+
+/*
+scalarBytes returns the 32 bytes big-endian encoding of the scalar modulo ORDER.
+*/
+func scalarBytes(scalar *big.Int) []byte {
+    result := make([]byte, SCALAR_SIZE)
+    if scalar == nil {
+        return result
+    }
+    b := new(big.Int).Mod(scalar, ORDER).Bytes()
+    copy(result[SCALAR_SIZE-len(b):], b)
+    return result
+}
+
+func encodePoint(point *p256.P256) string {
+    return hex.EncodeToString(pointBytes(point))
+}
+
+func encodeScalar(scalar *big.Int) string {
+    return hex.EncodeToString(scalarBytes(scalar))
+}
+
+func decodePoint(s string) (*p256.P256, error) {
+    b, err := hex.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    return new(p256.P256).SetBytes(b)
+}
+
+func decodeScalar(s string) (*big.Int, error) {
+    b, err := hex.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    return scalarFromBytes(b)
+}
+
+func scalarFromBytes(b []byte) (*big.Int, error) {
+    if len(b) != SCALAR_SIZE {
+        return nil, ErrInvalidScalar
+    }
+    scalar := new(big.Int).SetBytes(b)
+    if scalar.Cmp(ORDER) >= 0 {
+        return nil, ErrInvalidScalar
+    }
+    return scalar, nil
+}
+
+/*
+decoder reads the elements of a binary encoded proof. After the first error every
+read returns a zero value, the error being reported by finish.
+*/
+type decoder struct {
+    data []byte
+    err  error
+}
+
+/*
+newDecoder checks the header of the binary encoding.
+*/
+func newDecoder(data []byte, tag byte) *decoder {
+    d := &decoder{data: data}
+    version := d.readByte()
+    if d.err == nil && version != ENCODING_VERSION {
+        d.err = ErrUnsupportedVersion
+    }
+    if t := d.readByte(); d.err == nil && t != tag {
+        d.err = ErrInvalidProofType
+    }
+    return d
+}
+
+func (d *decoder) next(n int) []byte {
+    if d.err != nil {
+        return nil
+    }
+    if len(d.data) < n {
+        d.err = ErrTruncatedData
+        return nil
+    }
+    result := d.data[:n]
+    d.data = d.data[n:]
+    return result
+}
+
+func (d *decoder) readByte() byte {
+    b := d.next(1)
+    if b == nil {
+        return 0
+    }
+    return b[0]
+}
+
+func (d *decoder) point() *p256.P256 {
+    b := d.next(p256.COMPRESSED_SIZE)
+    if b == nil {
+        return nil
+    }
+    point, err := new(p256.P256).SetBytes(b)
+    if err != nil {
+        d.err = err
+    }
+    return point
+}
+
+func (d *decoder) scalar() *big.Int {
+    b := d.next(SCALAR_SIZE)
+    if b == nil {
+        return nil
+    }
+    scalar, err := scalarFromBytes(b)
+    if err != nil {
+        d.err = err
+    }
+    return scalar
+}
+
+func (d *decoder) bulletProof() BulletProof {
+    V := d.point()
+    proof := d.bulletProofMessages()
+    proof.V = V
+    return proof
+}
+
+func (d *decoder) bulletProofMessages() BulletProof {
+    var proof BulletProof
+    proof.A = d.point()
+    proof.S = d.point()
+    proof.T1 = d.point()
+    proof.T2 = d.point()
+    proof.Taux = d.scalar()
+    proof.Mu = d.scalar()
+    proof.Tprime = d.scalar()
+    proof.InnerProductProof = d.innerProductProof()
+    return proof
+}
+
+func (d *decoder) innerProductProof() InnerProductProof {
+    var proof InnerProductProof
+    rounds := int(d.readByte())
+    proof.Ls = make([]*p256.P256, rounds)
+    proof.Rs = make([]*p256.P256, rounds)
+    for i := 0; i < rounds; i++ {
+        proof.Ls[i] = d.point()
+        proof.Rs[i] = d.point()
+    }
+    proof.A = d.scalar()
+    proof.B = d.scalar()
+    return proof
+}
+
+/*
+finish returns the first error found while decoding, or ErrTrailingData if some
+data was not consumed.
+*/
+func (d *decoder) finish() error {
+    if d.err != nil {
+        return d.err
+    }
+    if len(d.data) != 0 {
+        return ErrTrailingData
+    }
+    return nil
+}
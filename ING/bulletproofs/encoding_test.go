@@ -0,0 +1,147 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "encoding/json"
+    "math/big"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestBinaryEncodeDecode(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    encoded, err := proof.MarshalBinary()
+    if err != nil {
+        t.Fatal("encode error:", err)
+    }
+    // 5 points, 3 scalars, 5 rounds of L and R, a and b, plus the header.
+    assert.Equal(t, 2+5*33+3*32+1+10*33+2*32, len(encoded))
+
+    var decodedProof BulletProof
+    err = decodedProof.UnmarshalBinary(encoded)
+    if err != nil {
+        t.Fatal("decode error:", err)
+    }
+    ok, _ := decodedProof.Verify(params)
+    assert.True(t, ok, "should verify")
+
+    reencoded, _ := decodedProof.MarshalBinary()
+    assert.Equal(t, encoded, reencoded, "encoding should be canonical")
+}
+
+func TestBinaryEncodeDecodeBPRP(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    proof, _ := ProveGeneric(new(big.Int).SetInt64(40), params)
+    encoded, _ := proof.MarshalBinary()
+
+    var decodedProof ProofBPRP
+    err := decodedProof.UnmarshalBinary(encoded)
+    if err != nil {
+        t.Fatal("decode error:", err)
+    }
+    ok, _ := decodedProof.Verify(params)
+    assert.True(t, ok, "should verify")
+}
+
+func TestBinaryEncodeDecodeAggregate(t *testing.T) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 2)
+    proof, _ := ProveAggregate([]*big.Int{big.NewInt(18), big.NewInt(65)}, params)
+    encoded, _ := proof.MarshalBinary()
+
+    var decodedProof AggregateBulletProof
+    err := decodedProof.UnmarshalBinary(encoded)
+    if err != nil {
+        t.Fatal("decode error:", err)
+    }
+    ok, _ := decodedProof.Verify(params)
+    assert.True(t, ok, "should verify")
+}
+
+func TestBinaryDecodeInvalid(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    encoded, _ := proof.MarshalBinary()
+    var decodedProof BulletProof
+
+    assert.Equal(t, ErrTruncatedData, decodedProof.UnmarshalBinary(encoded[:len(encoded)-1]))
+    assert.Equal(t, ErrTrailingData, decodedProof.UnmarshalBinary(append(encoded, 0)))
+
+    modified := append([]byte{}, encoded...)
+    modified[0] = ENCODING_VERSION + 1
+    assert.Equal(t, ErrUnsupportedVersion, decodedProof.UnmarshalBinary(modified))
+
+    modified = append([]byte{}, encoded...)
+    modified[1] = tagInnerProductProof
+    assert.Equal(t, ErrInvalidProofType, decodedProof.UnmarshalBinary(modified))
+
+    // Taux replaced by a value greater than the order
+    modified = append([]byte{}, encoded...)
+    for i := 0; i < SCALAR_SIZE; i++ {
+        modified[2+5*33+i] = 0xff
+    }
+    assert.Equal(t, ErrInvalidScalar, decodedProof.UnmarshalBinary(modified))
+
+    // V replaced by an x-coordinate that is not on the curve
+    modified = append([]byte{}, encoded...)
+    modified[2] = 2
+    for i := 1; i < 33; i++ {
+        modified[2+i] = 0
+    }
+    modified[2+32] = 5
+    assert.Error(t, decodedProof.UnmarshalBinary(modified))
+}
+
+func TestJsonDecodeInvalidScalar(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    jsonEncoded, _ := json.Marshal(proof)
+
+    var fields map[string]interface{}
+    _ = json.Unmarshal(jsonEncoded, &fields)
+    fields["Taux"] = "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+    jsonEncoded, _ = json.Marshal(fields)
+
+    var decodedProof BulletProof
+    err := json.Unmarshal(jsonEncoded, &decodedProof)
+    assert.Equal(t, ErrInvalidScalar, err)
+}
+
+func TestDecodeOldVersion(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    proof, _ := ProveGeneric(new(big.Int).SetInt64(40), params)
+    var decodedProof ProofBPRP
+
+    // Proofs encoded before the transcript was introduced
+    jsonEncoded, _ := json.Marshal(proof)
+    var fields map[string]interface{}
+    _ = json.Unmarshal(jsonEncoded, &fields)
+    fields["Version"] = 1
+    jsonEncoded, _ = json.Marshal(fields)
+    assert.Equal(t, ErrUnsupportedVersion, json.Unmarshal(jsonEncoded, &decodedProof))
+
+    binaryEncoded, _ := proof.MarshalBinary()
+    binaryEncoded[0] = 1
+    assert.Equal(t, ErrUnsupportedVersion, decodedProof.UnmarshalBinary(binaryEncoded))
+
+    // Legacy proofs have X/Y points and no version at all
+    legacy := []byte(`{"P1":{"V":{"X":1,"Y":2},"Params":{"N":32}},"P2":{"V":{"X":1,"Y":2}}}`)
+    assert.Equal(t, ErrUnsupportedVersion, json.Unmarshal(legacy, &decodedProof))
+}
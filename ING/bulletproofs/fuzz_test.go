@@ -0,0 +1,112 @@
+//go:build go1.18
+// +build go1.18
+
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "encoding/json"
+    "math/big"
+    "testing"
+)
+
+/*
+The fuzz tests decode arbitrary data and verify the result, which must never panic.
+They are seeded with valid encodings, and run with: go test -fuzz FuzzDecodeBulletProof
+*/
+
+func FuzzDecodeBulletProof(f *testing.F) {
+    params, _ := Setup(MAX_RANGE_END)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    binary, _ := proof.MarshalBinary()
+    encoded, _ := json.Marshal(proof)
+    f.Add(binary)
+    f.Add(encoded)
+    f.Fuzz(func(t *testing.T, data []byte) {
+        var decoded BulletProof
+        if decoded.UnmarshalBinary(data) == nil {
+            _, _ = decoded.Verify(params)
+        }
+        decoded = BulletProof{}
+        if json.Unmarshal(data, &decoded) == nil {
+            _, _ = decoded.Verify(params)
+        }
+    })
+}
+
+func FuzzDecodeProofBPRP(f *testing.F) {
+    params, _ := SetupGeneric(18, 200)
+    proof, _ := ProveGeneric(new(big.Int).SetInt64(40), params)
+    binary, _ := proof.MarshalBinary()
+    encoded, _ := json.Marshal(proof)
+    f.Add(binary)
+    f.Add(encoded)
+    f.Fuzz(func(t *testing.T, data []byte) {
+        var decoded ProofBPRP
+        if decoded.UnmarshalBinary(data) == nil {
+            _, _ = decoded.Verify(params)
+        }
+        decoded = ProofBPRP{}
+        if json.Unmarshal(data, &decoded) == nil {
+            _, _ = decoded.Verify(params)
+        }
+    })
+}
+
+func FuzzDecodeAggregateBulletProof(f *testing.F) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 2)
+    proof, _ := ProveAggregate([]*big.Int{big.NewInt(3), big.NewInt(4)}, params)
+    binary, _ := proof.MarshalBinary()
+    encoded, _ := json.Marshal(proof)
+    f.Add(binary)
+    f.Add(encoded)
+    f.Fuzz(func(t *testing.T, data []byte) {
+        var decoded AggregateBulletProof
+        if decoded.UnmarshalBinary(data) == nil {
+            _, _ = decoded.Verify(params)
+        }
+        decoded = AggregateBulletProof{}
+        if json.Unmarshal(data, &decoded) == nil {
+            _, _ = decoded.Verify(params)
+        }
+    })
+}
+
+func FuzzDecodeInnerProductProof(f *testing.F) {
+    a := []*big.Int{big.NewInt(2), big.NewInt(-1), big.NewInt(10), big.NewInt(6)}
+    b := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(10), big.NewInt(7)}
+    ipParams, _ := setupInnerProduct(nil, nil, nil, big.NewInt(142), 4)
+    commit := commitInnerProduct(ipParams.Gg, ipParams.Hh, a, b, 1)
+    proof, _ := proveInnerProduct(a, b, commit, ipParams)
+    ipParams.P = commit
+    binary, _ := proof.MarshalBinary()
+    encoded, _ := json.Marshal(proof)
+    f.Add(binary)
+    f.Add(encoded)
+    f.Fuzz(func(t *testing.T, data []byte) {
+        var decoded InnerProductProof
+        if decoded.UnmarshalBinary(data) == nil {
+            _, _ = decoded.Verify(ipParams)
+        }
+        decoded = InnerProductProof{}
+        if json.Unmarshal(data, &decoded) == nil {
+            _, _ = decoded.Verify(ipParams)
+        }
+    })
+}
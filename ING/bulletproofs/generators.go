@@ -0,0 +1,111 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the generators of the proofs. They are taken from the process-wide
+store of p256, so that each generator is derived by MapToGroup once per process, no
+matter how many setups are computed. The generator H of the Pedersen commitments gets
+a precomputed table, like the base generator G, which makes the commitments to fixed
+bases several times faster. The tables of the vector generators are only computed on
+request, see PrecomputeGenerators, since their size grows with the number of
+generators.
+*/
+
+package bulletproofs
+
+import (
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+// GENERATOR_TABLE_WINDOW is the window of the precomputed tables of Gg and Hh.
+const GENERATOR_TABLE_WINDOW = p256.FIXED_BASE_WINDOW
+
+/*
+generatorG returns the base generator of the curve.
+*/
+func generatorG() *p256.P256 {
+    return new(p256.P256).ScalarBaseMult(new(big.Int).SetInt64(1))
+}
+
+/*
+generatorH returns the generator H, computed using MapToGroup, such that there is no
+discrete logarithm relation with G.
+*/
+func generatorH() (*p256.P256, error) {
+    H, err := p256.Generator(SEEDH)
+    if err != nil {
+        return nil, err
+    }
+    _, err = p256.Precompute(H, p256.MAX_FIXED_BASE_WINDOW)
+    return H, err
+}
+
+/*
+generatorU returns the generator u of the inner product argument.
+*/
+func generatorU() (*p256.P256, error) {
+    U, err := p256.Generator(SEEDU)
+    if err != nil {
+        return nil, err
+    }
+    _, err = p256.Precompute(U, GENERATOR_TABLE_WINDOW)
+    return U, err
+}
+
+/*
+vectorGenerators returns the generators Gg[from:to] and Hh[from:to].
+*/
+func vectorGenerators(from, to int64) ([]*p256.P256, []*p256.P256, error) {
+    var err error
+    Gg := make([]*p256.P256, to-from)
+    Hh := make([]*p256.P256, to-from)
+    for i := from; i < to; i++ {
+        Gg[i-from], err = p256.Generator(SEEDH + "g" + string(rune(i)))
+        if err != nil {
+            return nil, nil, err
+        }
+        Hh[i-from], err = p256.Generator(SEEDH + "h" + string(rune(i)))
+        if err != nil {
+            return nil, nil, err
+        }
+    }
+    return Gg, Hh, nil
+}
+
+/*
+PrecomputeGenerators computes the tables of the first n generators Gg and Hh, which
+take about 60 KB each. From then on, all the proofs of the process use them. It is
+worth calling once by long running provers and verifiers, e.g. with n = 64 for the
+range proofs.
+*/
+func PrecomputeGenerators(n int64) error {
+    Gg, Hh, err := vectorGenerators(0, n)
+    if err != nil {
+        return err
+    }
+    for i := int64(0); i < n; i++ {
+        if _, err = p256.Precompute(Gg[i], GENERATOR_TABLE_WINDOW); err != nil {
+            return err
+        }
+        if _, err = p256.Precompute(Hh[i], GENERATOR_TABLE_WINDOW); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "math/big"
+    "sync"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestSetupConcurrent(t *testing.T) {
+    var wg sync.WaitGroup
+    params := make([]BulletProofSetupParams, 4)
+    for i := range params {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            params[i], _ = SetupBits(32)
+        }(i)
+    }
+    wg.Wait()
+    for i := range params {
+        assert.Equal(t, params[0].H.Bytes(), params[i].H.Bytes())
+        for j := range params[0].Gg {
+            assert.Equal(t, params[0].Gg[j].Bytes(), params[i].Gg[j].Bytes())
+            assert.Equal(t, params[0].Hh[j].Bytes(), params[i].Hh[j].Bytes())
+        }
+    }
+}
+
+func TestSetupMatchesMapToGroup(t *testing.T) {
+    params, _ := SetupBits(8)
+    H, _ := p256.MapToGroup(SEEDH)
+    assert.Equal(t, H.Bytes(), params.H.Bytes())
+    for i := int64(0); i < params.N; i++ {
+        g, _ := p256.MapToGroup(SEEDH + "g" + string(rune(i)))
+        h, _ := p256.MapToGroup(SEEDH + "h" + string(rune(i)))
+        assert.Equal(t, g.Bytes(), params.Gg[i].Bytes())
+        assert.Equal(t, h.Bytes(), params.Hh[i].Bytes())
+    }
+
+    // Modifying the params does not affect the later setups.
+    params.Gg[0].X.SetInt64(1)
+    other, _ := SetupBits(8)
+    g, _ := p256.MapToGroup(SEEDH + "g" + string(rune(0)))
+    assert.Equal(t, g.Bytes(), other.Gg[0].Bytes())
+}
+
+func TestPrecomputeGenerators(t *testing.T) {
+    assert.NoError(t, PrecomputeGenerators(8))
+    params, _ := SetupBits(8)
+    proof, err := Prove(big.NewInt(200), params)
+    assert.NoError(t, err)
+    ok, _ := proof.Verify(params)
+    assert.True(t, ok, "proof should verify with precomputed generators")
+}
@@ -0,0 +1,232 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "flag"
+    "io"
+    "io/ioutil"
+    "math/big"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+/*
+The known-answer tests contain proofs computed in the derandomized mode, without
+external randomness, so that they only depend on the statement and the witness.
+They are regenerated with: go test ./bulletproofs -run KnownAnswer -update
+*/
+
+var updateKAT = flag.Bool("update", false, "regenerate the known-answer test vectors")
+
+const katFile = "testdata/kat.json"
+
+type katRangeProof struct {
+    Bits   int64
+    Secret string
+    Gamma  string
+    Proof  string
+}
+
+type katGenericProof struct {
+    Lower  string
+    Upper  string
+    Secret string
+    Gamma  string
+    Proof  string
+}
+
+type katAggregateProof struct {
+    Bits    int64
+    Secrets []string
+    Gammas  []string
+    Proof   string
+}
+
+type katVectors struct {
+    RangeProof     []katRangeProof
+    GenericProof   []katGenericProof
+    AggregateProof []katAggregateProof
+}
+
+/*
+deterministic are the options of the known-answer tests.
+*/
+var deterministic = []ProverOption{Derandomized(), WithRand(nil)}
+
+func katInt(t *testing.T, s string) *big.Int {
+    n, ok := new(big.Int).SetString(s, 0)
+    if !ok {
+        t.Fatalf("invalid integer %q", s)
+    }
+    return n
+}
+
+func TestKnownAnswer(t *testing.T) {
+    var vectors katVectors
+    data, err := ioutil.ReadFile(katFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err = json.Unmarshal(data, &vectors); err != nil {
+        t.Fatal(err)
+    }
+
+    for i := range vectors.RangeProof {
+        v := &vectors.RangeProof[i]
+        params, _ := SetupBits(v.Bits)
+        proof, err := ProveWithCommitment(katInt(t, v.Secret), katInt(t, v.Gamma), params, deterministic...)
+        assert.NoError(t, err)
+        encoded, _ := proof.MarshalBinary()
+        checkKnownAnswer(t, &v.Proof, encoded)
+        ok, _ := proof.Verify(params)
+        assert.True(t, ok, "range proof %d should verify", i)
+    }
+
+    for i := range vectors.GenericProof {
+        v := &vectors.GenericProof[i]
+        params, _ := SetupGenericBig(katInt(t, v.Lower), katInt(t, v.Upper))
+        proof, err := ProveGenericWithCommitment(katInt(t, v.Secret), katInt(t, v.Gamma), params, deterministic...)
+        assert.NoError(t, err)
+        encoded, _ := proof.MarshalBinary()
+        checkKnownAnswer(t, &v.Proof, encoded)
+        ok, _ := proof.Verify(params)
+        assert.True(t, ok, "generic proof %d should verify", i)
+    }
+
+    for i := range vectors.AggregateProof {
+        v := &vectors.AggregateProof[i]
+        secrets := make([]*big.Int, len(v.Secrets))
+        gammas := make([]*big.Int, len(v.Gammas))
+        for j := range v.Secrets {
+            secrets[j] = katInt(t, v.Secrets[j])
+            gammas[j] = katInt(t, v.Gammas[j])
+        }
+        params, err := SetupAggregate(int64(1)<<uint(v.Bits), int64(len(secrets)))
+        assert.NoError(t, err)
+        proof, err := ProveAggregateWithCommitments(secrets, gammas, params, deterministic...)
+        assert.NoError(t, err)
+        encoded, _ := proof.MarshalBinary()
+        checkKnownAnswer(t, &v.Proof, encoded)
+        ok, _ := proof.Verify(params)
+        assert.True(t, ok, "aggregate proof %d should verify", i)
+    }
+
+    if *updateKAT {
+        data, _ = json.MarshalIndent(vectors, "", "  ")
+        if err = ioutil.WriteFile(katFile, append(data, '\n'), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+}
+
+/*
+checkKnownAnswer compares the encoded proof with the expected one, or replaces the
+expected proof when the vectors are regenerated.
+*/
+func checkKnownAnswer(t *testing.T, expected *string, encoded []byte) {
+    if *updateKAT {
+        *expected = hex.EncodeToString(encoded)
+        return
+    }
+    assert.Equal(t, *expected, hex.EncodeToString(encoded))
+}
+
+func TestDerandomized(t *testing.T) {
+    params, _ := SetupBits(32)
+    secret := big.NewInt(1234)
+    proof1, _ := ProveWithCommitment(secret, big.NewInt(5), params, deterministic...)
+    proof2, _ := ProveWithCommitment(secret, big.NewInt(5), params, deterministic...)
+    assert.Equal(t, proof1, proof2, "proofs without external randomness should be equal")
+
+    // With external randomness, the proofs of the derandomized mode still differ.
+    proof3, _ := ProveWithCommitment(secret, big.NewInt(5), params, Derandomized())
+    assert.NotEqual(t, proof1.A.Bytes(), proof3.A.Bytes())
+    ok, _ := proof3.Verify(params)
+    assert.True(t, ok)
+
+    // The blinding factors are never derived from the secret, which would let anyone
+    // check candidate secrets against the commitment.
+    _, err := Prove(secret, params, deterministic...)
+    assert.Equal(t, ErrNoRandomness, err)
+    generic, _ := SetupGeneric(18, 200)
+    _, err = ProveGeneric(big.NewInt(40), generic, deterministic...)
+    assert.Equal(t, ErrNoRandomness, err)
+    aggregate, _ := SetupAggregate(MAX_RANGE_END, 2)
+    _, err = ProveAggregate([]*big.Int{big.NewInt(1), big.NewInt(2)}, aggregate, deterministic...)
+    assert.Equal(t, ErrNoRandomness, err)
+    proof1, _ = Prove(secret, params, Derandomized())
+    proof2, _ = Prove(secret, params, Derandomized())
+    assert.NotEqual(t, proof1.V.Bytes(), proof2.V.Bytes())
+
+    // A random source that only returns zeros does not repeat the nonces of
+    // different statements.
+    zeros := WithRand(zeroReader{})
+    proof1, _ = ProveWithCommitment(secret, big.NewInt(5), params, Derandomized(), zeros)
+    proof2, _ = ProveWithCommitment(big.NewInt(1235), big.NewInt(5), params, Derandomized(), zeros)
+    assert.NotEqual(t, proof1.A.Bytes(), proof2.A.Bytes())
+    ok, _ = proof1.Verify(params)
+    assert.True(t, ok)
+}
+
+func TestProveRandError(t *testing.T) {
+    params, _ := SetupBits(32)
+    generic, _ := SetupGeneric(18, 200)
+    aggregate, _ := SetupAggregate(MAX_RANGE_END, 2)
+    secrets := []*big.Int{big.NewInt(1), big.NewInt(2)}
+
+    // The random source fails after the blinding factors.
+    _, err := Prove(big.NewInt(1234), params, WithRand(failingReader(32)))
+    assert.Error(t, err, "errors of the random source should be returned")
+    _, err = ProveGeneric(big.NewInt(40), generic, WithRand(failingReader(32)))
+    assert.Error(t, err)
+    _, err = ProveAggregate(secrets, aggregate, WithRand(failingReader(64)))
+    assert.Error(t, err)
+    _, err = Prove(big.NewInt(1234), params, Derandomized(), WithRand(failingReader(0)))
+    assert.Error(t, err)
+
+    r1cs, _ := SetupR1CS(8)
+    prover := NewR1CSProver(r1cs, WithRand(failingReader(0)))
+    _, x := prover.Commit(big.NewInt(1), big.NewInt(4))
+    productGadget(prover, x, x, x)
+    _, err = prover.Prove()
+    assert.Error(t, err)
+}
+
+/*
+zeroReader is a broken random source, that only returns zeros.
+*/
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+    for i := range p {
+        p[i] = 0
+    }
+    return len(p), nil
+}
+
+/*
+failingReader returns n random bytes, and then fails.
+*/
+func failingReader(n int64) io.Reader {
+    return io.LimitReader(rand.Reader, n)
+}
@@ -0,0 +1,502 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the multi-party computation of an aggregated range proof, in the
+style of the MPC module of dalek-cryptography's bulletproofs. Each of the m parties
+holds one committed value, and a dealer, which can be one of them, collects their
+messages and combines them into an AggregateBulletProof that verifies with
+AggregateBulletProof.Verify. The secrets and blinding factors never leave the
+parties. The protocol runs in three rounds:
+
+    party j -> dealer: BitCommitment{V_j, A_j, S_j}
+    dealer -> parties: BitChallenge{y, z}
+    party j -> dealer: PolyCommitment{T1_j, T2_j}
+    dealer -> parties: PolyChallenge{x}
+    party j -> dealer: ProofShare{taux_j, mu_j, t_j, l_j, r_j}
+
+The states of the parties and of the dealer are consumed by each round, and every
+message has a binary and a JSON encoding, see mpc_messages.go.
+*/
+
+package bulletproofs
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+    . "github.com/ing-bank/zkrp/util"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+// MPC_PARTY_LABEL is the label of the transcripts from which the parties derive their nonces.
+const MPC_PARTY_LABEL = "zkrp bulletproofs mpc party"
+
+var (
+    ErrStateConsumed   = errors.New("state of the protocol has already been used")
+    ErrMaliciousDealer = errors.New("challenge of the dealer would reveal the secret")
+)
+
+/*
+MalformedSharesError is returned by the dealer when the proof shares of some parties
+are not consistent with their commitments. Parties contains their positions.
+*/
+type MalformedSharesError struct {
+    Parties []int
+}
+
+func (e MalformedSharesError) Error() string {
+    return fmt.Sprintf("malformed proof shares from parties %v", e.Parties)
+}
+
+/*
+PartyAwaitingPosition is a party holding the secret committed in V = g^secret.h^gamma.
+*/
+type PartyAwaitingPosition struct {
+    secret *big.Int
+    gamma  *big.Int
+    V      *p256.P256
+    params BulletProofSetupParams
+    cfg    proverConfig
+}
+
+/*
+PartyAwaitingBitChallenge is a party that sent its BitCommitment.
+*/
+type PartyAwaitingBitChallenge struct {
+    party      *PartyAwaitingPosition
+    j          int
+    rng        io.Reader
+    aL, aR     []*big.Int
+    sL, sR     []*big.Int
+    alpha, rho *big.Int
+}
+
+/*
+PartyAwaitingPolyChallenge is a party that sent its PolyCommitment.
+*/
+type PartyAwaitingPolyChallenge struct {
+    gamma      *big.Int
+    z          *big.Int
+    j          int
+    l0, l1     []*big.Int
+    r0, r1     []*big.Int
+    alpha, rho *big.Int
+    tau1, tau2 *big.Int
+    used       bool
+}
+
+/*
+NewParty returns a party of the protocol, for the commitment g^secret.h^gamma. The
+params must be the ones of the aggregated proof, see SetupAggregate. The nonces are
+read from the random source of the options, which must not be nil: the challenges
+are chosen by the dealer, hence nonces reused with other challenges would reveal the
+secret.
+*/
+func NewParty(secret, gamma *big.Int, params BulletProofSetupParams, opts ...ProverOption) (*PartyAwaitingPosition, error) {
+    if secret == nil || gamma == nil {
+        return nil, errors.New("secret and blinding factor must not be nil")
+    }
+    cfg := newProverConfig(opts)
+    if cfg.rand == nil {
+        return nil, errors.New("parties require a random source")
+    }
+    gamma = bn.Mod(gamma, ORDER)
+    V, _ := CommitG1(secret, gamma, params.H)
+    return &PartyAwaitingPosition{secret: secret, gamma: gamma, V: V, params: params, cfg: cfg}, nil
+}
+
+/*
+AssignPosition sets the position j of the party in the aggregated proof, which
+selects its generators Gg[j.N:(j+1).N] and Hh[j.N:(j+1).N], and returns the
+commitments to the bits of the secret.
+*/
+func (p *PartyAwaitingPosition) AssignPosition(j int) (*PartyAwaitingBitChallenge, BitCommitment, error) {
+    n := p.params.N
+    if j < 0 || int64(len(p.params.Gg)) < int64(j+1)*n || int64(len(p.params.Hh)) < int64(j+1)*n {
+        return nil, BitCommitment{}, errors.New("not enough generators for the position of the party")
+    }
+    Gg := p.params.Gg[int64(j)*n : int64(j+1)*n]
+    Hh := p.params.Hh[int64(j)*n : int64(j+1)*n]
+
+    t := transcript.New(MPC_PARTY_LABEL)
+    t.AppendUint64("j", uint64(j))
+    appendPoint(t, "V", p.V)
+    rng, err := p.cfg.nonceReader(t, p.secret, p.gamma)
+    if err != nil {
+        return nil, BitCommitment{}, err
+    }
+    nonces, err := sampleScalars(rng, 2)
+    if err != nil {
+        return nil, BitCommitment{}, err
+    }
+    state := &PartyAwaitingBitChallenge{party: p, j: j, rng: rng, alpha: nonces[0], rho: nonces[1]}
+
+    bits, _ := Decompose(p.secret, 2, n)
+    state.aL, _ = VectorConvertToBig(bits, n)
+    aR, _ := computeAR(bits)
+    state.aR, _ = VectorConvertToBig(aR, n)
+    if state.sL, err = sampleRandomVector(rng, n); err != nil {
+        return nil, BitCommitment{}, err
+    }
+    if state.sR, err = sampleRandomVector(rng, n); err != nil {
+        return nil, BitCommitment{}, err
+    }
+    commitment := BitCommitment{
+        V: p.V,
+        A: commitVectorBig(state.aL, state.aR, state.alpha, p.params.H, Gg, Hh, n, p.cfg.workers),
+        S: commitVectorBig(state.sL, state.sR, state.rho, p.params.H, Gg, Hh, n, p.cfg.workers),
+    }
+    return state, commitment, nil
+}
+
+/*
+ApplyChallenge computes the coefficients of the polynomials l(X) and r(X) of the party,
+its part of the vectors of the aggregated proof, and returns the commitments to the
+coefficients of t(X) = < l(X), r(X) >:
+l(X) = aL - z.1^n + sL.X
+r(X) = y^(j.n).y^n . (aR + z.1^n + sR.X) + z^(2+j).2^n
+*/
+func (p *PartyAwaitingBitChallenge) ApplyChallenge(c BitChallenge) (*PartyAwaitingPolyChallenge, PolyCommitment, error) {
+    if p.party == nil {
+        return nil, PolyCommitment{}, ErrStateConsumed
+    }
+    if c.Y == nil || c.Z == nil || bn.Mod(c.Y, ORDER).Sign() == 0 {
+        return nil, PolyCommitment{}, ErrMaliciousDealer
+    }
+    party := p.party
+    p.party = nil
+    n := party.params.N
+    y, z := bn.Mod(c.Y, ORDER), bn.Mod(c.Z, ORDER)
+
+    offset := new(big.Int).Exp(y, big.NewInt(int64(p.j)*n), ORDER)
+    vy, _ := VectorScalarMul(powerOf(y, n), offset)
+    zj := new(big.Int).Exp(z, big.NewInt(int64(2+p.j)), ORDER)
+    z22n, _ := VectorScalarMul(powerOf(big.NewInt(2), n), zj)
+    vz, _ := VectorCopy(z, n)
+
+    state := &PartyAwaitingPolyChallenge{gamma: party.gamma, z: z, j: p.j, alpha: p.alpha, rho: p.rho}
+    state.l0, _ = VectorSub(p.aL, vz)
+    state.l1 = p.sL
+    aRz, _ := VectorAdd(p.aR, vz)
+    yaRz, _ := VectorMul(vy, aRz)
+    state.r0, _ = VectorAdd(yaRz, z22n)
+    state.r1, _ = VectorMul(vy, p.sR)
+
+    // t1 = < l0, r1 > + < l1, r0 >, t2 = < l1, r1 >
+    sp1, _ := ScalarProduct(state.l0, state.r1)
+    sp2, _ := ScalarProduct(state.l1, state.r0)
+    t1 := bn.Mod(bn.Add(sp1, sp2), ORDER)
+    t2, _ := ScalarProduct(state.l1, state.r1)
+    t2 = bn.Mod(t2, ORDER)
+
+    nonces, err := sampleScalars(p.rng, 2)
+    if err != nil {
+        return nil, PolyCommitment{}, err
+    }
+    state.tau1, state.tau2 = nonces[0], nonces[1]
+    T1, _ := CommitG1(t1, state.tau1, party.params.H)
+    T2, _ := CommitG1(t2, state.tau2, party.params.H)
+    return state, PolyCommitment{T1: T1, T2: T2}, nil
+}
+
+/*
+ApplyChallenge evaluates the polynomials of the party at x and returns its share of
+the proof. A zero challenge is refused, since l(0) reveals the bits of the secret.
+*/
+func (p *PartyAwaitingPolyChallenge) ApplyChallenge(c PolyChallenge) (ProofShare, error) {
+    if p.used {
+        return ProofShare{}, ErrStateConsumed
+    }
+    if c.X == nil || bn.Mod(c.X, ORDER).Sign() == 0 {
+        return ProofShare{}, ErrMaliciousDealer
+    }
+    p.used = true
+    x := bn.Mod(c.X, ORDER)
+
+    var share ProofShare
+    l1x, _ := VectorScalarMul(p.l1, x)
+    share.L, _ = VectorAdd(p.l0, l1x)
+    r1x, _ := VectorScalarMul(p.r1, x)
+    share.R, _ = VectorAdd(p.r0, r1x)
+    share.Tprime, _ = ScalarProduct(share.L, share.R)
+    share.Tprime = bn.Mod(share.Tprime, ORDER)
+
+    // taux = tau2.x^2 + tau1.x + z^(2+j).gamma, mu = alpha + rho.x
+    zj := new(big.Int).Exp(p.z, big.NewInt(int64(2+p.j)), ORDER)
+    taux := bn.Multiply(p.tau2, bn.Multiply(x, x))
+    taux = bn.Add(taux, bn.Multiply(p.tau1, x))
+    share.Taux = bn.Mod(bn.Add(taux, bn.Multiply(zj, p.gamma)), ORDER)
+    share.Mu = bn.Mod(bn.Add(p.alpha, bn.Multiply(p.rho, x)), ORDER)
+    return share, nil
+}
+
+/*
+DealerAwaitingBitCommitments is a dealer waiting for the BitCommitment of each
+party, in the order of their positions.
+*/
+type DealerAwaitingBitCommitments struct {
+    params BulletProofSetupParams
+    m      int64
+    cfg    proverConfig
+    t      *transcript.Transcript
+}
+
+/*
+DealerAwaitingPolyCommitments is a dealer waiting for the PolyCommitment of each
+party.
+*/
+type DealerAwaitingPolyCommitments struct {
+    dealer *DealerAwaitingBitCommitments
+    bits   []BitCommitment
+    A, S   *p256.P256
+    y, z   *big.Int
+    used   bool
+}
+
+/*
+DealerAwaitingProofShares is a dealer waiting for the ProofShare of each party.
+*/
+type DealerAwaitingProofShares struct {
+    dealer *DealerAwaitingPolyCommitments
+    polys  []PolyCommitment
+    T1, T2 *p256.P256
+    x      *big.Int
+    used   bool
+}
+
+/*
+NewDealer returns the dealer of an aggregated proof for m parties. The params must
+provide the generators for m values, see SetupAggregate, and m must be a power of 2.
+The option WithWorkers applies to the inner product argument computed by the dealer.
+*/
+func NewDealer(params BulletProofSetupParams, m int, opts ...ProverOption) (*DealerAwaitingBitCommitments, error) {
+    if m <= 0 || !IsPowerOfTwo(int64(m)) {
+        return nil, errors.New("number of parties is not a power of 2")
+    }
+    mn := int64(m) * params.N
+    if int64(len(params.Gg)) < mn || int64(len(params.Hh)) < mn {
+        return nil, errors.New("not enough generators for the number of parties")
+    }
+    params.Gg = params.Gg[:mn]
+    params.Hh = params.Hh[:mn]
+    return &DealerAwaitingBitCommitments{params: params, m: int64(m), cfg: newProverConfig(opts)}, nil
+}
+
+/*
+ReceiveBitCommitments combines the commitments of the parties, in the order of their
+positions, and returns the challenges y and z.
+*/
+func (d *DealerAwaitingBitCommitments) ReceiveBitCommitments(bits []BitCommitment) (*DealerAwaitingPolyCommitments, BitChallenge, error) {
+    if d.t != nil {
+        return nil, BitChallenge{}, ErrStateConsumed
+    }
+    if int64(len(bits)) != d.m {
+        return nil, BitChallenge{}, errors.New("number of bit commitments does not match the number of parties")
+    }
+    A := new(p256.P256).SetInfinity()
+    S := new(p256.P256).SetInfinity()
+    for _, bc := range bits {
+        if bc.V == nil || bc.A == nil || bc.S == nil {
+            return nil, BitChallenge{}, errors.New("bit commitment must not contain nil points")
+        }
+        A = new(p256.P256).Multiply(A, bc.A)
+        S = new(p256.P256).Multiply(S, bc.S)
+    }
+
+    // Same transcript as ProveAggregate
+    d.t = transcript.New(RANGE_PROOF_LABEL)
+    rangeProofDomainSep(d.t, d.params, d.m)
+    for _, bc := range bits {
+        appendPoint(d.t, "V", bc.V)
+    }
+    appendPoint(d.t, "A", A)
+    appendPoint(d.t, "S", S)
+    y := challengeScalar(d.t, "y")
+    z := challengeScalar(d.t, "z")
+    state := &DealerAwaitingPolyCommitments{dealer: d, bits: bits, A: A, S: S, y: y, z: z}
+    return state, BitChallenge{Y: y, Z: z}, nil
+}
+
+/*
+ReceivePolyCommitments combines the commitments of the parties and returns the
+challenge x.
+*/
+func (d *DealerAwaitingPolyCommitments) ReceivePolyCommitments(polys []PolyCommitment) (*DealerAwaitingProofShares, PolyChallenge, error) {
+    if d.used {
+        return nil, PolyChallenge{}, ErrStateConsumed
+    }
+    if len(polys) != len(d.bits) {
+        return nil, PolyChallenge{}, errors.New("number of poly commitments does not match the number of parties")
+    }
+    T1 := new(p256.P256).SetInfinity()
+    T2 := new(p256.P256).SetInfinity()
+    for _, pc := range polys {
+        if pc.T1 == nil || pc.T2 == nil {
+            return nil, PolyChallenge{}, errors.New("poly commitment must not contain nil points")
+        }
+        T1 = new(p256.P256).Multiply(T1, pc.T1)
+        T2 = new(p256.P256).Multiply(T2, pc.T2)
+    }
+    t := d.dealer.t
+    appendPoint(t, "T1", T1)
+    appendPoint(t, "T2", T2)
+    x := challengeScalar(t, "x")
+    state := &DealerAwaitingProofShares{dealer: d, polys: polys, T1: T1, T2: T2, x: x}
+    d.used = true
+    return state, PolyChallenge{X: x}, nil
+}
+
+/*
+ReceiveShares checks the share of each party against its commitments, and combines
+them into the aggregated proof. If some shares are malformed, a MalformedSharesError
+reports the positions of the parties that sent them. This includes the parties whose
+secret does not belong to the range, since their bits do not add up to the secret.
+*/
+func (d *DealerAwaitingProofShares) ReceiveShares(shares []ProofShare) (AggregateBulletProof, error) {
+    var proof AggregateBulletProof
+    if d.used {
+        return proof, ErrStateConsumed
+    }
+    state := d.dealer
+    dealer := state.dealer
+    params := dealer.params
+    if len(shares) != len(state.bits) {
+        return proof, errors.New("number of proof shares does not match the number of parties")
+    }
+    var bad []int
+    for j := range shares {
+        if !d.checkShare(j, shares[j]) {
+            bad = append(bad, j)
+        }
+    }
+    if len(bad) > 0 {
+        return proof, MalformedSharesError{Parties: bad}
+    }
+    d.used = true
+
+    mn := dealer.m * params.N
+    bl := make([]*big.Int, 0, mn)
+    br := make([]*big.Int, 0, mn)
+    taux, mu, tprime := big.NewInt(0), big.NewInt(0), big.NewInt(0)
+    for _, share := range shares {
+        bl = append(bl, share.L...)
+        br = append(br, share.R...)
+        taux = bn.Add(taux, share.Taux)
+        mu = bn.Add(mu, share.Mu)
+        tprime = bn.Add(tprime, share.Tprime)
+    }
+    taux, mu, tprime = bn.Mod(taux, ORDER), bn.Mod(mu, ORDER), bn.Mod(tprime, ORDER)
+
+    // Inner Product over (g, h', P.h^-mu, tprime), as in ProveAggregate
+    workers := dealer.cfg.workers
+    hprime := updateGenerators(params.Hh, state.y, mn, workers)
+    var err error
+    params.InnerProductParams, err = setupInnerProduct(params.H, params.Gg, hprime, tprime, mn)
+    if err != nil {
+        return proof, err
+    }
+    t := dealer.t
+    appendScalar(t, "taux", taux)
+    appendScalar(t, "mu", mu)
+    appendScalar(t, "t", tprime)
+    commit := commitInnerProduct(params.Gg, hprime, bl, br, workers)
+    proofip, _ := proveInnerProductWithTranscript(t, bl, br, commit, params.InnerProductParams, workers)
+
+    proof.V = make([]*p256.P256, len(state.bits))
+    for j, bc := range state.bits {
+        proof.V[j] = bc.V
+    }
+    proof.A = state.A
+    proof.S = state.S
+    proof.T1 = d.T1
+    proof.T2 = d.T2
+    proof.Taux = taux
+    proof.Mu = mu
+    proof.Tprime = tprime
+    proof.InnerProductProof = proofip
+    return proof, nil
+}
+
+/*
+checkShare returns true if and only if the share of the party j satisfies, with the
+generators of the party and y' = y^(j.n).y^n:
+t_j = < l_j, r_j >
+g^t_j.h^taux_j = V_j^(z^(2+j)).g^delta_j.T1_j^x.T2_j^(x^2)
+g^l_j.h'^r_j = A_j.S_j^x.g^(-z.1^n).h'^(z.y' + z^(2+j).2^n).h^-mu_j, where h'_i = h_i^(y'_i^-1)
+with delta_j = (z - z^2).< 1^n, y' > - z^(3+j).< 1^n, 2^n >.
+*/
+func (d *DealerAwaitingProofShares) checkShare(j int, share ProofShare) bool {
+    state := d.dealer
+    params := state.dealer.params
+    n := params.N
+    if int64(len(share.L)) != n || int64(len(share.R)) != n || share.Taux == nil || share.Mu == nil || share.Tprime == nil {
+        return false
+    }
+    for i := range share.L {
+        if share.L[i] == nil || share.R[i] == nil {
+            return false
+        }
+    }
+    bc, pc := state.bits[j], d.polys[j]
+    y, z, x := state.y, state.z, d.x
+
+    tj, _ := ScalarProduct(share.L, share.R)
+    if bn.Mod(bn.Sub(tj, share.Tprime), ORDER).Sign() != 0 {
+        return false
+    }
+
+    offset := new(big.Int).Exp(y, big.NewInt(int64(j)*n), ORDER)
+    vy, _ := VectorScalarMul(powerOf(y, n), offset)
+    zj := new(big.Int).Exp(z, big.NewInt(int64(2+j)), ORDER)
+    p2n := powerOf(big.NewInt(2), n)
+    sumY, sum2 := big.NewInt(0), big.NewInt(0)
+    for i := int64(0); i < n; i++ {
+        sumY = bn.Add(sumY, vy[i])
+        sum2 = bn.Add(sum2, p2n[i])
+    }
+    delta := bn.Multiply(bn.Sub(z, bn.Multiply(z, z)), sumY)
+    delta = bn.Mod(bn.Sub(delta, bn.Multiply(bn.Multiply(zj, z), sum2)), ORDER)
+    x2 := bn.Mod(bn.Multiply(x, x), ORDER)
+    // g^(t_j - delta).h^taux.V^-zj.T1^-x.T2^-x2 must be the point at infinity
+    c1, _ := VectorExp(
+        []*p256.P256{params.G, params.H, bc.V, pc.T1, pc.T2},
+        []*big.Int{bn.Mod(bn.Sub(share.Tprime, delta), ORDER), share.Taux, bn.Sub(ORDER, zj), bn.Sub(ORDER, x), bn.Sub(ORDER, x2)})
+    if !c1.IsZero() {
+        return false
+    }
+
+    // g^(l + z).h^(y'^-1 o (r - z.y' - zj.2^n)).h^mu.A^-1.S^-x must be the point at infinity
+    Gg := params.Gg[int64(j)*n : int64(j+1)*n]
+    Hh := params.Hh[int64(j)*n : int64(j+1)*n]
+    gExp := make([]*big.Int, n)
+    hExp := make([]*big.Int, n)
+    for i := int64(0); i < n; i++ {
+        gExp[i] = bn.Mod(bn.Add(share.L[i], z), ORDER)
+        e := bn.Sub(share.R[i], bn.Multiply(z, vy[i]))
+        e = bn.Sub(e, bn.Multiply(zj, p2n[i]))
+        hExp[i] = bn.Mod(bn.Multiply(e, bn.ModInverse(vy[i], ORDER)), ORDER)
+    }
+    points := concatPoints(Gg, Hh, []*p256.P256{params.H, bc.A, bc.S})
+    scalars := concatScalars(gExp, hExp, []*big.Int{bn.Mod(share.Mu, ORDER), bn.Sub(ORDER, big.NewInt(1)), bn.Sub(ORDER, x)})
+    c2, _ := VectorExp(points, scalars)
+    return c2.IsZero()
+}
@@ -0,0 +1,418 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the messages of the multi-party computation of an aggregated range
+proof, see mpc.go, and their encodings. They follow the conventions of encoding.go:
+the binary encoding starts with the version and the type of the message, and the JSON
+encoding contains the same elements as hexadecimal strings.
+*/
+
+package bulletproofs
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+/*
+BitCommitment is sent by a party to the dealer: its commitment V to the secret, and
+the commitments A and S to the bits of the secret and to the blinding vectors.
+*/
+type BitCommitment struct {
+    V *p256.P256
+    A *p256.P256
+    S *p256.P256
+}
+
+/*
+BitChallenge is sent by the dealer to the parties: the challenges y and z.
+*/
+type BitChallenge struct {
+    Y *big.Int
+    Z *big.Int
+}
+
+/*
+PolyCommitment is sent by a party to the dealer: the commitments to the coefficients
+t1 and t2 of its polynomial t(X).
+*/
+type PolyCommitment struct {
+    T1 *p256.P256
+    T2 *p256.P256
+}
+
+/*
+PolyChallenge is sent by the dealer to the parties: the challenge x.
+*/
+type PolyChallenge struct {
+    X *big.Int
+}
+
+/*
+ProofShare is sent by a party to the dealer: its vectors l(x) and r(x), their inner
+product t(x), and its parts of taux and mu.
+*/
+type ProofShare struct {
+    Taux   *big.Int
+    Mu     *big.Int
+    Tprime *big.Int
+    L      []*big.Int
+    R      []*big.Int
+}
+
+type bitCommitmentJSON struct {
+    Version int
+    V, A, S string
+}
+
+type bitChallengeJSON struct {
+    Version int
+    Y, Z    string
+}
+
+type polyCommitmentJSON struct {
+    Version int
+    T1, T2  string
+}
+
+type polyChallengeJSON struct {
+    Version int
+    X       string
+}
+
+type proofShareJSON struct {
+    Version          int
+    Taux, Mu, Tprime string
+    L, R             []string
+}
+
+/*
+MarshalBinary returns the binary encoding of the message.
+*/
+func (m BitCommitment) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    buffer.Write([]byte{ENCODING_VERSION, tagBitCommitment})
+    writePoints(&buffer, m.V, m.A, m.S)
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the message.
+*/
+func (m *BitCommitment) UnmarshalBinary(data []byte) error {
+    var decoded BitCommitment
+    d := newDecoder(data, tagBitCommitment)
+    decoded.V = d.point()
+    decoded.A = d.point()
+    decoded.S = d.point()
+    if err := d.finish(); err != nil {
+        return err
+    }
+    *m = decoded
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the message.
+*/
+func (m BitCommitment) MarshalJSON() ([]byte, error) {
+    return json.Marshal(bitCommitmentJSON{ENCODING_VERSION, encodePoint(m.V), encodePoint(m.A), encodePoint(m.S)})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the message.
+*/
+func (m *BitCommitment) UnmarshalJSON(data []byte) error {
+    var encoded bitCommitmentJSON
+    if err := decodeMessageJSON(data, &encoded); err != nil {
+        return err
+    }
+    points, err := decodePoints(encoded.V, encoded.A, encoded.S)
+    if err != nil {
+        return err
+    }
+    *m = BitCommitment{V: points[0], A: points[1], S: points[2]}
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the message.
+*/
+func (m BitChallenge) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    buffer.Write([]byte{ENCODING_VERSION, tagBitChallenge})
+    writeScalars(&buffer, m.Y, m.Z)
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the message.
+*/
+func (m *BitChallenge) UnmarshalBinary(data []byte) error {
+    var decoded BitChallenge
+    d := newDecoder(data, tagBitChallenge)
+    decoded.Y = d.scalar()
+    decoded.Z = d.scalar()
+    if err := d.finish(); err != nil {
+        return err
+    }
+    *m = decoded
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the message.
+*/
+func (m BitChallenge) MarshalJSON() ([]byte, error) {
+    return json.Marshal(bitChallengeJSON{ENCODING_VERSION, encodeScalar(m.Y), encodeScalar(m.Z)})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the message.
+*/
+func (m *BitChallenge) UnmarshalJSON(data []byte) error {
+    var encoded bitChallengeJSON
+    if err := decodeMessageJSON(data, &encoded); err != nil {
+        return err
+    }
+    scalars, err := decodeScalars(encoded.Y, encoded.Z)
+    if err != nil {
+        return err
+    }
+    *m = BitChallenge{Y: scalars[0], Z: scalars[1]}
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the message.
+*/
+func (m PolyCommitment) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    buffer.Write([]byte{ENCODING_VERSION, tagPolyCommitment})
+    writePoints(&buffer, m.T1, m.T2)
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the message.
+*/
+func (m *PolyCommitment) UnmarshalBinary(data []byte) error {
+    var decoded PolyCommitment
+    d := newDecoder(data, tagPolyCommitment)
+    decoded.T1 = d.point()
+    decoded.T2 = d.point()
+    if err := d.finish(); err != nil {
+        return err
+    }
+    *m = decoded
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the message.
+*/
+func (m PolyCommitment) MarshalJSON() ([]byte, error) {
+    return json.Marshal(polyCommitmentJSON{ENCODING_VERSION, encodePoint(m.T1), encodePoint(m.T2)})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the message.
+*/
+func (m *PolyCommitment) UnmarshalJSON(data []byte) error {
+    var encoded polyCommitmentJSON
+    if err := decodeMessageJSON(data, &encoded); err != nil {
+        return err
+    }
+    points, err := decodePoints(encoded.T1, encoded.T2)
+    if err != nil {
+        return err
+    }
+    *m = PolyCommitment{T1: points[0], T2: points[1]}
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the message.
+*/
+func (m PolyChallenge) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    buffer.Write([]byte{ENCODING_VERSION, tagPolyChallenge})
+    writeScalars(&buffer, m.X)
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the message.
+*/
+func (m *PolyChallenge) UnmarshalBinary(data []byte) error {
+    var decoded PolyChallenge
+    d := newDecoder(data, tagPolyChallenge)
+    decoded.X = d.scalar()
+    if err := d.finish(); err != nil {
+        return err
+    }
+    *m = decoded
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the message.
+*/
+func (m PolyChallenge) MarshalJSON() ([]byte, error) {
+    return json.Marshal(polyChallengeJSON{ENCODING_VERSION, encodeScalar(m.X)})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the message.
+*/
+func (m *PolyChallenge) UnmarshalJSON(data []byte) error {
+    var encoded polyChallengeJSON
+    if err := decodeMessageJSON(data, &encoded); err != nil {
+        return err
+    }
+    scalars, err := decodeScalars(encoded.X)
+    if err != nil {
+        return err
+    }
+    *m = PolyChallenge{X: scalars[0]}
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the message. The vectors are preceded
+by their size.
+*/
+func (m ProofShare) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    if len(m.L) != len(m.R) || len(m.L) > 255 {
+        return nil, errors.New("size of the vectors can not be encoded")
+    }
+    buffer.Write([]byte{ENCODING_VERSION, tagProofShare})
+    writeScalars(&buffer, m.Taux, m.Mu, m.Tprime)
+    buffer.WriteByte(byte(len(m.L)))
+    writeScalars(&buffer, m.L...)
+    writeScalars(&buffer, m.R...)
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the message.
+*/
+func (m *ProofShare) UnmarshalBinary(data []byte) error {
+    var decoded ProofShare
+    d := newDecoder(data, tagProofShare)
+    decoded.Taux = d.scalar()
+    decoded.Mu = d.scalar()
+    decoded.Tprime = d.scalar()
+    n := int(d.readByte())
+    decoded.L = make([]*big.Int, n)
+    decoded.R = make([]*big.Int, n)
+    for i := range decoded.L {
+        decoded.L[i] = d.scalar()
+    }
+    for i := range decoded.R {
+        decoded.R[i] = d.scalar()
+    }
+    if err := d.finish(); err != nil {
+        return err
+    }
+    *m = decoded
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the message.
+*/
+func (m ProofShare) MarshalJSON() ([]byte, error) {
+    encoded := proofShareJSON{
+        Version: ENCODING_VERSION,
+        Taux:    encodeScalar(m.Taux),
+        Mu:      encodeScalar(m.Mu),
+        Tprime:  encodeScalar(m.Tprime),
+        L:       make([]string, len(m.L)),
+        R:       make([]string, len(m.R)),
+    }
+    for i := range m.L {
+        encoded.L[i] = encodeScalar(m.L[i])
+    }
+    for i := range m.R {
+        encoded.R[i] = encodeScalar(m.R[i])
+    }
+    return json.Marshal(encoded)
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the message.
+*/
+func (m *ProofShare) UnmarshalJSON(data []byte) error {
+    var encoded proofShareJSON
+    if err := decodeMessageJSON(data, &encoded); err != nil {
+        return err
+    }
+    scalars, err := decodeScalars(encoded.Taux, encoded.Mu, encoded.Tprime)
+    if err != nil {
+        return err
+    }
+    L, err := decodeScalars(encoded.L...)
+    if err != nil {
+        return err
+    }
+    R, err := decodeScalars(encoded.R...)
+    if err != nil {
+        return err
+    }
+    *m = ProofShare{Taux: scalars[0], Mu: scalars[1], Tprime: scalars[2], L: L, R: R}
+    return nil
+}
+
+/*
+decodeMessageJSON checks the version of a JSON encoded message and decodes it,
+refusing unknown fields.
+*/
+func decodeMessageJSON(data []byte, v interface{}) error {
+    if err := checkVersion(data); err != nil {
+        return err
+    }
+    return decodeStrict(data, v)
+}
+
+func decodePoints(encoded ...string) ([]*p256.P256, error) {
+    var err error
+    result := make([]*p256.P256, len(encoded))
+    for i, s := range encoded {
+        if result[i], err = decodePoint(s); err != nil {
+            return nil, err
+        }
+    }
+    return result, nil
+}
+
+func decodeScalars(encoded ...string) ([]*big.Int, error) {
+    var err error
+    result := make([]*big.Int, len(encoded))
+    for i, s := range encoded {
+        if result[i], err = decodeScalar(s); err != nil {
+            return nil, err
+        }
+    }
+    return result, nil
+}
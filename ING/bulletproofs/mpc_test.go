@@ -0,0 +1,179 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "encoding"
+    "encoding/json"
+    "math/big"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+/*
+transportJSON and transportBinary send a message over a transport, i.e. encode and
+decode it.
+*/
+func transportJSON(t *testing.T, in interface{}, out interface{}) {
+    data, err := json.Marshal(in)
+    assert.Nil(t, err)
+    assert.Nil(t, json.Unmarshal(data, out))
+}
+
+func transportBinary(t *testing.T, in encoding.BinaryMarshaler, out encoding.BinaryUnmarshaler) {
+    data, err := in.MarshalBinary()
+    assert.Nil(t, err)
+    assert.Nil(t, out.UnmarshalBinary(data))
+}
+
+/*
+runMPC runs the protocol with one party per secret, all of them in memory, and returns
+the dealer waiting for the shares together with the shares. The messages sent by
+the parties use the JSON encoding and the ones of the dealer the binary encoding.
+*/
+func runMPC(t *testing.T, secrets []*big.Int, params BulletProofSetupParams) (*DealerAwaitingProofShares, []ProofShare) {
+    m := len(secrets)
+    dealer, err := NewDealer(params, m)
+    assert.Nil(t, err)
+
+    parties := make([]*PartyAwaitingBitChallenge, m)
+    bits := make([]BitCommitment, m)
+    for j, secret := range secrets {
+        gamma, _ := sampleScalars(newProverConfig(nil).rand, 1)
+        party, err := NewParty(secret, gamma[0], params)
+        assert.Nil(t, err)
+        var bc BitCommitment
+        parties[j], bc, err = party.AssignPosition(j)
+        assert.Nil(t, err)
+        transportJSON(t, bc, &bits[j])
+    }
+    dealer2, bitChallenge, err := dealer.ReceiveBitCommitments(bits)
+    assert.Nil(t, err)
+
+    polyParties := make([]*PartyAwaitingPolyChallenge, m)
+    polys := make([]PolyCommitment, m)
+    for j := range parties {
+        var challenge BitChallenge
+        transportBinary(t, bitChallenge, &challenge)
+        var pc PolyCommitment
+        polyParties[j], pc, err = parties[j].ApplyChallenge(challenge)
+        assert.Nil(t, err)
+        transportJSON(t, pc, &polys[j])
+    }
+    dealer3, polyChallenge, err := dealer2.ReceivePolyCommitments(polys)
+    assert.Nil(t, err)
+
+    shares := make([]ProofShare, m)
+    for j := range polyParties {
+        var challenge PolyChallenge
+        transportBinary(t, polyChallenge, &challenge)
+        share, err := polyParties[j].ApplyChallenge(challenge)
+        assert.Nil(t, err)
+        transportJSON(t, share, &shares[j])
+    }
+    return dealer3, shares
+}
+
+func TestMPCAggregatedProof(t *testing.T) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 4)
+    secrets := []*big.Int{big.NewInt(18), big.NewInt(4200), big.NewInt(0), big.NewInt(MAX_RANGE_END - 1)}
+    dealer, shares := runMPC(t, secrets, params)
+    proof, err := dealer.ReceiveShares(shares)
+    assert.Nil(t, err)
+
+    var decoded AggregateBulletProof
+    transportBinary(t, proof, &decoded)
+    ok, err := decoded.Verify(params)
+    assert.Nil(t, err)
+    assert.True(t, ok, "proof of the parties should verify with the aggregated verifier")
+
+    _, err = dealer.ReceiveShares(shares)
+    assert.Equal(t, ErrStateConsumed, err, "dealer should not be reused")
+}
+
+func TestMPCTwoParties(t *testing.T) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 2)
+    dealer, shares := runMPC(t, []*big.Int{big.NewInt(40), big.NewInt(65)}, params)
+    proof, err := dealer.ReceiveShares(shares)
+    assert.Nil(t, err)
+    ok, _ := proof.Verify(params)
+    assert.True(t, ok, "proof of 2 parties should verify")
+}
+
+func TestMPCOutOfRange(t *testing.T) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 2)
+    dealer, shares := runMPC(t, []*big.Int{big.NewInt(40), big.NewInt(MAX_RANGE_END)}, params)
+    _, err := dealer.ReceiveShares(shares)
+    assert.Equal(t, MalformedSharesError{Parties: []int{1}}, err, "party with a secret out of range should be reported")
+}
+
+func TestMPCMalformedShares(t *testing.T) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 4)
+    secrets := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)}
+    dealer, shares := runMPC(t, secrets, params)
+
+    shares[1].Taux = new(big.Int).Add(shares[1].Taux, big.NewInt(1))
+    shares[3].L = append([]*big.Int{}, shares[3].L...)
+    shares[3].L[0] = new(big.Int).Add(shares[3].L[0], big.NewInt(1))
+    _, err := dealer.ReceiveShares(shares)
+    assert.Equal(t, MalformedSharesError{Parties: []int{1, 3}}, err)
+
+    shares[3].L = shares[3].L[1:]
+    _, err = dealer.ReceiveShares(shares[:3])
+    assert.Error(t, err, "missing shares should be rejected")
+}
+
+func TestMPCMaliciousDealer(t *testing.T) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 1)
+    party, err := NewParty(big.NewInt(7), big.NewInt(8), params)
+    assert.Nil(t, err)
+    waiting, _, err := party.AssignPosition(0)
+    assert.Nil(t, err)
+    poly, _, err := waiting.ApplyChallenge(BitChallenge{Y: big.NewInt(3), Z: big.NewInt(5)})
+    assert.Nil(t, err)
+    _, _, err = waiting.ApplyChallenge(BitChallenge{Y: big.NewInt(3), Z: big.NewInt(5)})
+    assert.Equal(t, ErrStateConsumed, err)
+
+    _, err = poly.ApplyChallenge(PolyChallenge{X: big.NewInt(0)})
+    assert.Equal(t, ErrMaliciousDealer, err, "x = 0 would reveal the bits of the secret")
+    _, err = poly.ApplyChallenge(PolyChallenge{X: new(big.Int).Set(ORDER)})
+    assert.Equal(t, ErrMaliciousDealer, err)
+
+    _, _, err = party.AssignPosition(1)
+    assert.Error(t, err, "position without generators should be rejected")
+    _, err = NewDealer(params, 3)
+    assert.Error(t, err, "number of parties must be a power of 2")
+}
+
+func TestMPCMessagesEncoding(t *testing.T) {
+    share := ProofShare{Taux: big.NewInt(1), Mu: big.NewInt(2), Tprime: big.NewInt(3), L: []*big.Int{big.NewInt(4)}, R: []*big.Int{big.NewInt(5)}}
+    data, err := share.MarshalBinary()
+    assert.Nil(t, err)
+    var decoded ProofShare
+    assert.Equal(t, ErrTrailingData, decoded.UnmarshalBinary(append(data, 0)))
+    var challenge PolyChallenge
+    assert.Equal(t, ErrInvalidProofType, challenge.UnmarshalBinary(data))
+
+    encoded, _ := json.Marshal(share)
+    var fields map[string]interface{}
+    _ = json.Unmarshal(encoded, &fields)
+    fields["Version"] = ENCODING_VERSION - 1
+    encoded, _ = json.Marshal(fields)
+    assert.Equal(t, ErrUnsupportedVersion, json.Unmarshal(encoded, &decoded))
+}
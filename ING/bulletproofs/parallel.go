@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the helpers used to split the work of the provers between
+goroutines, see WithWorkers. With a single worker everything runs on the calling
+goroutine, and the results never depend on the number of workers.
+*/
+
+package bulletproofs
+
+import (
+    "math/big"
+    "sync"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+/*
+parallelRange calls fn on consecutive ranges [from, to) covering [0, n), using at
+most workers goroutines.
+*/
+func parallelRange(workers, n int, fn func(from, to int)) {
+    if workers > n {
+        workers = n
+    }
+    if workers <= 1 {
+        fn(0, n)
+        return
+    }
+    size := (n + workers - 1) / workers
+    var wg sync.WaitGroup
+    for from := 0; from < n; from += size {
+        to := from + size
+        if to > n {
+            to = n
+        }
+        wg.Add(1)
+        go func(from, to int) {
+            defer wg.Done()
+            fn(from, to)
+        }(from, to)
+    }
+    wg.Wait()
+}
+
+/*
+parallelDo runs the tasks concurrently if more than one worker is available, and
+one after the other otherwise.
+*/
+func parallelDo(workers int, tasks ...func()) {
+    parallelRange(workers, len(tasks), func(from, to int) {
+        for _, task := range tasks[from:to] {
+            task()
+        }
+    })
+}
+
+/*
+parallelVectorExp is equal to VectorExp, splitting the multi-scalar multiplication
+between the workers.
+*/
+func parallelVectorExp(workers int, a []*p256.P256, b []*big.Int) (*p256.P256, error) {
+    return p256.ParallelMultiScalarMult(a, b, workers)
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+var workerCounts = []int{2, 3, 8, 0}
+
+func withWorkers(n int) []ProverOption {
+    return append([]ProverOption{WithWorkers(n)}, deterministic...)
+}
+
+func TestParallelRange(t *testing.T) {
+    for _, workers := range []int{1, 2, 3, 7, 100} {
+        covered := make([]int, 10)
+        parallelRange(workers, len(covered), func(from, to int) {
+            for i := from; i < to; i++ {
+                covered[i]++
+            }
+        })
+        assert.Equal(t, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, covered, "workers: %d", workers)
+    }
+}
+
+func TestParallelProve(t *testing.T) {
+    params, _ := SetupBits(64)
+    serial, _ := ProveWithCommitment(big.NewInt(1234), big.NewInt(5), params, deterministic...)
+    expected, _ := serial.MarshalBinary()
+    for _, n := range workerCounts {
+        proof, err := ProveWithCommitment(big.NewInt(1234), big.NewInt(5), params, withWorkers(n)...)
+        assert.NoError(t, err)
+        encoded, _ := proof.MarshalBinary()
+        assert.Equal(t, expected, encoded, "proofs should not depend on the workers: %d", n)
+    }
+
+    // With random nonces, the proofs still verify.
+    proof, _ := Prove(big.NewInt(1234), params, WithWorkers(4))
+    ok, _ := proof.Verify(params)
+    assert.True(t, ok, "proof of 4 workers should verify")
+}
+
+func TestParallelProveGeneric(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    serial, _ := ProveGenericWithCommitment(big.NewInt(40), big.NewInt(5), params, deterministic...)
+    expected, _ := serial.MarshalBinary()
+    for _, n := range workerCounts {
+        proof, _ := ProveGenericWithCommitment(big.NewInt(40), big.NewInt(5), params, withWorkers(n)...)
+        encoded, _ := proof.MarshalBinary()
+        assert.Equal(t, expected, encoded, "proofs should not depend on the workers: %d", n)
+    }
+}
+
+func TestParallelProveAggregate(t *testing.T) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 4)
+    secrets := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)}
+    gammas := []*big.Int{big.NewInt(5), big.NewInt(6), big.NewInt(7), big.NewInt(8)}
+    serial, _ := ProveAggregateWithCommitments(secrets, gammas, params, deterministic...)
+    expected, _ := serial.MarshalBinary()
+    for _, n := range workerCounts {
+        proof, _ := ProveAggregateWithCommitments(secrets, gammas, params, withWorkers(n)...)
+        encoded, _ := proof.MarshalBinary()
+        assert.Equal(t, expected, encoded, "proofs should not depend on the workers: %d", n)
+    }
+    proof, _ := ProveAggregate(secrets, params, WithWorkers(4))
+    ok, _ := proof.Verify(params)
+    assert.True(t, ok, "proof of 4 workers should verify")
+}
+
+func TestParallelR1CS(t *testing.T) {
+    params, _ := SetupR1CS(64)
+    for _, n := range workerCounts {
+        prover := NewR1CSProver(params, WithWorkers(n))
+        V, x := prover.Commit(big.NewInt(3), big.NewInt(5))
+        Z, z := prover.Commit(big.NewInt(9), big.NewInt(6))
+        productGadget(prover, x, x, z)
+        proof, err := prover.Prove()
+        assert.NoError(t, err)
+
+        verifier := NewR1CSVerifier(params)
+        x = verifier.Commit(V)
+        z = verifier.Commit(Z)
+        productGadget(verifier, x, x, z)
+        ok, _ := verifier.Verify(proof)
+        assert.True(t, ok, "proof of %d workers should verify", n)
+    }
+}
+
+func BenchmarkProve64(b *testing.B) {
+    benchmarkProve(b, 1)
+}
+
+func BenchmarkProve64Parallel(b *testing.B) {
+    benchmarkProve(b, 0)
+}
+
+func benchmarkProve(b *testing.B, workers int) {
+    params, _ := SetupBits(64)
+    secret := big.NewInt(1234)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        Prove(secret, params, WithWorkers(workers))
+    }
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the constraint system builder of the arithmetic circuit proofs,
+see Section 5 of the Bulletproofs paper. A statement is described by multiplication
+gates a_L[i] . a_R[i] = a_O[i] and by linear constraints over the wires of the gates
+and the committed values V[j]. The same gadget code is executed by the prover, which
+knows the assignment of every variable, and by the verifier, which only knows the
+commitments.
+*/
+
+package bulletproofs
+
+import (
+    "math/big"
+
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+type variableKind int
+
+const (
+    variableOne variableKind = iota
+    variableCommitted
+    variableMultiplierLeft
+    variableMultiplierRight
+    variableMultiplierOutput
+)
+
+/*
+Variable is a wire of the constraint system: a committed value, an input or the
+output of a multiplication gate, or the constant 1.
+*/
+type Variable struct {
+    kind  variableKind
+    index int
+}
+
+/*
+One returns the variable that is always assigned to 1. It is used to add constants
+to linear combinations.
+*/
+func One() Variable {
+    return Variable{kind: variableOne}
+}
+
+/*
+LC returns the linear combination 1.v.
+*/
+func (v Variable) LC() LinearCombination {
+    return LinearCombination{}.AddTerm(v, big.NewInt(1))
+}
+
+type term struct {
+    variable    Variable
+    coefficient *big.Int
+}
+
+/*
+LinearCombination is a sum of variables multiplied by coefficients. Its methods
+return new linear combinations and never modify the receiver.
+*/
+type LinearCombination struct {
+    terms []term
+}
+
+/*
+Constant returns the linear combination c.1.
+*/
+func Constant(c *big.Int) LinearCombination {
+    return One().LC().Scale(c)
+}
+
+/*
+AddTerm returns lc + c.v.
+*/
+func (lc LinearCombination) AddTerm(v Variable, c *big.Int) LinearCombination {
+    terms := make([]term, len(lc.terms), len(lc.terms)+1)
+    copy(terms, lc.terms)
+    terms = append(terms, term{variable: v, coefficient: bn.Mod(c, ORDER)})
+    return LinearCombination{terms: terms}
+}
+
+/*
+Add returns lc + other.
+*/
+func (lc LinearCombination) Add(other LinearCombination) LinearCombination {
+    terms := make([]term, 0, len(lc.terms)+len(other.terms))
+    terms = append(terms, lc.terms...)
+    terms = append(terms, other.terms...)
+    return LinearCombination{terms: terms}
+}
+
+/*
+Sub returns lc - other.
+*/
+func (lc LinearCombination) Sub(other LinearCombination) LinearCombination {
+    return lc.Add(other.Neg())
+}
+
+/*
+Neg returns -lc.
+*/
+func (lc LinearCombination) Neg() LinearCombination {
+    return lc.Scale(big.NewInt(-1))
+}
+
+/*
+Scale returns c.lc.
+*/
+func (lc LinearCombination) Scale(c *big.Int) LinearCombination {
+    terms := make([]term, len(lc.terms))
+    for i, t := range lc.terms {
+        terms[i] = term{variable: t.variable, coefficient: bn.Mod(bn.Multiply(t.coefficient, c), ORDER)}
+    }
+    return LinearCombination{terms: terms}
+}
+
+/*
+ConstraintSystem is implemented by both the R1CSProver and the R1CSVerifier, so that
+gadgets are written once and used to prove and to verify the same statement.
+*/
+type ConstraintSystem interface {
+    // Multiply adds a multiplication gate whose inputs are left and right, and
+    // returns the variables of its left input, right input and output.
+    Multiply(left, right LinearCombination) (Variable, Variable, Variable)
+    // AllocateMultiplier adds a multiplication gate whose inputs are free variables.
+    // The prover provides their assignment, the verifier must pass nil values.
+    AllocateMultiplier(left, right *big.Int) (Variable, Variable, Variable, error)
+    // Constrain adds the constraint lc = 0.
+    Constrain(lc LinearCombination)
+}
+
+/*
+constraintSystem stores the gates and the linear constraints added by the gadgets.
+It is shared by the prover and the verifier.
+*/
+type constraintSystem struct {
+    // m is the number of committed values.
+    m int
+    // n is the number of multiplication gates.
+    n           int
+    constraints []LinearCombination
+}
+
+func (cs *constraintSystem) commit() Variable {
+    cs.m = cs.m + 1
+    return Variable{kind: variableCommitted, index: cs.m - 1}
+}
+
+func (cs *constraintSystem) multiplier() (Variable, Variable, Variable) {
+    cs.n = cs.n + 1
+    i := cs.n - 1
+    return Variable{kind: variableMultiplierLeft, index: i},
+        Variable{kind: variableMultiplierRight, index: i},
+        Variable{kind: variableMultiplierOutput, index: i}
+}
+
+/*
+multiply adds the gate left * right = output, and constrains its inputs to be equal
+to the linear combinations.
+*/
+func (cs *constraintSystem) multiply(left, right LinearCombination) (Variable, Variable, Variable) {
+    l, r, o := cs.multiplier()
+    cs.Constrain(left.Sub(l.LC()))
+    cs.Constrain(right.Sub(r.LC()))
+    return l, r, o
+}
+
+func (cs *constraintSystem) Constrain(lc LinearCombination) {
+    cs.constraints = append(cs.constraints, lc)
+}
+
+/*
+flatten combines the constraints using the powers of the challenge z, such that
+they hold if and only if, with high probability,
+< wL, aL > + < wR, aR > + < wO, aO > = < wV, v > + wc.
+The vectors are padded with zeros to size n.
+*/
+func (cs *constraintSystem) flatten(z *big.Int, n int64) ([]*big.Int, []*big.Int, []*big.Int, []*big.Int, *big.Int) {
+    wL, _ := VectorCopy(new(big.Int), n)
+    wR, _ := VectorCopy(new(big.Int), n)
+    wO, _ := VectorCopy(new(big.Int), n)
+    wV, _ := VectorCopy(new(big.Int), int64(cs.m))
+    wc := new(big.Int)
+
+    expz := z
+    for _, lc := range cs.constraints {
+        for _, t := range lc.terms {
+            c := bn.Mod(bn.Multiply(t.coefficient, expz), ORDER)
+            i := t.variable.index
+            switch t.variable.kind {
+            case variableMultiplierLeft:
+                wL[i] = bn.Mod(bn.Add(wL[i], c), ORDER)
+            case variableMultiplierRight:
+                wR[i] = bn.Mod(bn.Add(wR[i], c), ORDER)
+            case variableMultiplierOutput:
+                wO[i] = bn.Mod(bn.Add(wO[i], c), ORDER)
+            case variableCommitted:
+                wV[i] = bn.Mod(bn.Sub(wV[i], c), ORDER)
+            case variableOne:
+                wc = bn.Mod(bn.Sub(wc, c), ORDER)
+            }
+        }
+        expz = bn.Mod(bn.Multiply(expz, z), ORDER)
+    }
+    return wL, wR, wO, wV, wc
+}
@@ -0,0 +1,491 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the prover and the verifier of the arithmetic circuit proofs,
+following Section 5.3 of the Bulletproofs paper, where the committed values are
+Pedersen commitments V[j] = g^v[j].h^gamma[j] computed with CommitG1. The polynomials
+are arranged such that t(X) = < l(X), r(X) > has no constant term and the
+commitments to the gate wires fit in three points:
+l(X) = (aL + y^-n . wR).X + aO.X^2 + sL.X^3
+r(X) = y^n . aR.X + wL.X + wO - y^n + y^n . sR.X^3
+*/
+
+package bulletproofs
+
+import (
+    "errors"
+    "fmt"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+    . "github.com/ing-bank/zkrp/util"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+var MAX_R1CS_GATES int64 = 16384 // 2**14
+
+/*
+R1CSSetupParams contains the generators of the arithmetic circuit proofs. They
+allow proving statements with up to Capacity multiplication gates.
+*/
+type R1CSSetupParams struct {
+    // Capacity is the number of generators in Gg and Hh, a power of 2.
+    Capacity int64
+    // G and H are the generators of the Pedersen commitments to the values.
+    G *p256.P256
+    H *p256.P256
+    // Gg and Hh are the generators of the vector commitments to the gate wires.
+    Gg []*p256.P256
+    Hh []*p256.P256
+}
+
+/*
+R1CSProof contains the prover messages of an arithmetic circuit proof. The
+commitments to the values are provided to the verifier separately.
+*/
+type R1CSProof struct {
+    AI                *p256.P256
+    AO                *p256.P256
+    S                 *p256.P256
+    T1                *p256.P256
+    T3                *p256.P256
+    T4                *p256.P256
+    T5                *p256.P256
+    T6                *p256.P256
+    Taux              *big.Int
+    Mu                *big.Int
+    Tprime            *big.Int
+    InnerProductProof InnerProductProof
+}
+
+/*
+SetupR1CS computes the generators for constraint systems with up to capacity
+multiplication gates, where capacity is a power of 2. They are the same generators
+used by the range proofs.
+*/
+func SetupR1CS(capacity int64) (R1CSSetupParams, error) {
+    if capacity <= 0 || !IsPowerOfTwo(capacity) {
+        return R1CSSetupParams{}, fmt.Errorf("capacity should be a power of 2: %d", capacity)
+    }
+    if capacity > MAX_R1CS_GATES {
+        return R1CSSetupParams{}, fmt.Errorf("capacity can not be greater than %d", MAX_R1CS_GATES)
+    }
+    var err error
+    params := R1CSSetupParams{Capacity: capacity}
+    params.G = generatorG()
+    params.H, err = generatorH()
+    if err != nil {
+        return R1CSSetupParams{}, err
+    }
+    params.Gg, params.Hh, err = vectorGenerators(0, capacity)
+    if err != nil {
+        return R1CSSetupParams{}, err
+    }
+    return params, nil
+}
+
+/*
+paddedSize returns the number of gates of the proof, the number of gates of the
+constraint system rounded up to a power of 2.
+*/
+func (params R1CSSetupParams) paddedSize(cs *constraintSystem) (int64, error) {
+    n := int64(1)
+    for n < int64(cs.n) {
+        n = 2 * n
+    }
+    if n > params.Capacity || int64(len(params.Gg)) < n || int64(len(params.Hh)) < n {
+        return 0, fmt.Errorf("constraint system has %d gates, but the capacity is %d", cs.n, params.Capacity)
+    }
+    return n, nil
+}
+
+/*
+R1CSProver builds the constraint system together with the assignment of its
+variables, and computes the proof that the assignment satisfies it.
+*/
+type R1CSProver struct {
+    constraintSystem
+    params     R1CSSetupParams
+    cfg        proverConfig
+    transcript *transcript.Transcript
+    v          []*big.Int
+    gamma      []*big.Int
+    aL         []*big.Int
+    aR         []*big.Int
+    aO         []*big.Int
+}
+
+/*
+NewR1CSProver returns a prover for a new constraint system. The options set the
+randomness of Prove, the blinding factors of the commitments are chosen by the caller.
+*/
+func NewR1CSProver(params R1CSSetupParams, opts ...ProverOption) *R1CSProver {
+    return &R1CSProver{params: params, cfg: newProverConfig(opts), transcript: transcript.New(R1CS_PROOF_LABEL)}
+}
+
+/*
+Commit computes the commitment V = g^v.h^gamma and returns it together with the
+variable that represents v in the constraint system.
+*/
+func (prover *R1CSProver) Commit(v, gamma *big.Int) (*p256.P256, Variable) {
+    v = bn.Mod(v, ORDER)
+    gamma = bn.Mod(gamma, ORDER)
+    V, _ := CommitG1(v, gamma, prover.params.H)
+    appendPoint(prover.transcript, "V", V)
+    prover.v = append(prover.v, v)
+    prover.gamma = append(prover.gamma, gamma)
+    return V, prover.commit()
+}
+
+/*
+Multiply adds a multiplication gate whose inputs are the linear combinations.
+*/
+func (prover *R1CSProver) Multiply(left, right LinearCombination) (Variable, Variable, Variable) {
+    l := prover.eval(left)
+    r := prover.eval(right)
+    prover.assign(l, r)
+    return prover.multiply(left, right)
+}
+
+/*
+AllocateMultiplier adds a multiplication gate whose inputs are assigned to left and
+right.
+*/
+func (prover *R1CSProver) AllocateMultiplier(left, right *big.Int) (Variable, Variable, Variable, error) {
+    if left == nil || right == nil {
+        return Variable{}, Variable{}, Variable{}, errors.New("prover must assign the inputs of the multiplier")
+    }
+    prover.assign(bn.Mod(left, ORDER), bn.Mod(right, ORDER))
+    l, r, o := prover.multiplier()
+    return l, r, o, nil
+}
+
+func (prover *R1CSProver) assign(l, r *big.Int) {
+    prover.aL = append(prover.aL, l)
+    prover.aR = append(prover.aR, r)
+    prover.aO = append(prover.aO, bn.Mod(bn.Multiply(l, r), ORDER))
+}
+
+/*
+eval computes the value of the linear combination for the current assignment.
+*/
+func (prover *R1CSProver) eval(lc LinearCombination) *big.Int {
+    result := new(big.Int)
+    for _, t := range lc.terms {
+        var value *big.Int
+        switch t.variable.kind {
+        case variableOne:
+            value = big.NewInt(1)
+        case variableCommitted:
+            value = prover.v[t.variable.index]
+        case variableMultiplierLeft:
+            value = prover.aL[t.variable.index]
+        case variableMultiplierRight:
+            value = prover.aR[t.variable.index]
+        case variableMultiplierOutput:
+            value = prover.aO[t.variable.index]
+        }
+        result = bn.Mod(bn.Add(result, bn.Multiply(t.coefficient, value)), ORDER)
+    }
+    return result
+}
+
+/*
+Prove computes the proof that the assignment satisfies all the constraints. It
+returns an error if some constraint does not hold.
+*/
+func (prover *R1CSProver) Prove() (R1CSProof, error) {
+    var proof R1CSProof
+    for i, lc := range prover.constraints {
+        if prover.eval(lc).Sign() != 0 {
+            return proof, fmt.Errorf("constraint %d is not satisfied", i)
+        }
+    }
+    n, err := prover.params.paddedSize(&prover.constraintSystem)
+    if err != nil {
+        return proof, err
+    }
+    t := prover.transcript.Clone()
+    r1csDomainSep(t, prover.params, &prover.constraintSystem, n)
+    Gg := prover.params.Gg[:n]
+    Hh := prover.params.Hh[:n]
+    H := prover.params.H
+
+    // The unused gates are assigned to 0 . 0 = 0
+    aL := padVector(prover.aL, n)
+    aR := padVector(prover.aR, n)
+    aO := padVector(prover.aO, n)
+
+    // alpha, beta, rho and the blinding factors of T1, T3, ..., T6, followed by sL
+    // and sR. The witness is the committed values and the wires of the gates.
+    witness := append(append([]*big.Int{}, prover.v...), prover.gamma...)
+    witness = append(append(witness, prover.aL...), prover.aR...)
+    rng, err := prover.cfg.nonceReader(t, witness...)
+    if err != nil {
+        return proof, err
+    }
+    nonces, err := sampleScalars(rng, 8)
+    if err != nil {
+        return proof, err
+    }
+    alpha, beta, rho := nonces[0], nonces[1], nonces[2]
+    tau1, tau3, tau4, tau5, tau6 := nonces[3], nonces[4], nonces[5], nonces[6], nonces[7]
+    sL, err := sampleRandomVector(rng, n)
+    if err != nil {
+        return proof, err
+    }
+    sR, err := sampleRandomVector(rng, n)
+    if err != nil {
+        return proof, err
+    }
+
+    // Commitments to the wires: AI = h^alpha.g^aL.h^aR, AO = h^beta.g^aO and to the
+    // blinding vectors: S = h^rho.g^sL.h^sR
+    zeros, _ := VectorCopy(new(big.Int), n)
+    AI := commitVectorBig(aL, aR, alpha, H, Gg, Hh, n, prover.cfg.workers)
+    AO := commitVectorBig(aO, zeros, beta, H, Gg, Hh, n, prover.cfg.workers)
+    S := commitVectorBig(sL, sR, rho, H, Gg, Hh, n, prover.cfg.workers)
+
+    appendPoint(t, "AI", AI)
+    appendPoint(t, "AO", AO)
+    appendPoint(t, "S", S)
+    y := challengeScalar(t, "y")
+    z := challengeScalar(t, "z")
+
+    wL, wR, wO, wV, _ := prover.flatten(z, n)
+    vy := powerOf(y, n)
+    vyinv := powerOf(bn.ModInverse(y, ORDER), n)
+
+    // Coefficients of l(X) and r(X)
+    ywR, _ := VectorMul(vyinv, wR)
+    l1, _ := VectorAdd(aL, ywR)
+    l2 := aO
+    l3 := sL
+    r0, _ := VectorSub(wO, vy)
+    yaR, _ := VectorMul(vy, aR)
+    r1, _ := VectorAdd(yaR, wL)
+    r3, _ := VectorMul(vy, sR)
+
+    // Coefficients of t(X) = < l(X), r(X) >, t2 does not need to be committed
+    t1, _ := ScalarProduct(l1, r0)
+    t3 := innerProductSum(l2, r1, l3, r0)
+    t4 := innerProductSum(l1, r3, l3, r1)
+    t5, _ := ScalarProduct(l2, r3)
+    t6, _ := ScalarProduct(l3, r3)
+
+    T1, _ := CommitG1(t1, tau1, H)
+    T3, _ := CommitG1(t3, tau3, H)
+    T4, _ := CommitG1(t4, tau4, H)
+    T5, _ := CommitG1(t5, tau5, H)
+    T6, _ := CommitG1(t6, tau6, H)
+
+    appendPoint(t, "T1", T1)
+    appendPoint(t, "T3", T3)
+    appendPoint(t, "T4", T4)
+    appendPoint(t, "T5", T5)
+    appendPoint(t, "T6", T6)
+    x := challengeScalar(t, "x")
+
+    // The blinding factor of t2 is < wV, gamma >
+    tau2, _ := ScalarProduct(wV, prover.gamma)
+    vx := powerOf(x, 7)
+    taux, _ := ScalarProduct([]*big.Int{tau1, tau2, tau3, tau4, tau5, tau6}, vx[1:])
+    mu, _ := ScalarProduct([]*big.Int{alpha, beta, rho}, vx[1:4])
+
+    // l = l(x), r = r(x) and t' = < l, r >
+    l := evalPolynomial([][]*big.Int{zeros, l1, l2, l3}, vx)
+    r := evalPolynomial([][]*big.Int{r0, r1, zeros, r3}, vx)
+    tprime, _ := ScalarProduct(l, r)
+
+    appendScalar(t, "taux", taux)
+    appendScalar(t, "mu", mu)
+    appendScalar(t, "t", tprime)
+
+    // Inner Product over (g, h', P.h^-mu, tprime)
+    hprime := updateGenerators(Hh, y, n, prover.cfg.workers)
+    ipParams, err := setupInnerProduct(H, Gg, hprime, tprime, n)
+    if err != nil {
+        return proof, err
+    }
+    commit := commitInnerProduct(Gg, hprime, l, r, prover.cfg.workers)
+    proofip, err := proveInnerProductWithTranscript(t, l, r, commit, ipParams, prover.cfg.workers)
+    if err != nil {
+        return proof, err
+    }
+
+    proof.AI = AI
+    proof.AO = AO
+    proof.S = S
+    proof.T1 = T1
+    proof.T3 = T3
+    proof.T4 = T4
+    proof.T5 = T5
+    proof.T6 = T6
+    proof.Taux = taux
+    proof.Mu = mu
+    proof.Tprime = tprime
+    proof.InnerProductProof = proofip
+    return proof, nil
+}
+
+/*
+R1CSVerifier builds the constraint system from the commitments to the values, and
+verifies the proof that the prover knows an assignment that satisfies it.
+*/
+type R1CSVerifier struct {
+    constraintSystem
+    params     R1CSSetupParams
+    transcript *transcript.Transcript
+    V          []*p256.P256
+}
+
+/*
+NewR1CSVerifier returns a verifier for a new constraint system. The params must be
+computed by the verifier, using SetupR1CS.
+*/
+func NewR1CSVerifier(params R1CSSetupParams) *R1CSVerifier {
+    return &R1CSVerifier{params: params, transcript: transcript.New(R1CS_PROOF_LABEL)}
+}
+
+/*
+Commit returns the variable that represents the value committed in V. The
+commitments must be added in the same order as the prover did.
+*/
+func (verifier *R1CSVerifier) Commit(V *p256.P256) Variable {
+    appendPoint(verifier.transcript, "V", V)
+    verifier.V = append(verifier.V, V)
+    return verifier.commit()
+}
+
+/*
+Multiply adds a multiplication gate whose inputs are the linear combinations.
+*/
+func (verifier *R1CSVerifier) Multiply(left, right LinearCombination) (Variable, Variable, Variable) {
+    return verifier.multiply(left, right)
+}
+
+/*
+AllocateMultiplier adds a multiplication gate whose inputs are only known by the
+prover. The values are ignored.
+*/
+func (verifier *R1CSVerifier) AllocateMultiplier(left, right *big.Int) (Variable, Variable, Variable, error) {
+    l, r, o := verifier.multiplier()
+    return l, r, o, nil
+}
+
+/*
+Verify returns true if and only if the proof is valid for the constraint system.
+*/
+func (verifier *R1CSVerifier) Verify(proof R1CSProof) (bool, error) {
+    if err := checkPoints(verifier.V...); err != nil {
+        return false, err
+    }
+    n, err := verifier.params.paddedSize(&verifier.constraintSystem)
+    if err != nil {
+        return false, err
+    }
+    if err := proof.validate(n); err != nil {
+        return false, err
+    }
+    // The transcript is cloned, so that the verifier can be used more than once.
+    t := verifier.transcript.Clone()
+    r1csDomainSep(t, verifier.params, &verifier.constraintSystem, n)
+    Gg := verifier.params.Gg[:n]
+    Hh := verifier.params.Hh[:n]
+    H := verifier.params.H
+
+    y, z, x := proof.challenges(t)
+    wL, wR, wO, wV, wc := verifier.flatten(z, n)
+    vyinv := powerOf(bn.ModInverse(y, ORDER), n)
+    vx := powerOf(x, 7)
+
+    // ////////////////////////////////////////////////////////////////////////////
+    // Check that g^t'.h^taux = V^(x^2.wV).g^(x^2.(wc + delta)).T1^x.T3^x^3...T6^x^6
+    // ////////////////////////////////////////////////////////////////////////////
+    lhs, _ := CommitG1(proof.Tprime, proof.Taux, H)
+
+    // delta(y,z) = < y^-n . wR, wL >
+    ywR, _ := VectorMul(vyinv, wR)
+    delta, _ := ScalarProduct(ywR, wL)
+    x2wV, _ := VectorScalarMul(wV, vx[2])
+    gExp := bn.Mod(bn.Multiply(vx[2], bn.Add(wc, delta)), ORDER)
+    points := concatPoints(verifier.V, []*p256.P256{verifier.params.G, proof.T1, proof.T3, proof.T4, proof.T5, proof.T6})
+    scalars := concatScalars(x2wV, []*big.Int{gExp, vx[1], vx[3], vx[4], vx[5], vx[6]})
+    rhs, _ := VectorExp(points, scalars)
+    ct := rhs.Multiply(rhs, lhs.Neg(lhs)).IsZero()
+
+    // ////////////////////////////////////////////////////////////////////////////
+    // Compute P.h^-mu = AI^x.AO^x^2.S^x^3.g^(x.y^-n.wR).h^(y^-n.(x.wL + wO) - 1).h^-mu
+    // which must be a commitment to l and r, with respect to g and h'
+    // ////////////////////////////////////////////////////////////////////////////
+    gExps, _ := VectorScalarMul(ywR, x)
+    xwL, _ := VectorScalarMul(wL, x)
+    hExps, _ := VectorAdd(xwL, wO)
+    hExps, _ = VectorMul(hExps, vyinv)
+    ones, _ := VectorCopy(big.NewInt(1), n)
+    hExps, _ = VectorSub(hExps, ones)
+    mmu := bn.Mod(bn.Sub(ORDER, proof.Mu), ORDER)
+    points = concatPoints([]*p256.P256{proof.AI, proof.AO, proof.S, H}, Gg, Hh)
+    scalars = concatScalars([]*big.Int{vx[1], vx[2], vx[3], mmu}, gExps, hExps)
+    P, _ := VectorExp(points, scalars)
+
+    // Verify Inner Product Proof, i.e. that tprime = < l, r >
+    hprime := updateGenerators(Hh, y, n, 1)
+    ipParams, err := setupInnerProduct(H, Gg, hprime, proof.Tprime, n)
+    if err != nil {
+        return false, err
+    }
+    ipParams.P = P
+    ok, err := proof.InnerProductProof.verifyWithTranscript(t, ipParams)
+    if err != nil {
+        return false, err
+    }
+    return ct && ok, nil
+}
+
+/*
+padVector returns a copy of a padded with zeros to size n.
+*/
+func padVector(a []*big.Int, n int64) []*big.Int {
+    result, _ := VectorCopy(new(big.Int), n)
+    copy(result, a)
+    return result
+}
+
+/*
+innerProductSum returns < a, b > + < c, d >.
+*/
+func innerProductSum(a, b, c, d []*big.Int) *big.Int {
+    ab, _ := ScalarProduct(a, b)
+    cd, _ := ScalarProduct(c, d)
+    return bn.Mod(bn.Add(ab, cd), ORDER)
+}
+
+/*
+evalPolynomial computes sum_i coefficients[i].x^i, where the coefficients are
+vectors and vx contains the powers of x.
+*/
+func evalPolynomial(coefficients [][]*big.Int, vx []*big.Int) []*big.Int {
+    result := coefficients[0]
+    for i := 1; i < len(coefficients); i++ {
+        term, _ := VectorScalarMul(coefficients[i], vx[i])
+        result, _ = VectorAdd(result, term)
+    }
+    return result
+}
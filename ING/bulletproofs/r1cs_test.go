@@ -0,0 +1,163 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/stretchr/testify/assert"
+)
+
+/*
+productGadget constrains x . y = z.
+*/
+func productGadget(cs ConstraintSystem, x, y, z Variable) {
+    _, _, o := cs.Multiply(x.LC(), y.LC())
+    cs.Constrain(o.LC().Sub(z.LC()))
+}
+
+/*
+bitsGadget constrains lc to be in [0, 2^bits). The prover passes the value of lc,
+the verifier passes nil.
+*/
+func bitsGadget(cs ConstraintSystem, lc LinearCombination, value *big.Int, bits int) error {
+    sum := LinearCombination{}
+    for i := 0; i < bits; i++ {
+        var left, right *big.Int
+        if value != nil {
+            left = big.NewInt(int64(value.Bit(i)))
+            right = new(big.Int).Sub(big.NewInt(1), left)
+        }
+        l, r, o, err := cs.AllocateMultiplier(left, right)
+        if err != nil {
+            return err
+        }
+        // l . (1 - l) = 0
+        cs.Constrain(o.LC())
+        cs.Constrain(l.LC().Add(r.LC()).Sub(Constant(big.NewInt(1))))
+        sum = sum.AddTerm(l, new(big.Int).Lsh(big.NewInt(1), uint(i)))
+    }
+    cs.Constrain(sum.Sub(lc))
+    return nil
+}
+
+/*
+sumBelowGadget constrains a + b < limit, where limit - 1 - a - b fits in 16 bits.
+*/
+func sumBelowGadget(cs ConstraintSystem, a, b Variable, limit, value *big.Int) error {
+    diff := Constant(new(big.Int).Sub(limit, big.NewInt(1))).Sub(a.LC()).Sub(b.LC())
+    return bitsGadget(cs, diff, value, 16)
+}
+
+func TestR1CSProduct(t *testing.T) {
+    params, _ := SetupR1CS(8)
+    prover := NewR1CSProver(params)
+    gamma := big.NewInt(42)
+    X, x := prover.Commit(big.NewInt(3), gamma)
+    Y, y := prover.Commit(big.NewInt(7), gamma)
+    Z, z := prover.Commit(big.NewInt(21), gamma)
+    productGadget(prover, x, y, z)
+    proof, err := prover.Prove()
+    assert.NoError(t, err)
+
+    verifier := NewR1CSVerifier(params)
+    x = verifier.Commit(X)
+    y = verifier.Commit(Y)
+    z = verifier.Commit(Z)
+    productGadget(verifier, x, y, z)
+    ok, err := verifier.Verify(proof)
+    assert.NoError(t, err)
+    assert.True(t, ok, "product proof should verify")
+
+    // The verifier can not be convinced of another product.
+    verifier = NewR1CSVerifier(params)
+    x = verifier.Commit(X)
+    y = verifier.Commit(Y)
+    W, _ := prover.Commit(big.NewInt(22), gamma)
+    z = verifier.Commit(W)
+    productGadget(verifier, x, y, z)
+    ok, _ = verifier.Verify(proof)
+    assert.False(t, ok, "proof should not verify for another commitment")
+}
+
+func TestR1CSSumBelowLimit(t *testing.T) {
+    params, _ := SetupR1CS(16)
+    limit := big.NewInt(1000)
+    a, b := big.NewInt(400), big.NewInt(599)
+    value := new(big.Int).Sub(limit, big.NewInt(1))
+    value.Sub(value, a).Sub(value, b)
+
+    prover := NewR1CSProver(params)
+    A, va := prover.Commit(a, big.NewInt(5))
+    B, vb := prover.Commit(b, big.NewInt(6))
+    assert.NoError(t, sumBelowGadget(prover, va, vb, limit, value))
+    proof, err := prover.Prove()
+    assert.NoError(t, err)
+
+    verifier := NewR1CSVerifier(params)
+    va = verifier.Commit(A)
+    vb = verifier.Commit(B)
+    assert.NoError(t, sumBelowGadget(verifier, va, vb, limit, nil))
+    ok, err := verifier.Verify(proof)
+    assert.NoError(t, err)
+    assert.True(t, ok, "sum below the limit should verify")
+
+    // The verifier can be used again, and rejects a tampered proof.
+    proof.Tprime = new(big.Int).Add(proof.Tprime, big.NewInt(1))
+    ok, _ = verifier.Verify(proof)
+    assert.False(t, ok, "tampered proof should not verify")
+}
+
+func TestR1CSUnsatisfied(t *testing.T) {
+    params, _ := SetupR1CS(16)
+    limit := big.NewInt(1000)
+    a, b := big.NewInt(400), big.NewInt(600)
+
+    prover := NewR1CSProver(params)
+    _, va := prover.Commit(a, big.NewInt(5))
+    _, vb := prover.Commit(b, big.NewInt(6))
+    // a + b = limit, the difference does not decompose in bits
+    value := new(big.Int).Lsh(big.NewInt(1), 16)
+    value.Sub(value, big.NewInt(1))
+    assert.NoError(t, sumBelowGadget(prover, va, vb, limit, value))
+    _, err := prover.Prove()
+    assert.Error(t, err, "unsatisfied constraint system should be rejected")
+}
+
+func TestR1CSCapacity(t *testing.T) {
+    _, err := SetupR1CS(12)
+    assert.Error(t, err, "capacity should be a power of 2")
+
+    params, _ := SetupR1CS(8)
+    prover := NewR1CSProver(params)
+    _, va := prover.Commit(big.NewInt(1), big.NewInt(5))
+    _, vb := prover.Commit(big.NewInt(2), big.NewInt(6))
+    assert.NoError(t, sumBelowGadget(prover, va, vb, big.NewInt(1000), big.NewInt(996)))
+    _, err = prover.Prove()
+    assert.Error(t, err, "constraint system with more gates than the capacity should be rejected")
+}
+
+func TestR1CSMissingCommitment(t *testing.T) {
+    params, _ := SetupR1CS(8)
+    verifier := NewR1CSVerifier(params)
+    ok, err := verifier.Verify(R1CSProof{AI: new(p256.P256).SetInfinity()})
+    assert.Error(t, err)
+    assert.False(t, ok)
+}
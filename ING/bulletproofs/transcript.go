@@ -0,0 +1,199 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the messages absorbed by the Fiat-Shamir transcripts of the
+proofs. Points and scalars are absorbed using their canonical encodings, see
+encoding.go.
+*/
+
+package bulletproofs
+
+import (
+    "bytes"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+)
+
+// Labels of the transcripts of each proof system.
+const (
+    RANGE_PROOF_LABEL         = "zkrp bulletproofs range proof"
+    GENERIC_RANGE_PROOF_LABEL = "zkrp bulletproofs generic range proof"
+    INNER_PRODUCT_LABEL       = "zkrp bulletproofs inner product"
+    R1CS_PROOF_LABEL          = "zkrp bulletproofs r1cs proof"
+)
+
+/*
+rangeProofDomainSep absorbs the statement of a range proof over m values: the
+bit-length of the range and the generators.
+*/
+func rangeProofDomainSep(t *transcript.Transcript, params BulletProofSetupParams, m int64) {
+    mn := m * params.N
+    t.AppendMessage("dom-sep", []byte("rangeproof v1"))
+    t.AppendUint64("n", uint64(params.N))
+    t.AppendUint64("m", uint64(m))
+    appendPoint(t, "G", params.G)
+    appendPoint(t, "H", params.H)
+    appendPoints(t, "Gg", params.Gg[:mn])
+    appendPoints(t, "Hh", params.Hh[:mn])
+}
+
+/*
+genericDomainSep absorbs the statement of a generic range proof: the interval
+[A, B), its bit-length and the commitment C. The range proofs of both halves follow
+on the same transcript.
+*/
+func genericDomainSep(t *transcript.Transcript, params *bprp, C *p256.P256) {
+    t.AppendMessage("dom-sep", []byte("generic rangeproof v1"))
+    appendInteger(t, "A", params.A)
+    appendInteger(t, "B", params.B)
+    t.AppendUint64("n", uint64(params.N))
+    appendPoint(t, "C", C)
+}
+
+/*
+innerProductDomainSep absorbs the size of the vectors and the generator u of the
+inner product argument.
+*/
+func innerProductDomainSep(t *transcript.Transcript, params InnerProductParams) {
+    t.AppendMessage("dom-sep", []byte("ipp v1"))
+    t.AppendUint64("n", uint64(params.N))
+    appendPoint(t, "U", params.Uu)
+}
+
+/*
+r1csDomainSep absorbs the size of the constraint system, once all the values have
+been committed and all the gates added, and the generators used by the proof.
+*/
+func r1csDomainSep(t *transcript.Transcript, params R1CSSetupParams, cs *constraintSystem, n int64) {
+    t.AppendMessage("dom-sep", []byte("r1cs v1"))
+    t.AppendUint64("m", uint64(cs.m))
+    t.AppendUint64("n", uint64(cs.n))
+    t.AppendUint64("q", uint64(len(cs.constraints)))
+    appendPoint(t, "G", params.G)
+    appendPoint(t, "H", params.H)
+    appendPoints(t, "Gg", params.Gg[:n])
+    appendPoints(t, "Hh", params.Hh[:n])
+}
+
+/*
+challenges absorbs the messages of the arithmetic circuit proof and recovers the
+challenges y, z and x.
+*/
+func (proof *R1CSProof) challenges(t *transcript.Transcript) (*big.Int, *big.Int, *big.Int) {
+    appendPoint(t, "AI", proof.AI)
+    appendPoint(t, "AO", proof.AO)
+    appendPoint(t, "S", proof.S)
+    y := challengeScalar(t, "y")
+    z := challengeScalar(t, "z")
+    appendPoint(t, "T1", proof.T1)
+    appendPoint(t, "T3", proof.T3)
+    appendPoint(t, "T4", proof.T4)
+    appendPoint(t, "T5", proof.T5)
+    appendPoint(t, "T6", proof.T6)
+    x := challengeScalar(t, "x")
+    appendScalar(t, "taux", proof.Taux)
+    appendScalar(t, "mu", proof.Mu)
+    appendScalar(t, "t", proof.Tprime)
+    return y, z, x
+}
+
+/*
+newInnerProductTranscript returns the transcript of a standalone inner product
+argument, that absorbs the generators, the commitment P and the inner product c.
+Within a range proof these are determined by the messages already absorbed.
+*/
+func newInnerProductTranscript(params InnerProductParams, P *p256.P256) *transcript.Transcript {
+    t := transcript.New(INNER_PRODUCT_LABEL)
+    appendPoints(t, "Gg", params.Gg)
+    appendPoints(t, "Hh", params.Hh)
+    appendPoint(t, "P", P)
+    appendScalar(t, "c", params.Cc)
+    return t
+}
+
+/*
+challenges absorbs the messages of the range proof and recovers the challenges y, z
+and x. The inner product argument continues on the same transcript.
+*/
+func (proof *BulletProof) challenges(t *transcript.Transcript, params BulletProofSetupParams) (*big.Int, *big.Int, *big.Int) {
+    rangeProofDomainSep(t, params, 1)
+    appendPoint(t, "V", proof.V)
+    return rangeProofChallenges(t, proof.A, proof.S, proof.T1, proof.T2, proof.Taux, proof.Mu, proof.Tprime)
+}
+
+/*
+challenges absorbs the messages of the aggregated range proof and recovers the
+challenges y, z and x.
+*/
+func (proof *AggregateBulletProof) challenges(t *transcript.Transcript, params BulletProofSetupParams) (*big.Int, *big.Int, *big.Int) {
+    rangeProofDomainSep(t, params, int64(len(proof.V)))
+    for _, V := range proof.V {
+        appendPoint(t, "V", V)
+    }
+    return rangeProofChallenges(t, proof.A, proof.S, proof.T1, proof.T2, proof.Taux, proof.Mu, proof.Tprime)
+}
+
+/*
+rangeProofChallenges absorbs the messages that follow the commitments, in the order
+they are sent by the prover, and returns the challenges y, z and x.
+*/
+func rangeProofChallenges(t *transcript.Transcript, A, S, T1, T2 *p256.P256, taux, mu, tprime *big.Int) (*big.Int, *big.Int, *big.Int) {
+    appendPoint(t, "A", A)
+    appendPoint(t, "S", S)
+    y := challengeScalar(t, "y")
+    z := challengeScalar(t, "z")
+    appendPoint(t, "T1", T1)
+    appendPoint(t, "T2", T2)
+    x := challengeScalar(t, "x")
+    appendScalar(t, "taux", taux)
+    appendScalar(t, "mu", mu)
+    appendScalar(t, "t", tprime)
+    return y, z, x
+}
+
+func appendPoint(t *transcript.Transcript, label string, point *p256.P256) {
+    t.AppendMessage(label, pointBytes(point))
+}
+
+func appendPoints(t *transcript.Transcript, label string, points []*p256.P256) {
+    var buffer bytes.Buffer
+    writePoints(&buffer, points...)
+    t.AppendMessage(label, buffer.Bytes())
+}
+
+/*
+appendInteger absorbs an integer that is not reduced modulo ORDER, as a sign byte
+followed by its magnitude in big-endian order.
+*/
+func appendInteger(t *transcript.Transcript, label string, n *big.Int) {
+    sign := byte(0)
+    if n.Sign() < 0 {
+        sign = 1
+    }
+    t.AppendMessage(label, append([]byte{sign}, n.Bytes()...))
+}
+
+func appendScalar(t *transcript.Transcript, label string, scalar *big.Int) {
+    t.AppendMessage(label, scalarBytes(scalar))
+}
+
+func challengeScalar(t *transcript.Transcript, label string) *big.Int {
+    return t.ChallengeScalar(label, ORDER)
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofs
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestChallengesBindStatement(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    y, z, x := proof.challenges(transcript.New(RANGE_PROOF_LABEL), params)
+    assert.NotEqual(t, y, z, "y and z should be independent")
+
+    // The commitment V is part of the statement
+    other := proof
+    other.V = new(p256.P256).Multiply(proof.V, params.G)
+    y2, z2, x2 := other.challenges(transcript.New(RANGE_PROOF_LABEL), params)
+    assert.NotEqual(t, y, y2)
+    assert.NotEqual(t, z, z2)
+    assert.NotEqual(t, x, x2)
+
+    // And so are the generators
+    params64, _ := SetupBits(64)
+    params64.N = params.N
+    params64.Gg = params64.Gg[:params.N]
+    params64.Hh = params64.Hh[:params.N]
+    params64.H, _ = p256.MapToGroup("NotTheBulletproofsGenerator")
+    y3, _, _ := proof.challenges(transcript.New(RANGE_PROOF_LABEL), params64)
+    assert.NotEqual(t, y, y3)
+
+    // Proofs are domain-separated from the generic range proofs
+    y4, _, _ := proof.challenges(transcript.New(GENERIC_RANGE_PROOF_LABEL), params)
+    assert.NotEqual(t, y, y4)
+}
+
+func TestVerifySingleProofAsGeneric(t *testing.T) {
+    params, _ := SetupGeneric(0, MAX_RANGE_END)
+    proof1, _ := Prove(new(big.Int).SetInt64(MAX_RANGE_END-40), params.BP1)
+    proof2, _ := Prove(new(big.Int).SetInt64(40), params.BP2)
+    ok, _ := ProofBPRP{C: proof2.V, P1: proof1, P2: proof2}.Verify(params)
+    assert.False(t, ok, "independent range proofs should not verify as a generic proof")
+}
+
+func TestVerifyMixedGenericProofs(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    proof1, _ := ProveGeneric(new(big.Int).SetInt64(40), params)
+    proof2, _ := ProveGeneric(new(big.Int).SetInt64(150), params)
+    ok, _ := ProofBPRP{C: proof1.C, P1: proof1.P1, P2: proof2.P2}.Verify(params)
+    assert.False(t, ok, "halves of different generic proofs should not verify together")
+}
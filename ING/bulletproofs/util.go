@@ -19,8 +19,9 @@ package bulletproofs
 
 import (
     "bytes"
-    "crypto/sha256"
+    "encoding/json"
     "errors"
+    "fmt"
     "math/big"
 
     "github.com/ing-bank/zkrp/crypto/p256"
@@ -49,56 +50,13 @@ func powerOf(x *big.Int, n int64) []*big.Int {
 }
 
 /*
-Hash is responsible for the computing a Zp element given elements from GT and G1.
-*/
-func HashBP(A, S *p256.P256) (*big.Int, *big.Int, error) {
-
-    digest1 := sha256.New()
-    var buffer bytes.Buffer
-    buffer.WriteString(A.X.String())
-    buffer.WriteString(A.Y.String())
-    buffer.WriteString(S.X.String())
-    buffer.WriteString(S.Y.String())
-    digest1.Write(buffer.Bytes())
-    output1 := digest1.Sum(nil)
-    tmp1 := output1[0:]
-    result1 := new(big.Int).SetBytes(tmp1)
-
-    digest2 := sha256.New()
-    var buffer2 bytes.Buffer
-    buffer2.WriteString(A.X.String())
-    buffer2.WriteString(A.Y.String())
-    buffer2.WriteString(S.X.String())
-    buffer2.WriteString(S.Y.String())
-    buffer2.WriteString(result1.String())
-    digest2.Write(buffer.Bytes())
-    output2 := digest2.Sum(nil)
-    tmp2 := output2[0:]
-    result2 := new(big.Int).SetBytes(tmp2)
-
-    return result1, result2, nil
-}
-
-/*
-VectorExp computes Prod_i^n{a[i]^b[i]}.
+VectorExp computes Prod_i^n{a[i]^b[i]} using a multi-scalar multiplication.
 */
 func VectorExp(a []*p256.P256, b []*big.Int) (*p256.P256, error) {
-    var (
-        result  *p256.P256
-        i, n, m int64
-    )
-    n = int64(len(a))
-    m = int64(len(b))
-    if n != m {
+    if len(a) != len(b) {
         return nil, errors.New("Size of first argument is different from size of second argument.")
     }
-    i = 0
-    result = new(p256.P256).SetInfinity()
-    for i < n {
-        result.Multiply(result, new(p256.P256).ScalarMult(a[i], b[i]))
-        i = i + 1
-    }
-    return result, nil
+    return p256.MultiScalarMult(a, b)
 }
 
 /*
@@ -132,3 +90,18 @@ https://stackoverflow.com/a/600306/844313
 func IsPowerOfTwo(x int64) bool {
     return (x != 0) && ((x & (x - 1)) == 0)
 }
+
+/*
+decodeStrict decodes the JSON encoded data into v, failing if data contains fields
+that v does not declare. This is used to reject proofs that carry anything besides
+the prover messages, such as their own setup parameters.
+*/
+func decodeStrict(data []byte, v interface{}) error {
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.DisallowUnknownFields()
+    err := decoder.Decode(v)
+    if err != nil {
+        return fmt.Errorf("invalid proof: %v", err)
+    }
+    return nil
+}
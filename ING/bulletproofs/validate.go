@@ -0,0 +1,186 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the structural validation of the proofs and of the parameters,
+that the verifiers run before any computation. Proofs decoded with UnmarshalBinary or
+UnmarshalJSON are already valid, but proofs built in memory may contain nil values,
+points that are not on the curve, scalars that are not reduced or a number of rounds
+that does not match the parameters. They are rejected with ErrMalformedProof or
+ErrPointNotOnCurve, instead of causing a panic or being verified.
+*/
+
+package bulletproofs
+
+import (
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+var (
+    ErrMalformedProof  = errors.New("malformed proof")
+    ErrPointNotOnCurve = p256.ErrPointNotOnCurve
+    ErrInvalidParams   = errors.New("invalid setup parameters")
+)
+
+/*
+checkPoints returns ErrMalformedProof if a point is nil, and ErrPointNotOnCurve if a
+point is neither the point at infinity nor a point of the curve with reduced
+coordinates.
+*/
+func checkPoints(points ...*p256.P256) error {
+    for _, point := range points {
+        if point == nil || point.X == nil || point.Y == nil {
+            return ErrMalformedProof
+        }
+        if point.IsZero() {
+            continue
+        }
+        if point.X.Sign() < 0 || point.X.Cmp(p256.CURVE.P) >= 0 || point.Y.Sign() < 0 || point.Y.Cmp(p256.CURVE.P) >= 0 {
+            return ErrPointNotOnCurve
+        }
+        if !point.IsOnCurve() {
+            return ErrPointNotOnCurve
+        }
+    }
+    return nil
+}
+
+/*
+checkScalars returns ErrMalformedProof if a scalar is nil or does not belong to
+[0, ORDER).
+*/
+func checkScalars(scalars ...*big.Int) error {
+    for _, scalar := range scalars {
+        if scalar == nil || scalar.Sign() < 0 || scalar.Cmp(ORDER) >= 0 {
+            return ErrMalformedProof
+        }
+    }
+    return nil
+}
+
+/*
+log2 returns k such that n = 2^k, or -1 if n is not a positive power of 2.
+*/
+func log2(n int64) int {
+    if n <= 0 || !IsPowerOfTwo(n) {
+        return -1
+    }
+    k := 0
+    for (int64(1) << uint(k)) < n {
+        k = k + 1
+    }
+    return k
+}
+
+/*
+validate checks that the bit-length N is a power of 2 not greater than
+MAX_RANGE_BITS, and that the parameters provide the generators of m values.
+*/
+func (params BulletProofSetupParams) validate(m int64) error {
+    if log2(params.N) < 0 || params.N > MAX_RANGE_BITS {
+        return ErrInvalidParams
+    }
+    if int64(len(params.Gg)) < m*params.N || int64(len(params.Hh)) < m*params.N {
+        return ErrInvalidParams
+    }
+    if params.G == nil || params.H == nil {
+        return ErrInvalidParams
+    }
+    return nil
+}
+
+/*
+validate checks that the parameters of an inner product argument are complete, and
+that their points are on the curve.
+*/
+func (params InnerProductParams) validate() error {
+    if params.Cc == nil || params.Uu == nil || params.H == nil || params.P == nil {
+        return ErrInvalidParams
+    }
+    if err := checkPoints(params.Uu, params.H, params.P); err != nil {
+        return ErrInvalidParams
+    }
+    return nil
+}
+
+/*
+validate checks the structure of an inner product argument over vectors of size n:
+log2(n) rounds, points on the curve and reduced scalars.
+*/
+func (proof InnerProductProof) validate(n int64) error {
+    logn := log2(n)
+    if logn < 0 || len(proof.Ls) != logn || len(proof.Rs) != logn {
+        return ErrMalformedProof
+    }
+    if err := checkPoints(proof.Ls...); err != nil {
+        return err
+    }
+    if err := checkPoints(proof.Rs...); err != nil {
+        return err
+    }
+    return checkScalars(proof.A, proof.B)
+}
+
+/*
+validate checks the structure of a range proof over n bits.
+*/
+func (proof BulletProof) validate(n int64) error {
+    if err := checkPoints(proof.V, proof.A, proof.S, proof.T1, proof.T2); err != nil {
+        return err
+    }
+    if err := checkScalars(proof.Taux, proof.Mu, proof.Tprime); err != nil {
+        return err
+    }
+    return proof.InnerProductProof.validate(n)
+}
+
+/*
+validate checks the structure of an aggregated range proof over m.n bits.
+*/
+func (proof AggregateBulletProof) validate(n int64) error {
+    m := int64(len(proof.V))
+    if m == 0 || !IsPowerOfTwo(m) {
+        return ErrMalformedProof
+    }
+    if err := checkPoints(proof.V...); err != nil {
+        return err
+    }
+    if err := checkPoints(proof.A, proof.S, proof.T1, proof.T2); err != nil {
+        return err
+    }
+    if err := checkScalars(proof.Taux, proof.Mu, proof.Tprime); err != nil {
+        return err
+    }
+    return proof.InnerProductProof.validate(m * n)
+}
+
+/*
+validate checks the structure of an arithmetic circuit proof whose vectors have
+size n.
+*/
+func (proof R1CSProof) validate(n int64) error {
+    if err := checkPoints(proof.AI, proof.AO, proof.S, proof.T1, proof.T3, proof.T4, proof.T5, proof.T6); err != nil {
+        return err
+    }
+    if err := checkScalars(proof.Taux, proof.Mu, proof.Tprime); err != nil {
+        return err
+    }
+    return proof.InnerProductProof.validate(n)
+}
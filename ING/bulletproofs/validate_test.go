@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+
+package bulletproofs
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/stretchr/testify/assert"
+)
+
+func offCurvePoint() *p256.P256 {
+    return &p256.P256{X: big.NewInt(1), Y: big.NewInt(1)}
+}
+
+func unreducedPoint() *p256.P256 {
+    G := new(p256.P256).ScalarBaseMult(big.NewInt(1))
+    return &p256.P256{X: new(big.Int).Add(G.X, p256.CURVE.P), Y: G.Y}
+}
+
+func copyProof(proof BulletProof) BulletProof {
+    proof.InnerProductProof.Ls = append([]*p256.P256{}, proof.InnerProductProof.Ls...)
+    proof.InnerProductProof.Rs = append([]*p256.P256{}, proof.InnerProductProof.Rs...)
+    return proof
+}
+
+func TestVerifyMalformedProof(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    tests := []struct {
+        name   string
+        change func(*BulletProof)
+        err    error
+    }{
+        {"nil V", func(p *BulletProof) { p.V = nil }, ErrMalformedProof},
+        {"nil coordinate", func(p *BulletProof) { p.A = &p256.P256{X: p.A.X} }, ErrMalformedProof},
+        {"T1 off the curve", func(p *BulletProof) { p.T1 = offCurvePoint() }, ErrPointNotOnCurve},
+        {"S not reduced", func(p *BulletProof) { p.S = unreducedPoint() }, ErrPointNotOnCurve},
+        {"L off the curve", func(p *BulletProof) { p.InnerProductProof.Ls[2] = offCurvePoint() }, ErrPointNotOnCurve},
+        {"nil R", func(p *BulletProof) { p.InnerProductProof.Rs[0] = nil }, ErrMalformedProof},
+        {"nil taux", func(p *BulletProof) { p.Taux = nil }, ErrMalformedProof},
+        {"mu not reduced", func(p *BulletProof) { p.Mu = new(big.Int).Add(p.Mu, ORDER) }, ErrMalformedProof},
+        {"negative a", func(p *BulletProof) { p.InnerProductProof.A = big.NewInt(-1) }, ErrMalformedProof},
+        {"missing round", func(p *BulletProof) { p.InnerProductProof.Ls = p.InnerProductProof.Ls[1:] }, ErrMalformedProof},
+        {"extra round", func(p *BulletProof) {
+            p.InnerProductProof.Ls = append(p.InnerProductProof.Ls, p.A)
+            p.InnerProductProof.Rs = append(p.InnerProductProof.Rs, p.A)
+        }, ErrMalformedProof},
+        {"unbalanced rounds", func(p *BulletProof) { p.InnerProductProof.Rs = p.InnerProductProof.Rs[1:] }, ErrMalformedProof},
+    }
+    for _, test := range tests {
+        malformed := copyProof(proof)
+        test.change(&malformed)
+        ok, err := malformed.Verify(params)
+        assert.False(t, ok, test.name)
+        assert.Equal(t, test.err, err, test.name)
+    }
+    ok, _ := proof.Verify(params)
+    assert.True(t, ok, "the original proof should still verify")
+}
+
+func TestVerifyInvalidParams(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    invalid := params
+    invalid.N = 3
+    invalid.Gg = params.Gg[:3]
+    invalid.Hh = params.Hh[:3]
+    ok, err := proof.Verify(invalid)
+    assert.False(t, ok)
+    assert.Equal(t, ErrInvalidParams, err)
+}
+
+func TestVerifyMalformedInnerProduct(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proof, _ := Prove(new(big.Int).SetInt64(18), params)
+    ipp := proof.InnerProductProof
+    ipParams, _ := setupInnerProduct(params.H, params.Gg, params.Hh, nil, params.N)
+    ok, err := ipp.Verify(ipParams)
+    assert.False(t, ok)
+    assert.Equal(t, ErrInvalidParams, err, "the inner product is missing")
+
+    ipParams.Cc = big.NewInt(0)
+    ipp.B = nil
+    ok, err = ipp.Verify(ipParams)
+    assert.False(t, ok)
+    assert.Equal(t, ErrMalformedProof, err)
+}
+
+func TestVerifyMalformedAggregate(t *testing.T) {
+    params, _ := SetupAggregate(MAX_RANGE_END, 2)
+    proof, _ := ProveAggregate([]*big.Int{big.NewInt(3), big.NewInt(4)}, params)
+
+    malformed := proof
+    malformed.V = []*p256.P256{proof.V[0], offCurvePoint()}
+    ok, err := malformed.Verify(params)
+    assert.False(t, ok)
+    assert.Equal(t, ErrPointNotOnCurve, err)
+
+    malformed = proof
+    malformed.Tprime = ORDER
+    ok, err = malformed.Verify(params)
+    assert.False(t, ok)
+    assert.Equal(t, ErrMalformedProof, err)
+}
+
+func TestVerifyMalformedBPRP(t *testing.T) {
+    params, _ := SetupGeneric(18, 200)
+    proof, _ := ProveGeneric(new(big.Int).SetInt64(40), params)
+
+    malformed := proof
+    malformed.C = offCurvePoint()
+    ok, err := malformed.Verify(params)
+    assert.False(t, ok)
+    assert.Equal(t, ErrPointNotOnCurve, err)
+
+    malformed = proof
+    malformed.P2 = copyProof(proof.P2)
+    malformed.P2.InnerProductProof.Ls = nil
+    ok, err = malformed.Verify(params)
+    assert.False(t, ok)
+    assert.Equal(t, ErrMalformedProof, err)
+}
+
+func TestVerifyBatchMalformedProof(t *testing.T) {
+    params, _ := Setup(MAX_RANGE_END)
+    proof1, _ := Prove(new(big.Int).SetInt64(18), params)
+    proof2, _ := Prove(new(big.Int).SetInt64(40), params)
+    malformed := copyProof(proof2)
+    malformed.InnerProductProof.Rs[1] = offCurvePoint()
+    ok, invalid, err := VerifyBatch([]BulletProof{proof1, malformed}, params)
+    assert.Nil(t, err)
+    assert.False(t, ok)
+    assert.Equal(t, []int{1}, invalid)
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+Package bulletproofsplus implements the range proofs of the paper:
+Bulletproofs+: Shorter Proofs for a Privacy-Enhanced Distributed Ledger
+Heewon Chung, Kyoohyung Han, Chanyang Ju, Myungsun Kim and Jae Hong Seo
+https://eprint.iacr.org/2020/735.pdf
+
+The weighted inner product argument replaces the range proof polynomial and the inner
+product argument of Bulletproofs: a proof contains 3 points and 3 scalars besides the
+2.log(m.N) points of the rounds, instead of 4 points and 5 scalars. The proofs use
+the parameters of the package bulletproofs, hence the same generators and the same
+commitments V = g^secret.h^gamma, so that a committed value can be proven with either
+scheme.
+*/
+package bulletproofsplus
+
+import (
+    "github.com/ing-bank/zkrp/bulletproofs"
+)
+
+var ORDER = bulletproofs.ORDER
+
+/*
+SetupParams are the parameters of the range proofs, see BulletProofSetupParams.
+*/
+type SetupParams = bulletproofs.BulletProofSetupParams
+
+/*
+Setup computes the common parameters for the range [0, b), see bulletproofs.Setup.
+*/
+func Setup(b int64) (SetupParams, error) {
+    return bulletproofs.Setup(b)
+}
+
+/*
+SetupBits computes the common parameters for the range [0, 2^n), see
+bulletproofs.SetupBits.
+*/
+func SetupBits(n int64) (SetupParams, error) {
+    return bulletproofs.SetupBits(n)
+}
+
+/*
+SetupAggregate computes the common parameters for proving that m values belong to
+[0, b), see bulletproofs.SetupAggregate.
+*/
+func SetupAggregate(b, m int64) (SetupParams, error) {
+    return bulletproofs.SetupAggregate(b, m)
+}
@@ -0,0 +1,525 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the canonical encodings of the proofs, following the conventions
+of the package bulletproofs. The binary encoding starts with the version and the
+type of the encoded proof, followed by the prover messages: points use the 33 bytes
+compressed encoding and scalars 32 bytes in big-endian order. The JSON encoding
+contains the same elements, as hexadecimal strings.
+*/
+
+package bulletproofsplus
+
+import (
+    "bytes"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/bulletproofs"
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+// ENCODING_VERSION is the version of the binary and JSON encodings of the proofs.
+const ENCODING_VERSION = 1
+
+// SCALAR_SIZE is the size in bytes of an encoded scalar.
+const SCALAR_SIZE = 32
+
+// Type tags used in the header of the binary encoding.
+const (
+    tagProof          = 1
+    tagAggregateProof = 2
+)
+
+// The decoding errors are the ones of the package bulletproofs.
+var (
+    ErrUnsupportedVersion = bulletproofs.ErrUnsupportedVersion
+    ErrInvalidProofType   = bulletproofs.ErrInvalidProofType
+    ErrInvalidScalar      = bulletproofs.ErrInvalidScalar
+    ErrTrailingData       = bulletproofs.ErrTrailingData
+    ErrTruncatedData      = bulletproofs.ErrTruncatedData
+)
+
+/*
+MarshalBinary returns the canonical binary encoding of the proof.
+*/
+func (proof Proof) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    buffer.Write([]byte{ENCODING_VERSION, tagProof})
+    writePoints(&buffer, proof.V, proof.A)
+    err := proof.WeightedInnerProductProof.writeTo(&buffer)
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the canonical binary encoding of the proof, validating every
+point and scalar.
+*/
+func (proof *Proof) UnmarshalBinary(data []byte) error {
+    var decoded Proof
+    d := newDecoder(data, tagProof)
+    decoded.V = d.point()
+    decoded.A = d.point()
+    decoded.WeightedInnerProductProof = d.weightedInnerProductProof()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *proof = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the canonical binary encoding of the proof.
+*/
+func (proof AggregateProof) MarshalBinary() ([]byte, error) {
+    var buffer bytes.Buffer
+    if len(proof.V) == 0 || len(proof.V) > 255 {
+        return nil, errors.New("number of commitments can not be encoded")
+    }
+    buffer.Write([]byte{ENCODING_VERSION, tagAggregateProof, byte(len(proof.V))})
+    writePoints(&buffer, proof.V...)
+    writePoints(&buffer, proof.A)
+    err := proof.WeightedInnerProductProof.writeTo(&buffer)
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the canonical binary encoding of the proof, validating every
+point and scalar.
+*/
+func (proof *AggregateProof) UnmarshalBinary(data []byte) error {
+    var decoded AggregateProof
+    d := newDecoder(data, tagAggregateProof)
+    m := d.readByte()
+    if d.err == nil && m == 0 {
+        return errors.New("number of commitments must be greater than zero")
+    }
+    decoded.V = make([]*p256.P256, m)
+    for i := range decoded.V {
+        decoded.V[i] = d.point()
+    }
+    decoded.A = d.point()
+    decoded.WeightedInnerProductProof = d.weightedInnerProductProof()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *proof = decoded
+    return nil
+}
+
+/*
+weightedInnerProductProofJSON is the JSON encoding of WeightedInnerProductProof, with
+hexadecimal points and scalars.
+*/
+type weightedInnerProductProofJSON struct {
+    Ls []string
+    Rs []string
+    A  string
+    B  string
+    R  string
+    S  string
+    D  string
+}
+
+type proofJSON struct {
+    Version                   int
+    V                         string
+    A                         string
+    WeightedInnerProductProof weightedInnerProductProofJSON
+}
+
+type aggregateProofJSON struct {
+    Version                   int
+    V                         []string
+    A                         string
+    WeightedInnerProductProof weightedInnerProductProofJSON
+}
+
+/*
+MarshalJSON returns the JSON encoding of the proof.
+*/
+func (proof Proof) MarshalJSON() ([]byte, error) {
+    return json.Marshal(proofJSON{
+        Version:                   ENCODING_VERSION,
+        V:                         encodePoint(proof.V),
+        A:                         encodePoint(proof.A),
+        WeightedInnerProductProof: proof.WeightedInnerProductProof.toJSON(),
+    })
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the proof, validating every point and
+scalar.
+*/
+func (proof *Proof) UnmarshalJSON(data []byte) error {
+    var (
+        encoded proofJSON
+        result  Proof
+        err     error
+    )
+    if err = checkVersion(data); err != nil {
+        return err
+    }
+    if err = decodeStrict(data, &encoded); err != nil {
+        return err
+    }
+    if result.V, err = decodePoint(encoded.V); err != nil {
+        return err
+    }
+    if result.A, err = decodePoint(encoded.A); err != nil {
+        return err
+    }
+    if err = result.WeightedInnerProductProof.fromJSON(encoded.WeightedInnerProductProof); err != nil {
+        return err
+    }
+    *proof = result
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the proof.
+*/
+func (proof AggregateProof) MarshalJSON() ([]byte, error) {
+    encoded := aggregateProofJSON{
+        Version:                   ENCODING_VERSION,
+        V:                         make([]string, len(proof.V)),
+        A:                         encodePoint(proof.A),
+        WeightedInnerProductProof: proof.WeightedInnerProductProof.toJSON(),
+    }
+    for i, V := range proof.V {
+        encoded.V[i] = encodePoint(V)
+    }
+    return json.Marshal(encoded)
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the proof, validating every point and
+scalar.
+*/
+func (proof *AggregateProof) UnmarshalJSON(data []byte) error {
+    var (
+        encoded aggregateProofJSON
+        result  AggregateProof
+        err     error
+    )
+    if err = checkVersion(data); err != nil {
+        return err
+    }
+    if err = decodeStrict(data, &encoded); err != nil {
+        return err
+    }
+    if len(encoded.V) == 0 {
+        return errors.New("number of commitments must be greater than zero")
+    }
+    result.V = make([]*p256.P256, len(encoded.V))
+    for i := range encoded.V {
+        if result.V[i], err = decodePoint(encoded.V[i]); err != nil {
+            return err
+        }
+    }
+    if result.A, err = decodePoint(encoded.A); err != nil {
+        return err
+    }
+    if err = result.WeightedInnerProductProof.fromJSON(encoded.WeightedInnerProductProof); err != nil {
+        return err
+    }
+    *proof = result
+    return nil
+}
+
+/*
+checkVersion reads the version of a JSON encoded proof before decoding it.
+*/
+func checkVersion(data []byte) error {
+    var header struct {
+        Version int
+    }
+    err := json.Unmarshal(data, &header)
+    if err != nil {
+        return fmt.Errorf("invalid proof: %v", err)
+    }
+    if header.Version != ENCODING_VERSION {
+        return ErrUnsupportedVersion
+    }
+    return nil
+}
+
+/*
+decodeStrict decodes data into v, rejecting unknown fields.
+*/
+func decodeStrict(data []byte, v interface{}) error {
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.DisallowUnknownFields()
+    err := decoder.Decode(v)
+    if err != nil {
+        return fmt.Errorf("invalid proof: %v", err)
+    }
+    return nil
+}
+
+func (proof WeightedInnerProductProof) writeTo(buffer *bytes.Buffer) error {
+    if len(proof.Ls) != len(proof.Rs) {
+        return errors.New("number of L and R elements must be equal")
+    }
+    if len(proof.Ls) > 255 {
+        return errors.New("number of rounds can not be encoded")
+    }
+    buffer.WriteByte(byte(len(proof.Ls)))
+    for i := range proof.Ls {
+        writePoints(buffer, proof.Ls[i], proof.Rs[i])
+    }
+    writePoints(buffer, proof.A, proof.B)
+    writeScalars(buffer, proof.R, proof.S, proof.D)
+    return nil
+}
+
+func (proof WeightedInnerProductProof) toJSON() weightedInnerProductProofJSON {
+    encoded := weightedInnerProductProofJSON{
+        Ls: make([]string, len(proof.Ls)),
+        Rs: make([]string, len(proof.Rs)),
+        A:  encodePoint(proof.A),
+        B:  encodePoint(proof.B),
+        R:  encodeScalar(proof.R),
+        S:  encodeScalar(proof.S),
+        D:  encodeScalar(proof.D),
+    }
+    for i := range proof.Ls {
+        encoded.Ls[i] = encodePoint(proof.Ls[i])
+    }
+    for i := range proof.Rs {
+        encoded.Rs[i] = encodePoint(proof.Rs[i])
+    }
+    return encoded
+}
+
+func (proof *WeightedInnerProductProof) fromJSON(encoded weightedInnerProductProofJSON) error {
+    var (
+        result WeightedInnerProductProof
+        err    error
+    )
+    if len(encoded.Ls) != len(encoded.Rs) {
+        return errors.New("number of L and R elements must be equal")
+    }
+    result.Ls = make([]*p256.P256, len(encoded.Ls))
+    result.Rs = make([]*p256.P256, len(encoded.Rs))
+    for i := range encoded.Ls {
+        if result.Ls[i], err = decodePoint(encoded.Ls[i]); err != nil {
+            return err
+        }
+        if result.Rs[i], err = decodePoint(encoded.Rs[i]); err != nil {
+            return err
+        }
+    }
+    if result.A, err = decodePoint(encoded.A); err != nil {
+        return err
+    }
+    if result.B, err = decodePoint(encoded.B); err != nil {
+        return err
+    }
+    scalars := []string{encoded.R, encoded.S, encoded.D}
+    targets := []**big.Int{&result.R, &result.S, &result.D}
+    for i := range scalars {
+        if *targets[i], err = decodeScalar(scalars[i]); err != nil {
+            return err
+        }
+    }
+    *proof = result
+    return nil
+}
+
+func writePoints(buffer *bytes.Buffer, points ...*p256.P256) {
+    for _, point := range points {
+        buffer.Write(pointBytes(point))
+    }
+}
+
+func writeScalars(buffer *bytes.Buffer, scalars ...*big.Int) {
+    for _, scalar := range scalars {
+        buffer.Write(scalarBytes(scalar))
+    }
+}
+
+/*
+pointBytes returns the compressed encoding of the point, nil being encoded as the
+point at infinity.
+*/
+func pointBytes(point *p256.P256) []byte {
+    if point == nil {
+        return new(p256.P256).SetInfinity().Bytes()
+    }
+    return point.Bytes()
+}
+
+/*
+scalarBytes returns the 32 bytes big-endian encoding of the scalar modulo ORDER.
+*/
+func scalarBytes(scalar *big.Int) []byte {
+    result := make([]byte, SCALAR_SIZE)
+    if scalar == nil {
+        return result
+    }
+    b := new(big.Int).Mod(scalar, ORDER).Bytes()
+    copy(result[SCALAR_SIZE-len(b):], b)
+    return result
+}
+
+func encodePoint(point *p256.P256) string {
+    return hex.EncodeToString(pointBytes(point))
+}
+
+func encodeScalar(scalar *big.Int) string {
+    return hex.EncodeToString(scalarBytes(scalar))
+}
+
+func decodePoint(s string) (*p256.P256, error) {
+    b, err := hex.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    return new(p256.P256).SetBytes(b)
+}
+
+func decodeScalar(s string) (*big.Int, error) {
+    b, err := hex.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    return scalarFromBytes(b)
+}
+
+func scalarFromBytes(b []byte) (*big.Int, error) {
+    if len(b) != SCALAR_SIZE {
+        return nil, ErrInvalidScalar
+    }
+    scalar := new(big.Int).SetBytes(b)
+    if scalar.Cmp(ORDER) >= 0 {
+        return nil, ErrInvalidScalar
+    }
+    return scalar, nil
+}
+
+/*
+decoder reads the elements of a binary encoded proof. After the first error every
+read returns a zero value, the error being reported by finish.
+*/
+type decoder struct {
+    data []byte
+    err  error
+}
+
+/*
+newDecoder checks the header of the binary encoding.
+*/
+func newDecoder(data []byte, tag byte) *decoder {
+    d := &decoder{data: data}
+    version := d.readByte()
+    if d.err == nil && version != ENCODING_VERSION {
+        d.err = ErrUnsupportedVersion
+    }
+    if t := d.readByte(); d.err == nil && t != tag {
+        d.err = ErrInvalidProofType
+    }
+    return d
+}
+
+func (d *decoder) next(n int) []byte {
+    if d.err != nil {
+        return nil
+    }
+    if len(d.data) < n {
+        d.err = ErrTruncatedData
+        return nil
+    }
+    result := d.data[:n]
+    d.data = d.data[n:]
+    return result
+}
+
+func (d *decoder) readByte() byte {
+    b := d.next(1)
+    if b == nil {
+        return 0
+    }
+    return b[0]
+}
+
+func (d *decoder) point() *p256.P256 {
+    b := d.next(p256.COMPRESSED_SIZE)
+    if b == nil {
+        return nil
+    }
+    point, err := new(p256.P256).SetBytes(b)
+    if err != nil {
+        d.err = err
+    }
+    return point
+}
+
+func (d *decoder) scalar() *big.Int {
+    b := d.next(SCALAR_SIZE)
+    if b == nil {
+        return nil
+    }
+    scalar, err := scalarFromBytes(b)
+    if err != nil {
+        d.err = err
+    }
+    return scalar
+}
+
+func (d *decoder) weightedInnerProductProof() WeightedInnerProductProof {
+    var proof WeightedInnerProductProof
+    rounds := int(d.readByte())
+    proof.Ls = make([]*p256.P256, rounds)
+    proof.Rs = make([]*p256.P256, rounds)
+    for i := 0; i < rounds; i++ {
+        proof.Ls[i] = d.point()
+        proof.Rs[i] = d.point()
+    }
+    proof.A = d.point()
+    proof.B = d.point()
+    proof.R = d.scalar()
+    proof.S = d.scalar()
+    proof.D = d.scalar()
+    return proof
+}
+
+/*
+finish returns the first error found while decoding, or ErrTrailingData if some
+data was not consumed.
+*/
+func (d *decoder) finish() error {
+    if d.err != nil {
+        return d.err
+    }
+    if len(d.data) != 0 {
+        return ErrTrailingData
+    }
+    return nil
+}
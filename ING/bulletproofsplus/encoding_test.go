@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofsplus
+
+import (
+    "encoding/json"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/bulletproofs"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestBinaryEncodeDecode(t *testing.T) {
+    params, _ := Setup(bulletproofs.MAX_RANGE_END)
+    proof, err := Prove(big.NewInt(18), params)
+    assert.Nil(t, err)
+    encoded, err := proof.MarshalBinary()
+    assert.Nil(t, err)
+
+    var decoded Proof
+    assert.Nil(t, decoded.UnmarshalBinary(encoded))
+    ok, err := decoded.Verify(params)
+    assert.Nil(t, err)
+    assert.True(t, ok, "decoded proof should verify")
+
+    assert.Equal(t, ErrTrailingData, decoded.UnmarshalBinary(append(encoded, 0)))
+    assert.Equal(t, ErrTruncatedData, decoded.UnmarshalBinary(encoded[:len(encoded)-1]))
+    encoded[0] = ENCODING_VERSION + 1
+    assert.Equal(t, ErrUnsupportedVersion, decoded.UnmarshalBinary(encoded))
+}
+
+/*
+TestProofSize checks that the proofs are 97 bytes shorter than the ones of the package
+bulletproofs: one point and two scalars.
+*/
+func TestProofSize(t *testing.T) {
+    params, _ := Setup(bulletproofs.MAX_RANGE_END)
+    secret, gamma := big.NewInt(18), big.NewInt(7)
+    bp, _ := bulletproofs.ProveWithCommitment(secret, gamma, params)
+    bpp, _ := ProveWithCommitment(secret, gamma, params)
+    bpEncoded, err := bp.MarshalBinary()
+    assert.Nil(t, err)
+    bppEncoded, err := bpp.MarshalBinary()
+    assert.Nil(t, err)
+    assert.Equal(t, len(bpEncoded)-33-2*SCALAR_SIZE, len(bppEncoded))
+}
+
+func TestJsonEncodeDecode(t *testing.T) {
+    params, _ := Setup(bulletproofs.MAX_RANGE_END)
+    proof, err := Prove(big.NewInt(18), params)
+    assert.Nil(t, err)
+    encoded, err := json.Marshal(proof)
+    assert.Nil(t, err)
+
+    var decoded Proof
+    assert.Nil(t, json.Unmarshal(encoded, &decoded))
+    ok, err := decoded.Verify(params)
+    assert.Nil(t, err)
+    assert.True(t, ok, "decoded proof should verify")
+
+    var fields map[string]interface{}
+    _ = json.Unmarshal(encoded, &fields)
+    fields["Params"] = params
+    encoded, _ = json.Marshal(fields)
+    assert.NotNil(t, json.Unmarshal(encoded, &decoded), "should reject unknown fields")
+}
+
+func TestAggregateEncodeDecode(t *testing.T) {
+    params, _ := SetupAggregate(bulletproofs.MAX_RANGE_END, 2)
+    proof, err := ProveAggregate([]*big.Int{big.NewInt(1), big.NewInt(2)}, params)
+    assert.Nil(t, err)
+
+    encoded, err := proof.MarshalBinary()
+    assert.Nil(t, err)
+    var decoded AggregateProof
+    assert.Nil(t, decoded.UnmarshalBinary(encoded))
+    ok, _ := decoded.Verify(params)
+    assert.True(t, ok, "decoded binary proof should verify")
+    var single Proof
+    assert.Equal(t, ErrInvalidProofType, single.UnmarshalBinary(encoded))
+
+    encoded, err = json.Marshal(proof)
+    assert.Nil(t, err)
+    decoded = AggregateProof{}
+    assert.Nil(t, json.Unmarshal(encoded, &decoded))
+    ok, _ = decoded.Verify(params)
+    assert.True(t, ok, "decoded JSON proof should verify")
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the options of the provers, with the same meaning as the ones of
+the package bulletproofs. By default the blinding factors and the nonces are read
+from crypto/rand. WithRand replaces the random source, and Derandomized derives the
+nonces from the transcript and the witness, see the RNG of crypto/transcript. The
+blinding factors of the commitments are never derandomized.
+*/
+
+package bulletproofsplus
+
+import (
+    "crypto/rand"
+    "errors"
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/transcript"
+)
+
+// ErrNoRandomness is returned when the blinding factors can not be read from the random source.
+var ErrNoRandomness = errors.New("a random source is needed for the blinding factors")
+
+/*
+ProverOption configures the randomness of a prover.
+*/
+type ProverOption func(*proverConfig)
+
+type proverConfig struct {
+    rand         io.Reader
+    derandomized bool
+}
+
+/*
+WithRand sets the random source of the prover. Its errors are returned by the prover.
+*/
+func WithRand(r io.Reader) ProverOption {
+    return func(c *proverConfig) {
+        c.rand = r
+    }
+}
+
+/*
+Derandomized derives the nonces from the transcript, the secret values and the
+blinding factors, together with 32 bytes of the random source. The blinding factors
+of the commitments computed by the provers are still read from the random source, so
+with WithRand(nil) only the proofs for given commitments, such as
+ProveWithCommitment, are deterministic.
+*/
+func Derandomized() ProverOption {
+    return func(c *proverConfig) {
+        c.derandomized = true
+    }
+}
+
+/*
+newProverConfig applies the options. A nil random source is only allowed in the
+derandomized mode, otherwise crypto/rand is used.
+*/
+func newProverConfig(opts []ProverOption) proverConfig {
+    c := proverConfig{rand: rand.Reader}
+    for _, opt := range opts {
+        opt(&c)
+    }
+    if c.rand == nil && !c.derandomized {
+        c.rand = rand.Reader
+    }
+    return c
+}
+
+/*
+nonceReader returns the source of the random values sampled after the messages
+absorbed by t. In the derandomized mode it is the RNG of t, keyed with the witness.
+*/
+func (c proverConfig) nonceReader(t *transcript.Transcript, witness ...*big.Int) (io.Reader, error) {
+    if !c.derandomized {
+        return c.rand, nil
+    }
+    builder := t.BuildRng()
+    for _, w := range witness {
+        builder.RekeyWithWitness("witness", scalarBytes(w))
+    }
+    return builder.Finalize(c.rand)
+}
+
+/*
+sampleBlindings returns n blinding factors for the commitments computed by the
+prover. They are read from the random source even in the derandomized mode, since
+blinding factors derived from the secrets would let anyone check candidate secrets
+against the commitments.
+*/
+func (c proverConfig) sampleBlindings(n int) ([]*big.Int, error) {
+    if c.rand == nil {
+        return nil, ErrNoRandomness
+    }
+    return sampleScalars(c.rand, n)
+}
+
+/*
+sampleScalars returns n scalars read from r.
+*/
+func sampleScalars(r io.Reader, n int) ([]*big.Int, error) {
+    var err error
+    s := make([]*big.Int, n)
+    for i := range s {
+        s[i], err = rand.Int(r, ORDER)
+        if err != nil {
+            return nil, err
+        }
+    }
+    return s, nil
+}
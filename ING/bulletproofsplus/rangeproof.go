@@ -0,0 +1,337 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofsplus
+
+import (
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/bulletproofs"
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+    . "github.com/ing-bank/zkrp/util"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+/*
+Proof is the range proof of the value committed in V = g^secret.h^gamma. A commits
+to the bits of the secret, and the weighted inner product argument proves that they
+are bits of the committed value, see Section 4 of the paper.
+*/
+type Proof struct {
+    V                         *p256.P256
+    A                         *p256.P256
+    WeightedInnerProductProof WeightedInnerProductProof
+}
+
+/*
+AggregateProof is a single range proof for m committed values, whose size grows
+logarithmically in m.N.
+*/
+type AggregateProof struct {
+    V                         []*p256.P256
+    A                         *p256.P256
+    WeightedInnerProductProof WeightedInnerProductProof
+}
+
+/*
+Prove computes the range proof for secret, with a random blinding factor.
+*/
+func Prove(secret *big.Int, params SetupParams, opts ...ProverOption) (Proof, error) {
+    if secret == nil {
+        return Proof{}, errors.New("secret must not be nil")
+    }
+    gammas, err := newProverConfig(opts).sampleBlindings(1)
+    if err != nil {
+        return Proof{}, err
+    }
+    return ProveWithCommitment(secret, gammas[0], params, opts...)
+}
+
+/*
+ProveWithCommitment computes the range proof for the Pedersen commitment
+V = g^secret.h^gamma, computed with params.Pedersen(). It is verified with
+VerifyCommitment when V is issued to the verifier beforehand.
+*/
+func ProveWithCommitment(secret, gamma *big.Int, params SetupParams, opts ...ProverOption) (Proof, error) {
+    if secret == nil || gamma == nil {
+        return Proof{}, errors.New("secret and blinding factor must not be nil")
+    }
+    proof, err := proveRange([]*big.Int{secret}, []*big.Int{gamma}, params, newProverConfig(opts))
+    if err != nil {
+        return Proof{}, err
+    }
+    return Proof{V: proof.V[0], A: proof.A, WeightedInnerProductProof: proof.WeightedInnerProductProof}, nil
+}
+
+/*
+ProveOpening computes the range proof for the commitment opened by opening, which
+must have been computed with the Pedersen parameters of params.
+*/
+func ProveOpening(opening pedersen.Opening, params SetupParams, opts ...ProverOption) (Proof, error) {
+    if opening.Group != pedersen.P256 {
+        return Proof{}, pedersen.ErrGroupMismatch
+    }
+    return ProveWithCommitment(opening.Value, opening.Blinding, params, opts...)
+}
+
+/*
+ProveAggregate computes a single range proof for all the secrets, with random
+blinding factors. The number of secrets must be a power of 2 and params must provide
+enough generators for them, see SetupAggregate.
+*/
+func ProveAggregate(secrets []*big.Int, params SetupParams, opts ...ProverOption) (AggregateProof, error) {
+    for _, secret := range secrets {
+        if secret == nil {
+            return AggregateProof{}, errors.New("secret must not be nil")
+        }
+    }
+    gammas, err := newProverConfig(opts).sampleBlindings(len(secrets))
+    if err != nil {
+        return AggregateProof{}, err
+    }
+    return ProveAggregateWithCommitments(secrets, gammas, params, opts...)
+}
+
+/*
+ProveAggregateWithCommitments computes a single range proof for the commitments
+V_j = g^secrets_j.h^gammas_j.
+*/
+func ProveAggregateWithCommitments(secrets, gammas []*big.Int, params SetupParams, opts ...ProverOption) (AggregateProof, error) {
+    if len(secrets) != len(gammas) {
+        return AggregateProof{}, errors.New("number of secrets and blinding factors must be equal")
+    }
+    for j := range secrets {
+        if secrets[j] == nil || gammas[j] == nil {
+            return AggregateProof{}, errors.New("secret and blinding factor must not be nil")
+        }
+    }
+    return proveRange(secrets, gammas, params, newProverConfig(opts))
+}
+
+/*
+proveRange computes the range proof for m = len(secrets) values. With aL the bits of
+the secrets and aR = aL - 1^mn, A = Gg^aL.Hh^aR.h^alpha, and the weighted inner
+product argument is computed for
+aL^ = aL - z.1^mn, aR^ = aR + d o y^<-mn + z.1^mn, alpha^ = alpha + y^(mn+1).sum_j z^(2j).gamma_j,
+where d = (z^2.2^n || z^4.2^n || ... || z^(2m).2^n) and y^<-mn = (y^mn, ..., y).
+*/
+func proveRange(secrets, gammas []*big.Int, params SetupParams, cfg proverConfig) (AggregateProof, error) {
+    var proof AggregateProof
+    m := int64(len(secrets))
+    if m == 0 || !bulletproofs.IsPowerOfTwo(m) {
+        return proof, errors.New("number of secrets is not a power of 2")
+    }
+    mn := m * params.N
+    if int64(len(params.Gg)) < mn || int64(len(params.Hh)) < mn {
+        return proof, errors.New("not enough generators for the number of secrets")
+    }
+
+    proof.V = make([]*p256.P256, m)
+    gamma := make([]*big.Int, m)
+    aL := make([]*big.Int, 0, mn)
+    for j := int64(0); j < m; j++ {
+        gamma[j] = bn.Mod(gammas[j], ORDER)
+        proof.V[j], _ = CommitG1(secrets[j], gamma[j], params.H)
+        bits, _ := Decompose(secrets[j], 2, params.N)
+        for _, bit := range bits {
+            aL = append(aL, big.NewInt(bit))
+        }
+    }
+    t := transcript.New(RANGE_PROOF_LABEL)
+    rangeProofDomainSep(t, params, proof.V)
+
+    rng, err := cfg.nonceReader(t, append(append([]*big.Int{}, secrets...), gamma...)...)
+    if err != nil {
+        return proof, err
+    }
+    nonces, err := sampleScalars(rng, 1)
+    if err != nil {
+        return proof, err
+    }
+    alpha := nonces[0]
+
+    // A = Gg^aL.Hh^aR.h^alpha
+    aR := make([]*big.Int, mn)
+    for i := range aL {
+        aR[i] = bn.Mod(bn.Sub(aL[i], big.NewInt(1)), ORDER)
+    }
+    proof.A, _ = bulletproofs.VectorExp(concatPoints(params.Gg[:mn], params.Hh[:mn], []*p256.P256{params.H}), concatScalars(aL, aR, []*big.Int{alpha}))
+    y, z := rangeProofChallenges(t, proof.A)
+
+    d := powersOfTwo(z, params.N, m)
+    yPow := powerOf(y, mn+2)
+    aHat := make([]*big.Int, mn)
+    bHat := make([]*big.Int, mn)
+    for i := int64(0); i < mn; i++ {
+        aHat[i] = bn.Mod(bn.Sub(aL[i], z), ORDER)
+        bHat[i] = bn.Add(aR[i], bn.Multiply(d[i], yPow[mn-i]))
+        bHat[i] = bn.Mod(bn.Add(bHat[i], z), ORDER)
+    }
+    alphaHat := alpha
+    z2j := big.NewInt(1)
+    for j := int64(0); j < m; j++ {
+        z2j = bn.Mod(bn.Multiply(z2j, bn.Multiply(z, z)), ORDER)
+        alphaHat = bn.Add(alphaHat, bn.Multiply(bn.Multiply(yPow[mn+1], z2j), gamma[j]))
+    }
+    alphaHat = bn.Mod(alphaHat, ORDER)
+
+    proof.WeightedInnerProductProof, err = proveWeightedInnerProduct(t, rng, aHat, bHat, alphaHat, y, params.Gg[:mn], params.Hh[:mn], params.G, params.H)
+    return proof, err
+}
+
+/*
+Verify returns true if and only if the proof is valid with respect to params. The
+params must be computed by the verifier, using Setup, and never taken from the prover.
+*/
+func (proof *Proof) Verify(params SetupParams) (bool, error) {
+    aggregate := AggregateProof{V: []*p256.P256{proof.V}, A: proof.A, WeightedInnerProductProof: proof.WeightedInnerProductProof}
+    return aggregate.Verify(params)
+}
+
+/*
+VerifyCommitment returns true if and only if the proof is valid with respect to
+params and proves that the value committed in C belongs to the range. C is the
+commitment expected by the verifier, and the commitment carried by the proof is
+ignored.
+*/
+func (proof *Proof) VerifyCommitment(C pedersen.Commitment, params SetupParams) (bool, error) {
+    if !params.Pedersen().Owns(C) {
+        return false, pedersen.ErrGroupMismatch
+    }
+    V, err := C.P256()
+    if err != nil {
+        return false, err
+    }
+    expected := *proof
+    expected.V = V
+    return expected.Verify(params)
+}
+
+/*
+Commitment returns the commitment V to the secret.
+*/
+func (proof *Proof) Commitment() pedersen.Commitment {
+    return bulletproofs.NewCommitment(proof.V)
+}
+
+/*
+Verify returns true if and only if the aggregated proof is valid, i.e. all the
+committed values belong to [0, 2^N). The params must be computed by the verifier,
+using SetupAggregate. The weighted inner product argument is checked for
+A^ = A.Gg^(-z.1^mn).Hh^(d o y^<-mn + z.1^mn).prod_j V_j^(y^(mn+1).z^(2j)).g^zeta, where
+zeta = (z - z^2).sum_i y^i - z.y^(mn+1).sum_i d_i, with a single multi-scalar
+multiplication.
+*/
+func (proof *AggregateProof) Verify(params SetupParams) (bool, error) {
+    m := int64(len(proof.V))
+    if m == 0 || !bulletproofs.IsPowerOfTwo(m) {
+        return false, errors.New("number of commitments is not a power of 2")
+    }
+    mn := m * params.N
+    if int64(len(params.Gg)) < mn || int64(len(params.Hh)) < mn {
+        return false, errors.New("not enough generators for the number of commitments")
+    }
+    if proof.A == nil {
+        return false, errors.New("commitment A must not be nil")
+    }
+    for _, V := range proof.V {
+        if V == nil {
+            return false, errors.New("commitment must not be nil")
+        }
+    }
+
+    t := transcript.New(RANGE_PROOF_LABEL)
+    rangeProofDomainSep(t, params, proof.V)
+    y, z := rangeProofChallenges(t, proof.A)
+    gs, hs, points, scalars, pExp, err := proof.WeightedInnerProductProof.verificationTerms(t, mn, y, params.G, params.H)
+    if err != nil {
+        return false, err
+    }
+
+    // P = A^, with the coefficient pExp = -e^2
+    d := powersOfTwo(z, params.N, m)
+    yPow := powerOf(y, mn+2)
+    pz := bn.Multiply(pExp, z)
+    sumY := big.NewInt(0)
+    sumD := big.NewInt(0)
+    for i := int64(0); i < mn; i++ {
+        gs[i] = bn.Mod(bn.Sub(gs[i], pz), ORDER)
+        hExp := bn.Add(bn.Multiply(d[i], yPow[mn-i]), z)
+        hs[i] = bn.Mod(bn.Add(hs[i], bn.Multiply(pExp, hExp)), ORDER)
+        sumY = bn.Add(sumY, yPow[i+1])
+        sumD = bn.Add(sumD, d[i])
+    }
+    zeta := bn.Multiply(bn.Sub(z, bn.Multiply(z, z)), sumY)
+    zeta = bn.Sub(zeta, bn.Multiply(bn.Multiply(z, yPow[mn+1]), sumD))
+    points = append(points, proof.A, params.G)
+    scalars = append(scalars, pExp, bn.Mod(bn.Multiply(pExp, zeta), ORDER))
+    z2j := big.NewInt(1)
+    for j := int64(0); j < m; j++ {
+        z2j = bn.Mod(bn.Multiply(z2j, bn.Multiply(z, z)), ORDER)
+        points = append(points, proof.V[j])
+        scalars = append(scalars, bn.Mod(bn.Multiply(pExp, bn.Multiply(yPow[mn+1], z2j)), ORDER))
+    }
+
+    result, err := bulletproofs.VectorExp(concatPoints(params.Gg[:mn], params.Hh[:mn], points), concatScalars(gs, hs, scalars))
+    if err != nil {
+        return false, err
+    }
+    return result.IsZero(), nil
+}
+
+/*
+Commitments returns the commitments V_j to the secrets.
+*/
+func (proof *AggregateProof) Commitments() []pedersen.Commitment {
+    result := make([]pedersen.Commitment, len(proof.V))
+    for j, V := range proof.V {
+        result[j] = bulletproofs.NewCommitment(V)
+    }
+    return result
+}
+
+/*
+powersOfTwo returns d, the concatenation of z^(2j).2^n for j in [1, m].
+*/
+func powersOfTwo(z *big.Int, n, m int64) []*big.Int {
+    result := make([]*big.Int, 0, m*n)
+    z2j := big.NewInt(1)
+    for j := int64(0); j < m; j++ {
+        z2j = bn.Mod(bn.Multiply(z2j, bn.Multiply(z, z)), ORDER)
+        p := new(big.Int).Set(z2j)
+        for i := int64(0); i < n; i++ {
+            result = append(result, p)
+            p = bn.Mod(bn.Multiply(p, big.NewInt(2)), ORDER)
+        }
+    }
+    return result
+}
+
+/*
+powerOf returns the vector (1, x, x^2, ..., x^(n-1)).
+*/
+func powerOf(x *big.Int, n int64) []*big.Int {
+    result := make([]*big.Int, n)
+    result[0] = big.NewInt(1)
+    for i := int64(1); i < n; i++ {
+        result[i] = bn.Mod(bn.Multiply(result[i-1], x), ORDER)
+    }
+    return result
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofsplus
+
+import (
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/bulletproofs"
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
+    "github.com/stretchr/testify/assert"
+)
+
+func proveAndVerify(t *testing.T, x int64, params SetupParams) bool {
+    proof, err := Prove(big.NewInt(x), params)
+    assert.Nil(t, err)
+    ok, _ := proof.Verify(params)
+    return ok
+}
+
+func TestRangeProof(t *testing.T) {
+    params, err := Setup(bulletproofs.MAX_RANGE_END)
+    assert.Nil(t, err)
+    assert.True(t, proveAndVerify(t, 0, params), "x equal to range start should verify")
+    assert.True(t, proveAndVerify(t, 3, params), "x within range should verify")
+    assert.True(t, proveAndVerify(t, bulletproofs.MAX_RANGE_END-1, params), "x equal to range end - 1 should verify")
+    assert.False(t, proveAndVerify(t, -1, params), "x lower than range start should not verify")
+    assert.False(t, proveAndVerify(t, bulletproofs.MAX_RANGE_END, params), "x equal to range end should not verify")
+}
+
+func TestRangeProofBits(t *testing.T) {
+    for _, n := range []int64{1, 2, 8, 64} {
+        params, err := SetupBits(n)
+        assert.Nil(t, err)
+        max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(n)), big.NewInt(1))
+        proof, err := Prove(max, params)
+        assert.Nil(t, err)
+        ok, err := proof.Verify(params)
+        assert.Nil(t, err)
+        assert.True(t, ok, "2^n - 1 should verify for n = %d", n)
+    }
+}
+
+func TestRangeProofWrongParams(t *testing.T) {
+    params8, _ := SetupBits(8)
+    params16, _ := SetupBits(16)
+    proof, err := Prove(big.NewInt(200), params8)
+    assert.Nil(t, err)
+    ok, _ := proof.Verify(params16)
+    assert.False(t, ok, "should not verify with another bit-length")
+}
+
+func TestRangeProofTampered(t *testing.T) {
+    params, _ := SetupBits(16)
+    proof, err := Prove(big.NewInt(1000), params)
+    assert.Nil(t, err)
+
+    tampered := proof
+    tampered.WeightedInnerProductProof.R = new(big.Int).Add(proof.WeightedInnerProductProof.R, big.NewInt(1))
+    ok, _ := tampered.Verify(params)
+    assert.False(t, ok, "should not verify a tampered response")
+
+    tampered = proof
+    tampered.A = proof.WeightedInnerProductProof.B
+    ok, _ = tampered.Verify(params)
+    assert.False(t, ok, "should not verify a tampered commitment")
+
+    tampered = proof
+    tampered.WeightedInnerProductProof.Ls = proof.WeightedInnerProductProof.Ls[1:]
+    tampered.WeightedInnerProductProof.Rs = proof.WeightedInnerProductProof.Rs[1:]
+    ok, err = tampered.Verify(params)
+    assert.False(t, ok)
+    assert.NotNil(t, err, "should reject a wrong number of rounds")
+}
+
+/*
+TestCommitmentsMatchBulletproofs checks that both schemes commit to the secrets in
+the same way, so that a commitment can be proven with either of them.
+*/
+func TestCommitmentsMatchBulletproofs(t *testing.T) {
+    params, _ := Setup(bulletproofs.MAX_RANGE_END)
+    secret, gamma := big.NewInt(42), big.NewInt(12345)
+
+    bp, err := bulletproofs.ProveWithCommitment(secret, gamma, params)
+    assert.Nil(t, err)
+    bpp, err := ProveWithCommitment(secret, gamma, params)
+    assert.Nil(t, err)
+    assert.True(t, bp.Commitment().Equal(bpp.Commitment()), "commitments should be equal")
+
+    ok, err := bpp.VerifyCommitment(bp.Commitment(), params)
+    assert.Nil(t, err)
+    assert.True(t, ok, "should verify for the commitment of the bulletproof")
+    ok, err = bp.VerifyCommitment(bpp.Commitment(), params)
+    assert.Nil(t, err)
+    assert.True(t, ok, "bulletproof should verify for the commitment of the proof")
+
+    C, opening, err := params.Pedersen().CommitRandom(secret, nil)
+    assert.Nil(t, err)
+    bpp, err = ProveOpening(opening, params)
+    assert.Nil(t, err)
+    ok, err = bpp.VerifyCommitment(C, params)
+    assert.Nil(t, err)
+    assert.True(t, ok, "should verify for the commitment of the opening")
+
+    other, _, _ := params.Pedersen().CommitRandom(secret, nil)
+    ok, _ = bpp.VerifyCommitment(other, params)
+    assert.False(t, ok, "should not verify for another commitment")
+
+    h, _ := p256.MapToGroup("another generator")
+    P, _ := C.P256()
+    ok, err = bpp.VerifyCommitment(pedersen.NewP256Params(h).NewCommitment(group.NewP256(P)), params)
+    assert.Equal(t, pedersen.ErrGroupMismatch, err)
+    assert.False(t, ok, "should not verify for a commitment of another generator")
+}
+
+func TestAggregateProof(t *testing.T) {
+    params, err := SetupAggregate(bulletproofs.MAX_RANGE_END, 4)
+    assert.Nil(t, err)
+    secrets := []*big.Int{big.NewInt(0), big.NewInt(7), big.NewInt(1 << 20), big.NewInt(bulletproofs.MAX_RANGE_END - 1)}
+    proof, err := ProveAggregate(secrets, params)
+    assert.Nil(t, err)
+    ok, err := proof.Verify(params)
+    assert.Nil(t, err)
+    assert.True(t, ok, "aggregated proof should verify")
+
+    proof, err = ProveAggregate(secrets[:2], params)
+    assert.Nil(t, err)
+    ok, err = proof.Verify(params)
+    assert.Nil(t, err)
+    assert.True(t, ok, "aggregated proof of 2 values should verify")
+
+    secrets[1] = big.NewInt(bulletproofs.MAX_RANGE_END)
+    proof, err = ProveAggregate(secrets, params)
+    assert.Nil(t, err)
+    ok, _ = proof.Verify(params)
+    assert.False(t, ok, "aggregated proof with a value out of range should not verify")
+
+    _, err = ProveAggregate(secrets[:3], params)
+    assert.NotNil(t, err, "number of secrets must be a power of 2")
+}
+
+func TestAggregateCommitmentsMatchBulletproofs(t *testing.T) {
+    params, _ := SetupAggregate(bulletproofs.MAX_RANGE_END, 2)
+    single, _ := Setup(bulletproofs.MAX_RANGE_END)
+    secrets := []*big.Int{big.NewInt(3), big.NewInt(4)}
+    gammas := []*big.Int{big.NewInt(5), big.NewInt(6)}
+    proof, err := ProveAggregateWithCommitments(secrets, gammas, params)
+    assert.Nil(t, err)
+    for j, C := range proof.Commitments() {
+        bp, err := bulletproofs.ProveWithCommitment(secrets[j], gammas[j], single)
+        assert.Nil(t, err)
+        assert.True(t, bp.Commitment().Equal(C), "commitments should be equal")
+    }
+}
+
+func TestDerandomized(t *testing.T) {
+    params, _ := SetupBits(32)
+    proof1, err := ProveWithCommitment(big.NewInt(18), big.NewInt(5), params, Derandomized(), WithRand(nil))
+    assert.Nil(t, err)
+    proof2, err := ProveWithCommitment(big.NewInt(18), big.NewInt(5), params, Derandomized(), WithRand(nil))
+    assert.Nil(t, err)
+    b1, _ := proof1.MarshalBinary()
+    b2, _ := proof2.MarshalBinary()
+    assert.Equal(t, b1, b2, "derandomized proofs without random source should be equal")
+    ok, _ := proof1.Verify(params)
+    assert.True(t, ok)
+
+    // The blinding factors are never derived from the secret, which would let anyone
+    // check candidate secrets against the commitment.
+    _, err = Prove(big.NewInt(18), params, Derandomized(), WithRand(nil))
+    assert.Equal(t, ErrNoRandomness, err)
+    aggregate, _ := SetupAggregate(bulletproofs.MAX_RANGE_END, 2)
+    _, err = ProveAggregate([]*big.Int{big.NewInt(1), big.NewInt(2)}, aggregate, Derandomized(), WithRand(nil))
+    assert.Equal(t, ErrNoRandomness, err)
+    proof1, _ = Prove(big.NewInt(18), params, Derandomized())
+    proof2, _ = Prove(big.NewInt(18), params, Derandomized())
+    assert.NotEqual(t, proof1.V.Bytes(), proof2.V.Bytes())
+}
+
+func BenchmarkProve64(b *testing.B) {
+    params, _ := SetupBits(64)
+    secret := big.NewInt(1 << 40)
+    for i := 0; i < b.N; i++ {
+        _, _ = Prove(secret, params)
+    }
+}
+
+func BenchmarkVerify64(b *testing.B) {
+    params, _ := SetupBits(64)
+    proof, _ := Prove(big.NewInt(1<<40), params)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        _, _ = proof.Verify(params)
+    }
+}
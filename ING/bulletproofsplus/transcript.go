@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the messages absorbed by the Fiat-Shamir transcripts of the
+proofs. Points and scalars are absorbed using their canonical encodings, see
+encoding.go.
+*/
+
+package bulletproofsplus
+
+import (
+    "bytes"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+)
+
+// RANGE_PROOF_LABEL is the label of the transcripts of the range proofs.
+const RANGE_PROOF_LABEL = "zkrp bulletproofs+ range proof"
+
+/*
+rangeProofDomainSep absorbs the statement of a range proof over m values: the
+bit-length of the range, the generators and the commitments.
+*/
+func rangeProofDomainSep(t *transcript.Transcript, params SetupParams, V []*p256.P256) {
+    mn := int64(len(V)) * params.N
+    t.AppendMessage("dom-sep", []byte("rangeproof+ v1"))
+    t.AppendUint64("n", uint64(params.N))
+    t.AppendUint64("m", uint64(len(V)))
+    appendPoint(t, "G", params.G)
+    appendPoint(t, "H", params.H)
+    appendPoints(t, "Gg", params.Gg[:mn])
+    appendPoints(t, "Hh", params.Hh[:mn])
+    for _, Vj := range V {
+        appendPoint(t, "V", Vj)
+    }
+}
+
+/*
+challenges absorbs the commitment A and returns the challenges y and z.
+*/
+func rangeProofChallenges(t *transcript.Transcript, A *p256.P256) (*big.Int, *big.Int) {
+    appendPoint(t, "A", A)
+    y := challengeScalar(t, "y")
+    z := challengeScalar(t, "z")
+    return y, z
+}
+
+/*
+challenges absorbs the messages of the weighted inner product argument over n
+elements, and returns the challenge of each round and the final challenge.
+*/
+func (proof WeightedInnerProductProof) challenges(t *transcript.Transcript, n int64) ([]*big.Int, *big.Int) {
+    t.AppendMessage("dom-sep", []byte("wip v1"))
+    t.AppendUint64("n", uint64(n))
+    es := make([]*big.Int, len(proof.Ls))
+    for i := range proof.Ls {
+        appendPoint(t, "L", proof.Ls[i])
+        appendPoint(t, "R", proof.Rs[i])
+        es[i] = challengeScalar(t, "e")
+    }
+    appendPoint(t, "A", proof.A)
+    appendPoint(t, "B", proof.B)
+    return es, challengeScalar(t, "e")
+}
+
+func appendPoint(t *transcript.Transcript, label string, point *p256.P256) {
+    t.AppendMessage(label, pointBytes(point))
+}
+
+func appendPoints(t *transcript.Transcript, label string, points []*p256.P256) {
+    var buffer bytes.Buffer
+    writePoints(&buffer, points...)
+    t.AppendMessage(label, buffer.Bytes())
+}
+
+func challengeScalar(t *transcript.Transcript, label string) *big.Int {
+    return t.ChallengeScalar(label, ORDER)
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package bulletproofsplus
+
+import (
+    "errors"
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/bulletproofs"
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+/*
+WeightedInnerProductProof is the zero-knowledge weighted inner product argument of
+Section 3 of the paper. It proves the knowledge of a, b and alpha such that
+P = Gg^a.Hh^b.g^(a (.)y b).h^alpha, where a (.)y b = sum_i a_i.b_i.y^(i+1). Ls and Rs
+are the messages of the rounds, A and B the ones of the last round, and R, S and D
+the responses r', s' and delta'.
+*/
+type WeightedInnerProductProof struct {
+    Ls []*p256.P256
+    Rs []*p256.P256
+    A  *p256.P256
+    B  *p256.P256
+    R  *big.Int
+    S  *big.Int
+    D  *big.Int
+}
+
+/*
+proveWeightedInnerProduct computes the weighted inner product argument for the
+vectors a and b, with the generators Gg and Hh, g and h, deriving the challenges
+from t and reading the nonces from rng. Each round halves the vectors, as in
+Figure 1 of the paper.
+*/
+func proveWeightedInnerProduct(t *transcript.Transcript, rng io.Reader, a, b []*big.Int, alpha, y *big.Int, Gg, Hh []*p256.P256, g, h *p256.P256) (WeightedInnerProductProof, error) {
+    var proof WeightedInnerProductProof
+    n := int64(len(a))
+    if int64(len(b)) != n || int64(len(Gg)) != n || int64(len(Hh)) != n || !bulletproofs.IsPowerOfTwo(n) {
+        return proof, errors.New("size of the vectors must be a power of 2 equal to the number of generators")
+    }
+    t.AppendMessage("dom-sep", []byte("wip v1"))
+    t.AppendUint64("n", uint64(n))
+
+    for n > 1 {
+        n = n / 2
+        a1, a2 := a[:n], a[n:]
+        b1, b2 := b[:n], b[n:]
+        G1, G2 := Gg[:n], Gg[n:]
+        H1, H2 := Hh[:n], Hh[n:]
+        yn := new(big.Int).Exp(y, big.NewInt(n), ORDER)
+        yninv := bn.ModInverse(yn, ORDER)
+
+        // cL = a1 (.)y b2, cR = y^n'.(a2 (.)y b1)
+        cL := weightedProduct(a1, b2, y)
+        cR := bn.Mod(bn.Multiply(yn, weightedProduct(a2, b1, y)), ORDER)
+        nonces, err := sampleScalars(rng, 2)
+        if err != nil {
+            return proof, err
+        }
+        dL, dR := nonces[0], nonces[1]
+
+        // L = G2^(a1.y^-n').H1^b2.g^cL.h^dL, R = G1^(a2.y^n').H2^b1.g^cR.h^dR
+        L, _ := bulletproofs.VectorExp(concatPoints(G2, H1, []*p256.P256{g, h}), concatScalars(scalarMul(a1, yninv), b2, []*big.Int{cL, dL}))
+        R, _ := bulletproofs.VectorExp(concatPoints(G1, H2, []*p256.P256{g, h}), concatScalars(scalarMul(a2, yn), b1, []*big.Int{cR, dR}))
+        proof.Ls = append(proof.Ls, L)
+        proof.Rs = append(proof.Rs, R)
+        appendPoint(t, "L", L)
+        appendPoint(t, "R", R)
+        e := challengeScalar(t, "e")
+        einv := bn.ModInverse(e, ORDER)
+        e2 := bn.Mod(bn.Multiply(e, e), ORDER)
+        e2inv := bn.Mod(bn.Multiply(einv, einv), ORDER)
+
+        // G' = G1^(e^-1) o G2^(e.y^-n'), H' = H1^e o H2^(e^-1)
+        Gg = foldGenerators(G1, G2, einv, bn.Mod(bn.Multiply(e, yninv), ORDER))
+        Hh = foldGenerators(H1, H2, e, einv)
+        // a' = a1.e + a2.y^n'.e^-1, b' = b1.e^-1 + b2.e
+        a = addVectors(scalarMul(a1, e), scalarMul(a2, bn.Mod(bn.Multiply(yn, einv), ORDER)))
+        b = addVectors(scalarMul(b1, einv), scalarMul(b2, e))
+        // alpha' = dL.e^2 + alpha + dR.e^-2
+        alpha = bn.Add(alpha, bn.Multiply(dL, e2))
+        alpha = bn.Mod(bn.Add(alpha, bn.Multiply(dR, e2inv)), ORDER)
+    }
+
+    // Last round, over a single element
+    nonces, err := sampleScalars(rng, 4)
+    if err != nil {
+        return proof, err
+    }
+    r, s, delta, eta := nonces[0], nonces[1], nonces[2], nonces[3]
+    // A = G^r.H^s.g^(r.y.b + s.y.a).h^delta, B = g^(r.y.s).h^eta
+    rb := bn.Multiply(r, b[0])
+    sa := bn.Multiply(s, a[0])
+    ga := bn.Mod(bn.Multiply(y, bn.Add(rb, sa)), ORDER)
+    gb := bn.Mod(bn.Multiply(y, bn.Multiply(r, s)), ORDER)
+    proof.A, _ = bulletproofs.VectorExp([]*p256.P256{Gg[0], Hh[0], g, h}, []*big.Int{r, s, ga, delta})
+    proof.B, _ = bulletproofs.VectorExp([]*p256.P256{g, h}, []*big.Int{gb, eta})
+    appendPoint(t, "A", proof.A)
+    appendPoint(t, "B", proof.B)
+    e := challengeScalar(t, "e")
+
+    // r' = r + a.e, s' = s + b.e, delta' = eta + delta.e + alpha.e^2
+    proof.R = bn.Mod(bn.Add(r, bn.Multiply(a[0], e)), ORDER)
+    proof.S = bn.Mod(bn.Add(s, bn.Multiply(b[0], e)), ORDER)
+    d := bn.Add(eta, bn.Multiply(delta, e))
+    proof.D = bn.Mod(bn.Add(d, bn.Multiply(alpha, bn.Multiply(e, e))), ORDER)
+    return proof, nil
+}
+
+/*
+verificationTerms returns the terms of the equation of the last round, once the
+generators are folded:
+P^(e^2).prod_j (L_j^(e_j^2).R_j^(e_j^-2))^(e^2).A^e.B = G'^(r'.e).H'^(s'.e).g^(r'.y.s').h^delta'
+The terms are returned as the exponents of Gg and Hh, gs and hs, followed by the
+other points and their exponents, all of them moved to the right-hand side. The
+coefficient of P, that the caller adds, is -e^2, which is returned as well.
+*/
+func (proof WeightedInnerProductProof) verificationTerms(t *transcript.Transcript, n int64, y *big.Int, g, h *p256.P256) ([]*big.Int, []*big.Int, []*p256.P256, []*big.Int, *big.Int, error) {
+    if len(proof.Ls) != len(proof.Rs) || int64(1)<<uint(len(proof.Ls)) != n {
+        return nil, nil, nil, nil, nil, errors.New("number of rounds does not match the size of the vectors")
+    }
+    if proof.A == nil || proof.B == nil || proof.R == nil || proof.S == nil || proof.D == nil {
+        return nil, nil, nil, nil, nil, errors.New("weighted inner product proof is missing an element")
+    }
+    es, e := proof.challenges(t, n)
+    e2 := bn.Mod(bn.Multiply(e, e), ORDER)
+    s := foldingScalars(es, n)
+
+    // G' = prod_i Gg_i^(s_i.y^-i) and H' = prod_i Hh_i^(s_i^-1), where s_i^-1 = s_(n-1-i)
+    gs := make([]*big.Int, n)
+    hs := make([]*big.Int, n)
+    re := bn.Mod(bn.Multiply(proof.R, e), ORDER)
+    se := bn.Mod(bn.Multiply(proof.S, e), ORDER)
+    yinv := bn.ModInverse(y, ORDER)
+    yi := big.NewInt(1)
+    for i := int64(0); i < n; i++ {
+        gs[i] = bn.Mod(bn.Multiply(re, bn.Multiply(s[i], yi)), ORDER)
+        hs[i] = bn.Mod(bn.Multiply(se, s[n-1-i]), ORDER)
+        yi = bn.Mod(bn.Multiply(yi, yinv), ORDER)
+    }
+
+    ryS := bn.Mod(bn.Multiply(bn.Multiply(proof.R, y), proof.S), ORDER)
+    points := []*p256.P256{g, h, proof.A, proof.B}
+    scalars := []*big.Int{ryS, proof.D, bn.Sub(ORDER, e), bn.Sub(ORDER, big.NewInt(1))}
+    for j := range proof.Ls {
+        ej2 := bn.Mod(bn.Multiply(es[j], es[j]), ORDER)
+        ej2inv := bn.ModInverse(ej2, ORDER)
+        points = append(points, proof.Ls[j], proof.Rs[j])
+        scalars = append(scalars, bn.Sub(ORDER, bn.Mod(bn.Multiply(e2, ej2), ORDER)), bn.Sub(ORDER, bn.Mod(bn.Multiply(e2, ej2inv), ORDER)))
+    }
+    return gs, hs, points, scalars, bn.Sub(ORDER, e2), nil
+}
+
+/*
+weightedProduct returns a (.)y b = sum_i a_i.b_i.y^(i+1).
+*/
+func weightedProduct(a, b []*big.Int, y *big.Int) *big.Int {
+    result := big.NewInt(0)
+    yi := new(big.Int).Set(y)
+    for i := range a {
+        result = bn.Add(result, bn.Multiply(bn.Multiply(a[i], b[i]), yi))
+        yi = bn.Mod(bn.Multiply(yi, y), ORDER)
+    }
+    return bn.Mod(result, ORDER)
+}
+
+/*
+foldingScalars returns the vector s such that s_i = prod_j e_j^b(i,j), where b(i,j)
+is 1 if the bit j of i, counting from the most significant one, is set and -1
+otherwise.
+*/
+func foldingScalars(e []*big.Int, n int64) []*big.Int {
+    logn := len(e)
+    s := make([]*big.Int, n)
+    s[0] = big.NewInt(1)
+    for j := 0; j < logn; j++ {
+        s[0] = bn.Mod(bn.Multiply(s[0], bn.ModInverse(e[j], ORDER)), ORDER)
+    }
+    for i := int64(1); i < n; i++ {
+        // k is the position of the most significant bit of i
+        k := 0
+        for (i >> uint(k+1)) > 0 {
+            k = k + 1
+        }
+        e2 := bn.Mod(bn.Multiply(e[logn-1-k], e[logn-1-k]), ORDER)
+        s[i] = bn.Mod(bn.Multiply(s[i-(int64(1)<<uint(k))], e2), ORDER)
+    }
+    return s
+}
+
+/*
+foldGenerators computes g[i]^x.h[i]^y for each i.
+*/
+func foldGenerators(g, h []*p256.P256, x, y *big.Int) []*p256.P256 {
+    result := make([]*p256.P256, len(g))
+    for i := range g {
+        result[i], _ = bulletproofs.VectorExp([]*p256.P256{g[i], h[i]}, []*big.Int{x, y})
+    }
+    return result
+}
+
+func scalarMul(a []*big.Int, x *big.Int) []*big.Int {
+    result := make([]*big.Int, len(a))
+    for i := range a {
+        result[i] = bn.Mod(bn.Multiply(a[i], x), ORDER)
+    }
+    return result
+}
+
+func addVectors(a, b []*big.Int) []*big.Int {
+    result := make([]*big.Int, len(a))
+    for i := range a {
+        result[i] = bn.Mod(bn.Add(a[i], b[i]), ORDER)
+    }
+    return result
+}
+
+func concatPoints(vectors ...[]*p256.P256) []*p256.P256 {
+    var result []*p256.P256
+    for _, v := range vectors {
+        result = append(result, v...)
+    }
+    return result
+}
+
+func concatScalars(vectors ...[]*big.Int) []*big.Int {
+    var result []*big.Int
+    for _, v := range vectors {
+        result = append(result, v...)
+    }
+    return result
+}
@@ -26,210 +26,200 @@ package ccs08
 
 import (
     "bytes"
-    "crypto/rand"
     "errors"
-    "math"
     "math/big"
     "strconv"
 
-    "github.com/ing-bank/zkrp/crypto/bbsignatures"
     "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
+    "github.com/ing-bank/zkrp/crypto/transcript"
     . "github.com/ing-bank/zkrp/util"
     "github.com/ing-bank/zkrp/util/bn"
-    "github.com/ing-bank/zkrp/util/intconversion"
 )
 
 /*
-paramsSet contains elements generated by the verifier, which are necessary for the prover.
-This must be computed in a trusted setup.
+ProofSet contains the necessary elements for the ZK Set Membership proof: the
+commitment C, the first messages V, A and D of the prover, the challenge and the
+responses. The nonces of the prover are not part of the proof, since they would
+reveal the secret.
 */
-type paramsSet struct {
-    signatures map[int64]*bn256.G2
-    H          *bn256.G2
-    kp         bbsignatures.Keypair
-    // u determines the amount of signatures we need in the public params.
-    // Each signature can be compressed to just 1 field element of 256 bits.
-    // Then the parameters have minimum size equal to 256*u bits.
-    // l determines how many pairings we need to compute, then in order to improve
-    // verifier`s performance we want to minize it.
-    // Namely, we have 2*l pairings for the prover and 3*l for the verifier.
+type ProofSet struct {
+    C         *bn256.G2
+    V         *bn256.G2
+    A         *bn256.GT
+    D         *bn256.G2
+    Challenge *big.Int
+    Zr        *big.Int
+    Zsig      *big.Int
+    Zv        *big.Int
 }
 
 /*
-paramsUL contains elements generated by the verifier, which are necessary for the prover.
-This must be computed in a trusted setup.
-*/
-type paramsUL struct {
-    signatures map[string]*bn256.G2
-    H          *bn256.G2
-    kp         bbsignatures.Keypair
-    // u determines the amount of signatures we need in the public params.
-    // Each signature can be compressed to just 1 field element of 256 bits.
-    // Then the parameters have minimum size equal to 256*u bits.
-    // l determines how many pairings we need to compute, then in order to improve
-    // verifier`s performance we want to minize it.
-    // Namely, we have 2*l pairings for the prover and 3*l for the verifier.
-    u, l int64
-}
-
-/*
-proofSet contains the necessary elements for the ZK Set Membership proof.
+ProofUL contains the necessary elements for the ZK proof that the secret belongs to
+[0,u^l), with one V, A, Zsig and Zv per digit of the secret in base u.
 */
-type proofSet struct {
-    V              *bn256.G2
-    D, C           *bn256.G2
-    a              *bn256.GT
-    s, t, zsig, zv *big.Int
-    c, m, zr       *big.Int
+type ProofUL struct {
+    C         *bn256.G2
+    V         []*bn256.G2
+    A         []*bn256.GT
+    D         *bn256.G2
+    Challenge *big.Int
+    Zr        *big.Int
+    Zsig      []*big.Int
+    Zv        []*big.Int
 }
 
 /*
-proofUL contains the necessary elements for the ZK proof.
+ProveSetOpening produces the ZK Set Membership proof for the commitment opened by
+opening, which must have been computed with the Pedersen parameters of p.
 */
-type proofUL struct {
-    V              []*bn256.G2
-    D, C           *bn256.G2
-    a              []*bn256.GT
-    s, t, zsig, zv []*big.Int
-    c, m, zr       *big.Int
+func ProveSetOpening(opening pedersen.Opening, p ParamsSet, opts ...Option) (ProofSet, error) {
+    if opening.Group != pedersen.BN256G2 {
+        return ProofSet{}, pedersen.ErrGroupMismatch
+    }
+    if !opening.Value.IsInt64() {
+        return ProofSet{}, errors.New("Could not generate proof. Element does not belong to the interval.")
+    }
+    return ProveSet(opening.Value.Int64(), opening.Blinding, p, opts...)
 }
 
 /*
-SetupSet generates the signature for the elements in the set.
+ProveSet method is used to produce the ZK Set Membership proof.
 */
-func SetupSet(s []int64) (paramsSet, error) {
-    var (
-        i int
-        p paramsSet
-    )
-    p.kp, _ = bbsignatures.Keygen()
-
-    p.signatures = make(map[int64]*bn256.G2)
-    for i = 0; i < len(s); i++ {
-        sig_i, _ := bbsignatures.Sign(new(big.Int).SetInt64(int64(s[i])), p.kp.Privk)
-        p.signatures[s[i]] = sig_i
+func ProveSet(x int64, r *big.Int, p ParamsSet, opts ...Option) (ProofSet, error) {
+    A, ok := p.signatures[x]
+    if !ok {
+        return ProofSet{}, errors.New("Could not generate proof. Element does not belong to the interval.")
     }
-    // Issue #12: p.H must be computed using MapToPoint method.
-    h := intconversion.BigFromBase10("18560948149108576432482904553159745978835170526553990798435819795989606410925")
-    p.H = new(bn256.G2).ScalarBaseMult(h)
-    return p, nil
+    return proveSet(new(big.Int).SetInt64(x), r, A, &p.VerifierParamsSet, newConfig(opts))
 }
 
 /*
-SetupUL generates the signature for the interval [0,u^l).
-The value of u should be roughly b/log(b), but we can choose smaller values in
-order to get smaller parameters, at the cost of having worse performance.
+proveSet produces the ZK Set Membership proof for the element x, whose signature
+is A.
 */
-func SetupUL(u, l int64) (paramsUL, error) {
+func proveSet(x, r *big.Int, A *bn256.G2, p *VerifierParamsSet, cfg config) (ProofSet, error) {
     var (
-        i int64
-        p paramsUL
+        v, m, s, t *big.Int
+        proof_out  ProofSet
     )
-    p.kp, _ = bbsignatures.Keygen()
-
-    p.signatures = make(map[string]*bn256.G2)
-    for i = 0; i < u; i++ {
-        sig_i, _ := bbsignatures.Sign(new(big.Int).SetInt64(i), p.kp.Privk)
-        p.signatures[strconv.FormatInt(i, 10)] = sig_i
-    }
-    // Issue #12: p.H must be computed using MapToPoint method.
-    h := intconversion.BigFromBase10("18560948149108576432482904553159745978835170526553990798435819795989606410925")
-    p.H = new(bn256.G2).ScalarBaseMult(h)
-    p.u = u
-    p.l = l
-    return p, nil
-}
 
-/*
-ProveSet method is used to produce the ZK Set Membership proof.
-*/
-func ProveSet(x int64, r *big.Int, p paramsSet) (proofSet, error) {
-    var (
-        v         *big.Int
-        proof_out proofSet
-    )
+    // Consider passing C as input,
+    // so that it is possible to delegate the commitment computation to an external party.
+    proof_out.C, _ = Commit(x, r, p.H)
+
+    // m, v, s and t
+    seed := transcript.New(SET_LABEL)
+    p.domainSep(seed, proof_out.C)
+    rng, err := cfg.nonceReader(seed, x, r)
+    if err != nil {
+        return proof_out, err
+    }
+    nonces, err := sampleScalars(rng, 4)
+    if err != nil {
+        return proof_out, err
+    }
+    m, v, s, t = nonces[0], nonces[1], nonces[2], nonces[3]
 
     // Initialize variables
     proof_out.D = new(bn256.G2)
     proof_out.D.SetInfinity()
-    proof_out.m, _ = rand.Int(rand.Reader, bn256.Order)
-
-    v, _ = rand.Int(rand.Reader, bn256.Order)
-    A, ok := p.signatures[x]
-
-    if !ok {
-        return proof_out, errors.New("Could not generate proof. Element does not belong to the interval.")
-    }
 
     // D = g^s.H^m
-    D := new(bn256.G2).ScalarMult(p.H, proof_out.m)
-    proof_out.s, _ = rand.Int(rand.Reader, bn256.Order)
-    aux := new(bn256.G2).ScalarBaseMult(proof_out.s)
+    D := new(bn256.G2).ScalarMult(p.H, m)
+    aux := new(bn256.G2).ScalarBaseMult(s)
     D.Add(D, aux)
 
     proof_out.V = new(bn256.G2).ScalarMult(A, v)
-    proof_out.t, _ = rand.Int(rand.Reader, bn256.Order)
-    proof_out.a = bn256.Pair(G1, proof_out.V)
-    proof_out.a.ScalarMult(proof_out.a, proof_out.s)
-    proof_out.a.Invert(proof_out.a)
-    proof_out.a.Add(proof_out.a, new(bn256.GT).ScalarMult(E, proof_out.t))
+    proof_out.A = bn256.Pair(G1, proof_out.V)
+    proof_out.A.ScalarMult(proof_out.A, s)
+    proof_out.A.Invert(proof_out.A)
+    proof_out.A.Add(proof_out.A, new(bn256.GT).ScalarMult(E, t))
     proof_out.D.Add(proof_out.D, D)
 
-    // Consider passing C as input,
-    // so that it is possible to delegate the commitment computation to an external party.
-    proof_out.C, _ = Commit(new(big.Int).SetInt64(x), r, p.H)
     // Fiat-Shamir heuristic
-    proof_out.c, _ = HashSet(proof_out.a, proof_out.D)
-    proof_out.c = bn.Mod(proof_out.c, bn256.Order)
-
-    proof_out.zr = bn.Sub(proof_out.m, bn.Multiply(r, proof_out.c))
-    proof_out.zr = bn.Mod(proof_out.zr, bn256.Order)
-    proof_out.zsig = bn.Sub(proof_out.s, bn.Multiply(new(big.Int).SetInt64(x), proof_out.c))
-    proof_out.zsig = bn.Mod(proof_out.zsig, bn256.Order)
-    proof_out.zv = bn.Sub(proof_out.t, bn.Multiply(v, proof_out.c))
-    proof_out.zv = bn.Mod(proof_out.zv, bn256.Order)
+    proof_out.Challenge = proof_out.challenge(p)
+
+    proof_out.Zr = bn.Sub(m, bn.Multiply(r, proof_out.Challenge))
+    proof_out.Zr = bn.Mod(proof_out.Zr, bn256.Order)
+    proof_out.Zsig = bn.Sub(s, bn.Multiply(x, proof_out.Challenge))
+    proof_out.Zsig = bn.Mod(proof_out.Zsig, bn256.Order)
+    proof_out.Zv = bn.Sub(t, bn.Multiply(v, proof_out.Challenge))
+    proof_out.Zv = bn.Mod(proof_out.Zv, bn256.Order)
     return proof_out, nil
 }
 
+/*
+ProveULOpening produces the ZKRP proof for the commitment opened by opening, which
+must have been computed with the Pedersen parameters of p.
+*/
+func ProveULOpening(opening pedersen.Opening, p ParamsUL, opts ...Option) (ProofUL, error) {
+    if opening.Group != pedersen.BN256G2 {
+        return ProofUL{}, pedersen.ErrGroupMismatch
+    }
+    return ProveUL(opening.Value, opening.Blinding, p, opts...)
+}
+
 /*
 ProveUL method is used to produce the ZKRP proof that secret x belongs to the interval [0,U^L].
 */
-func ProveUL(x, r *big.Int, p paramsUL) (proofUL, error) {
+func ProveUL(x, r *big.Int, p ParamsUL, opts ...Option) (ProofUL, error) {
+    return proveUL(transcript.New(UL_LABEL), x, r, p, newConfig(opts))
+}
+
+/*
+proveUL produces the ZKRP proof for the interval [0,U^L], deriving the challenge from t.
+*/
+func proveUL(t *transcript.Transcript, x, r *big.Int, p ParamsUL, cfg config) (ProofUL, error) {
     var (
         i         int64
-        v         []*big.Int
-        proof_out proofUL
+        v, s, tau []*big.Int
+        m         *big.Int
+        proof_out ProofUL
     )
     decx, _ := Decompose(x, p.u, p.l)
 
+    // Consider passing C as input,
+    // so that it is possible to delegate the commitment computation to an external party.
+    proof_out.C, _ = Commit(x, r, p.H)
+
+    // m, followed by v, s and tau
+    seed := t.Clone()
+    p.domainSep(seed, proof_out.C)
+    rng, err := cfg.nonceReader(seed, x, r)
+    if err != nil {
+        return proof_out, err
+    }
+    nonces, err := sampleScalars(rng, int(1+3*p.l))
+    if err != nil {
+        return proof_out, err
+    }
+
     // Initialize variables
-    v = make([]*big.Int, p.l)
+    v = nonces[1 : 1+p.l]
     proof_out.V = make([]*bn256.G2, p.l)
-    proof_out.a = make([]*bn256.GT, p.l)
-    proof_out.s = make([]*big.Int, p.l)
-    proof_out.t = make([]*big.Int, p.l)
-    proof_out.zsig = make([]*big.Int, p.l)
-    proof_out.zv = make([]*big.Int, p.l)
+    proof_out.A = make([]*bn256.GT, p.l)
+    s = nonces[1+p.l : 1+2*p.l]
+    tau = nonces[1+2*p.l:]
+    proof_out.Zsig = make([]*big.Int, p.l)
+    proof_out.Zv = make([]*big.Int, p.l)
     proof_out.D = new(bn256.G2)
     proof_out.D.SetInfinity()
-    proof_out.m, _ = rand.Int(rand.Reader, bn256.Order)
+    m = nonces[0]
 
     // D = H^m
-    D := new(bn256.G2).ScalarMult(p.H, proof_out.m)
+    D := new(bn256.G2).ScalarMult(p.H, m)
     for i = 0; i < p.l; i++ {
-        v[i], _ = rand.Int(rand.Reader, bn256.Order)
         A, ok := p.signatures[strconv.FormatInt(decx[i], 10)]
         if ok {
             proof_out.V[i] = new(bn256.G2).ScalarMult(A, v[i])
-            proof_out.s[i], _ = rand.Int(rand.Reader, bn256.Order)
-            proof_out.t[i], _ = rand.Int(rand.Reader, bn256.Order)
-            proof_out.a[i] = bn256.Pair(G1, proof_out.V[i])
-            proof_out.a[i].ScalarMult(proof_out.a[i], proof_out.s[i])
-            proof_out.a[i].Invert(proof_out.a[i])
-            proof_out.a[i].Add(proof_out.a[i], new(bn256.GT).ScalarMult(E, proof_out.t[i]))
+            proof_out.A[i] = bn256.Pair(G1, proof_out.V[i])
+            proof_out.A[i].ScalarMult(proof_out.A[i], s[i])
+            proof_out.A[i].Invert(proof_out.A[i])
+            proof_out.A[i].Add(proof_out.A[i], new(bn256.GT).ScalarMult(E, tau[i]))
 
             ui := new(big.Int).Exp(new(big.Int).SetInt64(p.u), new(big.Int).SetInt64(i), nil)
-            muisi := new(big.Int).Mul(proof_out.s[i], ui)
+            muisi := new(big.Int).Mul(s[i], ui)
             muisi = bn.Mod(muisi, bn256.Order)
             aux := new(bn256.G2).ScalarBaseMult(muisi)
             D.Add(D, aux)
@@ -239,20 +229,16 @@ func ProveUL(x, r *big.Int, p paramsUL) (proofUL, error) {
     }
     proof_out.D.Add(proof_out.D, D)
 
-    // Consider passing C as input,
-    // so that it is possible to delegate the commitment computation to an external party.
-    proof_out.C, _ = Commit(x, r, p.H)
     // Fiat-Shamir heuristic
-    proof_out.c, _ = Hash(proof_out.a, proof_out.D)
-    proof_out.c = bn.Mod(proof_out.c, bn256.Order)
+    proof_out.Challenge = proof_out.challenge(t, &p.VerifierParamsUL)
 
-    proof_out.zr = bn.Sub(proof_out.m, bn.Multiply(r, proof_out.c))
-    proof_out.zr = bn.Mod(proof_out.zr, bn256.Order)
+    proof_out.Zr = bn.Sub(m, bn.Multiply(r, proof_out.Challenge))
+    proof_out.Zr = bn.Mod(proof_out.Zr, bn256.Order)
     for i = 0; i < p.l; i++ {
-        proof_out.zsig[i] = bn.Sub(proof_out.s[i], bn.Multiply(new(big.Int).SetInt64(decx[i]), proof_out.c))
-        proof_out.zsig[i] = bn.Mod(proof_out.zsig[i], bn256.Order)
-        proof_out.zv[i] = bn.Sub(proof_out.t[i], bn.Multiply(v[i], proof_out.c))
-        proof_out.zv[i] = bn.Mod(proof_out.zv[i], bn256.Order)
+        proof_out.Zsig[i] = bn.Sub(s[i], bn.Multiply(new(big.Int).SetInt64(decx[i]), proof_out.Challenge))
+        proof_out.Zsig[i] = bn.Mod(proof_out.Zsig[i], bn256.Order)
+        proof_out.Zv[i] = bn.Sub(tau[i], bn.Multiply(v[i], proof_out.Challenge))
+        proof_out.Zv[i] = bn.Mod(proof_out.Zv[i], bn256.Order)
     }
     return proof_out, nil
 }
@@ -260,16 +246,26 @@ func ProveUL(x, r *big.Int, p paramsUL) (proofUL, error) {
 /*
 VerifySet is used to validate the ZK Set Membership proof. It returns true iff the proof is valid.
 */
-func VerifySet(proof_out *proofSet, p *paramsSet) (bool, error) {
+func VerifySet(proof_out *ProofSet, p *VerifierParamsSet) (bool, error) {
     var (
         D      *bn256.G2
         r1, r2 bool
         p1, p2 *bn256.GT
     )
+    if p.y == nil || p.H == nil {
+        return false, errors.New("params are not initialized")
+    }
+    if !proof_out.complete() {
+        return false, errors.New("proof is missing an element")
+    }
+    // c == Hash(transcript) ?
+    if proof_out.challenge(p).Cmp(proof_out.Challenge) != 0 {
+        return false, nil
+    }
     // D == C^c.h^ zr.g^zsig ?
-    D = new(bn256.G2).ScalarMult(proof_out.C, proof_out.c)
-    D.Add(D, new(bn256.G2).ScalarMult(p.H, proof_out.zr))
-    aux := new(bn256.G2).ScalarBaseMult(proof_out.zsig)
+    D = new(bn256.G2).ScalarMult(proof_out.C, proof_out.Challenge)
+    D.Add(D, new(bn256.G2).ScalarMult(p.H, proof_out.Zr))
+    aux := new(bn256.G2).ScalarBaseMult(proof_out.Zsig)
     D.Add(D, aux)
 
     DBytes := D.Marshal()
@@ -278,36 +274,105 @@ func VerifySet(proof_out *proofSet, p *paramsSet) (bool, error) {
 
     r2 = true
     // a == [e(V,y)^c].[e(V,g)^-zsig].[e(g,g)^zv]
-    p1 = bn256.Pair(p.kp.Pubk, proof_out.V)
-    p1.ScalarMult(p1, proof_out.c)
+    p1 = bn256.Pair(p.y, proof_out.V)
+    p1.ScalarMult(p1, proof_out.Challenge)
     p2 = bn256.Pair(G1, proof_out.V)
-    p2.ScalarMult(p2, proof_out.zsig)
+    p2.ScalarMult(p2, proof_out.Zsig)
     p2.Invert(p2)
     p1.Add(p1, p2)
-    p1.Add(p1, new(bn256.GT).ScalarMult(E, proof_out.zv))
+    p1.Add(p1, new(bn256.GT).ScalarMult(E, proof_out.Zv))
 
     pBytes := p1.Marshal()
-    aBytes := proof_out.a.Marshal()
+    aBytes := proof_out.A.Marshal()
     r2 = r2 && bytes.Equal(pBytes, aBytes)
     return r1 && r2, nil
 }
 
+/*
+VerifySetCommitment validates the ZK Set Membership proof for the commitment C
+expected by the verifier, ignoring the commitment carried by the proof.
+*/
+func VerifySetCommitment(proof_out *ProofSet, p *VerifierParamsSet, C pedersen.Commitment) (bool, error) {
+    if !p.Pedersen().Owns(C) {
+        return false, pedersen.ErrGroupMismatch
+    }
+    point, err := C.G2()
+    if err != nil {
+        return false, err
+    }
+    expected := *proof_out
+    expected.C = point
+    return VerifySet(&expected, p)
+}
+
+/*
+Commitment returns the commitment C to the element of the set.
+*/
+func (proof_out *ProofSet) Commitment() pedersen.Commitment {
+    return newCommitment(proof_out.C)
+}
+
 /*
 VerifyUL is used to validate the ZKRP proof. It returns true iff the proof is valid.
 */
-func VerifyUL(proof_out *proofUL, p *paramsUL) (bool, error) {
+func VerifyUL(proof_out *ProofUL, p *VerifierParamsUL) (bool, error) {
+    return verifyUL(transcript.New(UL_LABEL), proof_out, p)
+}
+
+/*
+VerifyULCommitment validates the ZKRP proof for the commitment C expected by the
+verifier, ignoring the commitment carried by the proof.
+*/
+func VerifyULCommitment(proof_out *ProofUL, p *VerifierParamsUL, C pedersen.Commitment) (bool, error) {
+    if !p.Pedersen().Owns(C) {
+        return false, pedersen.ErrGroupMismatch
+    }
+    point, err := C.G2()
+    if err != nil {
+        return false, err
+    }
+    expected := *proof_out
+    expected.C = point
+    return VerifyUL(&expected, p)
+}
+
+/*
+Commitment returns the commitment C to the secret.
+*/
+func (proof_out *ProofUL) Commitment() pedersen.Commitment {
+    return newCommitment(proof_out.C)
+}
+
+/*
+verifyUL validates the ZKRP proof, deriving the challenge from t.
+*/
+func verifyUL(t *transcript.Transcript, proof_out *ProofUL, p *VerifierParamsUL) (bool, error) {
     var (
         i      int64
         D      *bn256.G2
         r1, r2 bool
         p1, p2 *bn256.GT
     )
+    if p.y == nil || p.H == nil {
+        return false, errors.New("params are not initialized")
+    }
+    if int64(len(proof_out.V)) != p.l || int64(len(proof_out.A)) != p.l ||
+        int64(len(proof_out.Zsig)) != p.l || int64(len(proof_out.Zv)) != p.l {
+        return false, errors.New("number of digits does not match the parameters")
+    }
+    if !proof_out.complete() {
+        return false, errors.New("proof is missing an element")
+    }
+    // c == Hash(transcript) ?
+    if proof_out.challenge(t, p).Cmp(proof_out.Challenge) != 0 {
+        return false, nil
+    }
     // D == C^c.h^ zr.g^zsig ?
-    D = new(bn256.G2).ScalarMult(proof_out.C, proof_out.c)
-    D.Add(D, new(bn256.G2).ScalarMult(p.H, proof_out.zr))
+    D = new(bn256.G2).ScalarMult(proof_out.C, proof_out.Challenge)
+    D.Add(D, new(bn256.G2).ScalarMult(p.H, proof_out.Zr))
     for i = 0; i < p.l; i++ {
         ui := new(big.Int).Exp(new(big.Int).SetInt64(p.u), new(big.Int).SetInt64(i), nil)
-        muizsigi := new(big.Int).Mul(proof_out.zsig[i], ui)
+        muizsigi := new(big.Int).Mul(proof_out.Zsig[i], ui)
         muizsigi = bn.Mod(muizsigi, bn256.Order)
         aux := new(bn256.G2).ScalarBaseMult(muizsigi)
         D.Add(D, aux)
@@ -320,108 +385,234 @@ func VerifyUL(proof_out *proofUL, p *paramsUL) (bool, error) {
     r2 = true
     for i = 0; i < p.l; i++ {
         // a == [e(V,y)^c].[e(V,g)^-zsig].[e(g,g)^zv]
-        p1 = bn256.Pair(p.kp.Pubk, proof_out.V[i])
-        p1.ScalarMult(p1, proof_out.c)
+        p1 = bn256.Pair(p.y, proof_out.V[i])
+        p1.ScalarMult(p1, proof_out.Challenge)
         p2 = bn256.Pair(G1, proof_out.V[i])
-        p2.ScalarMult(p2, proof_out.zsig[i])
+        p2.ScalarMult(p2, proof_out.Zsig[i])
         p2.Invert(p2)
         p1.Add(p1, p2)
-        p1.Add(p1, new(bn256.GT).ScalarMult(E, proof_out.zv[i]))
+        p1.Add(p1, new(bn256.GT).ScalarMult(E, proof_out.Zv[i]))
 
         pBytes := p1.Marshal()
-        aBytes := proof_out.a[i].Marshal()
+        aBytes := proof_out.A[i].Marshal()
         r2 = r2 && bytes.Equal(pBytes, aBytes)
     }
     return r1 && r2, nil
 }
 
 /*
-proof contains the necessary elements for the ZK proof.
+complete returns true if none of the elements of the proof is nil.
+*/
+func (proof_out *ProofSet) complete() bool {
+    return proof_out.C != nil && proof_out.V != nil && proof_out.A != nil && proof_out.D != nil &&
+        proof_out.Challenge != nil && proof_out.Zr != nil && proof_out.Zsig != nil && proof_out.Zv != nil
+}
+
+/*
+complete returns true if none of the elements of the proof is nil.
+*/
+func (proof_out *ProofUL) complete() bool {
+    if proof_out.C == nil || proof_out.D == nil || proof_out.Challenge == nil || proof_out.Zr == nil {
+        return false
+    }
+    for i := range proof_out.V {
+        if proof_out.V[i] == nil || proof_out.A[i] == nil || proof_out.Zsig[i] == nil || proof_out.Zv[i] == nil {
+            return false
+        }
+    }
+    return true
+}
+
+/*
+Params contains the parameters of the range proof for the interval [a, b), computed
+by Setup. They must be computed by the issuer, in a trusted setup.
 */
-type proof struct {
-    p1, p2 proofUL
+type Params struct {
+    p    *ParamsUL
+    a, b int64
 }
 
 /*
-params contains elements generated by the verifier, which are necessary for the prover.
-This must be computed in a trusted setup.
+VerifierParams contains the elements needed to verify a range proof for the interval
+[a, b).
 */
-type params struct {
-    p    *paramsUL
+type VerifierParams struct {
+    p    VerifierParamsUL
     a, b int64
 }
 
-type ccs08 struct {
-    p         *params
-    x, r      *big.Int
-    proof_out proof
+/*
+Proof contains the ZK Range Proof that the secret committed in C belongs to [a, b).
+P1 proves that x - b + u^l belongs to [0,u^l), and P2 that x - a belongs to [0,u^l).
+The commitments of P1 and P2 are derived from C by the verifier.
+*/
+type Proof struct {
+    C  *bn256.G2
+    P1 ProofUL
+    P2 ProofUL
 }
 
 /*
-SetupInnerProduct receives integers a and b, and configures the parameters for the rangeproof scheme.
+Setup receives integers a and b, and configures the parameters for the rangeproof
+scheme over the interval [a, b), with a fresh key which is discarded. The base u and
+the number of digits l are given by WithDigits, or chosen by the cost model for the
+preference of WithPreference, see EstimateCost.
 */
-func (zkrp *ccs08) Setup(a, b int64) error {
-    // Compute optimal values for u and l
-    var (
-        u, l int64
-        logb float64
-        p    *params
-    )
-    if a > b {
-        zkrp.p = nil
-        return errors.New("a must be less than or equal to b")
-    }
-    p = new(params)
-    logb = math.Log(float64(b))
-    if logb != 0 {
-        // u = b / int64(logb)
-        u = 57
-        if u != 0 {
-            l = 0
-            for i := b; i > 0; i = i / u {
-                l = l + 1
-            }
-            params_out, e := SetupUL(u, l)
-            p.p = &params_out
-            p.a = a
-            p.b = b
-            zkrp.p = p
-            return e
-        } else {
-            zkrp.p = nil
-            return errors.New("u is zero")
-        }
-    } else {
-        zkrp.p = nil
-        return errors.New("log(b) is zero")
+func Setup(a, b int64, opts ...Option) (Params, error) {
+    var p Params
+    u, l, err := digits(a, b, newConfig(opts))
+    if err != nil {
+        return p, err
+    }
+    params_out, err := SetupUL(u, l, opts...)
+    p.p = &params_out
+    p.a = a
+    p.b = b
+    return p, err
+}
+
+/*
+NewVerifierParams returns the parameters of the verifier of the range proofs for
+[a, b), for the public key y of the issuer. The options must select the same digits
+as the options of the setup.
+*/
+func NewVerifierParams(y *bn256.G1, a, b int64, opts ...Option) (VerifierParams, error) {
+    u, l, err := digits(a, b, newConfig(opts))
+    if err != nil {
+        return VerifierParams{}, err
+    }
+    return VerifierParams{p: NewVerifierParamsUL(y, u, l), a: a, b: b}, nil
+}
+
+/*
+Verifier returns the public part of the parameters, needed by the verifier.
+*/
+func (p *Params) Verifier() VerifierParams {
+    return VerifierParams{p: p.p.VerifierParamsUL, a: p.a, b: p.b}
+}
+
+/*
+Pedersen returns the parameters of the commitments to the secrets, g^x.H^r in G2.
+*/
+func (p *Params) Pedersen() pedersen.Params {
+    return p.p.Pedersen()
+}
+
+/*
+Pedersen returns the parameters of the commitments to the secrets, g^x.H^r in G2.
+*/
+func (p *VerifierParams) Pedersen() pedersen.Params {
+    return p.p.Pedersen()
+}
+
+/*
+ProveOpening produces the ZK Range Proof for the commitment opened by opening, which
+must have been computed with the Pedersen parameters of p.
+*/
+func ProveOpening(opening pedersen.Opening, p Params, opts ...Option) (Proof, error) {
+    if opening.Group != pedersen.BN256G2 {
+        return Proof{}, pedersen.ErrGroupMismatch
     }
+    return Prove(opening.Value, opening.Blinding, p, opts...)
 }
 
 /*
-Prove method is responsible for generating the zero knowledge proof.
+Prove method is responsible for generating the zero knowledge proof that the secret
+x, committed with the blinding factor r, belongs to [a, b).
 */
-func (zkrp *ccs08) Prove() error {
-    ul := new(big.Int).Exp(new(big.Int).SetInt64(zkrp.p.p.u), new(big.Int).SetInt64(zkrp.p.p.l), nil)
+func Prove(x, r *big.Int, p Params, opts ...Option) (Proof, error) {
+    var proof_out Proof
+    if p.p == nil {
+        return proof_out, errors.New("params are not initialized")
+    }
+    if x.Cmp(big.NewInt(p.a)) < 0 || x.Cmp(big.NewInt(p.b)) >= 0 {
+        return proof_out, errors.New("Could not generate proof. Element does not belong to the interval.")
+    }
+    cfg := newConfig(opts)
+    ul := new(big.Int).Exp(new(big.Int).SetInt64(p.p.u), new(big.Int).SetInt64(p.p.l), nil)
+
+    // Both proofs share a transcript, which is bound to the interval [a, b]
+    v := p.Verifier()
+    t := v.transcript()
 
     // x - b + ul
-    xb := new(big.Int).Sub(zkrp.x, new(big.Int).SetInt64(zkrp.p.b))
+    xb := new(big.Int).Sub(x, new(big.Int).SetInt64(p.b))
     xb.Add(xb, ul)
-    first, _ := ProveUL(xb, zkrp.r, *zkrp.p.p)
+    first, err := proveUL(t, xb, r, *p.p, cfg)
+    if err != nil {
+        return proof_out, err
+    }
 
     // x - a
-    xa := new(big.Int).Sub(zkrp.x, new(big.Int).SetInt64(zkrp.p.a))
-    second, _ := ProveUL(xa, zkrp.r, *zkrp.p.p)
+    xa := new(big.Int).Sub(x, new(big.Int).SetInt64(p.a))
+    second, err := proveUL(t, xa, r, *p.p, cfg)
+    if err != nil {
+        return proof_out, err
+    }
 
-    zkrp.proof_out.p1 = first
-    zkrp.proof_out.p2 = second
-    return nil
+    // The secret may be negative, and is reduced as in the shifted commitments.
+    proof_out.C, _ = Commit(bn.Mod(x, bn256.Order), r, p.p.H)
+    proof_out.P1 = first
+    proof_out.P2 = second
+    return proof_out, nil
+}
+
+/*
+Verify is responsible for validating the proof, for the commitment C carried by the
+proof. It returns true iff the proof is valid.
+*/
+func Verify(proof_out *Proof, p *VerifierParams) (bool, error) {
+    if proof_out.C == nil {
+        return false, errors.New("commitment must not be nil")
+    }
+    ul := new(big.Int).Exp(new(big.Int).SetInt64(p.p.u), new(big.Int).SetInt64(p.p.l), nil)
+
+    // The commitments of both proofs are derived from C, which binds them to the
+    // same secret and to the interval.
+    first, second := proof_out.P1, proof_out.P2
+    first.C = shiftCommitment(proof_out.C, new(big.Int).Sub(ul, big.NewInt(p.b)))
+    second.C = shiftCommitment(proof_out.C, big.NewInt(-p.a))
+
+    t := p.transcript()
+    ok1, err := verifyUL(t, &first, &p.p)
+    if !ok1 {
+        return false, err
+    }
+    ok2, err := verifyUL(t, &second, &p.p)
+    if !ok2 {
+        return false, err
+    }
+    return ok1 && ok2, nil
+}
+
+/*
+VerifyCommitment validates the ZK Range Proof for the commitment C expected by the
+verifier, ignoring the commitment carried by the proof.
+*/
+func VerifyCommitment(proof_out *Proof, p *VerifierParams, C pedersen.Commitment) (bool, error) {
+    if !p.Pedersen().Owns(C) {
+        return false, pedersen.ErrGroupMismatch
+    }
+    point, err := C.G2()
+    if err != nil {
+        return false, err
+    }
+    expected := *proof_out
+    expected.C = point
+    return Verify(&expected, p)
+}
+
+/*
+Commitment returns the commitment C to the secret.
+*/
+func (proof_out *Proof) Commitment() pedersen.Commitment {
+    return newCommitment(proof_out.C)
 }
 
 /*
-Verify is responsible for validating the proof.
+shiftCommitment returns C.g^k, the commitment to x + k with the blinding factor of C.
 */
-func (zkrp *ccs08) Verify() (bool, error) {
-    first, _ := VerifyUL(&zkrp.proof_out.p1, zkrp.p.p)
-    second, _ := VerifyUL(&zkrp.proof_out.p2, zkrp.p.p)
-    return first && second, nil
+func shiftCommitment(C *bn256.G2, k *big.Int) *bn256.G2 {
+    shift := new(bn256.G2).ScalarBaseMult(bn.Mod(k, bn256.Order))
+    return new(bn256.G2).Add(C, shift)
 }
@@ -101,7 +101,7 @@ func TestZKRP_UL(t *testing.T) {
     p, _ := SetupUL(10, 5)
     r, _ = rand.Int(rand.Reader, bn256.Order)
     proof_out, _ := ProveUL(new(big.Int).SetInt64(42176), r, p)
-    result, _ := VerifyUL(&proof_out, &p)
+    result, _ := VerifyUL(&proof_out, &p.VerifierParamsUL)
     if result != true {
         t.Errorf("Assert failure: expected true, actual: %t", result)
     }
@@ -111,11 +111,8 @@ func TestZKRP_UL(t *testing.T) {
 Tests if the SetupInnerProduct algorithm is rejecting wrong input as expected.
 */
 func TestZKRPSetupInput(t *testing.T) {
-    var (
-        zkrp ccs08
-    )
-    e := zkrp.Setup(1900, 1899)
-    result := e.Error() != "a must be less than or equal to b"
+    _, e := Setup(1900, 1899)
+    result := e == nil || e.Error() != "a must be less than or equal to b"
     if result {
         t.Errorf("Assert failure: expected true, actual: %t", result)
     }
@@ -137,29 +134,220 @@ func TestZKSet(t *testing.T) {
     p, _ := SetupSet(s)
     r, _ = rand.Int(rand.Reader, bn256.Order)
     proof_out, _ := ProveSet(12, r, p)
-    result, _ := VerifySet(&proof_out, &p)
+    result, _ := VerifySet(&proof_out, &p.VerifierParamsSet)
     if result != true {
         t.Errorf("Assert failure: expected true, actual: %t", result)
     }
 }
 
+/*
+Tests the proofs for commitments computed with the Pedersen parameters.
+*/
+func TestZKPedersenCommitments(t *testing.T) {
+    s := []int64{7, 12, 42}
+    ps, _ := SetupSet(s)
+    C, opening, _ := ps.Pedersen().CommitRandom(big.NewInt(7), rand.Reader)
+    proof_set, err := ProveSetOpening(opening, ps)
+    if err != nil {
+        t.Fatal(err)
+    }
+    result, _ := VerifySetCommitment(&proof_set, &ps.VerifierParamsSet, C)
+    if result != true || !proof_set.Commitment().Equal(C) {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+    other, _ := ps.Pedersen().Commit(big.NewInt(12), opening.Blinding)
+    result, _ = VerifySetCommitment(&proof_set, &ps.VerifierParamsSet, other)
+    if result != false {
+        t.Errorf("Assert failure: expected false, actual: %t", result)
+    }
+
+    pu, _ := SetupUL(10, 5)
+    C1, o1 := pu.Pedersen().Commit(big.NewInt(40000), big.NewInt(11))
+    C2, o2 := pu.Pedersen().Commit(big.NewInt(2176), big.NewInt(22))
+    sum, _ := C1.Add(C2)
+    osum, _ := o1.Add(o2)
+    proof_ul, err := ProveULOpening(osum, pu)
+    if err != nil {
+        t.Fatal(err)
+    }
+    result, _ = VerifyULCommitment(&proof_ul, &pu.VerifierParamsUL, sum)
+    if result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+    result, _ = VerifyULCommitment(&proof_ul, &pu.VerifierParamsUL, C1)
+    if result != false {
+        t.Errorf("Assert failure: expected false, actual: %t", result)
+    }
+}
+
 /*
 Tests the entire ZK Range Proof (CCS08) protocol.
 */
 func TestZKRP(t *testing.T) {
-    var (
-        result bool
-        zkrp   ccs08
-    )
-    zkrp.Setup(347184000, 599644800)
-    zkrp.x = new(big.Int).SetInt64(419835123)
-    zkrp.r, _ = rand.Int(rand.Reader, bn256.Order)
-    e := zkrp.Prove()
+    p, _ := Setup(347184000, 599644800)
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    proof_out, e := Prove(new(big.Int).SetInt64(419835123), r, p)
     if e != nil {
         t.Errorf("Error while proving ZKRP: %s", e.Error())
     }
-    result, _ = zkrp.Verify()
+    v := p.Verifier()
+    result, _ := Verify(&proof_out, &v)
+    if result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+}
+
+/*
+Tests that the verifier recomputes the challenge instead of using the one in the proof.
+*/
+func TestZKRP_ULTamperedChallenge(t *testing.T) {
+    p, _ := SetupUL(10, 5)
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    proof_out, _ := ProveUL(new(big.Int).SetInt64(42176), r, p)
+    proof_out.Challenge = bn.Mod(bn.Add(proof_out.Challenge, new(big.Int).SetInt64(1)), bn256.Order)
+    result, _ := VerifyUL(&proof_out, &p.VerifierParamsUL)
+    if result != false {
+        t.Errorf("Assert failure: expected false, actual: %t", result)
+    }
+}
+
+/*
+Tests that both proofs of the ZK Range Proof must come from the same execution.
+*/
+func TestZKRPMixedProofs(t *testing.T) {
+    p, _ := Setup(347184000, 599644800)
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    proof1, _ := Prove(new(big.Int).SetInt64(419835123), r, p)
+    proof2, _ := Prove(new(big.Int).SetInt64(519835123), r, p)
+    proof1.P2 = proof2.P2
+    v := p.Verifier()
+    result, _ := Verify(&proof1, &v)
+    if result != false {
+        t.Errorf("Assert failure: expected false, actual: %t", result)
+    }
+}
+
+/*
+Tests that the range proof is bound to the commitment expected by the verifier.
+*/
+func TestZKRPCommitment(t *testing.T) {
+    p, _ := Setup(18, 200)
+    C, opening, _ := p.Pedersen().CommitRandom(big.NewInt(40), rand.Reader)
+    proof_out, err := ProveOpening(opening, p)
+    if err != nil {
+        t.Fatal(err)
+    }
+    v := p.Verifier()
+    result, _ := VerifyCommitment(&proof_out, &v, C)
+    if result != true || !proof_out.Commitment().Equal(C) {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+    other, _ := p.Pedersen().Commit(big.NewInt(41), opening.Blinding)
+    result, _ = VerifyCommitment(&proof_out, &v, other)
+    if result != false {
+        t.Errorf("Assert failure: expected false, actual: %t", result)
+    }
+}
+
+/*
+Tests that the prover rejects secrets outside of the interval [a, b).
+*/
+func TestZKRPOutOfRange(t *testing.T) {
+    p, _ := Setup(18, 200)
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    for _, x := range []int64{17, 200, -1} {
+        _, err := Prove(new(big.Int).SetInt64(x), r, p)
+        if err == nil {
+            t.Errorf("Assert failure: proof of %d should fail", x)
+        }
+    }
+}
+
+/*
+Tests that proofs with missing elements are rejected without a panic.
+*/
+func TestVerifyIncompleteProofs(t *testing.T) {
+    ps, _ := SetupSet([]int64{12, 42})
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    proof_set, _ := ProveSet(42, r, ps)
+    proof_set.Zv = nil
+    result, err := VerifySet(&proof_set, &ps.VerifierParamsSet)
+    if result != false || err == nil {
+        t.Errorf("Assert failure: expected an error, actual: %t", result)
+    }
+
+    pu, _ := SetupUL(10, 5)
+    proof_ul, _ := ProveUL(new(big.Int).SetInt64(42176), r, pu)
+    proof_ul.A[2] = nil
+    result, err = VerifyUL(&proof_ul, &pu.VerifierParamsUL)
+    if result != false || err == nil {
+        t.Errorf("Assert failure: expected an error, actual: %t", result)
+    }
+}
+
+/*
+Tests that the verifiers only need the public key of the issuer, and reject the
+proofs computed with the signatures of another key.
+*/
+func TestVerifierParams(t *testing.T) {
+    ps, _ := SetupSet([]int64{12, 42})
+    other, _ := SetupSet([]int64{12, 42})
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    proof_set, _ := ProveSet(42, r, ps)
+
+    v := NewVerifierParamsSet(ps.PublicKey())
+    result, _ := VerifySet(&proof_set, &v)
+    if result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+    v = NewVerifierParamsSet(other.PublicKey())
+    result, _ = VerifySet(&proof_set, &v)
+    if result != false {
+        t.Errorf("Assert failure: expected false, actual: %t", result)
+    }
+
+    pu, _ := SetupUL(10, 5)
+    proof_ul, _ := ProveUL(new(big.Int).SetInt64(42176), r, pu)
+    vu := NewVerifierParamsUL(pu.PublicKey(), 10, 5)
+    result, _ = VerifyUL(&proof_ul, &vu)
     if result != true {
         t.Errorf("Assert failure: expected true, actual: %t", result)
     }
+
+    p, _ := Setup(18, 200)
+    proof_out, _ := Prove(new(big.Int).SetInt64(40), r, p)
+    vr, _ := NewVerifierParams(p.p.PublicKey(), 18, 200)
+    result, _ = Verify(&proof_out, &vr)
+    if result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+    var empty VerifierParams
+    result, err := Verify(&proof_out, &empty)
+    if result != false || err == nil {
+        t.Errorf("Assert failure: expected an error, actual: %t", result)
+    }
+}
+
+/*
+Tests that the signatures of a setup are not witnesses for the sets of another setup:
+a proof that 5 belongs to {12, 42}, computed with the signature of the digit 5 of the
+parameters for [0,10^5), is rejected.
+*/
+func TestSetupKeys(t *testing.T) {
+    ps, _ := SetupSet([]int64{12, 42})
+    pu, _ := SetupUL(10, 5)
+    forged := ParamsSet{
+        signatures:        map[int64]*bn256.G2{5: pu.signatures["5"]},
+        VerifierParamsSet: ps.VerifierParamsSet,
+    }
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    proof_out, _ := ProveSet(5, r, forged)
+    v := ps.Verifier()
+    result, _ := VerifySet(&proof_out, &v)
+    if result != false {
+        t.Errorf("Assert failure: expected false, actual: %t", result)
+    }
+    if ps.PublicKey().String() == pu.PublicKey().String() {
+        t.Errorf("Assert failure: the setups should use distinct keys")
+    }
 }
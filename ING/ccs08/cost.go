@@ -0,0 +1,205 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the choice of the base u and of the number of digits l of the
+range proofs. The secrets are decomposed in l digits in base u, so that the issuer
+signs u digits, while the prover computes 2l pairings and the verifier 4l pairings.
+Setup chooses the values of u and l minimizing a weighted sum of these costs, with
+the weights given by the Preference of the options.
+*/
+
+package ccs08
+
+import (
+    "errors"
+    "math"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+)
+
+// MAX_BASE is the largest base u chosen by Setup, which bounds the size of the parameters.
+const MAX_BASE = 1 << 16
+
+/*
+Preference selects the weights of the cost model of Setup.
+*/
+type Preference int
+
+const (
+    // Balanced weights a pairing as much as four signatures of the parameters.
+    Balanced Preference = iota
+    // SmallParams favours a small base u, at the cost of more pairings.
+    SmallParams
+    // FastVerify favours few digits l, at the cost of larger parameters.
+    FastVerify
+)
+
+/*
+weights contains the costs of a signature of the parameters, of a pairing of the
+prover and of a pairing of the verifier.
+*/
+type weights struct {
+    signature, prover, verifier int64
+}
+
+var preferenceWeights = map[Preference]weights{
+    Balanced:    {1, 4, 4},
+    SmallParams: {4, 1, 1},
+    FastVerify:  {1, 4, 64},
+}
+
+/*
+Cost contains the expected costs of the range proofs for the base U and L digits.
+The sizes are the lengths of the binary encodings.
+*/
+type Cost struct {
+    U                int64
+    L                int64
+    ParamsSize       int
+    ProofSize        int
+    ProverPairings   int64
+    VerifierPairings int64
+}
+
+/*
+NewCost returns the expected costs of the range proofs for the base u and l digits.
+*/
+func NewCost(u, l int64) Cost {
+    proofULSize := 2*G2_SIZE + 2*SCALAR_SIZE + 1 + int(l)*(G2_SIZE+GT_SIZE+2*SCALAR_SIZE)
+    return Cost{
+        U:                u,
+        L:                l,
+        ParamsSize:       2 + G1_SIZE + 4*8 + int(u)*G2_SIZE,
+        ProofSize:        2 + G2_SIZE + 2*proofULSize,
+        ProverPairings:   2 * l,
+        VerifierPairings: 4 * l,
+    }
+}
+
+func (c Cost) score(w weights) int64 {
+    return w.signature*c.U + w.prover*c.ProverPairings + w.verifier*c.VerifierPairings
+}
+
+/*
+EstimateCost returns the expected costs of the range proofs for the interval [a, b),
+with the base and the number of digits that Setup chooses for the options.
+*/
+func EstimateCost(a, b int64, opts ...Option) (Cost, error) {
+    u, l, err := digits(a, b, newConfig(opts))
+    if err != nil {
+        return Cost{}, err
+    }
+    return NewCost(u, l), nil
+}
+
+/*
+Cost returns the expected costs of the range proofs for the parameters.
+*/
+func (p *Params) Cost() Cost {
+    return NewCost(p.p.u, p.p.l)
+}
+
+/*
+Cost returns the expected costs of the range proofs for the parameters.
+*/
+func (p *VerifierParams) Cost() Cost {
+    return NewCost(p.p.u, p.p.l)
+}
+
+/*
+digits computes the values of u and l for the interval [a, b). The values given by
+WithDigits are checked, otherwise the values minimizing the cost model are chosen
+among those such that u^l >= b - a.
+*/
+func digits(a, b int64, cfg config) (int64, int64, error) {
+    if a > b {
+        return 0, 0, errors.New("a must be less than or equal to b")
+    }
+    if cfg.u != 0 || cfg.l != 0 {
+        return cfg.u, cfg.l, checkDigits(a, b, cfg.u, cfg.l)
+    }
+    w, ok := preferenceWeights[cfg.preference]
+    if !ok {
+        return 0, 0, errors.New("unknown preference")
+    }
+    n := intervalSize(a, b)
+    var best Cost
+    for l := int64(1); l <= MAX_DIGITS; l++ {
+        u := minBase(n, l)
+        if u > MAX_BASE {
+            continue
+        }
+        c := NewCost(u, l)
+        if best.L == 0 || c.score(w) < best.score(w) {
+            best = c
+        }
+    }
+    return best.U, best.L, nil
+}
+
+/*
+checkDigits returns an error if the range proofs for the base u and l digits can not
+prove that a secret belongs to [a, b). The range proofs are sound when 2u^l is less
+than the order of the group, and complete when u^l >= b - a.
+*/
+func checkDigits(a, b, u, l int64) error {
+    if !validDigits(u, l) {
+        return ErrInvalidParams
+    }
+    ul := power(u, l)
+    if ul.Cmp(intervalSize(a, b)) < 0 || new(big.Int).Lsh(ul, 1).Cmp(bn256.Order) >= 0 {
+        return ErrInvalidParams
+    }
+    return nil
+}
+
+/*
+intervalSize returns b - a, which may not fit in an int64.
+*/
+func intervalSize(a, b int64) *big.Int {
+    return new(big.Int).Sub(big.NewInt(b), big.NewInt(a))
+}
+
+/*
+minBase returns the smallest base u >= 2 such that u^l >= n, or MAX_BASE + 1 if it
+is larger than MAX_BASE.
+*/
+func minBase(n *big.Int, l int64) int64 {
+    f, _ := new(big.Float).SetInt(n).Float64()
+    estimate := math.Ceil(math.Pow(f, 1/float64(l)))
+    if estimate > MAX_BASE+1 {
+        return MAX_BASE + 1
+    }
+    // Correct the rounding errors of the estimate.
+    u := int64(estimate)
+    if u < 2 {
+        u = 2
+    }
+    for u > 2 && power(u-1, l).Cmp(n) >= 0 {
+        u--
+    }
+    for power(u, l).Cmp(n) < 0 {
+        u++
+    }
+    return u
+}
+
+func power(u, l int64) *big.Int {
+    return new(big.Int).Exp(big.NewInt(u), big.NewInt(l), nil)
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ccs08
+
+import (
+    "crypto/rand"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+)
+
+/*
+Tests the values of u and l chosen for the preferences.
+*/
+func TestDigitsPreference(t *testing.T) {
+    expected := map[Preference][2]int64{
+        Balanced:    {41, 6},
+        SmallParams: {8, 11},
+        FastVerify:  {256, 4},
+    }
+    for preference, digits := range expected {
+        c, err := EstimateCost(0, 1<<32, WithPreference(preference))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if c.U != digits[0] || c.L != digits[1] {
+            t.Errorf("Assert failure: expected (%d, %d), actual: (%d, %d)", digits[0], digits[1], c.U, c.L)
+        }
+    }
+    if _, err := EstimateCost(0, 1<<32, WithPreference(Preference(42))); err == nil {
+        t.Errorf("Assert failure: expected error for an unknown preference")
+    }
+}
+
+/*
+Tests that the base chosen for each interval is the smallest one for its number of
+digits.
+*/
+func TestDigitsMinimal(t *testing.T) {
+    intervals := [][2]int64{{0, 0}, {0, 1}, {18, 200}, {-1000, -10}, {0, 1 << 40}, {-1 << 63, 1<<63 - 1}}
+    for _, interval := range intervals {
+        for _, preference := range []Preference{Balanced, SmallParams, FastVerify} {
+            c, err := EstimateCost(interval[0], interval[1], WithPreference(preference))
+            if err != nil {
+                t.Fatal(err)
+            }
+            n := intervalSize(interval[0], interval[1])
+            if power(c.U, c.L).Cmp(n) < 0 {
+                t.Errorf("Assert failure: %d^%d is less than %v", c.U, c.L, n)
+            }
+            if c.U > 2 && power(c.U-1, c.L).Cmp(n) >= 0 {
+                t.Errorf("Assert failure: %d^%d is not less than %v", c.U-1, c.L, n)
+            }
+            if checkDigits(interval[0], interval[1], c.U, c.L) != nil {
+                t.Errorf("Assert failure: (%d, %d) should be valid", c.U, c.L)
+            }
+        }
+    }
+    if _, err := EstimateCost(10, 5); err == nil {
+        t.Errorf("Assert failure: expected error for an empty interval")
+    }
+}
+
+/*
+Tests the digits given by WithDigits.
+*/
+func TestWithDigits(t *testing.T) {
+    p, err := Setup(18, 200, WithDigits(10, 3))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if c := p.Cost(); c.U != 10 || c.L != 3 {
+        t.Errorf("Assert failure: expected (10, 3), actual: (%d, %d)", c.U, c.L)
+    }
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    proof_out, _ := Prove(new(big.Int).SetInt64(199), r, p)
+    verifier, _ := NewVerifierParams(p.p.PublicKey(), 18, 200, WithDigits(10, 3))
+    if result, _ := Verify(&proof_out, &verifier); result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+    // The verifier must select the same digits as the issuer.
+    verifier, _ = NewVerifierParams(p.p.PublicKey(), 18, 200)
+    if result, _ := Verify(&proof_out, &verifier); result != false {
+        t.Errorf("Assert failure: expected false, actual: %t", result)
+    }
+
+    // 10^2 is less than 200 - 18, and 2^20^13 is larger than the order.
+    invalid := [][2]int64{{10, 2}, {1, 10}, {10, 0}, {1 << 20, 13}}
+    for _, digits := range invalid {
+        if _, err = Setup(18, 200, WithDigits(digits[0], digits[1])); err == nil {
+            t.Errorf("Assert failure: expected error for (%d, %d)", digits[0], digits[1])
+        }
+    }
+}
+
+/*
+Tests that the expected sizes are the sizes of the binary encodings.
+*/
+func TestCostSize(t *testing.T) {
+    p, _ := Setup(-1000, -10, WithPreference(SmallParams))
+    c := p.Cost()
+    verifier := p.Verifier()
+    if verifier.Cost() != c {
+        t.Errorf("Assert failure: expected %v, actual: %v", c, verifier.Cost())
+    }
+    expected, _ := EstimateCost(-1000, -10, WithPreference(SmallParams))
+    if expected != c {
+        t.Errorf("Assert failure: expected %v, actual: %v", expected, c)
+    }
+
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    proof_out, err := Prove(new(big.Int).SetInt64(-500), r, p)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if result, _ := Verify(&proof_out, &verifier); result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+    encoded, _ := p.MarshalBinary()
+    if len(encoded) != c.ParamsSize {
+        t.Errorf("Assert failure: expected %d, actual: %d", c.ParamsSize, len(encoded))
+    }
+    encoded, _ = proof_out.MarshalBinary()
+    if len(encoded) != c.ProofSize {
+        t.Errorf("Assert failure: expected %d, actual: %d", c.ProofSize, len(encoded))
+    }
+}
+
+/*
+Tests that the decoders reject parameters whose digits do not cover the interval.
+*/
+func TestDecodeInvalidDigits(t *testing.T) {
+    ps, _ := SetupSet([]int64{12})
+    v := VerifierParams{p: NewVerifierParamsUL(ps.PublicKey(), 2, 2), a: 0, b: 100}
+    encoded, _ := v.MarshalBinary()
+    var decoded VerifierParams
+    if err := decoded.UnmarshalBinary(encoded); err != ErrInvalidParams {
+        t.Errorf("Assert failure: expected %v, actual: %v", ErrInvalidParams, err)
+    }
+    encoded, _ = v.MarshalJSON()
+    if err := decoded.UnmarshalJSON(encoded); err != ErrInvalidParams {
+        t.Errorf("Assert failure: expected %v, actual: %v", ErrInvalidParams, err)
+    }
+}
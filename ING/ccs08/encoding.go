@@ -0,0 +1,1422 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+
+/*
+This file contains the encodings of the parameters and of the proofs. The binary
+encoding starts with the version and the type of the encoded value, followed by its
+elements: points of G1 and G2 use the encoding of crypto/group, elements of GT the
+384 bytes of Marshal, scalars 32 bytes and integers 8 bytes in big-endian order. The
+JSON encoding contains the same elements, as hexadecimal strings. Decoding checks
+that the points belong to their group and are canonically encoded, and that the
+scalars are lower than the order of bn256, and that the signatures of the parameters
+of the provers are valid for the public key. The generator H is not encoded, since it
+is fixed.
+*/
+
+package ccs08
+
+import (
+    "bytes"
+    "encoding/binary"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math/big"
+    "sort"
+    "strconv"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/util"
+    "github.com/ing-bank/zkrp/util/bn"
+)
+
+// ENCODING_VERSION is the version of the binary and JSON encodings.
+const ENCODING_VERSION = 1
+
+// Sizes in bytes of the encoded elements.
+const (
+    SCALAR_SIZE = 32
+    G1_SIZE     = 64
+    G2_SIZE     = 128
+    GT_SIZE     = 384
+)
+
+// MAX_DIGITS is the largest number of digits l accepted by the decoders.
+const MAX_DIGITS = 64
+
+// Type tags used in the header of the binary encoding.
+const (
+    tagVerifierParamsSet = 1
+    tagVerifierParamsUL  = 2
+    tagVerifierParams    = 3
+    tagParamsSet         = 4
+    tagParamsUL          = 5
+    tagParams            = 6
+    tagProofSet          = 7
+    tagProofUL           = 8
+    tagProof             = 9
+    tagParamsStringSet   = 10
+)
+
+var (
+    ErrUnsupportedVersion = errors.New("unsupported encoding version")
+    ErrInvalidType        = errors.New("encoded data does not contain the expected type")
+    ErrInvalidPoint       = errors.New("invalid encoding of a group element")
+    ErrInvalidScalar      = errors.New("scalar is not lower than the order of the curve")
+    ErrInvalidParams      = errors.New("invalid parameters")
+    ErrTrailingData       = errors.New("unexpected data after the encoded value")
+    ErrTruncatedData      = errors.New("encoded value is truncated")
+    ErrInvalidSignature   = errors.New("signature is not valid for the public key")
+)
+
+/*
+MarshalBinary returns the binary encoding of the parameters.
+*/
+func (p VerifierParamsSet) MarshalBinary() ([]byte, error) {
+    buffer := newBuffer(tagVerifierParamsSet)
+    p.writeTo(buffer)
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the parameters.
+*/
+func (p *VerifierParamsSet) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagVerifierParamsSet)
+    decoded := d.verifierParamsSet()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *p = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the parameters.
+*/
+func (p VerifierParamsUL) MarshalBinary() ([]byte, error) {
+    buffer := newBuffer(tagVerifierParamsUL)
+    p.writeTo(buffer)
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the parameters.
+*/
+func (p *VerifierParamsUL) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagVerifierParamsUL)
+    decoded := d.verifierParamsUL()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *p = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the parameters.
+*/
+func (p VerifierParams) MarshalBinary() ([]byte, error) {
+    buffer := newBuffer(tagVerifierParams)
+    p.p.writeTo(buffer)
+    writeInts(buffer, p.a, p.b)
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the parameters.
+*/
+func (p *VerifierParams) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagVerifierParams)
+    decoded := VerifierParams{p: d.verifierParamsUL()}
+    decoded.a, decoded.b = d.interval(&decoded.p)
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *p = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the parameters, with the signatures
+sorted by element.
+*/
+func (p ParamsSet) MarshalBinary() ([]byte, error) {
+    buffer := newBuffer(tagParamsSet)
+    p.VerifierParamsSet.writeTo(buffer)
+    elements := p.elements()
+    writeInts(buffer, int64(len(elements)))
+    for _, e := range elements {
+        writeInts(buffer, e)
+        writeG2(buffer, p.signatures[e])
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the parameters.
+*/
+func (p *ParamsSet) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagParamsSet)
+    decoded := ParamsSet{VerifierParamsSet: d.verifierParamsSet()}
+    decoded.signatures = make(map[int64]*bn256.G2)
+    // The elements must be strictly increasing, so that the encoding is canonical.
+    n := d.int64()
+    if n < 0 {
+        d.fail(ErrInvalidParams)
+    }
+    var previous int64
+    for i := int64(0); i < n && d.err == nil; i++ {
+        e := d.int64()
+        if i > 0 && e <= previous {
+            d.fail(ErrInvalidParams)
+        }
+        decoded.signatures[e] = d.g2()
+        previous = e
+    }
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    if err = decoded.checkSignatures(); err != nil {
+        return err
+    }
+    *p = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the parameters, with the signatures
+sorted by element. The scalars of the elements are not encoded.
+*/
+func (p ParamsStringSet) MarshalBinary() ([]byte, error) {
+    buffer := newBuffer(tagParamsStringSet)
+    p.VerifierParamsSet.writeTo(buffer)
+    elements := p.Elements()
+    writeInts(buffer, int64(len(elements)))
+    for _, e := range elements {
+        writeInts(buffer, int64(len(e)))
+        buffer.Write(e)
+        writeG2(buffer, p.signatures[p.elements[string(e)].String()])
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the parameters.
+*/
+func (p *ParamsStringSet) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagParamsStringSet)
+    decoded := ParamsStringSet{VerifierParamsSet: d.verifierParamsSet()}
+    decoded.elements = make(map[string]*big.Int)
+    decoded.signatures = make(map[string]*bn256.G2)
+    // The elements must be strictly increasing, so that the encoding is canonical.
+    n := d.int64()
+    if n < 0 {
+        d.fail(ErrInvalidParams)
+    }
+    var previous []byte
+    for i := int64(0); i < n && d.err == nil; i++ {
+        size := d.int64()
+        if size < 0 || size > int64(len(d.data)) {
+            d.fail(ErrTruncatedData)
+            break
+        }
+        e := d.next(int(size))
+        if i > 0 && bytes.Compare(e, previous) <= 0 {
+            d.fail(ErrInvalidParams)
+        }
+        decoded.addSignature(e, d.g2())
+        previous = e
+    }
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    if err = decoded.checkSignatures(); err != nil {
+        return err
+    }
+    *p = decoded
+    return nil
+}
+
+/*
+addSignature adds the element e, with the signature of its scalar, to the parameters.
+*/
+func (p *ParamsStringSet) addSignature(e []byte, signature *bn256.G2) {
+    x := HashElement(e)
+    p.elements[string(e)] = x
+    p.signatures[x.String()] = signature
+}
+
+/*
+MarshalBinary returns the binary encoding of the parameters, with the signatures
+sorted by digit.
+*/
+func (p ParamsUL) MarshalBinary() ([]byte, error) {
+    buffer := newBuffer(tagParamsUL)
+    p.writeTo(buffer)
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the parameters.
+*/
+func (p *ParamsUL) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagParamsUL)
+    decoded := d.paramsUL()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    if err = decoded.checkSignatures(); err != nil {
+        return err
+    }
+    *p = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the parameters.
+*/
+func (p Params) MarshalBinary() ([]byte, error) {
+    if p.p == nil {
+        return nil, ErrInvalidParams
+    }
+    buffer := newBuffer(tagParams)
+    p.p.writeTo(buffer)
+    writeInts(buffer, p.a, p.b)
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the parameters.
+*/
+func (p *Params) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagParams)
+    ul := d.paramsUL()
+    decoded := Params{p: &ul}
+    decoded.a, decoded.b = d.interval(&decoded.p.VerifierParamsUL)
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    if err = decoded.p.checkSignatures(); err != nil {
+        return err
+    }
+    *p = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the proof.
+*/
+func (proof_out ProofSet) MarshalBinary() ([]byte, error) {
+    if !proof_out.complete() {
+        return nil, errors.New("proof is missing an element")
+    }
+    buffer := newBuffer(tagProofSet)
+    writeG2(buffer, proof_out.C, proof_out.V)
+    writeGT(buffer, proof_out.A)
+    writeG2(buffer, proof_out.D)
+    writeScalars(buffer, proof_out.Challenge, proof_out.Zr, proof_out.Zsig, proof_out.Zv)
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the proof.
+*/
+func (proof_out *ProofSet) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagProofSet)
+    decoded := d.proofSet()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *proof_out = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the proof.
+*/
+func (proof_out ProofUL) MarshalBinary() ([]byte, error) {
+    buffer := newBuffer(tagProofUL)
+    err := proof_out.writeTo(buffer)
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the proof.
+*/
+func (proof_out *ProofUL) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagProofUL)
+    decoded := d.proofUL()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *proof_out = decoded
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the proof.
+*/
+func (proof_out Proof) MarshalBinary() ([]byte, error) {
+    if proof_out.C == nil {
+        return nil, errors.New("commitment must not be nil")
+    }
+    buffer := newBuffer(tagProof)
+    writeG2(buffer, proof_out.C)
+    err := proof_out.P1.writeTo(buffer)
+    if err != nil {
+        return nil, err
+    }
+    err = proof_out.P2.writeTo(buffer)
+    if err != nil {
+        return nil, err
+    }
+    return buffer.Bytes(), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of the proof.
+*/
+func (proof_out *Proof) UnmarshalBinary(data []byte) error {
+    d := newDecoder(data, tagProof)
+    var decoded Proof
+    decoded.C = d.g2()
+    decoded.P1 = d.proofUL()
+    decoded.P2 = d.proofUL()
+    err := d.finish()
+    if err != nil {
+        return err
+    }
+    *proof_out = decoded
+    return nil
+}
+
+/*
+elements returns the elements of the set, in increasing order.
+*/
+func (p *ParamsSet) elements() []int64 {
+    elements := make([]int64, 0, len(p.signatures))
+    for e := range p.signatures {
+        elements = append(elements, e)
+    }
+    sort.Slice(elements, func(i, j int) bool { return elements[i] < elements[j] })
+    return elements
+}
+
+func (p VerifierParamsSet) writeTo(buffer *bytes.Buffer) {
+    writeG1(buffer, p.y)
+}
+
+func (p VerifierParamsUL) writeTo(buffer *bytes.Buffer) {
+    writeG1(buffer, p.y)
+    writeInts(buffer, p.u, p.l)
+}
+
+func (p ParamsUL) writeTo(buffer *bytes.Buffer) {
+    p.VerifierParamsUL.writeTo(buffer)
+    for i := int64(0); i < p.u; i++ {
+        writeG2(buffer, p.signatures[strconv.FormatInt(i, 10)])
+    }
+}
+
+func (proof_out ProofUL) writeTo(buffer *bytes.Buffer) error {
+    l := len(proof_out.V)
+    if l > MAX_DIGITS || len(proof_out.A) != l || len(proof_out.Zsig) != l || len(proof_out.Zv) != l {
+        return errors.New("number of digits does not match the proof")
+    }
+    if !proof_out.complete() {
+        return errors.New("proof is missing an element")
+    }
+    writeG2(buffer, proof_out.C, proof_out.D)
+    writeScalars(buffer, proof_out.Challenge, proof_out.Zr)
+    buffer.WriteByte(byte(l))
+    for i := 0; i < l; i++ {
+        writeG2(buffer, proof_out.V[i])
+        writeGT(buffer, proof_out.A[i])
+        writeScalars(buffer, proof_out.Zsig[i], proof_out.Zv[i])
+    }
+    return nil
+}
+
+func newBuffer(tag byte) *bytes.Buffer {
+    buffer := new(bytes.Buffer)
+    buffer.Write([]byte{ENCODING_VERSION, tag})
+    return buffer
+}
+
+func writeG1(buffer *bytes.Buffer, points ...*bn256.G1) {
+    for _, point := range points {
+        buffer.Write(g1Bytes(point))
+    }
+}
+
+func writeG2(buffer *bytes.Buffer, points ...*bn256.G2) {
+    for _, point := range points {
+        buffer.Write(g2Bytes(point))
+    }
+}
+
+func writeGT(buffer *bytes.Buffer, elements ...*bn256.GT) {
+    for _, e := range elements {
+        buffer.Write(e.Marshal())
+    }
+}
+
+func writeScalars(buffer *bytes.Buffer, scalars ...*big.Int) {
+    for _, scalar := range scalars {
+        buffer.Write(scalarBytes(scalar))
+    }
+}
+
+func writeInts(buffer *bytes.Buffer, values ...int64) {
+    for _, v := range values {
+        b := make([]byte, 8)
+        binary.BigEndian.PutUint64(b, uint64(v))
+        buffer.Write(b)
+    }
+}
+
+/*
+g1Bytes returns the encoding of crypto/group, where the point at infinity, and a nil
+point, are encoded as zeros.
+*/
+func g1Bytes(point *bn256.G1) []byte {
+    if point == nil {
+        return make([]byte, G1_SIZE)
+    }
+    return group.NewG1(point).Bytes()
+}
+
+/*
+g2Bytes returns the encoding of crypto/group, where the point at infinity, and a nil
+point, are encoded as zeros.
+*/
+func g2Bytes(point *bn256.G2) []byte {
+    if point == nil {
+        return make([]byte, G2_SIZE)
+    }
+    return group.NewG2(point).Bytes()
+}
+
+func scalarBytes(scalar *big.Int) []byte {
+    result := make([]byte, SCALAR_SIZE)
+    if scalar == nil {
+        return result
+    }
+    b := new(big.Int).Mod(scalar, bn256.Order).Bytes()
+    copy(result[SCALAR_SIZE-len(b):], b)
+    return result
+}
+
+type decoder struct {
+    data []byte
+    err  error
+}
+
+/*
+newDecoder checks the header of the binary encoding.
+*/
+func newDecoder(data []byte, tag byte) *decoder {
+    d := &decoder{data: data}
+    version := d.readByte()
+    if d.err == nil && version != ENCODING_VERSION {
+        d.err = ErrUnsupportedVersion
+    }
+    if t := d.readByte(); d.err == nil && t != tag {
+        d.err = ErrInvalidType
+    }
+    return d
+}
+
+func (d *decoder) fail(err error) {
+    if d.err == nil {
+        d.err = err
+    }
+}
+
+func (d *decoder) next(n int) []byte {
+    if d.err != nil {
+        return nil
+    }
+    if len(d.data) < n {
+        d.err = ErrTruncatedData
+        return nil
+    }
+    result := d.data[:n]
+    d.data = d.data[n:]
+    return result
+}
+
+func (d *decoder) readByte() byte {
+    b := d.next(1)
+    if b == nil {
+        return 0
+    }
+    return b[0]
+}
+
+func (d *decoder) int64() int64 {
+    b := d.next(8)
+    if b == nil {
+        return 0
+    }
+    return int64(binary.BigEndian.Uint64(b))
+}
+
+/*
+g1 decodes a point of G1, and checks that its encoding is canonical.
+*/
+func (d *decoder) g1() *bn256.G1 {
+    b := d.next(G1_SIZE)
+    if b == nil {
+        return nil
+    }
+    e, err := group.BN256G1.Decode(b)
+    if err != nil || !bytes.Equal(e.Bytes(), b) {
+        d.err = ErrInvalidPoint
+        return nil
+    }
+    point, _ := group.ToG1(e)
+    return point
+}
+
+/*
+g2 decodes a point of G2, and checks that its encoding is canonical and that it
+belongs to the subgroup of order bn256.Order.
+*/
+func (d *decoder) g2() *bn256.G2 {
+    b := d.next(G2_SIZE)
+    if b == nil {
+        return nil
+    }
+    e, err := group.BN256G2.Decode(b)
+    if err != nil || !bytes.Equal(e.Bytes(), b) {
+        d.err = ErrInvalidPoint
+        return nil
+    }
+    point, _ := group.ToG2(e)
+    if !new(bn256.G2).ScalarMult(point, bn256.Order).IsZero() {
+        d.err = ErrInvalidPoint
+        return nil
+    }
+    return point
+}
+
+/*
+gt decodes an element of GT, and checks that its encoding is canonical.
+*/
+func (d *decoder) gt() *bn256.GT {
+    b := d.next(GT_SIZE)
+    if b == nil {
+        return nil
+    }
+    e, ok := new(bn256.GT).Unmarshal(b)
+    if !ok || !bytes.Equal(e.Marshal(), b) {
+        d.err = ErrInvalidPoint
+        return nil
+    }
+    return e
+}
+
+func (d *decoder) scalar() *big.Int {
+    b := d.next(SCALAR_SIZE)
+    if b == nil {
+        return nil
+    }
+    scalar := new(big.Int).SetBytes(b)
+    if scalar.Cmp(bn256.Order) >= 0 {
+        d.err = ErrInvalidScalar
+        return nil
+    }
+    return scalar
+}
+
+func (d *decoder) verifierParamsSet() VerifierParamsSet {
+    return NewVerifierParamsSet(d.g1())
+}
+
+func (d *decoder) verifierParamsUL() VerifierParamsUL {
+    y := d.g1()
+    u := d.int64()
+    l := d.int64()
+    if d.err == nil && !validDigits(u, l) {
+        d.err = ErrInvalidParams
+    }
+    return NewVerifierParamsUL(y, u, l)
+}
+
+func (d *decoder) paramsUL() ParamsUL {
+    p := ParamsUL{VerifierParamsUL: d.verifierParamsUL()}
+    p.signatures = make(map[string]*bn256.G2)
+    for i := int64(0); i < p.u && d.err == nil; i++ {
+        p.signatures[strconv.FormatInt(i, 10)] = d.g2()
+    }
+    return p
+}
+
+func (d *decoder) interval(p *VerifierParamsUL) (int64, int64) {
+    a := d.int64()
+    b := d.int64()
+    if d.err == nil && (a > b || checkDigits(a, b, p.u, p.l) != nil) {
+        d.err = ErrInvalidParams
+    }
+    return a, b
+}
+
+func (d *decoder) proofSet() ProofSet {
+    var proof_out ProofSet
+    proof_out.C = d.g2()
+    proof_out.V = d.g2()
+    proof_out.A = d.gt()
+    proof_out.D = d.g2()
+    proof_out.Challenge = d.scalar()
+    proof_out.Zr = d.scalar()
+    proof_out.Zsig = d.scalar()
+    proof_out.Zv = d.scalar()
+    return proof_out
+}
+
+func (d *decoder) proofUL() ProofUL {
+    var proof_out ProofUL
+    proof_out.C = d.g2()
+    proof_out.D = d.g2()
+    proof_out.Challenge = d.scalar()
+    proof_out.Zr = d.scalar()
+    l := int(d.readByte())
+    if l > MAX_DIGITS {
+        d.fail(ErrInvalidParams)
+        return proof_out
+    }
+    proof_out.V = make([]*bn256.G2, l)
+    proof_out.A = make([]*bn256.GT, l)
+    proof_out.Zsig = make([]*big.Int, l)
+    proof_out.Zv = make([]*big.Int, l)
+    for i := 0; i < l; i++ {
+        proof_out.V[i] = d.g2()
+        proof_out.A[i] = d.gt()
+        proof_out.Zsig[i] = d.scalar()
+        proof_out.Zv[i] = d.scalar()
+    }
+    return proof_out
+}
+
+/*
+finish returns the first error found while decoding, or ErrTrailingData if some
+data was not consumed.
+*/
+func (d *decoder) finish() error {
+    if d.err != nil {
+        return d.err
+    }
+    if len(d.data) != 0 {
+        return ErrTrailingData
+    }
+    return nil
+}
+
+/*
+validDigits returns true if u and l describe a non empty interval [0,u^l).
+*/
+func validDigits(u, l int64) bool {
+    return u >= 2 && l >= 1 && l <= MAX_DIGITS
+}
+
+/*
+validSignature returns true if sig is the signature of m for the public key y, that
+is if e(y.g^m, sig) = e(g, g2).
+*/
+func validSignature(sig *bn256.G2, m *big.Int, y *bn256.G1) bool {
+    ym := new(bn256.G1).ScalarBaseMult(bn.Mod(m, bn256.Order))
+    ym.Add(ym, y)
+    return bytes.Equal(bn256.Pair(ym, sig).Marshal(), util.E.Marshal())
+}
+
+func (p *ParamsSet) checkSignatures() error {
+    for e, sig := range p.signatures {
+        if !validSignature(sig, big.NewInt(e), p.y) {
+            return ErrInvalidSignature
+        }
+    }
+    return nil
+}
+
+func (p *ParamsStringSet) checkSignatures() error {
+    for _, x := range p.elements {
+        if !validSignature(p.signatures[x.String()], x, p.y) {
+            return ErrInvalidSignature
+        }
+    }
+    return nil
+}
+
+func (p *ParamsUL) checkSignatures() error {
+    for i := int64(0); i < p.u; i++ {
+        if !validSignature(p.signatures[strconv.FormatInt(i, 10)], big.NewInt(i), p.y) {
+            return ErrInvalidSignature
+        }
+    }
+    return nil
+}
+
+type verifierParamsSetJSON struct {
+    PublicKey string
+}
+
+type verifierParamsULJSON struct {
+    PublicKey string
+    U         int64
+    L         int64
+}
+
+type paramsSetJSON struct {
+    PublicKey  string
+    Signatures map[string]string
+}
+
+type paramsULJSON struct {
+    verifierParamsULJSON
+    Signatures []string
+}
+
+type proofSetJSON struct {
+    C         string
+    V         string
+    A         string
+    D         string
+    Challenge string
+    Zr        string
+    Zsig      string
+    Zv        string
+}
+
+type proofULJSON struct {
+    C         string
+    D         string
+    Challenge string
+    Zr        string
+    V         []string
+    A         []string
+    Zsig      []string
+    Zv        []string
+}
+
+/*
+MarshalJSON returns the JSON encoding of the parameters.
+*/
+func (p VerifierParamsSet) MarshalJSON() ([]byte, error) {
+    return json.Marshal(struct {
+        Version int
+        verifierParamsSetJSON
+    }{ENCODING_VERSION, verifierParamsSetJSON{encodeHex(g1Bytes(p.y))}})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the parameters.
+*/
+func (p *VerifierParamsSet) UnmarshalJSON(data []byte) error {
+    var decoded struct {
+        Version int
+        verifierParamsSetJSON
+    }
+    if err := unmarshalJSON(data, &decoded); err != nil {
+        return err
+    }
+    y, err := g1FromHex(decoded.PublicKey)
+    if err != nil {
+        return err
+    }
+    *p = NewVerifierParamsSet(y)
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the parameters.
+*/
+func (p VerifierParamsUL) MarshalJSON() ([]byte, error) {
+    return json.Marshal(struct {
+        Version int
+        verifierParamsULJSON
+    }{ENCODING_VERSION, p.toJSON()})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the parameters.
+*/
+func (p *VerifierParamsUL) UnmarshalJSON(data []byte) error {
+    var decoded struct {
+        Version int
+        verifierParamsULJSON
+    }
+    if err := unmarshalJSON(data, &decoded); err != nil {
+        return err
+    }
+    result, err := decoded.decode()
+    if err != nil {
+        return err
+    }
+    *p = result
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the parameters.
+*/
+func (p VerifierParams) MarshalJSON() ([]byte, error) {
+    return json.Marshal(struct {
+        Version int
+        verifierParamsULJSON
+        A int64
+        B int64
+    }{ENCODING_VERSION, p.p.toJSON(), p.a, p.b})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the parameters.
+*/
+func (p *VerifierParams) UnmarshalJSON(data []byte) error {
+    var decoded struct {
+        Version int
+        verifierParamsULJSON
+        A int64
+        B int64
+    }
+    if err := unmarshalJSON(data, &decoded); err != nil {
+        return err
+    }
+    ul, err := decoded.decode()
+    if err != nil {
+        return err
+    }
+    if decoded.A > decoded.B || checkDigits(decoded.A, decoded.B, ul.u, ul.l) != nil {
+        return ErrInvalidParams
+    }
+    *p = VerifierParams{p: ul, a: decoded.A, b: decoded.B}
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the parameters. The signatures are indexed
+by the decimal representation of the elements.
+*/
+func (p ParamsSet) MarshalJSON() ([]byte, error) {
+    encoded := paramsSetJSON{
+        PublicKey:  encodeHex(g1Bytes(p.y)),
+        Signatures: make(map[string]string),
+    }
+    for e, signature := range p.signatures {
+        encoded.Signatures[strconv.FormatInt(e, 10)] = encodeHex(g2Bytes(signature))
+    }
+    return json.Marshal(struct {
+        Version int
+        paramsSetJSON
+    }{ENCODING_VERSION, encoded})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the parameters.
+*/
+func (p *ParamsSet) UnmarshalJSON(data []byte) error {
+    var decoded struct {
+        Version int
+        paramsSetJSON
+    }
+    if err := unmarshalJSON(data, &decoded); err != nil {
+        return err
+    }
+    y, err := g1FromHex(decoded.PublicKey)
+    if err != nil {
+        return err
+    }
+    result := ParamsSet{VerifierParamsSet: NewVerifierParamsSet(y)}
+    result.signatures = make(map[int64]*bn256.G2)
+    for key, s := range decoded.Signatures {
+        e, err := strconv.ParseInt(key, 10, 64)
+        if err != nil || strconv.FormatInt(e, 10) != key {
+            return ErrInvalidParams
+        }
+        if result.signatures[e], err = g2FromHex(s); err != nil {
+            return err
+        }
+    }
+    if err = result.checkSignatures(); err != nil {
+        return err
+    }
+    *p = result
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the parameters. The signatures are indexed
+by the hexadecimal encoding of the elements.
+*/
+func (p ParamsStringSet) MarshalJSON() ([]byte, error) {
+    encoded := paramsSetJSON{
+        PublicKey:  encodeHex(g1Bytes(p.y)),
+        Signatures: make(map[string]string),
+    }
+    for e, x := range p.elements {
+        encoded.Signatures[encodeHex([]byte(e))] = encodeHex(g2Bytes(p.signatures[x.String()]))
+    }
+    return json.Marshal(struct {
+        Version int
+        paramsSetJSON
+    }{ENCODING_VERSION, encoded})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the parameters.
+*/
+func (p *ParamsStringSet) UnmarshalJSON(data []byte) error {
+    var decoded struct {
+        Version int
+        paramsSetJSON
+    }
+    if err := unmarshalJSON(data, &decoded); err != nil {
+        return err
+    }
+    y, err := g1FromHex(decoded.PublicKey)
+    if err != nil {
+        return err
+    }
+    result := ParamsStringSet{
+        elements:          make(map[string]*big.Int),
+        signatures:        make(map[string]*bn256.G2),
+        VerifierParamsSet: NewVerifierParamsSet(y),
+    }
+    for key, s := range decoded.Signatures {
+        e, err := hex.DecodeString(key)
+        if err != nil || encodeHex(e) != key {
+            return ErrInvalidParams
+        }
+        signature, err := g2FromHex(s)
+        if err != nil {
+            return err
+        }
+        result.addSignature(e, signature)
+    }
+    if err = result.checkSignatures(); err != nil {
+        return err
+    }
+    *p = result
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the parameters, with the signatures sorted
+by digit.
+*/
+func (p ParamsUL) MarshalJSON() ([]byte, error) {
+    return json.Marshal(struct {
+        Version int
+        paramsULJSON
+    }{ENCODING_VERSION, p.toJSON()})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the parameters.
+*/
+func (p *ParamsUL) UnmarshalJSON(data []byte) error {
+    var decoded struct {
+        Version int
+        paramsULJSON
+    }
+    if err := unmarshalJSON(data, &decoded); err != nil {
+        return err
+    }
+    result, err := decoded.decode()
+    if err != nil {
+        return err
+    }
+    if err = result.checkSignatures(); err != nil {
+        return err
+    }
+    *p = result
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the parameters.
+*/
+func (p Params) MarshalJSON() ([]byte, error) {
+    if p.p == nil {
+        return nil, ErrInvalidParams
+    }
+    return json.Marshal(struct {
+        Version int
+        paramsULJSON
+        A int64
+        B int64
+    }{ENCODING_VERSION, p.p.toJSON(), p.a, p.b})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the parameters.
+*/
+func (p *Params) UnmarshalJSON(data []byte) error {
+    var decoded struct {
+        Version int
+        paramsULJSON
+        A int64
+        B int64
+    }
+    if err := unmarshalJSON(data, &decoded); err != nil {
+        return err
+    }
+    ul, err := decoded.decode()
+    if err != nil {
+        return err
+    }
+    if decoded.A > decoded.B || checkDigits(decoded.A, decoded.B, ul.u, ul.l) != nil {
+        return ErrInvalidParams
+    }
+    if err = ul.checkSignatures(); err != nil {
+        return err
+    }
+    *p = Params{p: &ul, a: decoded.A, b: decoded.B}
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the proof.
+*/
+func (proof_out ProofSet) MarshalJSON() ([]byte, error) {
+    if !proof_out.complete() {
+        return nil, errors.New("proof is missing an element")
+    }
+    return json.Marshal(struct {
+        Version int
+        proofSetJSON
+    }{ENCODING_VERSION, proofSetJSON{
+        C:         encodeHex(g2Bytes(proof_out.C)),
+        V:         encodeHex(g2Bytes(proof_out.V)),
+        A:         encodeHex(proof_out.A.Marshal()),
+        D:         encodeHex(g2Bytes(proof_out.D)),
+        Challenge: encodeHex(scalarBytes(proof_out.Challenge)),
+        Zr:        encodeHex(scalarBytes(proof_out.Zr)),
+        Zsig:      encodeHex(scalarBytes(proof_out.Zsig)),
+        Zv:        encodeHex(scalarBytes(proof_out.Zv)),
+    }})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the proof.
+*/
+func (proof_out *ProofSet) UnmarshalJSON(data []byte) error {
+    var (
+        decoded struct {
+            Version int
+            proofSetJSON
+        }
+        result ProofSet
+        err    error
+    )
+    if err = unmarshalJSON(data, &decoded); err != nil {
+        return err
+    }
+    points := []string{decoded.C, decoded.V, decoded.D}
+    targets := []**bn256.G2{&result.C, &result.V, &result.D}
+    for i := range points {
+        if *targets[i], err = g2FromHex(points[i]); err != nil {
+            return err
+        }
+    }
+    if result.A, err = gtFromHex(decoded.A); err != nil {
+        return err
+    }
+    scalars := []string{decoded.Challenge, decoded.Zr, decoded.Zsig, decoded.Zv}
+    scalarTargets := []**big.Int{&result.Challenge, &result.Zr, &result.Zsig, &result.Zv}
+    for i := range scalars {
+        if *scalarTargets[i], err = scalarFromHex(scalars[i]); err != nil {
+            return err
+        }
+    }
+    *proof_out = result
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the proof.
+*/
+func (proof_out ProofUL) MarshalJSON() ([]byte, error) {
+    encoded, err := proof_out.toJSON()
+    if err != nil {
+        return nil, err
+    }
+    return json.Marshal(struct {
+        Version int
+        proofULJSON
+    }{ENCODING_VERSION, encoded})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the proof.
+*/
+func (proof_out *ProofUL) UnmarshalJSON(data []byte) error {
+    var decoded struct {
+        Version int
+        proofULJSON
+    }
+    if err := unmarshalJSON(data, &decoded); err != nil {
+        return err
+    }
+    result, err := decoded.decode()
+    if err != nil {
+        return err
+    }
+    *proof_out = result
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the proof.
+*/
+func (proof_out Proof) MarshalJSON() ([]byte, error) {
+    if proof_out.C == nil {
+        return nil, errors.New("commitment must not be nil")
+    }
+    p1, err := proof_out.P1.toJSON()
+    if err != nil {
+        return nil, err
+    }
+    p2, err := proof_out.P2.toJSON()
+    if err != nil {
+        return nil, err
+    }
+    return json.Marshal(struct {
+        Version int
+        C       string
+        P1      proofULJSON
+        P2      proofULJSON
+    }{ENCODING_VERSION, encodeHex(g2Bytes(proof_out.C)), p1, p2})
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of the proof.
+*/
+func (proof_out *Proof) UnmarshalJSON(data []byte) error {
+    var (
+        decoded struct {
+            Version int
+            C       string
+            P1      proofULJSON
+            P2      proofULJSON
+        }
+        result Proof
+        err    error
+    )
+    if err = unmarshalJSON(data, &decoded); err != nil {
+        return err
+    }
+    if result.C, err = g2FromHex(decoded.C); err != nil {
+        return err
+    }
+    if result.P1, err = decoded.P1.decode(); err != nil {
+        return err
+    }
+    if result.P2, err = decoded.P2.decode(); err != nil {
+        return err
+    }
+    *proof_out = result
+    return nil
+}
+
+func (p VerifierParamsUL) toJSON() verifierParamsULJSON {
+    return verifierParamsULJSON{encodeHex(g1Bytes(p.y)), p.u, p.l}
+}
+
+func (encoded verifierParamsULJSON) decode() (VerifierParamsUL, error) {
+    y, err := g1FromHex(encoded.PublicKey)
+    if err != nil {
+        return VerifierParamsUL{}, err
+    }
+    if !validDigits(encoded.U, encoded.L) {
+        return VerifierParamsUL{}, ErrInvalidParams
+    }
+    return NewVerifierParamsUL(y, encoded.U, encoded.L), nil
+}
+
+func (p ParamsUL) toJSON() paramsULJSON {
+    encoded := paramsULJSON{verifierParamsULJSON: p.VerifierParamsUL.toJSON()}
+    encoded.Signatures = make([]string, p.u)
+    for i := range encoded.Signatures {
+        encoded.Signatures[i] = encodeHex(g2Bytes(p.signatures[strconv.Itoa(i)]))
+    }
+    return encoded
+}
+
+func (encoded paramsULJSON) decode() (ParamsUL, error) {
+    var (
+        p   ParamsUL
+        err error
+    )
+    p.VerifierParamsUL, err = encoded.verifierParamsULJSON.decode()
+    if err != nil {
+        return p, err
+    }
+    if int64(len(encoded.Signatures)) != p.u {
+        return p, ErrInvalidParams
+    }
+    p.signatures = make(map[string]*bn256.G2)
+    for i, s := range encoded.Signatures {
+        if p.signatures[strconv.Itoa(i)], err = g2FromHex(s); err != nil {
+            return p, err
+        }
+    }
+    return p, nil
+}
+
+func (proof_out ProofUL) toJSON() (proofULJSON, error) {
+    if err := proof_out.writeTo(new(bytes.Buffer)); err != nil {
+        return proofULJSON{}, err
+    }
+    l := len(proof_out.V)
+    encoded := proofULJSON{
+        C:         encodeHex(g2Bytes(proof_out.C)),
+        D:         encodeHex(g2Bytes(proof_out.D)),
+        Challenge: encodeHex(scalarBytes(proof_out.Challenge)),
+        Zr:        encodeHex(scalarBytes(proof_out.Zr)),
+        V:         make([]string, l),
+        A:         make([]string, l),
+        Zsig:      make([]string, l),
+        Zv:        make([]string, l),
+    }
+    for i := 0; i < l; i++ {
+        encoded.V[i] = encodeHex(g2Bytes(proof_out.V[i]))
+        encoded.A[i] = encodeHex(proof_out.A[i].Marshal())
+        encoded.Zsig[i] = encodeHex(scalarBytes(proof_out.Zsig[i]))
+        encoded.Zv[i] = encodeHex(scalarBytes(proof_out.Zv[i]))
+    }
+    return encoded, nil
+}
+
+func (encoded proofULJSON) decode() (ProofUL, error) {
+    var (
+        result ProofUL
+        err    error
+    )
+    l := len(encoded.V)
+    if l > MAX_DIGITS || len(encoded.A) != l || len(encoded.Zsig) != l || len(encoded.Zv) != l {
+        return result, errors.New("number of digits does not match the proof")
+    }
+    if result.C, err = g2FromHex(encoded.C); err != nil {
+        return result, err
+    }
+    if result.D, err = g2FromHex(encoded.D); err != nil {
+        return result, err
+    }
+    if result.Challenge, err = scalarFromHex(encoded.Challenge); err != nil {
+        return result, err
+    }
+    if result.Zr, err = scalarFromHex(encoded.Zr); err != nil {
+        return result, err
+    }
+    result.V = make([]*bn256.G2, l)
+    result.A = make([]*bn256.GT, l)
+    result.Zsig = make([]*big.Int, l)
+    result.Zv = make([]*big.Int, l)
+    for i := 0; i < l; i++ {
+        if result.V[i], err = g2FromHex(encoded.V[i]); err != nil {
+            return result, err
+        }
+        if result.A[i], err = gtFromHex(encoded.A[i]); err != nil {
+            return result, err
+        }
+        if result.Zsig[i], err = scalarFromHex(encoded.Zsig[i]); err != nil {
+            return result, err
+        }
+        if result.Zv[i], err = scalarFromHex(encoded.Zv[i]); err != nil {
+            return result, err
+        }
+    }
+    return result, nil
+}
+
+/*
+hexDecoder returns a decoder of the hexadecimal string s, without header.
+*/
+func hexDecoder(s string) *decoder {
+    b, err := hex.DecodeString(s)
+    if err != nil {
+        return &decoder{err: fmt.Errorf("invalid encoding: %v", err)}
+    }
+    return &decoder{data: b}
+}
+
+func g1FromHex(s string) (*bn256.G1, error) {
+    d := hexDecoder(s)
+    point := d.g1()
+    return point, d.finish()
+}
+
+func g2FromHex(s string) (*bn256.G2, error) {
+    d := hexDecoder(s)
+    point := d.g2()
+    return point, d.finish()
+}
+
+func gtFromHex(s string) (*bn256.GT, error) {
+    d := hexDecoder(s)
+    e := d.gt()
+    return e, d.finish()
+}
+
+func scalarFromHex(s string) (*big.Int, error) {
+    d := hexDecoder(s)
+    scalar := d.scalar()
+    return scalar, d.finish()
+}
+
+func encodeHex(b []byte) string {
+    return hex.EncodeToString(b)
+}
+
+/*
+unmarshalJSON checks the version of the JSON encoding, then decodes it into v,
+rejecting unknown fields.
+*/
+func unmarshalJSON(data []byte, v interface{}) error {
+    var header struct {
+        Version int
+    }
+    if err := json.Unmarshal(data, &header); err != nil {
+        return fmt.Errorf("invalid encoding: %v", err)
+    }
+    if header.Version != ENCODING_VERSION {
+        return ErrUnsupportedVersion
+    }
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.DisallowUnknownFields()
+    if err := decoder.Decode(v); err != nil {
+        return fmt.Errorf("invalid encoding: %v", err)
+    }
+    return nil
+}
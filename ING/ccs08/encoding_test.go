@@ -0,0 +1,224 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+
+package ccs08
+
+import (
+    "bytes"
+    "crypto/rand"
+    "encoding/json"
+    "math/big"
+    "strings"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+)
+
+/*
+Tests that the parameters and the proofs computed after a round trip through the
+binary and JSON encodings are accepted, and that the encodings are canonical.
+*/
+func TestEncodeDecode(t *testing.T) {
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+
+    ps, _ := SetupSet([]int64{71, 12, 42, 61})
+    var decodedSet ParamsSet
+    roundTrip(t, ps, &decodedSet)
+    proof_set, err := ProveSet(42, r, decodedSet)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var decodedProofSet ProofSet
+    roundTrip(t, proof_set, &decodedProofSet)
+    var verifierSet VerifierParamsSet
+    roundTrip(t, ps.Verifier(), &verifierSet)
+    if result, _ := VerifySet(&decodedProofSet, &verifierSet); result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+
+    pu, _ := SetupUL(10, 5)
+    var decodedUL ParamsUL
+    roundTrip(t, pu, &decodedUL)
+    proof_ul, err := ProveUL(new(big.Int).SetInt64(42176), r, decodedUL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var decodedProofUL ProofUL
+    roundTrip(t, proof_ul, &decodedProofUL)
+    var verifierUL VerifierParamsUL
+    roundTrip(t, pu.Verifier(), &verifierUL)
+    if result, _ := VerifyUL(&decodedProofUL, &verifierUL); result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+
+    p, _ := Setup(18, 200)
+    var decodedParams Params
+    roundTrip(t, p, &decodedParams)
+    proof_out, err := Prove(new(big.Int).SetInt64(40), r, decodedParams)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var decodedProof Proof
+    roundTrip(t, proof_out, &decodedProof)
+    var verifier VerifierParams
+    roundTrip(t, p.Verifier(), &verifier)
+    if result, _ := Verify(&decodedProof, &verifier); result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+}
+
+type encodable interface {
+    MarshalBinary() ([]byte, error)
+    MarshalJSON() ([]byte, error)
+}
+
+type decodable interface {
+    encodable
+    UnmarshalBinary([]byte) error
+    UnmarshalJSON([]byte) error
+}
+
+/*
+roundTrip decodes the binary and JSON encodings of value into decoded, and checks
+that decoded has the same encodings.
+*/
+func roundTrip(t *testing.T, value encodable, decoded decodable) {
+    encoded, err := value.MarshalBinary()
+    if err != nil {
+        t.Fatal("encode error:", err)
+    }
+    if err = decoded.UnmarshalBinary(encoded); err != nil {
+        t.Fatal("decode error:", err)
+    }
+    reencoded, _ := decoded.MarshalBinary()
+    if !bytes.Equal(encoded, reencoded) {
+        t.Errorf("Assert failure: the binary encoding should be canonical")
+    }
+
+    encoded, err = json.Marshal(value)
+    if err != nil {
+        t.Fatal("encode error:", err)
+    }
+    if err = json.Unmarshal(encoded, decoded); err != nil {
+        t.Fatal("decode error:", err)
+    }
+    reencoded, _ = json.Marshal(decoded)
+    if !bytes.Equal(encoded, reencoded) {
+        t.Errorf("Assert failure: the JSON encoding should be canonical")
+    }
+}
+
+/*
+Tests that invalid binary encodings are rejected.
+*/
+func TestBinaryDecodeInvalid(t *testing.T) {
+    p, _ := SetupSet([]int64{12, 42})
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    proof_set, _ := ProveSet(12, r, p)
+    encoded, _ := proof_set.MarshalBinary()
+
+    tamper := func(offset int, value byte) []byte {
+        result := append([]byte{}, encoded...)
+        result[offset] = value
+        return result
+    }
+    scalarOffset := 2 + 2*G2_SIZE + GT_SIZE + G2_SIZE
+    tests := []struct {
+        name string
+        data []byte
+        err  error
+    }{
+        {"empty", nil, ErrTruncatedData},
+        {"version", tamper(0, ENCODING_VERSION+1), ErrUnsupportedVersion},
+        {"type", tamper(1, tagProofUL), ErrInvalidType},
+        {"truncated", encoded[:len(encoded)-1], ErrTruncatedData},
+        {"trailing", append(append([]byte{}, encoded...), 0), ErrTrailingData},
+        {"point", tamper(2+G2_SIZE-1, encoded[2+G2_SIZE-1]^1), ErrInvalidPoint},
+        {"scalar", tamper(scalarOffset, 0xff), ErrInvalidScalar},
+    }
+    for _, test := range tests {
+        var decoded ProofSet
+        err := decoded.UnmarshalBinary(test.data)
+        if err != test.err {
+            t.Errorf("Assert failure: %s: expected %v, actual: %v", test.name, test.err, err)
+        }
+    }
+
+    encodedParams, _ := p.VerifierParamsSet.MarshalBinary()
+    var decodedParams VerifierParamsUL
+    if err := decodedParams.UnmarshalBinary(encodedParams); err != ErrInvalidType {
+        t.Errorf("Assert failure: expected %v, actual: %v", ErrInvalidType, err)
+    }
+    pu := NewVerifierParamsUL(p.PublicKey(), 1, 5)
+    encodedParams, _ = pu.MarshalBinary()
+    if err := decodedParams.UnmarshalBinary(encodedParams); err != ErrInvalidParams {
+        t.Errorf("Assert failure: expected %v, actual: %v", ErrInvalidParams, err)
+    }
+}
+
+/*
+Tests that invalid JSON encodings are rejected.
+*/
+func TestJsonDecodeInvalid(t *testing.T) {
+    p, _ := SetupSet([]int64{12, 42})
+    encoded, _ := json.Marshal(p)
+
+    var decoded ParamsSet
+    invalid := []string{
+        strings.Replace(string(encoded), `"Version":1`, `"Version":2`, 1),
+        strings.Replace(string(encoded), `"12":`, `"012":`, 1),
+        strings.Replace(string(encoded), `"PublicKey"`, `"Unknown":"","PublicKey"`, 1),
+        strings.Replace(string(encoded), `"PublicKey":"`, `"PublicKey":"00`, 1),
+    }
+    for i, data := range invalid {
+        if err := json.Unmarshal([]byte(data), &decoded); err == nil {
+            t.Errorf("Assert failure: encoding %d should be rejected", i)
+        }
+    }
+    if err := json.Unmarshal([]byte(invalid[0]), &decoded); err != ErrUnsupportedVersion {
+        t.Errorf("Assert failure: expected %v, actual: %v", ErrUnsupportedVersion, err)
+    }
+}
+
+/*
+Tests that the parameters whose signatures do not match the public key are rejected.
+*/
+func TestDecodeInvalidSignatures(t *testing.T) {
+    other, _ := SetupSet([]int64{12})
+    ps, _ := SetupSet([]int64{12, 42})
+    ps.VerifierParamsSet = other.VerifierParamsSet
+    pss, _ := SetupStringSet([][]byte{[]byte("NL")})
+    pss.VerifierParamsSet = other.VerifierParamsSet
+    pu, _ := SetupUL(4, 2)
+    pu.y = other.y
+    p, _ := Setup(18, 200)
+    p.p.signatures["3"] = p.p.signatures["2"]
+
+    values := []encodable{ps, pss, pu, p}
+    decoded := []decodable{new(ParamsSet), new(ParamsStringSet), new(ParamsUL), new(Params)}
+    for i := range values {
+        encoded, _ := values[i].MarshalBinary()
+        if err := decoded[i].UnmarshalBinary(encoded); err != ErrInvalidSignature {
+            t.Errorf("Assert failure: expected %v, actual: %v", ErrInvalidSignature, err)
+        }
+        encoded, _ = values[i].MarshalJSON()
+        if err := decoded[i].UnmarshalJSON(encoded); err != ErrInvalidSignature {
+            t.Errorf("Assert failure: expected %v, actual: %v", ErrInvalidSignature, err)
+        }
+    }
+}
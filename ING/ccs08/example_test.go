@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+
+package ccs08_test
+
+import (
+    "crypto/rand"
+    "fmt"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/ccs08"
+)
+
+/*
+The issuer computes the parameters for the interval [18, 200), and the prover proves
+that its committed age belongs to it. The verifier only knows the public key of the
+issuer.
+*/
+func ExampleSetup() {
+    params, err := ccs08.Setup(18, 200)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    C, opening, _ := params.Pedersen().CommitRandom(big.NewInt(40), rand.Reader)
+    proof, err := ccs08.ProveOpening(opening, params)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+
+    verifier := params.Verifier()
+    ok, _ := ccs08.VerifyCommitment(&proof, &verifier, C)
+    fmt.Println(ok)
+    // Output: true
+}
+
+/*
+The issuer signs the elements of the set, and the prover proves that its secret is
+one of them.
+*/
+func ExampleSetupSet() {
+    params, err := ccs08.SetupSet([]int64{12, 42, 61, 71})
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    r, _ := rand.Int(rand.Reader, params.Pedersen().Group().Order())
+    proof, err := ccs08.ProveSet(42, r, params)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    ok, _ := ccs08.VerifySet(&proof, &params.VerifierParamsSet)
+    fmt.Println(ok)
+
+    _, err = ccs08.ProveSet(43, r, params)
+    fmt.Println(err != nil)
+    // Output:
+    // true
+    // true
+}
+
+/*
+The secret is written with l = 5 digits in base u = 10, which proves that it belongs
+to [0, 10^5).
+*/
+func ExampleSetupUL() {
+    params, err := ccs08.SetupUL(10, 5)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    r, _ := rand.Int(rand.Reader, params.Pedersen().Group().Order())
+    proof, err := ccs08.ProveUL(big.NewInt(42176), r, params)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    ok, _ := ccs08.VerifyUL(&proof, &params.VerifierParamsUL)
+    fmt.Println(ok)
+    // Output: true
+}
+
+/*
+The elements of a set of strings are mapped to scalars with HashElement. The verifier
+only needs the public key of the issuer.
+*/
+func ExampleSetupStringSet() {
+    countries := [][]byte{[]byte("BE"), []byte("DE"), []byte("FR"), []byte("LU"), []byte("NL")}
+    params, err := ccs08.SetupStringSet(countries)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    r, _ := rand.Int(rand.Reader, params.Pedersen().Group().Order())
+    proof, err := ccs08.ProveStringSet([]byte("NL"), r, params)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    verifier := params.Verifier()
+    ok, _ := ccs08.VerifySet(&proof, &verifier)
+    fmt.Println(ok)
+
+    _, err = ccs08.ProveStringSet([]byte("GB"), r, params)
+    fmt.Println(err != nil)
+    // Output:
+    // true
+    // true
+}
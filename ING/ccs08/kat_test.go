@@ -0,0 +1,191 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ccs08
+
+import (
+    "encoding/hex"
+    "encoding/json"
+    "flag"
+    "io"
+    "io/ioutil"
+    "math/big"
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/transcript"
+)
+
+/*
+The known-answer tests contain proofs computed in the derandomized mode, without
+external randomness, for keys read from the RNG of a transcript labelled with
+KeySeed. They are regenerated with: go test ./ccs08 -run KnownAnswer -update
+*/
+
+var updateKAT = flag.Bool("update", false, "regenerate the known-answer test vectors")
+
+const katFile = "testdata/kat.json"
+
+/*
+katProof contains the commitment, the challenge and the responses of a proof.
+*/
+type katProof struct {
+    C         string
+    Challenge string
+    Zr        string
+    Zsig      []string
+    Zv        []string
+}
+
+type katULProof struct {
+    U       int64
+    L       int64
+    KeySeed string
+    Secret  string
+    R       string
+    Proof   katProof
+}
+
+type katSetProof struct {
+    Set     []int64
+    KeySeed string
+    Secret  int64
+    R       string
+    Proof   katProof
+}
+
+type katVectors struct {
+    ULProof  []katULProof
+    SetProof []katSetProof
+}
+
+var deterministic = []Option{Derandomized(), WithRand(nil)}
+
+/*
+keyReader returns the deterministic source of the keys of the known-answer tests.
+*/
+func keyReader(seed string) io.Reader {
+    rng, _ := transcript.New(seed).BuildRng().Finalize(nil)
+    return rng
+}
+
+func katInt(t *testing.T, s string) *big.Int {
+    n, ok := new(big.Int).SetString(s, 0)
+    if !ok {
+        t.Fatalf("invalid integer %q", s)
+    }
+    return n
+}
+
+func hexInts(values []*big.Int) []string {
+    result := make([]string, len(values))
+    for i, v := range values {
+        result[i] = hex.EncodeToString(v.Bytes())
+    }
+    return result
+}
+
+func TestKnownAnswer(t *testing.T) {
+    var vectors katVectors
+    data, err := ioutil.ReadFile(katFile)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err = json.Unmarshal(data, &vectors); err != nil {
+        t.Fatal(err)
+    }
+
+    for i := range vectors.ULProof {
+        v := &vectors.ULProof[i]
+        p, _ := SetupUL(v.U, v.L, WithRand(keyReader(v.KeySeed)))
+        proof_out, err := ProveUL(katInt(t, v.Secret), katInt(t, v.R), p, deterministic...)
+        if err != nil {
+            t.Fatal(err)
+        }
+        checkKnownAnswer(t, &v.Proof, katProof{
+            C:         hex.EncodeToString(proof_out.C.Marshal()),
+            Challenge: hex.EncodeToString(proof_out.Challenge.Bytes()),
+            Zr:        hex.EncodeToString(proof_out.Zr.Bytes()),
+            Zsig:      hexInts(proof_out.Zsig),
+            Zv:        hexInts(proof_out.Zv),
+        })
+        if result, _ := VerifyUL(&proof_out, &p.VerifierParamsUL); !result {
+            t.Errorf("Assert failure: proof %d should verify", i)
+        }
+    }
+
+    for i := range vectors.SetProof {
+        v := &vectors.SetProof[i]
+        p, _ := SetupSet(v.Set, WithRand(keyReader(v.KeySeed)))
+        proof_out, err := ProveSet(v.Secret, katInt(t, v.R), p, deterministic...)
+        if err != nil {
+            t.Fatal(err)
+        }
+        checkKnownAnswer(t, &v.Proof, katProof{
+            C:         hex.EncodeToString(proof_out.C.Marshal()),
+            Challenge: hex.EncodeToString(proof_out.Challenge.Bytes()),
+            Zr:        hex.EncodeToString(proof_out.Zr.Bytes()),
+            Zsig:      hexInts([]*big.Int{proof_out.Zsig}),
+            Zv:        hexInts([]*big.Int{proof_out.Zv}),
+        })
+        if result, _ := VerifySet(&proof_out, &p.VerifierParamsSet); !result {
+            t.Errorf("Assert failure: proof %d should verify", i)
+        }
+    }
+
+    if *updateKAT {
+        data, _ = json.MarshalIndent(vectors, "", "  ")
+        if err = ioutil.WriteFile(katFile, append(data, '\n'), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+}
+
+/*
+checkKnownAnswer compares the proof with the expected one, or replaces the expected
+proof when the vectors are regenerated.
+*/
+func checkKnownAnswer(t *testing.T, expected *katProof, actual katProof) {
+    if *updateKAT {
+        *expected = actual
+        return
+    }
+    if !reflect.DeepEqual(*expected, actual) {
+        t.Errorf("Assert failure: expected %v, actual: %v", *expected, actual)
+    }
+}
+
+/*
+Tests that the errors of the random source are returned.
+*/
+func TestRandError(t *testing.T) {
+    _, err := SetupUL(10, 5, WithRand(strings.NewReader("short")))
+    if err == nil {
+        t.Errorf("Assert failure: setup should fail")
+    }
+    p, _ := SetupUL(10, 5)
+    _, err = ProveUL(new(big.Int).SetInt64(42176), new(big.Int).SetInt64(3), p, WithRand(strings.NewReader(strings.Repeat("a", 100))))
+    if err == nil {
+        t.Errorf("Assert failure: proof should fail")
+    }
+    s, _ := SetupSet([]int64{12, 42})
+    _, err = ProveSet(12, new(big.Int).SetInt64(3), s, Derandomized(), WithRand(strings.NewReader("short")))
+    if err == nil {
+        t.Errorf("Assert failure: proof should fail")
+    }
+}
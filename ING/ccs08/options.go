@@ -0,0 +1,148 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the options of the setups and the provers. By default the keys,
+the blinding factors and the nonces are read from crypto/rand. WithRand replaces the
+random source, and Derandomized derives the nonces of the provers from the statement
+and the witness, see the RNG of crypto/transcript. WithDigits and WithPreference
+select the digits of the range proofs, see cost.go.
+*/
+
+package ccs08
+
+import (
+    "crypto/rand"
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+)
+
+/*
+Option configures the randomness used by a setup or a prover, or the digits of the
+range proofs.
+*/
+type Option func(*config)
+
+type config struct {
+    rand         io.Reader
+    derandomized bool
+    u, l         int64
+    preference   Preference
+}
+
+/*
+WithRand sets the random source. Its errors are returned by the setups and the
+provers.
+*/
+func WithRand(r io.Reader) Option {
+    return func(c *config) {
+        c.rand = r
+    }
+}
+
+/*
+Derandomized derives the nonces of the provers from the statement and the secret
+values, together with 32 bytes of the random source. A random source that fails to
+provide fresh randomness then can not leak the secret values. With WithRand(nil), the
+proofs are deterministic. It has no effect on the setups.
+*/
+func Derandomized() Option {
+    return func(c *config) {
+        c.derandomized = true
+    }
+}
+
+/*
+WithDigits sets the base u and the number of digits l of the range proofs, instead of
+the values chosen by the cost model. Setup fails if u^l is less than b - a.
+*/
+func WithDigits(u, l int64) Option {
+    return func(c *config) {
+        c.u = u
+        c.l = l
+    }
+}
+
+/*
+WithPreference sets the weights of the cost model which chooses the base and the
+number of digits of the range proofs. The default is Balanced.
+*/
+func WithPreference(p Preference) Option {
+    return func(c *config) {
+        c.preference = p
+    }
+}
+
+/*
+newConfig applies the options. A nil random source is only allowed in the
+derandomized mode, otherwise crypto/rand is used.
+*/
+func newConfig(opts []Option) config {
+    c := config{rand: rand.Reader}
+    for _, opt := range opts {
+        opt(&c)
+    }
+    if c.rand == nil && !c.derandomized {
+        c.rand = rand.Reader
+    }
+    return c
+}
+
+/*
+setupReader returns the source of the keys of the setups, which are never
+derandomized.
+*/
+func (c config) setupReader() io.Reader {
+    if c.rand == nil {
+        return rand.Reader
+    }
+    return c.rand
+}
+
+/*
+nonceReader returns the source of the nonces of a proof whose statement was absorbed
+by t. In the derandomized mode it is the RNG of t, keyed with the witness.
+*/
+func (c config) nonceReader(t *transcript.Transcript, witness ...*big.Int) (io.Reader, error) {
+    if !c.derandomized {
+        return c.rand, nil
+    }
+    builder := t.BuildRng()
+    for _, w := range witness {
+        b := new(big.Int).Mod(w, bn256.Order).Bytes()
+        builder.RekeyWithWitness("witness", append(make([]byte, 32-len(b)), b...))
+    }
+    return builder.Finalize(c.rand)
+}
+
+/*
+sampleScalars returns n scalars read from r.
+*/
+func sampleScalars(r io.Reader, n int) ([]*big.Int, error) {
+    var err error
+    s := make([]*big.Int, n)
+    for i := range s {
+        s[i], err = rand.Int(r, bn256.Order)
+        if err != nil {
+            return nil, err
+        }
+    }
+    return s, nil
+}
@@ -0,0 +1,232 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the keys and the parameters of the proofs. Each setup signs the
+elements with a fresh key of the issuer, which is discarded: the proofs do not bind
+the set, so the signatures of a key shared by several sets would be witnesses for
+all of them. The provers receive the public parameters ParamsSet and ParamsUL, which
+contain the signatures, and the verifiers only need their public part,
+VerifierParamsSet and VerifierParamsUL.
+*/
+
+package ccs08
+
+import (
+    "math/big"
+    "strconv"
+
+    "github.com/ing-bank/zkrp/crypto/bbsignatures"
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
+    "github.com/ing-bank/zkrp/util/intconversion"
+)
+
+/*
+VerifierParamsSet contains the elements needed to verify a ZK Set Membership proof:
+the public key y of the issuer and the generator H of the commitments.
+*/
+type VerifierParamsSet struct {
+    y *bn256.G1
+    H *bn256.G2
+}
+
+/*
+VerifierParamsUL contains the elements needed to verify a proof for [0,u^l): the
+public key y of the issuer, the generator H of the commitments, u and l.
+*/
+type VerifierParamsUL struct {
+    y *bn256.G1
+    H *bn256.G2
+    // u determines the amount of signatures we need in the public params.
+    // Each signature can be compressed to just 1 field element of 256 bits.
+    // Then the parameters have minimum size equal to 256*u bits.
+    // l determines how many pairings we need to compute, then in order to improve
+    // verifier`s performance we want to minize it.
+    // Namely, a range proof needs 2*l pairings for the prover and 4*l for the verifier,
+    // since the verifier computes two pairings per digit of its two proofs for [0,u^l).
+    u, l int64
+}
+
+/*
+ParamsSet contains elements generated by the issuer, which are necessary for the prover:
+the signatures of the elements of the set. This must be computed in a trusted setup.
+*/
+type ParamsSet struct {
+    signatures map[int64]*bn256.G2
+    VerifierParamsSet
+}
+
+/*
+ParamsUL contains elements generated by the issuer, which are necessary for the prover:
+the signatures of the digits [0,u). This must be computed in a trusted setup.
+*/
+type ParamsUL struct {
+    signatures map[string]*bn256.G2
+    VerifierParamsUL
+}
+
+/*
+generateKey generates a signing key of the issuer, read from the random source of
+the options.
+*/
+func generateKey(opts []Option) (bbsignatures.Keypair, error) {
+    return bbsignatures.KeygenWithReader(newConfig(opts).setupReader())
+}
+
+/*
+SetupSet generates the signature for the elements in the set, with a fresh key which
+is discarded, so that the signatures are only witnesses for this set. The key pair is
+read from the random source of the options.
+*/
+func SetupSet(s []int64, opts ...Option) (ParamsSet, error) {
+    var (
+        i int
+        p ParamsSet
+    )
+    kp, err := generateKey(opts)
+    if err != nil {
+        return p, err
+    }
+    p.signatures = make(map[int64]*bn256.G2)
+    for i = 0; i < len(s); i++ {
+        sig_i, err := bbsignatures.Sign(new(big.Int).SetInt64(int64(s[i])), kp.Privk)
+        if err != nil {
+            return p, err
+        }
+        p.signatures[s[i]] = sig_i
+    }
+    p.VerifierParamsSet = NewVerifierParamsSet(kp.Pubk)
+    return p, nil
+}
+
+/*
+SetupUL generates the signature for the interval [0,u^l), with a fresh key which is
+discarded.
+Smaller values of u give smaller parameters, at the cost of more digits l, and so of
+slower proofs. Setup chooses u and l with a cost model, see EstimateCost.
+*/
+func SetupUL(u, l int64, opts ...Option) (ParamsUL, error) {
+    var (
+        i int64
+        p ParamsUL
+    )
+    kp, err := generateKey(opts)
+    if err != nil {
+        return p, err
+    }
+    p.signatures = make(map[string]*bn256.G2)
+    for i = 0; i < u; i++ {
+        sig_i, err := bbsignatures.Sign(new(big.Int).SetInt64(i), kp.Privk)
+        if err != nil {
+            return p, err
+        }
+        p.signatures[strconv.FormatInt(i, 10)] = sig_i
+    }
+    p.VerifierParamsUL = NewVerifierParamsUL(kp.Pubk, u, l)
+    return p, nil
+}
+
+/*
+NewVerifierParamsSet returns the parameters of the verifier of the set membership
+proofs, for the public key y of the issuer.
+*/
+func NewVerifierParamsSet(y *bn256.G1) VerifierParamsSet {
+    return VerifierParamsSet{y: y, H: generatorH()}
+}
+
+/*
+NewVerifierParamsUL returns the parameters of the verifier of the proofs for
+[0,u^l), for the public key y of the issuer.
+*/
+func NewVerifierParamsUL(y *bn256.G1, u, l int64) VerifierParamsUL {
+    return VerifierParamsUL{y: y, H: generatorH(), u: u, l: l}
+}
+
+/*
+Verifier returns the public part of the parameters, needed by the verifier.
+*/
+func (p *ParamsSet) Verifier() VerifierParamsSet {
+    return p.VerifierParamsSet
+}
+
+/*
+Verifier returns the public part of the parameters, needed by the verifier.
+*/
+func (p *ParamsUL) Verifier() VerifierParamsUL {
+    return p.VerifierParamsUL
+}
+
+/*
+PublicKey returns the public key of the issuer.
+*/
+func (p *VerifierParamsSet) PublicKey() *bn256.G1 {
+    return p.y
+}
+
+/*
+PublicKey returns the public key of the issuer.
+*/
+func (p *VerifierParamsUL) PublicKey() *bn256.G1 {
+    return p.y
+}
+
+/*
+U returns the base of the decomposition of the secrets.
+*/
+func (p *VerifierParamsUL) U() int64 {
+    return p.u
+}
+
+/*
+L returns the number of digits of the secrets.
+*/
+func (p *VerifierParamsUL) L() int64 {
+    return p.l
+}
+
+/*
+Pedersen returns the parameters of the commitments to the elements of the set,
+g^x.H^r in G2.
+*/
+func (p *VerifierParamsSet) Pedersen() pedersen.Params {
+    return pedersen.NewG2Params(p.H)
+}
+
+/*
+Pedersen returns the parameters of the commitments to the secrets, g^x.H^r in G2.
+*/
+func (p *VerifierParamsUL) Pedersen() pedersen.Params {
+    return pedersen.NewG2Params(p.H)
+}
+
+/*
+newCommitment returns the commitment C of the proofs, computed with the generator H.
+*/
+func newCommitment(C *bn256.G2) pedersen.Commitment {
+    return pedersen.NewG2Params(generatorH()).NewCommitment(group.NewG2(C))
+}
+
+/*
+generatorH returns the generator H of the commitments.
+*/
+func generatorH() *bn256.G2 {
+    // Issue #12: p.H must be computed using MapToPoint method.
+    h := intconversion.BigFromBase10("18560948149108576432482904553159745978835170526553990798435819795989606410925")
+    return new(bn256.G2).ScalarBaseMult(h)
+}
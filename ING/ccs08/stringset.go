@@ -0,0 +1,149 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+
+/*
+This file contains the set membership proofs for sets of byte strings, such as
+country codes or document types. Each element is mapped to a scalar by HashElement,
+a domain separated hash, and the proof is a ZK Set Membership proof for the scalar.
+The verifiers use VerifySet, and do not need the elements of the set.
+*/
+
+package ccs08
+
+import (
+    "bytes"
+    "errors"
+    "math/big"
+    "sort"
+
+    "github.com/ing-bank/zkrp/crypto/bbsignatures"
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+)
+
+// ELEMENT_LABEL is the label of the transcript of HashElement.
+const ELEMENT_LABEL = "zkrp ccs08 set element"
+
+/*
+ParamsStringSet contains elements generated by the issuer, which are necessary for the
+prover: the scalars of the elements of the set, and their signatures. This must be
+computed in a trusted setup. Since the proofs do not depend on the set, each set is
+signed with its own key by SetupStringSet.
+*/
+type ParamsStringSet struct {
+    // elements maps the elements to their scalars, and signatures maps the decimal
+    // representation of the scalars to their signatures.
+    elements   map[string]*big.Int
+    signatures map[string]*bn256.G2
+    VerifierParamsSet
+}
+
+/*
+HashElement maps the element x of a set to a scalar of bn256. Commitments to x are
+commitments to this scalar.
+*/
+func HashElement(x []byte) *big.Int {
+    t := transcript.New(ELEMENT_LABEL)
+    t.AppendMessage("element", x)
+    return t.ChallengeScalar("x", bn256.Order)
+}
+
+/*
+SetupStringSet generates the signature for the elements in the set, with a fresh key
+which is discarded, so that the signatures are only witnesses for this set. The key
+pair is read from the random source of the options.
+*/
+func SetupStringSet(s [][]byte, opts ...Option) (ParamsStringSet, error) {
+    kp, err := generateKey(opts)
+    if err != nil {
+        return ParamsStringSet{}, err
+    }
+    p := ParamsStringSet{
+        elements:          make(map[string]*big.Int),
+        signatures:        make(map[string]*bn256.G2),
+        VerifierParamsSet: NewVerifierParamsSet(kp.Pubk),
+    }
+    for _, e := range s {
+        x := HashElement(e)
+        sig_i, err := bbsignatures.Sign(x, kp.Privk)
+        if err != nil {
+            return p, err
+        }
+        p.elements[string(e)] = x
+        p.signatures[x.String()] = sig_i
+    }
+    return p, nil
+}
+
+/*
+Elements returns the elements of the set, in increasing order.
+*/
+func (p *ParamsStringSet) Elements() [][]byte {
+    elements := make([][]byte, 0, len(p.elements))
+    for e := range p.elements {
+        elements = append(elements, []byte(e))
+    }
+    sort.Slice(elements, func(i, j int) bool { return bytes.Compare(elements[i], elements[j]) < 0 })
+    return elements
+}
+
+/*
+Contains returns true if x belongs to the set.
+*/
+func (p *ParamsStringSet) Contains(x []byte) bool {
+    _, ok := p.elements[string(x)]
+    return ok
+}
+
+/*
+Verifier returns the public part of the parameters, needed by the verifier.
+*/
+func (p *ParamsStringSet) Verifier() VerifierParamsSet {
+    return p.VerifierParamsSet
+}
+
+/*
+ProveStringSet produces the ZK Set Membership proof that the element x, committed as
+HashElement(x) with the blinding factor r, belongs to the set.
+*/
+func ProveStringSet(x []byte, r *big.Int, p ParamsStringSet, opts ...Option) (ProofSet, error) {
+    if !p.Contains(x) {
+        return ProofSet{}, errors.New("Could not generate proof. Element does not belong to the set.")
+    }
+    return p.prove(p.elements[string(x)], r, newConfig(opts))
+}
+
+/*
+ProveStringSetOpening produces the ZK Set Membership proof for the commitment opened
+by opening, whose value must be HashElement(x) for an element x of the set.
+*/
+func ProveStringSetOpening(opening pedersen.Opening, p ParamsStringSet, opts ...Option) (ProofSet, error) {
+    if opening.Group != pedersen.BN256G2 {
+        return ProofSet{}, pedersen.ErrGroupMismatch
+    }
+    return p.prove(opening.Value, opening.Blinding, newConfig(opts))
+}
+
+func (p *ParamsStringSet) prove(x, r *big.Int, cfg config) (ProofSet, error) {
+    A, ok := p.signatures[x.String()]
+    if !ok {
+        return ProofSet{}, errors.New("Could not generate proof. Element does not belong to the set.")
+    }
+    return proveSet(x, r, A, &p.VerifierParamsSet, cfg)
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ccs08
+
+import (
+    "crypto/rand"
+    "encoding/json"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+)
+
+var countries = [][]byte{[]byte("NL"), []byte("BE"), []byte("DE"), []byte("FR"), []byte("LU")}
+
+/*
+Tests that the proofs for the elements of the set are accepted, and that no proof is
+computed for other elements.
+*/
+func TestStringSet(t *testing.T) {
+    p, err := SetupStringSet(countries)
+    if err != nil {
+        t.Fatal(err)
+    }
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    for _, e := range countries {
+        proof_out, err := ProveStringSet(e, r, p)
+        if err != nil {
+            t.Fatal(err)
+        }
+        result, _ := VerifySet(&proof_out, &p.VerifierParamsSet)
+        if result != true {
+            t.Errorf("Assert failure: expected true, actual: %t", result)
+        }
+    }
+
+    if p.Contains([]byte("GB")) {
+        t.Errorf("Assert failure: GB should not belong to the set")
+    }
+    _, err = ProveStringSet([]byte("GB"), r, p)
+    if err == nil {
+        t.Errorf("Assert failure: expected error for an element outside the set")
+    }
+    // The elements are distinct from their bytes read as integers.
+    _, err = ProveStringSet([]byte("nl"), r, p)
+    if err == nil {
+        t.Errorf("Assert failure: expected error for an element outside the set")
+    }
+}
+
+/*
+Tests the proofs for a commitment to the scalar of an element.
+*/
+func TestStringSetOpening(t *testing.T) {
+    p, _ := SetupStringSet(countries)
+    C, opening, _ := p.Pedersen().CommitRandom(HashElement([]byte("DE")), rand.Reader)
+    proof_out, err := ProveStringSetOpening(opening, p)
+    if err != nil {
+        t.Fatal(err)
+    }
+    result, _ := VerifySetCommitment(&proof_out, &p.VerifierParamsSet, C)
+    if result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+
+    _, opening, _ = p.Pedersen().CommitRandom(HashElement([]byte("GB")), rand.Reader)
+    _, err = ProveStringSetOpening(opening, p)
+    if err == nil {
+        t.Errorf("Assert failure: expected error for an element outside the set")
+    }
+}
+
+/*
+Tests that the elements are returned in increasing order.
+*/
+func TestStringSetElements(t *testing.T) {
+    p, _ := SetupStringSet(countries)
+    expected := []string{"BE", "DE", "FR", "LU", "NL"}
+    elements := p.Elements()
+    if len(elements) != len(expected) {
+        t.Fatalf("Assert failure: expected %d elements, actual: %d", len(expected), len(elements))
+    }
+    for i, e := range elements {
+        if string(e) != expected[i] {
+            t.Errorf("Assert failure: expected %s, actual: %s", expected[i], e)
+        }
+    }
+}
+
+/*
+Tests the round trip of the parameters through the binary and JSON encodings, and
+that non-canonical encodings are rejected.
+*/
+func TestStringSetEncodeDecode(t *testing.T) {
+    p, _ := SetupStringSet(append(countries, []byte{}, []byte{0, 1}))
+    var decoded ParamsStringSet
+    roundTrip(t, p, &decoded)
+    for _, e := range p.Elements() {
+        if !decoded.Contains(e) {
+            t.Errorf("Assert failure: %x should belong to the set", e)
+        }
+    }
+    r, _ := rand.Int(rand.Reader, bn256.Order)
+    proof_out, err := ProveStringSet([]byte{0, 1}, r, decoded)
+    if err != nil {
+        t.Fatal(err)
+    }
+    result, _ := VerifySet(&proof_out, &p.VerifierParamsSet)
+    if result != true {
+        t.Errorf("Assert failure: expected true, actual: %t", result)
+    }
+
+    // The elements of the binary encoding must be strictly increasing.
+    single, _ := SetupStringSet([][]byte{[]byte("NL")})
+    encoded, _ := single.MarshalBinary()
+    entry := encoded[len(encoded)-(8+2+G2_SIZE):]
+    duplicated := append(append([]byte{}, encoded...), entry...)
+    duplicated[len(encoded)-len(entry)-1]++
+    if err = decoded.UnmarshalBinary(duplicated); err != ErrInvalidParams {
+        t.Errorf("Assert failure: expected %v, actual: %v", ErrInvalidParams, err)
+    }
+    if err = decoded.UnmarshalBinary(encoded[:len(encoded)-1]); err != ErrTruncatedData {
+        t.Errorf("Assert failure: expected %v, actual: %v", ErrTruncatedData, err)
+    }
+
+    // The elements of the JSON encoding must be in lowercase hexadecimal.
+    data, _ := json.Marshal(single)
+    var fields map[string]interface{}
+    json.Unmarshal(data, &fields)
+    signatures := fields["Signatures"].(map[string]interface{})
+    signatures["4E4C"] = signatures["4e4c"]
+    delete(signatures, "4e4c")
+    data, _ = json.Marshal(fields)
+    if err = json.Unmarshal(data, &decoded); err != ErrInvalidParams {
+        t.Errorf("Assert failure: expected %v, actual: %v", ErrInvalidParams, err)
+    }
+}
+
+/*
+Tests that HashElement is deterministic and separates the elements.
+*/
+func TestHashElement(t *testing.T) {
+    if HashElement([]byte("NL")).Cmp(HashElement([]byte("NL"))) != 0 {
+        t.Errorf("Assert failure: HashElement should be deterministic")
+    }
+    if HashElement([]byte("NL")).Cmp(HashElement([]byte("BE"))) == 0 {
+        t.Errorf("Assert failure: distinct elements should have distinct scalars")
+    }
+    if HashElement([]byte("NL")).Cmp(bn256.Order) >= 0 {
+        t.Errorf("Assert failure: the scalar should be reduced")
+    }
+}
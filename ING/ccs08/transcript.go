@@ -0,0 +1,110 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the messages absorbed by the Fiat-Shamir transcripts of the
+proofs. Group elements are absorbed using their Marshal encoding.
+*/
+
+package ccs08
+
+import (
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+)
+
+// Labels of the transcripts of each proof system.
+const (
+    SET_LABEL   = "zkrp ccs08 set membership"
+    UL_LABEL    = "zkrp ccs08 range proof [0,u^l)"
+    RANGE_LABEL = "zkrp ccs08 range proof"
+)
+
+/*
+domainSep absorbs the statement of the set membership proof: the parameters and the
+commitment C.
+*/
+func (p *VerifierParamsSet) domainSep(t *transcript.Transcript, C *bn256.G2) {
+    appendG2(t, "H", p.H)
+    appendG1(t, "y", p.y)
+    appendG2(t, "C", C)
+}
+
+/*
+domainSep absorbs the statement of the proof for [0,u^l): the parameters and the
+commitment C.
+*/
+func (p *VerifierParamsUL) domainSep(t *transcript.Transcript, C *bn256.G2) {
+    t.AppendMessage("dom-sep", []byte("ul v1"))
+    t.AppendUint64("u", uint64(p.u))
+    t.AppendUint64("l", uint64(p.l))
+    appendG2(t, "H", p.H)
+    appendG1(t, "y", p.y)
+    appendG2(t, "C", C)
+}
+
+/*
+challenge computes the challenge c of the set membership proof from its statement,
+the commitment C, and the first messages of the prover.
+*/
+func (proof_out *ProofSet) challenge(p *VerifierParamsSet) *big.Int {
+    t := transcript.New(SET_LABEL)
+    p.domainSep(t, proof_out.C)
+    appendG2(t, "V", proof_out.V)
+    appendGT(t, "a", proof_out.A)
+    appendG2(t, "D", proof_out.D)
+    return t.ChallengeScalar("c", bn256.Order)
+}
+
+/*
+challenge computes the challenge c of the proof for [0,u^l), continuing the
+transcript t.
+*/
+func (proof_out *ProofUL) challenge(t *transcript.Transcript, p *VerifierParamsUL) *big.Int {
+    p.domainSep(t, proof_out.C)
+    for i := range proof_out.V {
+        appendG2(t, "V", proof_out.V[i])
+        appendGT(t, "a", proof_out.A[i])
+    }
+    appendG2(t, "D", proof_out.D)
+    return t.ChallengeScalar("c", bn256.Order)
+}
+
+/*
+transcript returns the transcript shared by both proofs of the range proof, which
+absorbs the interval [a, b].
+*/
+func (p *VerifierParams) transcript() *transcript.Transcript {
+    t := transcript.New(RANGE_LABEL)
+    t.AppendUint64("a", uint64(p.a))
+    t.AppendUint64("b", uint64(p.b))
+    return t
+}
+
+func appendG1(t *transcript.Transcript, label string, g *bn256.G1) {
+    t.AppendMessage(label, g.Marshal())
+}
+
+func appendG2(t *transcript.Transcript, label string, g *bn256.G2) {
+    t.AppendMessage(label, g.Marshal())
+}
+
+func appendGT(t *transcript.Transcript, label string, g *bn256.GT) {
+    t.AppendMessage(label, g.Marshal())
+}
@@ -0,0 +1,372 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+Package crossgroup proves that two Pedersen commitments, in two groups of different
+orders, commit to the same value x in [0, 2^n). It links, for example, the
+commitment V of a Bulletproof, in secp256k1, with the commitment C of a ccs08 proof,
+in G2 of bn256, so that a verifier learns that both proofs are about the same
+attribute.
+
+Since the orders differ, a single sigma protocol can not prove the equality of the
+discrete logarithms. The prover instead commits to each bit b_i of x in both groups,
+C_i = g1^b_i.h1^r_i and D_i = g2^b_i.h2^s_i, with blinding factors such that
+prod_i C_i^(2^i) and prod_i D_i^(2^i) are the two commitments. For each bit, an OR
+proof shows that either C_i = h1^r_i and D_i = h2^s_i, or C_i/g1 = h1^r_i and
+D_i/g2 = h2^s_i, so that both commitments hold the same bit. The challenges are
+integers lower than 2^128, smaller than the orders of both groups, and the branch
+challenges of each bit sum to the challenge modulo 2^128.
+
+The commitments of the bulletproofs and ccs08 proofs are returned by their
+Commitment methods, and their Pedersen parameters by the Pedersen methods of their
+setup parameters.
+*/
+package crossgroup
+
+import (
+    "crypto/rand"
+    "errors"
+    "fmt"
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+)
+
+// CROSS_GROUP_LABEL is the label of the transcripts of the proofs.
+const CROSS_GROUP_LABEL = "zkrp cross-group equality"
+
+// MAX_BITS is the maximal bit-length of the committed values.
+const MAX_BITS = 64
+
+// CHALLENGE_BITS is the bit-length of the challenges.
+const CHALLENGE_BITS = 128
+
+var challengeModulus = new(big.Int).Lsh(big.NewInt(1), CHALLENGE_BITS)
+
+/*
+Proof contains the commitments to the bits of the value in both groups, and the OR
+proof of each bit. For bit i, E[i] is the challenge of the branch b_i = 0, and
+Z1[i] and Z2[i] are the responses of both branches in each group.
+*/
+type Proof struct {
+    C1, C2    []group.Element
+    Challenge *big.Int
+    E         []*big.Int
+    Z1, Z2    [][2]*big.Int
+}
+
+/*
+Option configures the prover.
+*/
+type Option func(*config)
+
+type config struct {
+    rand io.Reader
+}
+
+/*
+WithRand sets the random source of the prover, crypto/rand by default. The nonces are
+derived from the transcript and the openings together with 32 bytes of the random
+source. With nil, the proofs are deterministic.
+*/
+func WithRand(r io.Reader) Option {
+    return func(c *config) {
+        c.rand = r
+    }
+}
+
+/*
+Prove returns the proof that the commitments of the openings o1, computed with p1,
+and o2, computed with p2, commit to the same value of n bits.
+*/
+func Prove(n int, p1 pedersen.Params, o1 pedersen.Opening, p2 pedersen.Params, o2 pedersen.Opening, opts ...Option) (Proof, error) {
+    var proof Proof
+    cfg := config{rand: rand.Reader}
+    for _, opt := range opts {
+        opt(&cfg)
+    }
+    if err := checkParams(n, p1, p2); err != nil {
+        return proof, err
+    }
+    if o1.Group != p1.Group() || o2.Group != p2.Group() {
+        return proof, group.ErrGroupMismatch
+    }
+    x := o1.Value
+    if x.Cmp(o2.Value) != 0 {
+        return proof, errors.New("openings do not contain the same value")
+    }
+    if x.Sign() < 0 || x.BitLen() > n {
+        return proof, fmt.Errorf("value does not fit in %d bits", n)
+    }
+    C1, _ := p1.Commit(o1.Value, o1.Blinding)
+    C2, _ := p2.Commit(o2.Value, o2.Blinding)
+
+    t := transcript.New(CROSS_GROUP_LABEL)
+    domainSep(t, n, p1, C1, p2, C2)
+    builder := t.BuildRng()
+    builder.RekeyWithWitness("x", x.Bytes())
+    builder.RekeyWithWitness("r1", o1.Blinding.Bytes())
+    builder.RekeyWithWitness("r2", o2.Blinding.Bytes())
+    rng, err := builder.Finalize(cfg.rand)
+    if err != nil {
+        return proof, err
+    }
+
+    // Blinding factors of the bits, such that sum_i 2^i.r_i = r
+    r1, err := bitBlindings(rng, p1.Group(), o1.Blinding, n)
+    if err != nil {
+        return proof, err
+    }
+    r2, err := bitBlindings(rng, p2.Group(), o2.Blinding, n)
+    if err != nil {
+        return proof, err
+    }
+    proof.C1 = make([]group.Element, n)
+    proof.C2 = make([]group.Element, n)
+    for i := 0; i < n; i++ {
+        b := big.NewInt(int64(x.Bit(i)))
+        proof.C1[i] = commit(p1, b, r1[i])
+        proof.C2[i] = commit(p2, b, r2[i])
+    }
+    appendBits(t, proof.C1, proof.C2)
+
+    // First messages: the branch of the bit is computed honestly, the other one is
+    // simulated with a random challenge and random responses.
+    proof.E = make([]*big.Int, n)
+    proof.Z1 = make([][2]*big.Int, n)
+    proof.Z2 = make([][2]*big.Int, n)
+    k1 := make([]*big.Int, n)
+    k2 := make([]*big.Int, n)
+    simulated := make([]*big.Int, n)
+    for i := 0; i < n; i++ {
+        b := int(x.Bit(i))
+        var A1, A2 [2]group.Element
+        if k1[i], err = group.RandomScalar(p1.Group(), rng); err != nil {
+            return proof, err
+        }
+        if k2[i], err = group.RandomScalar(p2.Group(), rng); err != nil {
+            return proof, err
+        }
+        A1[b] = p1.H().ScalarMult(k1[i])
+        A2[b] = p2.H().ScalarMult(k2[i])
+
+        if simulated[i], err = rand.Int(rng, challengeModulus); err != nil {
+            return proof, err
+        }
+        if proof.Z1[i][1-b], err = group.RandomScalar(p1.Group(), rng); err != nil {
+            return proof, err
+        }
+        if proof.Z2[i][1-b], err = group.RandomScalar(p2.Group(), rng); err != nil {
+            return proof, err
+        }
+        A1[1-b] = firstMessage(p1, proof.C1[i], 1-b, simulated[i], proof.Z1[i][1-b])
+        A2[1-b] = firstMessage(p2, proof.C2[i], 1-b, simulated[i], proof.Z2[i][1-b])
+        appendFirstMessages(t, A1, A2)
+    }
+    proof.Challenge = challenge(t)
+
+    // Responses of the branch of each bit, whose challenge is the challenge minus
+    // the simulated one.
+    for i := 0; i < n; i++ {
+        b := int(x.Bit(i))
+        e := new(big.Int).Sub(proof.Challenge, simulated[i])
+        e.Mod(e, challengeModulus)
+        proof.Z1[i][b] = response(p1.Group(), k1[i], e, r1[i])
+        proof.Z2[i][b] = response(p2.Group(), k2[i], e, r2[i])
+        if b == 0 {
+            proof.E[i] = e
+        } else {
+            proof.E[i] = simulated[i]
+        }
+    }
+    return proof, nil
+}
+
+/*
+Verify returns true if and only if the proof shows that C1, computed with p1, and C2,
+computed with p2, commit to the same value of n bits.
+*/
+func (proof Proof) Verify(n int, p1 pedersen.Params, C1 pedersen.Commitment, p2 pedersen.Params, C2 pedersen.Commitment) (bool, error) {
+    if err := checkParams(n, p1, p2); err != nil {
+        return false, err
+    }
+    if !p1.Owns(C1) || !p2.Owns(C2) {
+        return false, group.ErrGroupMismatch
+    }
+    if err := proof.check(n, p1.Group(), p2.Group()); err != nil {
+        return false, err
+    }
+
+    // prod_i C_i^(2^i) must be the commitments
+    sum1, sum2 := p1.Group().Identity(), p2.Group().Identity()
+    for i := n - 1; i >= 0; i-- {
+        sum1 = sum1.Add(sum1).Add(proof.C1[i])
+        sum2 = sum2.Add(sum2).Add(proof.C2[i])
+    }
+    if !sum1.Equal(C1.Element()) || !sum2.Equal(C2.Element()) {
+        return false, nil
+    }
+
+    t := transcript.New(CROSS_GROUP_LABEL)
+    domainSep(t, n, p1, C1, p2, C2)
+    appendBits(t, proof.C1, proof.C2)
+    for i := 0; i < n; i++ {
+        e := [2]*big.Int{proof.E[i], new(big.Int).Sub(proof.Challenge, proof.E[i])}
+        e[1].Mod(e[1], challengeModulus)
+        var A1, A2 [2]group.Element
+        for b := 0; b < 2; b++ {
+            A1[b] = firstMessage(p1, proof.C1[i], b, e[b], proof.Z1[i][b])
+            A2[b] = firstMessage(p2, proof.C2[i], b, e[b], proof.Z2[i][b])
+        }
+        appendFirstMessages(t, A1, A2)
+    }
+    return challenge(t).Cmp(proof.Challenge) == 0, nil
+}
+
+/*
+check returns an error if the proof does not contain n bits, or if a scalar is out
+of range.
+*/
+func (proof Proof) check(n int, g1, g2 group.Group) error {
+    if len(proof.C1) != n || len(proof.C2) != n || len(proof.E) != n || len(proof.Z1) != n || len(proof.Z2) != n {
+        return errors.New("number of bits of the proof does not match")
+    }
+    if err := group.SameGroup(g1, proof.C1...); err != nil {
+        return err
+    }
+    if err := group.SameGroup(g2, proof.C2...); err != nil {
+        return err
+    }
+    if !inRange(proof.Challenge, challengeModulus) {
+        return errors.New("challenge is out of range")
+    }
+    for i := 0; i < n; i++ {
+        ok := inRange(proof.E[i], challengeModulus)
+        for b := 0; b < 2; b++ {
+            ok = ok && inRange(proof.Z1[i][b], g1.Order()) && inRange(proof.Z2[i][b], g2.Order())
+        }
+        if !ok {
+            return errors.New("scalar of the proof is out of range")
+        }
+    }
+    return nil
+}
+
+func inRange(x, bound *big.Int) bool {
+    return x != nil && x.Sign() >= 0 && x.Cmp(bound) < 0
+}
+
+/*
+checkParams returns an error if the values of n bits, or the challenges, do not fit
+in both groups.
+*/
+func checkParams(n int, p1, p2 pedersen.Params) error {
+    if n <= 0 || n > MAX_BITS {
+        return fmt.Errorf("bit-length must be between 1 and %d: %d", MAX_BITS, n)
+    }
+    if p1.Group() == nil || p2.Group() == nil {
+        return group.ErrUnknownGroup
+    }
+    return nil
+}
+
+/*
+bitBlindings returns n blinding factors r_i such that sum_i 2^i.r_i = r modulo the
+order of g.
+*/
+func bitBlindings(rng io.Reader, g group.Group, r *big.Int, n int) ([]*big.Int, error) {
+    order := g.Order()
+    result := make([]*big.Int, n)
+    last := new(big.Int).Set(r)
+    for i := 0; i < n-1; i++ {
+        ri, err := group.RandomScalar(g, rng)
+        if err != nil {
+            return nil, err
+        }
+        result[i] = ri
+        last.Sub(last, new(big.Int).Lsh(ri, uint(i)))
+    }
+    inv := new(big.Int).ModInverse(new(big.Int).Lsh(big.NewInt(1), uint(n-1)), order)
+    last.Mul(last, inv)
+    result[n-1] = last.Mod(last, order)
+    return result, nil
+}
+
+func commit(p pedersen.Params, b, r *big.Int) group.Element {
+    C, _ := p.Commit(b, r)
+    return C.Element()
+}
+
+/*
+firstMessage returns h^z.(C/g^b)^-e, the first message for which z answers the
+challenge e in the branch b.
+*/
+func firstMessage(p pedersen.Params, C group.Element, b int, e, z *big.Int) group.Element {
+    Y := C
+    if b == 1 {
+        Y = C.Add(p.G().Neg())
+    }
+    return p.H().ScalarMult(z).Add(Y.ScalarMult(new(big.Int).Neg(e)))
+}
+
+/*
+response returns k + e.r modulo the order of g.
+*/
+func response(g group.Group, k, e, r *big.Int) *big.Int {
+    z := new(big.Int).Mul(e, r)
+    z.Add(z, k)
+    return z.Mod(z, g.Order())
+}
+
+/*
+domainSep absorbs the statement: the bit-length, the generators and the commitments.
+*/
+func domainSep(t *transcript.Transcript, n int, p1 pedersen.Params, C1 pedersen.Commitment, p2 pedersen.Params, C2 pedersen.Commitment) {
+    t.AppendMessage("dom-sep", []byte("cross-group equality v1"))
+    t.AppendUint64("n", uint64(n))
+    for _, p := range []pedersen.Params{p1, p2} {
+        t.AppendMessage("group", []byte(p.Group().Name()))
+        t.AppendMessage("g", p.G().Bytes())
+        t.AppendMessage("h", p.H().Bytes())
+    }
+    t.AppendMessage("C1", C1.Element().Bytes())
+    t.AppendMessage("C2", C2.Element().Bytes())
+}
+
+func appendBits(t *transcript.Transcript, C1, C2 []group.Element) {
+    for i := range C1 {
+        t.AppendMessage("C1i", C1[i].Bytes())
+        t.AppendMessage("C2i", C2[i].Bytes())
+    }
+}
+
+func appendFirstMessages(t *transcript.Transcript, A1, A2 [2]group.Element) {
+    for b := 0; b < 2; b++ {
+        t.AppendMessage("A1", A1[b].Bytes())
+        t.AppendMessage("A2", A2[b].Bytes())
+    }
+}
+
+/*
+challenge returns a challenge lower than 2^CHALLENGE_BITS.
+*/
+func challenge(t *transcript.Transcript) *big.Int {
+    return new(big.Int).SetBytes(t.ChallengeBytes("c", CHALLENGE_BITS/8))
+}
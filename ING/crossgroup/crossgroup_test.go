@@ -0,0 +1,213 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package crossgroup
+
+import (
+    "encoding/json"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/bulletproofs"
+    "github.com/ing-bank/zkrp/ccs08"
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
+    "github.com/stretchr/testify/assert"
+)
+
+func randomParams(t *testing.T, g group.Group) pedersen.Params {
+    x, err := group.RandomScalar(g, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return pedersen.NewParams(g.Generator().ScalarMult(x))
+}
+
+func commitBoth(t *testing.T, x int64, p1, p2 pedersen.Params) (pedersen.Commitment, pedersen.Opening, pedersen.Commitment, pedersen.Opening) {
+    C1, o1, err := p1.CommitRandom(big.NewInt(x), nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    C2, o2, err := p2.CommitRandom(big.NewInt(x), nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return C1, o1, C2, o2
+}
+
+func TestCrossGroupEquality(t *testing.T) {
+    p1 := randomParams(t, group.P256)
+    p2 := randomParams(t, group.BN256G2)
+    for _, x := range []int64{0, 1, 42, 1<<32 - 1} {
+        C1, o1, C2, o2 := commitBoth(t, x, p1, p2)
+        proof, err := Prove(32, p1, o1, p2, o2)
+        assert.Nil(t, err, "should prove equal values")
+        ok, err := proof.Verify(32, p1, C1, p2, C2)
+        assert.Nil(t, err)
+        assert.True(t, ok, "should verify equal values")
+    }
+}
+
+func TestCrossGroupBulletproofsCCS08(t *testing.T) {
+    bpParams, err := bulletproofs.Setup(bulletproofs.MAX_RANGE_END)
+    assert.Nil(t, err)
+    setParams, err := ccs08.SetupSet([]int64{12, 42, 61, 71})
+    assert.Nil(t, err)
+    ulParams, err := ccs08.SetupUL(16, 8)
+    assert.Nil(t, err)
+
+    _, o1, err := bpParams.Pedersen().CommitRandom(big.NewInt(42), nil)
+    assert.Nil(t, err)
+    _, o2, err := setParams.Pedersen().CommitRandom(big.NewInt(42), nil)
+    assert.Nil(t, err)
+    _, o3, err := ulParams.Pedersen().CommitRandom(big.NewInt(42), nil)
+    assert.Nil(t, err)
+
+    bp, err := bulletproofs.ProveOpening(o1, bpParams)
+    assert.Nil(t, err)
+    set, err := ccs08.ProveSetOpening(o2, setParams)
+    assert.Nil(t, err)
+    ul, err := ccs08.ProveULOpening(o3, ulParams)
+    assert.Nil(t, err)
+
+    // The commitment V of the bulletproof and C of the set membership proof
+    link, err := Prove(32, bpParams.Pedersen(), o1, setParams.Pedersen(), o2)
+    assert.Nil(t, err)
+    ok, err := link.Verify(32, bpParams.Pedersen(), bp.Commitment(), setParams.Pedersen(), set.Commitment())
+    assert.Nil(t, err)
+    assert.True(t, ok, "should link the bulletproof and the set membership proof")
+
+    // The commitment C of the range proof
+    link, err = Prove(32, bpParams.Pedersen(), o1, ulParams.Pedersen(), o3)
+    assert.Nil(t, err)
+    ok, err = link.Verify(32, bpParams.Pedersen(), bp.Commitment(), ulParams.Pedersen(), ul.Commitment())
+    assert.Nil(t, err)
+    assert.True(t, ok, "should link the bulletproof and the range proof")
+
+    // A set membership proof for another commitment to the same value is not linked
+    set, err = ccs08.ProveSet(42, big.NewInt(7), setParams)
+    assert.Nil(t, err)
+    ok, _ = link.Verify(32, bpParams.Pedersen(), bp.Commitment(), setParams.Pedersen(), set.Commitment())
+    assert.False(t, ok, "should not link another commitment")
+}
+
+func TestCrossGroupDifferentValues(t *testing.T) {
+    p1 := randomParams(t, group.P256)
+    p2 := randomParams(t, group.BN256G2)
+    C1, o1, _, _ := commitBoth(t, 42, p1, p2)
+    _, _, C2, o2 := commitBoth(t, 43, p1, p2)
+    _, err := Prove(32, p1, o1, p2, o2)
+    assert.NotNil(t, err, "should not prove different values")
+
+    // Proof for 42 in both groups, checked against a commitment to 43
+    _, _, C3, o3 := commitBoth(t, 42, p1, p2)
+    proof, err := Prove(32, p1, o1, p2, o3)
+    assert.Nil(t, err)
+    ok, _ := proof.Verify(32, p1, C1, p2, C2)
+    assert.False(t, ok, "should not verify different values")
+    ok, _ = proof.Verify(32, p1, C1, p2, C3)
+    assert.True(t, ok)
+}
+
+func TestCrossGroupBounds(t *testing.T) {
+    p1 := randomParams(t, group.P256)
+    p2 := randomParams(t, group.BN256G1)
+    _, o1, _, o2 := commitBoth(t, 256, p1, p2)
+    _, err := Prove(8, p1, o1, p2, o2)
+    assert.NotNil(t, err, "should not prove a value greater than 2^n")
+    _, err = Prove(MAX_BITS+1, p1, o1, p2, o2)
+    assert.NotNil(t, err, "should not accept more than MAX_BITS bits")
+
+    C1, o1, C2, o2 := commitBoth(t, 255, p1, p2)
+    proof, err := Prove(8, p1, o1, p2, o2)
+    assert.Nil(t, err)
+    ok, err := proof.Verify(16, p1, C1, p2, C2)
+    assert.False(t, ok, "should not verify another bit-length")
+    assert.NotNil(t, err)
+    _, err = proof.Verify(8, p2, C2, p1, C1)
+    assert.NotNil(t, err, "should not verify with swapped groups")
+}
+
+func TestCrossGroupTampered(t *testing.T) {
+    p1 := randomParams(t, group.P256)
+    p2 := randomParams(t, group.BN256G2)
+    C1, o1, C2, o2 := commitBoth(t, 5, p1, p2)
+    proof, err := Prove(4, p1, o1, p2, o2)
+    assert.Nil(t, err)
+
+    tampered := proof
+    tampered.E = append([]*big.Int{}, proof.E...)
+    tampered.E[0] = new(big.Int).Add(proof.E[0], big.NewInt(1))
+    tampered.E[0].Mod(tampered.E[0], challengeModulus)
+    ok, _ := tampered.Verify(4, p1, C1, p2, C2)
+    assert.False(t, ok, "should not verify a tampered challenge")
+
+    tampered = proof
+    tampered.Z2 = append([][2]*big.Int{}, proof.Z2...)
+    tampered.Z2[1] = [2]*big.Int{proof.Z2[1][1], proof.Z2[1][0]}
+    ok, _ = tampered.Verify(4, p1, C1, p2, C2)
+    assert.False(t, ok, "should not verify tampered responses")
+
+    // Swapping two bit commitments keeps the products in neither group
+    tampered = proof
+    tampered.C1 = append(proof.C1[:0:0], proof.C1...)
+    tampered.C1[0], tampered.C1[1] = proof.C1[1], proof.C1[0]
+    ok, _ = tampered.Verify(4, p1, C1, p2, C2)
+    assert.False(t, ok, "should not verify swapped bit commitments")
+
+    tampered = proof
+    tampered.E = append([]*big.Int{}, proof.E...)
+    tampered.E[0] = new(big.Int).Set(challengeModulus)
+    _, err = tampered.Verify(4, p1, C1, p2, C2)
+    assert.NotNil(t, err, "should reject a challenge out of range")
+}
+
+func TestCrossGroupDeterministic(t *testing.T) {
+    p1 := randomParams(t, group.P256)
+    p2 := randomParams(t, group.BN256G2)
+    _, o1, _, o2 := commitBoth(t, 9, p1, p2)
+    proof1, err := Prove(8, p1, o1, p2, o2, WithRand(nil))
+    assert.Nil(t, err)
+    proof2, err := Prove(8, p1, o1, p2, o2, WithRand(nil))
+    assert.Nil(t, err)
+    assert.Equal(t, 0, proof1.Challenge.Cmp(proof2.Challenge), "should be deterministic without a random source")
+    proof3, err := Prove(8, p1, o1, p2, o2)
+    assert.Nil(t, err)
+    assert.NotEqual(t, 0, proof1.Challenge.Cmp(proof3.Challenge), "should be randomized by default")
+}
+
+func TestCrossGroupJSON(t *testing.T) {
+    p1 := randomParams(t, group.P256)
+    p2 := randomParams(t, group.BN256G2)
+    C1, o1, C2, o2 := commitBoth(t, 100, p1, p2)
+    proof, err := Prove(8, p1, o1, p2, o2)
+    assert.Nil(t, err)
+    encoded, err := json.Marshal(proof)
+    assert.Nil(t, err)
+
+    var decoded Proof
+    assert.Nil(t, json.Unmarshal(encoded, &decoded))
+    ok, err := decoded.Verify(8, p1, C1, p2, C2)
+    assert.Nil(t, err)
+    assert.True(t, ok, "should verify the decoded proof")
+
+    var raw map[string]interface{}
+    assert.Nil(t, json.Unmarshal(encoded, &raw))
+    raw["Version"] = 2
+    encoded, _ = json.Marshal(raw)
+    assert.Equal(t, ErrUnsupportedVersion, json.Unmarshal(encoded, &decoded))
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the JSON encoding of the proofs. The groups are identified by their
+names, the points by the hexadecimal string of their encoding, and the scalars by
+hexadecimal strings of 32 bytes in big-endian order.
+*/
+
+package crossgroup
+
+import (
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+)
+
+// ENCODING_VERSION is the version of the JSON encoding of the proofs.
+const ENCODING_VERSION = 1
+
+// SCALAR_SIZE is the size in bytes of an encoded scalar.
+const SCALAR_SIZE = 32
+
+var (
+    ErrUnsupportedVersion = errors.New("unsupported proof encoding version")
+    ErrInvalidEncoding    = errors.New("invalid encoding of the proof")
+)
+
+type proofJSON struct {
+    Version   int
+    Group1    string
+    Group2    string
+    C1, C2    []string
+    Challenge string
+    E         []string
+    Z1, Z2    [][2]string
+}
+
+/*
+MarshalJSON returns the JSON encoding of the proof.
+*/
+func (proof Proof) MarshalJSON() ([]byte, error) {
+    n := len(proof.C1)
+    if n == 0 {
+        return nil, ErrInvalidEncoding
+    }
+    if err := proof.check(n, proof.C1[0].Group(), proof.C2[0].Group()); err != nil {
+        return nil, err
+    }
+    encoded := proofJSON{
+        Version:   ENCODING_VERSION,
+        Group1:    proof.C1[0].Group().Name(),
+        Group2:    proof.C2[0].Group().Name(),
+        C1:        make([]string, n),
+        C2:        make([]string, n),
+        Challenge: encodeScalar(proof.Challenge),
+        E:         make([]string, n),
+        Z1:        make([][2]string, n),
+        Z2:        make([][2]string, n),
+    }
+    for i := 0; i < n; i++ {
+        encoded.C1[i] = hex.EncodeToString(proof.C1[i].Bytes())
+        encoded.C2[i] = hex.EncodeToString(proof.C2[i].Bytes())
+        encoded.E[i] = encodeScalar(proof.E[i])
+        for b := 0; b < 2; b++ {
+            encoded.Z1[i][b] = encodeScalar(proof.Z1[i][b])
+            encoded.Z2[i][b] = encodeScalar(proof.Z2[i][b])
+        }
+    }
+    return json.Marshal(encoded)
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of a proof. The bit-length and the groups are
+checked against the statement by Verify.
+*/
+func (proof *Proof) UnmarshalJSON(data []byte) error {
+    var decoded proofJSON
+    if err := json.Unmarshal(data, &decoded); err != nil {
+        return err
+    }
+    if decoded.Version != ENCODING_VERSION {
+        return ErrUnsupportedVersion
+    }
+    g1, err := group.ByName(decoded.Group1)
+    if err != nil {
+        return err
+    }
+    g2, err := group.ByName(decoded.Group2)
+    if err != nil {
+        return err
+    }
+    n := len(decoded.C1)
+    if n == 0 || len(decoded.C2) != n || len(decoded.E) != n || len(decoded.Z1) != n || len(decoded.Z2) != n {
+        return ErrInvalidEncoding
+    }
+    var result Proof
+    if result.Challenge, err = decodeScalar(decoded.Challenge); err != nil {
+        return err
+    }
+    result.C1 = make([]group.Element, n)
+    result.C2 = make([]group.Element, n)
+    result.E = make([]*big.Int, n)
+    result.Z1 = make([][2]*big.Int, n)
+    result.Z2 = make([][2]*big.Int, n)
+    for i := 0; i < n; i++ {
+        if result.C1[i], err = decodePoint(g1, decoded.C1[i]); err != nil {
+            return err
+        }
+        if result.C2[i], err = decodePoint(g2, decoded.C2[i]); err != nil {
+            return err
+        }
+        if result.E[i], err = decodeScalar(decoded.E[i]); err != nil {
+            return err
+        }
+        for b := 0; b < 2; b++ {
+            if result.Z1[i][b], err = decodeScalar(decoded.Z1[i][b]); err != nil {
+                return err
+            }
+            if result.Z2[i][b], err = decodeScalar(decoded.Z2[i][b]); err != nil {
+                return err
+            }
+        }
+    }
+    *proof = result
+    return nil
+}
+
+func encodeScalar(x *big.Int) string {
+    b := x.Bytes()
+    result := make([]byte, SCALAR_SIZE)
+    copy(result[SCALAR_SIZE-len(b):], b)
+    return hex.EncodeToString(result)
+}
+
+func decodeScalar(s string) (*big.Int, error) {
+    b, err := hex.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    if len(b) != SCALAR_SIZE {
+        return nil, ErrInvalidEncoding
+    }
+    return new(big.Int).SetBytes(b), nil
+}
+
+func decodePoint(g group.Group, s string) (group.Element, error) {
+    b, err := hex.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    return g.Decode(b)
+}
@@ -27,6 +27,7 @@ Eurocrypt 2004
 import (
     "crypto/rand"
     "errors"
+    "io"
     "math/big"
 
     "github.com/ing-bank/zkrp/crypto/bn256"
@@ -42,20 +43,28 @@ type Keypair struct {
 keygen is responsible for the key generation.
 */
 func Keygen() (Keypair, error) {
+    return KeygenWithReader(rand.Reader)
+}
+
+/*
+KeygenWithReader generates the key pair reading the private key from r. Errors of r
+are returned.
+*/
+func KeygenWithReader(r io.Reader) (Keypair, error) {
     var (
         kp  Keypair
         e   error
         res bool
     )
-    kp.Privk, e = rand.Int(rand.Reader, bn256.Order)
+    kp.Privk, e = rand.Int(r, bn256.Order)
     if e != nil {
         return kp, e
     }
     kp.Pubk, res = new(bn256.G1).Unmarshal(new(bn256.G1).ScalarBaseMult(kp.Privk).Marshal())
-    if res {
+    if !res {
         return kp, errors.New("Could not compute scalar multiplication.")
     }
-    return kp, e
+    return kp, nil
 }
 
 /*
@@ -19,6 +19,7 @@ package bbsignatures
 
 import (
     "math/big"
+    "strings"
     "testing"
 )
 
@@ -31,3 +32,20 @@ func TestKeyGen(t *testing.T) {
         t.Fail()
     }
 }
+
+func TestKeygenWithReader(t *testing.T) {
+    kp, err := Keygen()
+    if err != nil {
+        t.Errorf("Unexpected error: %s", err)
+    }
+    seed := strings.Repeat("k", 64)
+    kp1, _ := KeygenWithReader(strings.NewReader(seed))
+    kp2, _ := KeygenWithReader(strings.NewReader(seed))
+    if kp1.Privk.Cmp(kp2.Privk) != 0 || kp1.Privk.Cmp(kp.Privk) == 0 {
+        t.Errorf("Assert failure: the key should only depend on the reader")
+    }
+    _, err = KeygenWithReader(strings.NewReader("short"))
+    if err == nil {
+        t.Errorf("Assert failure: errors of the reader should be returned")
+    }
+}
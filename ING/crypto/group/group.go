@@ -0,0 +1,353 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+Package group abstracts the groups of prime order used by the proofs: secp256k1,
+from the package p256, and the groups G1 and G2 of the bn256 pairing. The elements
+wrap the points of these packages, so that the protocols built on top of them do
+not depend on the curve.
+*/
+package group
+
+import (
+    "crypto/rand"
+    "errors"
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+var (
+    ErrUnknownGroup  = errors.New("unknown group")
+    ErrInvalidPoint  = errors.New("invalid encoding of group element")
+    ErrGroupMismatch = errors.New("elements belong to different groups")
+)
+
+/*
+Group is a group of prime order. The implementations are P256, BN256G1 and BN256G2.
+*/
+type Group interface {
+    // Name returns the name of the group, used by the JSON encodings.
+    Name() string
+    // ID returns the identifier of the group, used by the binary encodings.
+    ID() byte
+    // Order returns the order of the group.
+    Order() *big.Int
+    // Generator returns the standard generator of the group.
+    Generator() Element
+    // Identity returns the neutral element of the group.
+    Identity() Element
+    // Decode returns the element encoded by Bytes.
+    Decode(b []byte) (Element, error)
+}
+
+/*
+Element is an element of a Group. The operations never modify their operands, and
+the operands must belong to the same group.
+*/
+type Element interface {
+    Group() Group
+    Add(b Element) Element
+    Neg() Element
+    ScalarMult(k *big.Int) Element
+    Equal(b Element) bool
+    // Bytes returns the canonical encoding of the element.
+    Bytes() []byte
+}
+
+var (
+    // P256 is the secp256k1 curve, used by Bulletproofs.
+    P256 Group = p256Group{}
+    // BN256G1 is the group G1 of the bn256 pairing.
+    BN256G1 Group = g1Group{}
+    // BN256G2 is the group G2 of the bn256 pairing, used by ccs08.
+    BN256G2 Group = g2Group{}
+)
+
+/*
+ByID returns the group with identifier id.
+*/
+func ByID(id byte) (Group, error) {
+    for _, g := range []Group{P256, BN256G1, BN256G2} {
+        if g.ID() == id {
+            return g, nil
+        }
+    }
+    return nil, ErrUnknownGroup
+}
+
+/*
+ByName returns the group with the given name.
+*/
+func ByName(name string) (Group, error) {
+    for _, g := range []Group{P256, BN256G1, BN256G2} {
+        if g.Name() == name {
+            return g, nil
+        }
+    }
+    return nil, ErrUnknownGroup
+}
+
+/*
+RandomScalar returns a scalar read from r, lower than the order of g. If r is nil,
+crypto/rand is used.
+*/
+func RandomScalar(g Group, r io.Reader) (*big.Int, error) {
+    if r == nil {
+        r = rand.Reader
+    }
+    return rand.Int(r, g.Order())
+}
+
+/*
+NewP256 returns the element of secp256k1 represented by p.
+*/
+func NewP256(p *p256.P256) Element {
+    return p256Element{p}
+}
+
+/*
+NewG1 returns the element of G1 represented by p.
+*/
+func NewG1(p *bn256.G1) Element {
+    return g1Element{p}
+}
+
+/*
+NewG2 returns the element of G2 represented by p.
+*/
+func NewG2(p *bn256.G2) Element {
+    return g2Element{p}
+}
+
+/*
+ToP256 returns the point of secp256k1 represented by e.
+*/
+func ToP256(e Element) (*p256.P256, error) {
+    p, ok := e.(p256Element)
+    if !ok {
+        return nil, ErrGroupMismatch
+    }
+    return p.p, nil
+}
+
+/*
+ToG1 returns the point of G1 represented by e.
+*/
+func ToG1(e Element) (*bn256.G1, error) {
+    p, ok := e.(g1Element)
+    if !ok {
+        return nil, ErrGroupMismatch
+    }
+    return p.p, nil
+}
+
+/*
+ToG2 returns the point of G2 represented by e.
+*/
+func ToG2(e Element) (*bn256.G2, error) {
+    p, ok := e.(g2Element)
+    if !ok {
+        return nil, ErrGroupMismatch
+    }
+    return p.p, nil
+}
+
+/*
+SameGroup returns nil if all the elements belong to g.
+*/
+func SameGroup(g Group, elements ...Element) error {
+    for _, e := range elements {
+        if e == nil || g == nil || e.Group() != g {
+            return ErrGroupMismatch
+        }
+    }
+    return nil
+}
+
+// Identifiers of the groups in the binary encodings.
+const (
+    idP256    = 1
+    idBN256G1 = 2
+    idBN256G2 = 3
+)
+
+type p256Group struct{}
+
+func (p256Group) Name() string    { return "secp256k1" }
+func (p256Group) ID() byte        { return idP256 }
+func (p256Group) Order() *big.Int { return p256.CURVE.N }
+
+func (p256Group) Generator() Element {
+    return p256Element{new(p256.P256).ScalarBaseMult(big.NewInt(1))}
+}
+
+func (p256Group) Identity() Element {
+    return p256Element{new(p256.P256).SetInfinity()}
+}
+
+func (p256Group) Decode(b []byte) (Element, error) {
+    p, err := new(p256.P256).SetBytes(b)
+    if err != nil {
+        return nil, err
+    }
+    return p256Element{p}, nil
+}
+
+type p256Element struct {
+    p *p256.P256
+}
+
+func (a p256Element) Group() Group {
+    return P256
+}
+
+func (a p256Element) Add(b Element) Element {
+    return p256Element{new(p256.P256).Multiply(a.p, b.(p256Element).p)}
+}
+
+func (a p256Element) Neg() Element {
+    return a.ScalarMult(big.NewInt(-1))
+}
+
+func (a p256Element) ScalarMult(k *big.Int) Element {
+    return p256Element{new(p256.P256).ScalarMult(a.p, new(big.Int).Mod(k, p256.CURVE.N))}
+}
+
+func (a p256Element) Equal(b Element) bool {
+    return b != nil && b.Group() == P256 && string(a.Bytes()) == string(b.Bytes())
+}
+
+func (a p256Element) Bytes() []byte {
+    return a.p.Bytes()
+}
+
+type g1Group struct{}
+
+func (g1Group) Name() string    { return "bn256-g1" }
+func (g1Group) ID() byte        { return idBN256G1 }
+func (g1Group) Order() *big.Int { return bn256.Order }
+
+func (g1Group) Generator() Element {
+    return g1Element{new(bn256.G1).ScalarBaseMult(big.NewInt(1))}
+}
+
+func (g1Group) Identity() Element {
+    return g1Element{new(bn256.G1).SetInfinity()}
+}
+
+func (g1Group) Decode(b []byte) (Element, error) {
+    p, ok := new(bn256.G1).Unmarshal(b)
+    if !ok {
+        return nil, ErrInvalidPoint
+    }
+    return g1Element{p}, nil
+}
+
+type g1Element struct {
+    p *bn256.G1
+}
+
+func (a g1Element) Group() Group {
+    return BN256G1
+}
+
+func (a g1Element) Add(b Element) Element {
+    return g1Element{new(bn256.G1).Add(a.p, b.(g1Element).p)}
+}
+
+func (a g1Element) Neg() Element {
+    return g1Element{new(bn256.G1).Neg(a.p)}
+}
+
+func (a g1Element) ScalarMult(k *big.Int) Element {
+    return g1Element{new(bn256.G1).ScalarMult(a.p, new(big.Int).Mod(k, bn256.Order))}
+}
+
+func (a g1Element) Equal(b Element) bool {
+    return b != nil && b.Group() == BN256G1 && string(a.Bytes()) == string(b.Bytes())
+}
+
+/*
+Bytes returns the encoding of bn256, except for the point at infinity which is
+encoded as zeros. The point is copied, since the encoding normalizes it.
+*/
+func (a g1Element) Bytes() []byte {
+    if a.p.IsZero() {
+        return make([]byte, 64)
+    }
+    return new(bn256.G1).Add(a.p, new(bn256.G1).SetInfinity()).Marshal()
+}
+
+type g2Group struct{}
+
+func (g2Group) Name() string    { return "bn256-g2" }
+func (g2Group) ID() byte        { return idBN256G2 }
+func (g2Group) Order() *big.Int { return bn256.Order }
+
+func (g2Group) Generator() Element {
+    return g2Element{new(bn256.G2).ScalarBaseMult(big.NewInt(1))}
+}
+
+func (g2Group) Identity() Element {
+    return g2Element{new(bn256.G2).SetInfinity()}
+}
+
+func (g2Group) Decode(b []byte) (Element, error) {
+    p, ok := new(bn256.G2).Unmarshal(b)
+    if !ok {
+        return nil, ErrInvalidPoint
+    }
+    return g2Element{p}, nil
+}
+
+type g2Element struct {
+    p *bn256.G2
+}
+
+func (a g2Element) Group() Group {
+    return BN256G2
+}
+
+func (a g2Element) Add(b Element) Element {
+    return g2Element{new(bn256.G2).Add(a.p, b.(g2Element).p)}
+}
+
+func (a g2Element) Neg() Element {
+    return g2Element{new(bn256.G2).Neg(a.p)}
+}
+
+func (a g2Element) ScalarMult(k *big.Int) Element {
+    return g2Element{new(bn256.G2).ScalarMult(a.p, new(big.Int).Mod(k, bn256.Order))}
+}
+
+func (a g2Element) Equal(b Element) bool {
+    return b != nil && b.Group() == BN256G2 && string(a.Bytes()) == string(b.Bytes())
+}
+
+/*
+Bytes returns the encoding of bn256, except for the point at infinity which is
+encoded as zeros. The point is copied, since the encoding normalizes it.
+*/
+func (a g2Element) Bytes() []byte {
+    if a.p.IsZero() {
+        return make([]byte, 128)
+    }
+    return new(bn256.G2).Add(a.p, new(bn256.G2).SetInfinity()).Marshal()
+}
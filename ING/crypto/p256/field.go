@@ -0,0 +1,193 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+/*
+This file contains the arithmetic of the base field of secp256k1, used by the
+multi-scalar multiplication. The prime has the special form p = 2^256 - c, with c
+of 33 bits, so the reduction of a product only needs multiplications by c instead of
+a division.
+*/
+
+import (
+    "math/big"
+    "math/bits"
+)
+
+// fieldC is 2^256 - p.
+const fieldC = 0x1000003D1
+
+// fieldP contains the limbs of p, the least significant first.
+var fieldP = fieldElement{0xFFFFFFFEFFFFFC2F, 0xFFFFFFFFFFFFFFFF, 0xFFFFFFFFFFFFFFFF, 0xFFFFFFFFFFFFFFFF}
+
+/*
+fieldElement is an integer modulo p, stored as 4 limbs of 64 bits, the least
+significant first. Every operation returns a fully reduced element, lower than p.
+*/
+type fieldElement [4]uint64
+
+/*
+newFieldElement converts x, which must be in [0, p), to a field element.
+*/
+func newFieldElement(x *big.Int) fieldElement {
+    var (
+        e fieldElement
+        b [32]byte
+    )
+    // big.Int.FillBytes requires Go 1.15, so the bytes are padded to the left here
+    xb := x.Bytes()
+    copy(b[32-len(xb):], xb)
+    for i := 0; i < 4; i++ {
+        for j := 0; j < 8; j++ {
+            e[i] |= uint64(b[31-8*i-j]) << uint(8*j)
+        }
+    }
+    return e
+}
+
+/*
+big returns the field element as a big integer.
+*/
+func (e *fieldElement) big() *big.Int {
+    var b [32]byte
+    for i := 0; i < 4; i++ {
+        for j := 0; j < 8; j++ {
+            b[31-8*i-j] = byte(e[i] >> uint(8*j))
+        }
+    }
+    return new(big.Int).SetBytes(b[:])
+}
+
+func (e *fieldElement) isZero() bool {
+    return e[0]|e[1]|e[2]|e[3] == 0
+}
+
+/*
+fieldAdd sets r = a + b mod p.
+*/
+func fieldAdd(r, a, b *fieldElement) {
+    s0, c := bits.Add64(a[0], b[0], 0)
+    s1, c := bits.Add64(a[1], b[1], c)
+    s2, c := bits.Add64(a[2], b[2], c)
+    s3, c := bits.Add64(a[3], b[3], c)
+    // s - p = s + c mod 2^256. It is the result if a + b overflows 2^256, or if
+    // adding c overflows, i.e. if s is greater than or equal to p.
+    u0, d := bits.Add64(s0, fieldC, 0)
+    u1, d := bits.Add64(s1, 0, d)
+    u2, d := bits.Add64(s2, 0, d)
+    u3, d := bits.Add64(s3, 0, d)
+    if c|d != 0 {
+        r[0], r[1], r[2], r[3] = u0, u1, u2, u3
+    } else {
+        r[0], r[1], r[2], r[3] = s0, s1, s2, s3
+    }
+}
+
+/*
+fieldSub sets r = a - b mod p.
+*/
+func fieldSub(r, a, b *fieldElement) {
+    s0, c := bits.Sub64(a[0], b[0], 0)
+    s1, c := bits.Sub64(a[1], b[1], c)
+    s2, c := bits.Sub64(a[2], b[2], c)
+    s3, c := bits.Sub64(a[3], b[3], c)
+    // On borrow add p, i.e. subtract c modulo 2^256. The result can not borrow again.
+    s0, c = bits.Sub64(s0, c*fieldC, 0)
+    s1, c = bits.Sub64(s1, 0, c)
+    s2, c = bits.Sub64(s2, 0, c)
+    s3, _ = bits.Sub64(s3, 0, c)
+    r[0], r[1], r[2], r[3] = s0, s1, s2, s3
+}
+
+/*
+mulAdd returns a.b + c + d as two limbs, the most significant first.
+*/
+func mulAdd(a, b, c, d uint64) (uint64, uint64) {
+    hi, lo := bits.Mul64(a, b)
+    var carry uint64
+    lo, carry = bits.Add64(lo, c, 0)
+    hi += carry
+    lo, carry = bits.Add64(lo, d, 0)
+    hi += carry
+    return hi, lo
+}
+
+/*
+fieldMul sets r = a.b mod p.
+*/
+func fieldMul(r, a, b *fieldElement) {
+    // 512 bits product t, computed row by row
+    c, t0 := mulAdd(a[0], b[0], 0, 0)
+    c, t1 := mulAdd(a[0], b[1], 0, c)
+    c, t2 := mulAdd(a[0], b[2], 0, c)
+    c, t3 := mulAdd(a[0], b[3], 0, c)
+    t4 := c
+
+    c, t1 = mulAdd(a[1], b[0], t1, 0)
+    c, t2 = mulAdd(a[1], b[1], t2, c)
+    c, t3 = mulAdd(a[1], b[2], t3, c)
+    c, t4 = mulAdd(a[1], b[3], t4, c)
+    t5 := c
+
+    c, t2 = mulAdd(a[2], b[0], t2, 0)
+    c, t3 = mulAdd(a[2], b[1], t3, c)
+    c, t4 = mulAdd(a[2], b[2], t4, c)
+    c, t5 = mulAdd(a[2], b[3], t5, c)
+    t6 := c
+
+    c, t3 = mulAdd(a[3], b[0], t3, 0)
+    c, t4 = mulAdd(a[3], b[1], t4, c)
+    c, t5 = mulAdd(a[3], b[2], t5, c)
+    c, t6 = mulAdd(a[3], b[3], t6, c)
+    t7 := c
+
+    // Writing t = h.2^256 + l, t = l + h.c mod p.
+    c, s0 := mulAdd(t4, fieldC, t0, 0)
+    c, s1 := mulAdd(t5, fieldC, t1, c)
+    c, s2 := mulAdd(t6, fieldC, t2, c)
+    c, s3 := mulAdd(t7, fieldC, t3, c)
+    // c has at most 34 bits, fold it once more.
+    hi, lo := bits.Mul64(c, fieldC)
+    s0, c = bits.Add64(s0, lo, 0)
+    s1, c = bits.Add64(s1, hi, c)
+    s2, c = bits.Add64(s2, 0, c)
+    s3, c = bits.Add64(s3, 0, c)
+    // If it overflows again the value is small, and adding c can not overflow.
+    s0, c = bits.Add64(s0, c*fieldC, 0)
+    s1, c = bits.Add64(s1, 0, c)
+    s2, c = bits.Add64(s2, 0, c)
+    s3, _ = bits.Add64(s3, 0, c)
+
+    // Subtract p if s is greater than or equal to p, see fieldAdd.
+    u0, d := bits.Add64(s0, fieldC, 0)
+    u1, d := bits.Add64(s1, 0, d)
+    u2, d := bits.Add64(s2, 0, d)
+    u3, d := bits.Add64(s3, 0, d)
+    if d != 0 {
+        r[0], r[1], r[2], r[3] = u0, u1, u2, u3
+    } else {
+        r[0], r[1], r[2], r[3] = s0, s1, s2, s3
+    }
+}
+
+/*
+fieldSquare sets r = a^2 mod p.
+*/
+func fieldSquare(r, a *fieldElement) {
+    fieldMul(r, a, a)
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+/*
+This file contains the fixed-base scalar multiplication. For a point P that is used
+as base many times, such as the generators of the commitments, the multiples
+d.(2^w)^i.P are computed once for every digit d < 2^w and every window i. A scalar
+multiplication then costs one mixed addition per window and no doubling.
+
+The tables are kept in a process-wide store, together with the generators derived
+by MapToGroup, so that they are computed once per process and shared by all the
+goroutines.
+*/
+
+import (
+    "errors"
+    "fmt"
+    "math/big"
+    "sync"
+)
+
+// FIXED_BASE_WINDOW is the default number of bits of the scalar processed per lookup.
+const FIXED_BASE_WINDOW = 4
+
+// MAX_FIXED_BASE_WINDOW is the largest window, whose table takes about 512 KB.
+const MAX_FIXED_BASE_WINDOW = 8
+
+/*
+FixedBaseTable contains the multiples of a point used for fixed-base scalar
+multiplications. It is never modified after its creation, so it can be used
+concurrently.
+*/
+type FixedBaseTable struct {
+    window int
+    digits int
+    // multiples[i*digits + d - 1] = d.(2^window)^i.P
+    multiples []affinePoint
+}
+
+/*
+NewFixedBaseTable computes the table of p, which must not be the point at infinity,
+for windows of the given number of bits, between 1 and MAX_FIXED_BASE_WINDOW. A
+scalar multiplication costs 256/window additions, and the table contains
+(2^window - 1).256/window points.
+*/
+func NewFixedBaseTable(p *P256, window int) (*FixedBaseTable, error) {
+    if window < 1 || window > MAX_FIXED_BASE_WINDOW {
+        return nil, fmt.Errorf("window must be between 1 and %d bits", MAX_FIXED_BASE_WINDOW)
+    }
+    if p == nil || p.IsZero() {
+        return nil, errors.New("can not precompute the point at infinity")
+    }
+    digits := 1<<uint(window) - 1
+    windows := (256 + window - 1) / window
+    multiples := make([]jacobianPoint, windows*digits)
+    var base jacobianPoint
+    a := newAffinePoint(p)
+    base.setAffine(&a)
+    for i := 0; i < windows; i++ {
+        w := multiples[i*digits : (i+1)*digits]
+        w[0] = base
+        for d := 1; d < digits; d++ {
+            w[d].add(&w[d-1], &base)
+        }
+        // (2^window)^(i+1).P = (2^window - 1).(2^window)^i.P + (2^window)^i.P
+        base.add(&w[digits-1], &base)
+    }
+    return &FixedBaseTable{window: window, digits: digits, multiples: normalize(multiples)}, nil
+}
+
+/*
+ScalarMult returns P^k, where P is the base of the table.
+*/
+func (t *FixedBaseTable) ScalarMult(k *big.Int) *P256 {
+    var result jacobianPoint
+    e := newFieldElement(new(big.Int).Mod(k, CURVE.N))
+    t.accumulate(&result, &e)
+    return result.toP256()
+}
+
+/*
+accumulate adds P^k to result.
+*/
+func (t *FixedBaseTable) accumulate(result *jacobianPoint, k *fieldElement) {
+    for i := 0; i*t.window < 256; i++ {
+        d := digit(k, i*t.window, t.window)
+        if d != 0 {
+            result.addAffine(result, &t.multiples[i*t.digits+int(d)-1])
+        }
+    }
+}
+
+var (
+    // tables maps the compressed encoding of a point to its *FixedBaseTable.
+    tables sync.Map
+    // generators maps the seed of a generator to its *generatorEntry.
+    generators sync.Map
+
+    baseTableOnce sync.Once
+    baseTable     *FixedBaseTable
+)
+
+type generatorEntry struct {
+    once  sync.Once
+    point *P256
+    err   error
+}
+
+/*
+Precompute computes the table of p for the given window, if p has no table in the
+store yet, and returns the table of p. From then on, ScalarMult and MultiScalarMult
+use the table for the base p.
+*/
+func Precompute(p *P256, window int) (*FixedBaseTable, error) {
+    key := string(p.Bytes())
+    if table, ok := tables.Load(key); ok {
+        return table.(*FixedBaseTable), nil
+    }
+    table, err := NewFixedBaseTable(p, window)
+    if err != nil {
+        return nil, err
+    }
+    stored, _ := tables.LoadOrStore(key, table)
+    return stored.(*FixedBaseTable), nil
+}
+
+/*
+lookupTable returns the table of p, or nil if it was not precomputed.
+*/
+func lookupTable(p *P256) *FixedBaseTable {
+    table, ok := tables.Load(string(p.Bytes()))
+    if !ok {
+        return nil
+    }
+    return table.(*FixedBaseTable)
+}
+
+/*
+baseGeneratorTable returns the table of the base generator of the curve.
+*/
+func baseGeneratorTable() *FixedBaseTable {
+    baseTableOnce.Do(func() {
+        baseTable, _ = NewFixedBaseTable(&P256{X: CURVE.Gx, Y: CURVE.Gy}, MAX_FIXED_BASE_WINDOW)
+    })
+    return baseTable
+}
+
+/*
+Generator returns MapToGroup(seed). Each generator is derived once per process, and
+later calls return a copy of the stored point.
+*/
+func Generator(seed string) (*P256, error) {
+    entry, _ := generators.LoadOrStore(seed, new(generatorEntry))
+    g := entry.(*generatorEntry)
+    g.once.Do(func() {
+        g.point, g.err = MapToGroup(seed)
+    })
+    if g.err != nil {
+        return nil, g.err
+    }
+    return &P256{X: new(big.Int).Set(g.point.X), Y: new(big.Int).Set(g.point.Y)}, nil
+}
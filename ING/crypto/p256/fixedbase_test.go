@@ -0,0 +1,149 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/rand"
+    "math/big"
+    "sync"
+    "testing"
+)
+
+/*
+curveScalarMult computes a^k without the precomputed tables.
+*/
+func curveScalarMult(a *P256, k *big.Int) *P256 {
+    k = new(big.Int).Mod(k, CURVE.N)
+    if k.Sign() == 0 {
+        return new(P256).SetInfinity()
+    }
+    x, y := CURVE.ScalarMult(a.X, a.Y, k.Bytes())
+    return &P256{X: x, Y: y}
+}
+
+func TestFixedBaseTable(t *testing.T) {
+    p, _ := MapToGroup("TestFixedBaseTable")
+    scalars := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(15), big.NewInt(16), big.NewInt(-3),
+        new(big.Int).Sub(CURVE.N, big.NewInt(1)), CURVE.N, new(big.Int).Lsh(big.NewInt(1), 255)}
+    for i := 0; i < TestCount; i++ {
+        k, _ := rand.Int(rand.Reader, CURVE.N)
+        scalars = append(scalars, k)
+    }
+    for _, window := range []int{1, 3, FIXED_BASE_WINDOW, MAX_FIXED_BASE_WINDOW} {
+        table, err := NewFixedBaseTable(p, window)
+        if err != nil {
+            t.Fatal(err)
+        }
+        for _, k := range scalars {
+            assertEqualPoints(t, curveScalarMult(p, k), table.ScalarMult(k))
+        }
+    }
+}
+
+func TestFixedBaseTableInvalid(t *testing.T) {
+    p, _ := MapToGroup("TestFixedBaseTable")
+    if _, err := NewFixedBaseTable(p, MAX_FIXED_BASE_WINDOW+1); err == nil {
+        t.Errorf("window greater than the maximum should be rejected")
+    }
+    if _, err := NewFixedBaseTable(new(P256).SetInfinity(), FIXED_BASE_WINDOW); err == nil {
+        t.Errorf("point at infinity should be rejected")
+    }
+}
+
+func TestScalarBaseMultTable(t *testing.T) {
+    g := &P256{X: CURVE.Gx, Y: CURVE.Gy}
+    for i := 0; i < TestCount; i++ {
+        k, _ := rand.Int(rand.Reader, CURVE.N)
+        assertEqualPoints(t, curveScalarMult(g, k), new(P256).ScalarBaseMult(k))
+    }
+}
+
+func TestPrecompute(t *testing.T) {
+    p, _ := MapToGroup("TestPrecompute")
+    k, _ := rand.Int(rand.Reader, CURVE.N)
+    expected := curveScalarMult(p, k)
+    if lookupTable(p) != nil {
+        t.Fatal("table should not be computed yet")
+    }
+    Precompute(p, FIXED_BASE_WINDOW)
+    if lookupTable(p) == nil {
+        t.Fatal("table should be in the store")
+    }
+    assertEqualPoints(t, expected, new(P256).ScalarMult(p, k))
+
+    // Multi-scalar multiplications mixing points with and without tables.
+    points, scalars := randomMSMInput(10)
+    points = append(points, p)
+    scalars = append(scalars, k)
+    expected = new(P256).SetInfinity()
+    for i := range points {
+        expected.Multiply(expected, curveScalarMult(points[i], scalars[i]))
+    }
+    result, _ := MultiScalarMult(points, scalars)
+    assertEqualPoints(t, expected, result)
+}
+
+func TestGenerator(t *testing.T) {
+    seed := "TestGenerator"
+    expected, _ := MapToGroup(seed)
+
+    var wg sync.WaitGroup
+    results := make([]*P256, 8)
+    for i := range results {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            results[i], _ = Generator(seed)
+            Precompute(results[i], FIXED_BASE_WINDOW)
+        }(i)
+    }
+    wg.Wait()
+    for _, g := range results {
+        assertEqualPoints(t, expected, g)
+    }
+
+    // Each call returns a copy of the stored generator.
+    results[0].X.SetInt64(1)
+    g, _ := Generator(seed)
+    assertEqualPoints(t, expected, g)
+}
+
+func BenchmarkScalarMultFixedBase4(b *testing.B) {
+    benchmarkScalarMultFixedBase(b, FIXED_BASE_WINDOW)
+}
+
+func BenchmarkScalarMultFixedBase8(b *testing.B) {
+    benchmarkScalarMultFixedBase(b, MAX_FIXED_BASE_WINDOW)
+}
+
+func benchmarkScalarMultFixedBase(b *testing.B, window int) {
+    p, _ := MapToGroup("BenchmarkScalarMultFixedBase")
+    table, _ := NewFixedBaseTable(p, window)
+    k, _ := rand.Int(rand.Reader, CURVE.N)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        table.ScalarMult(k)
+    }
+}
+
+func BenchmarkNewFixedBaseTable(b *testing.B) {
+    p, _ := MapToGroup("BenchmarkNewFixedBaseTable")
+    for i := 0; i < b.N; i++ {
+        NewFixedBaseTable(p, FIXED_BASE_WINDOW)
+    }
+}
@@ -0,0 +1,237 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+/*
+This file contains the group law of secp256k1 in Jacobian coordinates, where (X, Y, Z)
+represents the affine point (X/Z^2, Y/Z^3). It avoids the field inversion of each
+affine addition, which is only computed once at the end of a multi-scalar
+multiplication. The formulas are taken from:
+http://hyperelliptic.org/EFD/g1p/auto-shortw-jacobian-0.html
+*/
+
+import (
+    "math/big"
+)
+
+/*
+jacobianPoint is a point in Jacobian coordinates. Z equal to zero represents the
+point at infinity.
+*/
+type jacobianPoint struct {
+    x, y, z fieldElement
+}
+
+// affinePoint is a point in affine coordinates, used as input of mixed additions.
+type affinePoint struct {
+    x, y fieldElement
+}
+
+var fieldOne = fieldElement{1, 0, 0, 0}
+
+/*
+newAffinePoint converts p, which must not be the point at infinity.
+*/
+func newAffinePoint(p *P256) affinePoint {
+    return affinePoint{x: newFieldElement(p.X), y: newFieldElement(p.Y)}
+}
+
+func (p *jacobianPoint) isInfinity() bool {
+    return p.z.isZero()
+}
+
+func (p *jacobianPoint) setInfinity() *jacobianPoint {
+    *p = jacobianPoint{}
+    return p
+}
+
+func (p *jacobianPoint) setAffine(a *affinePoint) *jacobianPoint {
+    p.x = a.x
+    p.y = a.y
+    p.z = fieldOne
+    return p
+}
+
+/*
+toP256 converts the point to affine coordinates.
+*/
+func (p *jacobianPoint) toP256() *P256 {
+    if p.isInfinity() {
+        return new(P256).SetInfinity()
+    }
+    zinv := newFieldElement(new(big.Int).ModInverse(p.z.big(), CURVE.P))
+    var zinv2, zinv3, x, y fieldElement
+    fieldSquare(&zinv2, &zinv)
+    fieldMul(&zinv3, &zinv2, &zinv)
+    fieldMul(&x, &p.x, &zinv2)
+    fieldMul(&y, &p.y, &zinv3)
+    return &P256{X: x.big(), Y: y.big()}
+}
+
+/*
+double sets p = 2.a, using dbl-2009-l.
+*/
+func (p *jacobianPoint) double(a *jacobianPoint) *jacobianPoint {
+    if a.isInfinity() {
+        return p.setInfinity()
+    }
+    var A, B, C, D, E, F, t fieldElement
+    fieldSquare(&A, &a.x)
+    fieldSquare(&B, &a.y)
+    fieldSquare(&C, &B)
+    // D = 2((X+B)^2 - A - C)
+    fieldAdd(&t, &a.x, &B)
+    fieldSquare(&D, &t)
+    fieldSub(&D, &D, &A)
+    fieldSub(&D, &D, &C)
+    fieldAdd(&D, &D, &D)
+    // E = 3A, F = E^2
+    fieldAdd(&E, &A, &A)
+    fieldAdd(&E, &E, &A)
+    fieldSquare(&F, &E)
+    // Z3 = 2.Y.Z
+    var z3 fieldElement
+    fieldMul(&z3, &a.y, &a.z)
+    fieldAdd(&z3, &z3, &z3)
+    // X3 = F - 2D
+    var x3 fieldElement
+    fieldSub(&x3, &F, &D)
+    fieldSub(&x3, &x3, &D)
+    // Y3 = E(D - X3) - 8C
+    var y3 fieldElement
+    fieldSub(&t, &D, &x3)
+    fieldMul(&y3, &E, &t)
+    fieldAdd(&C, &C, &C)
+    fieldAdd(&C, &C, &C)
+    fieldAdd(&C, &C, &C)
+    fieldSub(&y3, &y3, &C)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
+
+/*
+add sets p = a + b, using add-2007-bl. It handles the cases where a and b are equal,
+opposite or the point at infinity.
+*/
+func (p *jacobianPoint) add(a, b *jacobianPoint) *jacobianPoint {
+    if a.isInfinity() {
+        *p = *b
+        return p
+    }
+    if b.isInfinity() {
+        *p = *a
+        return p
+    }
+    var z1z1, z2z2, u1, u2, s1, s2, h, r, t fieldElement
+    fieldSquare(&z1z1, &a.z)
+    fieldSquare(&z2z2, &b.z)
+    fieldMul(&u1, &a.x, &z2z2)
+    fieldMul(&u2, &b.x, &z1z1)
+    fieldMul(&s1, &a.y, &b.z)
+    fieldMul(&s1, &s1, &z2z2)
+    fieldMul(&s2, &b.y, &a.z)
+    fieldMul(&s2, &s2, &z1z1)
+    fieldSub(&h, &u2, &u1)
+    fieldSub(&r, &s2, &s1)
+    if h.isZero() {
+        if r.isZero() {
+            return p.double(a)
+        }
+        return p.setInfinity()
+    }
+    var i, j, v fieldElement
+    // I = (2H)^2, J = H.I, r = 2(S2 - S1), V = U1.I
+    fieldAdd(&i, &h, &h)
+    fieldSquare(&i, &i)
+    fieldMul(&j, &h, &i)
+    fieldAdd(&r, &r, &r)
+    fieldMul(&v, &u1, &i)
+    // Z3 = ((Z1 + Z2)^2 - Z1Z1 - Z2Z2).H
+    var z3 fieldElement
+    fieldAdd(&t, &a.z, &b.z)
+    fieldSquare(&z3, &t)
+    fieldSub(&z3, &z3, &z1z1)
+    fieldSub(&z3, &z3, &z2z2)
+    fieldMul(&z3, &z3, &h)
+    // X3 = r^2 - J - 2V
+    var x3 fieldElement
+    fieldSquare(&x3, &r)
+    fieldSub(&x3, &x3, &j)
+    fieldSub(&x3, &x3, &v)
+    fieldSub(&x3, &x3, &v)
+    // Y3 = r(V - X3) - 2.S1.J
+    var y3 fieldElement
+    fieldSub(&t, &v, &x3)
+    fieldMul(&y3, &r, &t)
+    fieldMul(&s1, &s1, &j)
+    fieldAdd(&s1, &s1, &s1)
+    fieldSub(&y3, &y3, &s1)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
+
+/*
+addAffine sets p = a + b, where b is in affine coordinates, using madd-2007-bl.
+*/
+func (p *jacobianPoint) addAffine(a *jacobianPoint, b *affinePoint) *jacobianPoint {
+    if a.isInfinity() {
+        return p.setAffine(b)
+    }
+    var z1z1, u2, s2, h, hh, r, t fieldElement
+    fieldSquare(&z1z1, &a.z)
+    fieldMul(&u2, &b.x, &z1z1)
+    fieldMul(&s2, &b.y, &a.z)
+    fieldMul(&s2, &s2, &z1z1)
+    fieldSub(&h, &u2, &a.x)
+    fieldSub(&r, &s2, &a.y)
+    if h.isZero() {
+        if r.isZero() {
+            return p.double(a)
+        }
+        return p.setInfinity()
+    }
+    var i, j, v fieldElement
+    // HH = H^2, I = 4HH, J = H.I, r = 2(S2 - Y1), V = X1.I
+    fieldSquare(&hh, &h)
+    fieldAdd(&i, &hh, &hh)
+    fieldAdd(&i, &i, &i)
+    fieldMul(&j, &h, &i)
+    fieldAdd(&r, &r, &r)
+    fieldMul(&v, &a.x, &i)
+    // Z3 = (Z1 + H)^2 - Z1Z1 - HH
+    var z3 fieldElement
+    fieldAdd(&t, &a.z, &h)
+    fieldSquare(&z3, &t)
+    fieldSub(&z3, &z3, &z1z1)
+    fieldSub(&z3, &z3, &hh)
+    // X3 = r^2 - J - 2V
+    var x3 fieldElement
+    fieldSquare(&x3, &r)
+    fieldSub(&x3, &x3, &j)
+    fieldSub(&x3, &x3, &v)
+    fieldSub(&x3, &x3, &v)
+    // Y3 = r(V - X3) - 2.Y1.J
+    var y3, y1j fieldElement
+    fieldSub(&t, &v, &x3)
+    fieldMul(&y3, &r, &t)
+    fieldMul(&y1j, &a.y, &j)
+    fieldAdd(&y1j, &y1j, &y1j)
+    fieldSub(&y3, &y3, &y1j)
+    p.x, p.y, p.z = x3, y3, z3
+    return p
+}
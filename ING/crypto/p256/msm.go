@@ -0,0 +1,258 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+/*
+This file contains the multi-scalar multiplication, i.e. the computation of
+Prod_i P_i^k_i, where the group operation is written multiplicatively as in the
+rest of the library. Small inputs use the method of Straus, with a window of
+STRAUS_WINDOW bits per point. Larger inputs use the bucket method of Pippenger,
+whose cost per point decreases with the number of points. The points that have a
+precomputed table, see fixedbase.go, are added using their table.
+*/
+
+import (
+    "errors"
+    "math/big"
+    "math/bits"
+    "sync"
+)
+
+// STRAUS_WINDOW is the number of bits of the scalars processed at once by Straus.
+const STRAUS_WINDOW = 4
+
+// PIPPENGER_THRESHOLD is the number of points from which Pippenger is used.
+var PIPPENGER_THRESHOLD = 80
+
+// MIN_PARALLEL_POINTS is the smallest number of points given to a goroutine by
+// ParallelMultiScalarMult.
+const MIN_PARALLEL_POINTS = 32
+
+/*
+MultiScalarMult computes Prod_i points[i]^scalars[i]. The scalars are reduced modulo
+the order of the curve, so they may be negative or greater than the order.
+*/
+func MultiScalarMult(points []*P256, scalars []*big.Int) (*P256, error) {
+    if len(points) != len(scalars) {
+        return nil, errors.New("number of points is different from the number of scalars")
+    }
+    // Below the threshold, the points with a precomputed table do not need doublings.
+    useTables := len(points) < PIPPENGER_THRESHOLD
+    ps := make([]affinePoint, 0, len(points))
+    ks := make([]fieldElement, 0, len(points))
+    var (
+        fixed   []*FixedBaseTable
+        fixedKs []fieldElement
+    )
+    for i := range points {
+        if points[i] == nil || points[i].IsZero() || scalars[i] == nil {
+            continue
+        }
+        k := new(big.Int).Mod(scalars[i], CURVE.N)
+        if k.Sign() == 0 {
+            continue
+        }
+        if useTables {
+            if table := lookupTable(points[i]); table != nil {
+                fixed = append(fixed, table)
+                fixedKs = append(fixedKs, newFieldElement(k))
+                continue
+            }
+        }
+        ps = append(ps, newAffinePoint(points[i]))
+        ks = append(ks, newFieldElement(k))
+    }
+
+    var result jacobianPoint
+    if len(ps) < PIPPENGER_THRESHOLD {
+        straus(&result, ps, ks)
+    } else {
+        pippenger(&result, ps, ks)
+    }
+    for i := range fixed {
+        fixed[i].accumulate(&result, &fixedKs[i])
+    }
+    return result.toP256(), nil
+}
+
+/*
+ParallelMultiScalarMult is equal to MultiScalarMult, but splits the points between at
+most workers goroutines, each of them computing the multi-scalar multiplication of
+at least MIN_PARALLEL_POINTS points. With less than 2 workers, it is equal to
+MultiScalarMult.
+*/
+func ParallelMultiScalarMult(points []*P256, scalars []*big.Int, workers int) (*P256, error) {
+    if len(points) != len(scalars) {
+        return nil, errors.New("number of points is different from the number of scalars")
+    }
+    chunks := len(points) / MIN_PARALLEL_POINTS
+    if chunks > workers {
+        chunks = workers
+    }
+    if chunks < 2 {
+        return MultiScalarMult(points, scalars)
+    }
+    size := (len(points) + chunks - 1) / chunks
+    results := make([]*P256, chunks)
+    var wg sync.WaitGroup
+    for i := range results {
+        from, to := i*size, (i+1)*size
+        if to > len(points) {
+            to = len(points)
+        }
+        wg.Add(1)
+        go func(i, from, to int) {
+            defer wg.Done()
+            results[i], _ = MultiScalarMult(points[from:to], scalars[from:to])
+        }(i, from, to)
+    }
+    wg.Wait()
+    result := new(P256).SetInfinity()
+    for _, r := range results {
+        result.Multiply(result, r)
+    }
+    return result, nil
+}
+
+/*
+straus computes the multi-scalar multiplication using a table of the multiples
+P_i, P_i^2, ..., P_i^(2^w-1) of each point, and a single sequence of doublings.
+*/
+func straus(result *jacobianPoint, ps []affinePoint, ks []fieldElement) {
+    const size = 1<<STRAUS_WINDOW - 1
+    multiples := make([]jacobianPoint, size*len(ps))
+    for i := range ps {
+        table := multiples[size*i : size*(i+1)]
+        table[0].setAffine(&ps[i])
+        for j := 1; j < size; j++ {
+            table[j].addAffine(&table[j-1], &ps[i])
+        }
+    }
+    tables := normalize(multiples)
+
+    result.setInfinity()
+    for w := 256/STRAUS_WINDOW - 1; w >= 0; w-- {
+        for j := 0; j < STRAUS_WINDOW; j++ {
+            result.double(result)
+        }
+        for i := range ks {
+            d := digit(&ks[i], w*STRAUS_WINDOW, STRAUS_WINDOW)
+            if d != 0 {
+                result.addAffine(result, &tables[size*i+int(d)-1])
+            }
+        }
+    }
+}
+
+/*
+pippenger computes the multi-scalar multiplication with the bucket method. For each
+window of c bits, every point is added to the bucket of its digit d, and the sum
+of d.B_d over the buckets is computed with two running sums.
+*/
+func pippenger(result *jacobianPoint, ps []affinePoint, ks []fieldElement) {
+    c := pippengerWindow(len(ps))
+    buckets := make([]jacobianPoint, 1<<uint(c)-1)
+    var sum, total jacobianPoint
+
+    result.setInfinity()
+    for w := (256+c-1)/c - 1; w >= 0; w-- {
+        for j := 0; j < c; j++ {
+            result.double(result)
+        }
+        for j := range buckets {
+            buckets[j].setInfinity()
+        }
+        for i := range ks {
+            d := digit(&ks[i], w*c, c)
+            if d != 0 {
+                buckets[d-1].addAffine(&buckets[d-1], &ps[i])
+            }
+        }
+        sum.setInfinity()
+        total.setInfinity()
+        for j := len(buckets) - 1; j >= 0; j-- {
+            sum.add(&sum, &buckets[j])
+            total.add(&total, &sum)
+        }
+        result.add(result, &total)
+    }
+}
+
+/*
+pippengerWindow returns the size in bits of the windows of Pippenger for n points,
+which is close to log2(n) - 2.
+*/
+func pippengerWindow(n int) int {
+    c := bits.Len(uint(n)) - 2
+    if c < 2 {
+        c = 2
+    }
+    if c > 16 {
+        c = 16
+    }
+    return c
+}
+
+/*
+digit returns the c bits of k starting at position pos.
+*/
+func digit(k *fieldElement, pos, c int) uint64 {
+    limb := pos / 64
+    shift := uint(pos % 64)
+    if limb >= 4 {
+        return 0
+    }
+    d := k[limb] >> shift
+    if shift+uint(c) > 64 && limb+1 < 4 {
+        d |= k[limb+1] << (64 - shift)
+    }
+    return d & (1<<uint(c) - 1)
+}
+
+/*
+normalize converts points, which must not be the point at infinity, to affine
+coordinates. It uses the trick of Montgomery to compute a single field inversion.
+*/
+func normalize(points []jacobianPoint) []affinePoint {
+    result := make([]affinePoint, len(points))
+    if len(points) == 0 {
+        return result
+    }
+    // products[i] = z_0 . z_1 ... z_i
+    products := make([]fieldElement, len(points))
+    products[0] = points[0].z
+    for i := 1; i < len(points); i++ {
+        fieldMul(&products[i], &products[i-1], &points[i].z)
+    }
+    inv := newFieldElement(new(big.Int).ModInverse(products[len(points)-1].big(), CURVE.P))
+    for i := len(points) - 1; i >= 0; i-- {
+        // zinv = 1/z_i, then inv = 1/(z_0 ... z_(i-1))
+        var zinv, zinv2, zinv3 fieldElement
+        if i > 0 {
+            fieldMul(&zinv, &inv, &products[i-1])
+            fieldMul(&inv, &inv, &points[i].z)
+        } else {
+            zinv = inv
+        }
+        fieldSquare(&zinv2, &zinv)
+        fieldMul(&zinv3, &zinv2, &zinv)
+        fieldMul(&result[i].x, &points[i].x, &zinv2)
+        fieldMul(&result[i].y, &points[i].y, &zinv3)
+    }
+    return result
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package p256
+
+import (
+    "crypto/rand"
+    "math/big"
+    "testing"
+)
+
+func TestFieldArithmetic(t *testing.T) {
+    P := CURVE.P
+    pm1 := new(big.Int).Sub(P, big.NewInt(1))
+    values := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), pm1, new(big.Int).Sub(P, big.NewInt(2)),
+        new(big.Int).Lsh(big.NewInt(1), 255), new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), new(big.Int).Lsh(big.NewInt(1), 32))}
+    for i := 0; i < TestCount; i++ {
+        v, _ := rand.Int(rand.Reader, P)
+        values = append(values, v)
+    }
+    for i := range values {
+        values[i].Mod(values[i], P)
+        a := values[i]
+        b := values[(i*7+3)%len(values)]
+        ea := newFieldElement(a)
+        eb := newFieldElement(b)
+        if ea.big().Cmp(a) != 0 {
+            t.Fatalf("conversion of %s failed", a)
+        }
+        var r fieldElement
+        fieldAdd(&r, &ea, &eb)
+        checkField(t, "add", r, new(big.Int).Add(a, b))
+        fieldSub(&r, &ea, &eb)
+        checkField(t, "sub", r, new(big.Int).Sub(a, b))
+        fieldMul(&r, &ea, &eb)
+        checkField(t, "mul", r, new(big.Int).Mul(a, b))
+        fieldSquare(&r, &ea)
+        checkField(t, "square", r, new(big.Int).Mul(a, a))
+    }
+}
+
+func checkField(t *testing.T, op string, r fieldElement, expected *big.Int) {
+    expected.Mod(expected, CURVE.P)
+    if r.big().Cmp(expected) != 0 {
+        t.Fatalf("%s: expected %s, actual %s", op, expected, r.big())
+    }
+    for i := 3; i >= 0; i-- {
+        if r[i] != fieldP[i] {
+            if r[i] > fieldP[i] {
+                t.Fatalf("%s: result is not reduced", op)
+            }
+            break
+        }
+    }
+}
+
+func TestJacobianDoubleAndAdd(t *testing.T) {
+    g := new(P256).ScalarBaseMult(big.NewInt(1))
+    ag := newAffinePoint(g)
+    var p, q, r jacobianPoint
+    p.setAffine(&ag)
+    q.double(&p)
+    r.add(&q, &p)
+    assertEqualPoints(t, new(P256).ScalarBaseMult(big.NewInt(3)), r.toP256())
+    // equal points
+    r.add(&q, &q)
+    assertEqualPoints(t, new(P256).ScalarBaseMult(big.NewInt(4)), r.toP256())
+    r.addAffine(&p, &ag)
+    assertEqualPoints(t, new(P256).ScalarBaseMult(big.NewInt(2)), r.toP256())
+    // opposite points
+    mg := newAffinePoint(new(P256).ScalarBaseMult(new(big.Int).Sub(CURVE.N, big.NewInt(1))))
+    r.addAffine(&p, &mg)
+    if !r.isInfinity() {
+        t.Errorf("Assert failure: expected point at infinity")
+    }
+}
+
+func TestMultiScalarMult(t *testing.T) {
+    for _, n := range []int{0, 1, 2, 5, PIPPENGER_THRESHOLD - 1, PIPPENGER_THRESHOLD, 130} {
+        points, scalars := randomMSMInput(n)
+        if n > 4 {
+            // Edge cases: infinity, zero and negative scalars, scalars greater than the
+            // order and repeated points.
+            points[0] = new(P256).SetInfinity()
+            scalars[1] = big.NewInt(0)
+            scalars[2] = big.NewInt(-5)
+            scalars[3] = new(big.Int).Add(CURVE.N, big.NewInt(3))
+            points[4] = points[3]
+        }
+        result, err := MultiScalarMult(points, scalars)
+        if err != nil {
+            t.Fatal(err)
+        }
+        assertEqualPoints(t, naiveMSM(points, scalars), result)
+    }
+}
+
+func TestParallelMultiScalarMult(t *testing.T) {
+    points, scalars := randomMSMInput(4*MIN_PARALLEL_POINTS + 3)
+    expected, _ := MultiScalarMult(points, scalars)
+    for _, workers := range []int{0, 1, 2, 3, 16} {
+        result, err := ParallelMultiScalarMult(points, scalars, workers)
+        if err != nil {
+            t.Fatal(err)
+        }
+        assertEqualPoints(t, expected, result)
+    }
+    _, err := ParallelMultiScalarMult(make([]*P256, 2), make([]*big.Int, 3), 2)
+    if err == nil {
+        t.Errorf("Assert failure: expected error")
+    }
+}
+
+func TestMultiScalarMultOpposite(t *testing.T) {
+    g := new(P256).ScalarBaseMult(big.NewInt(1))
+    result, _ := MultiScalarMult([]*P256{g, g}, []*big.Int{big.NewInt(7), big.NewInt(-7)})
+    if !result.IsZero() {
+        t.Errorf("Assert failure: expected point at infinity")
+    }
+}
+
+func TestMultiScalarMultSizeMismatch(t *testing.T) {
+    _, err := MultiScalarMult(make([]*P256, 2), make([]*big.Int, 3))
+    if err == nil {
+        t.Errorf("Assert failure: expected error")
+    }
+}
+
+func BenchmarkMultiScalarMult64(b *testing.B) {
+    benchmarkMSM(b, 64)
+}
+
+func BenchmarkMultiScalarMult1024(b *testing.B) {
+    benchmarkMSM(b, 1024)
+}
+
+func BenchmarkNaiveMSM64(b *testing.B) {
+    points, scalars := randomMSMInput(64)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        naiveMSM(points, scalars)
+    }
+}
+
+func benchmarkMSM(b *testing.B, n int) {
+    points, scalars := randomMSMInput(n)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        MultiScalarMult(points, scalars)
+    }
+}
+
+func randomMSMInput(n int) ([]*P256, []*big.Int) {
+    points := make([]*P256, n)
+    scalars := make([]*big.Int, n)
+    for i := 0; i < n; i++ {
+        k, _ := rand.Int(rand.Reader, CURVE.N)
+        points[i] = new(P256).ScalarBaseMult(k)
+        scalars[i], _ = rand.Int(rand.Reader, CURVE.N)
+    }
+    return points, scalars
+}
+
+func naiveMSM(points []*P256, scalars []*big.Int) *P256 {
+    result := new(P256).SetInfinity()
+    for i := range points {
+        if points[i].IsZero() {
+            continue
+        }
+        result.Multiply(result, new(P256).ScalarMult(points[i], scalars[i]))
+    }
+    return result
+}
+
+func assertEqualPoints(t *testing.T, expected, actual *P256) {
+    if expected.IsZero() && actual.IsZero() {
+        return
+    }
+    if expected.IsZero() || actual.IsZero() || expected.X.Cmp(actual.X) != 0 || expected.Y.Cmp(actual.Y) != 0 {
+        t.Errorf("Assert failure: expected %s, actual %s", expected, actual)
+    }
+}
@@ -35,6 +35,14 @@ var (
     CURVE = S256()
 )
 
+// COMPRESSED_SIZE is the size in bytes of the compressed encoding of a point.
+const COMPRESSED_SIZE = 33
+
+var (
+    ErrInvalidEncoding = errors.New("invalid encoding of elliptic curve point")
+    ErrPointNotOnCurve = errors.New("elliptic curve point is not on the curve")
+)
+
 /*
 Elliptic Curve Point struct.
 */
@@ -102,7 +110,8 @@ func (p *P256) Double(a *P256) *P256 {
 }
 
 /*
-ScalarMul encapsulates the scalar Multiplication Algorithm from secP256k1.
+ScalarMul encapsulates the scalar Multiplication Algorithm from secP256k1. If a
+table of a was computed by Precompute, the fixed-base algorithm is used instead.
 */
 func (p *P256) ScalarMult(a *P256, n *big.Int) *P256 {
     if a.IsZero() {
@@ -112,6 +121,12 @@ func (p *P256) ScalarMult(a *P256, n *big.Int) *P256 {
     if cmp == 0 {
         return p.SetInfinity()
     }
+    if table := lookupTable(a); table != nil {
+        r := table.ScalarMult(n)
+        p.X = r.X
+        p.Y = r.Y
+        return p
+    }
     n = bn.Mod(n, CURVE.N)
     bns := n.Bytes()
     resx, resy := CURVE.ScalarMult(a.X, a.Y, bns)
@@ -121,18 +136,17 @@ func (p *P256) ScalarMult(a *P256, n *big.Int) *P256 {
 }
 
 /*
-ScalarBaseMult returns the Scalar Multiplication by the base generator.
+ScalarBaseMult returns the Scalar Multiplication by the base generator, using a
+precomputed table.
 */
 func (p *P256) ScalarBaseMult(n *big.Int) *P256 {
     cmp := n.Cmp(big.NewInt(0))
     if cmp == 0 {
         return p.SetInfinity()
     }
-    n = bn.Mod(n, CURVE.N)
-    bns := n.Bytes()
-    resx, resy := CURVE.ScalarBaseMult(bns)
-    p.X = resx
-    p.Y = resy
+    r := baseGeneratorTable().ScalarMult(n)
+    p.X = r.X
+    p.Y = r.Y
     return p
 }
 
@@ -261,3 +275,55 @@ func (p *P256) IsOnCurve() bool {
 
     return x3.Cmp(y2) == 0
 }
+
+/*
+Bytes returns the compressed encoding of the point, as defined in SEC 1: the byte 0x02
+or 0x03, according to the parity of Y, followed by the 32 bytes of X. The point at
+infinity is encoded as 33 zero bytes.
+*/
+func (p *P256) Bytes() []byte {
+    result := make([]byte, COMPRESSED_SIZE)
+    if p.IsZero() {
+        return result
+    }
+    result[0] = 2 + byte(p.Y.Bit(0))
+    x := bn.Mod(p.X, CURVE.P).Bytes()
+    copy(result[COMPRESSED_SIZE-len(x):], x)
+    return result
+}
+
+/*
+SetBytes decodes the compressed encoding produced by Bytes. It returns an error if
+the encoding is not canonical or if it does not correspond to a point on the curve.
+*/
+func (p *P256) SetBytes(b []byte) (*P256, error) {
+    if len(b) != COMPRESSED_SIZE {
+        return nil, ErrInvalidEncoding
+    }
+    if b[0] == 0 {
+        for i := 1; i < COMPRESSED_SIZE; i++ {
+            if b[i] != 0 {
+                return nil, ErrInvalidEncoding
+            }
+        }
+        return p.SetInfinity(), nil
+    }
+    if b[0] != 2 && b[0] != 3 {
+        return nil, ErrInvalidEncoding
+    }
+    x := new(big.Int).SetBytes(b[1:])
+    if x.Cmp(CURVE.P) >= 0 {
+        return nil, ErrInvalidEncoding
+    }
+    fx, _ := F(x)
+    y := new(big.Int).ModSqrt(fx, CURVE.P)
+    if y == nil {
+        return nil, ErrPointNotOnCurve
+    }
+    if y.Bit(0) != uint(b[0]&1) {
+        y.Sub(CURVE.P, y)
+    }
+    p.X = x
+    p.Y = y
+    return p, nil
+}
@@ -91,3 +91,47 @@ func BenchmarkScalarMultP256(b *testing.B) {
         _ = new(P256).ScalarBaseMult(new(big.Int).SetBytes(a))
     }
 }
+
+func TestCompressedEncoding(t *testing.T) {
+    for i := 0; i < 16; i++ {
+        k, _ := rand.Int(rand.Reader, CURVE.N)
+        p := new(P256).ScalarBaseMult(k)
+        b := p.Bytes()
+        if len(b) != COMPRESSED_SIZE {
+            t.Fatalf("Assert failure: expected %d bytes, actual: %d", COMPRESSED_SIZE, len(b))
+        }
+        q, err := new(P256).SetBytes(b)
+        if err != nil {
+            t.Fatalf("Assert failure: unexpected error %s", err)
+        }
+        if q.X.Cmp(p.X) != 0 || q.Y.Cmp(p.Y) != 0 {
+            t.Errorf("Assert failure: decoded point differs from the encoded one")
+        }
+    }
+    inf, err := new(P256).SetBytes(new(P256).SetInfinity().Bytes())
+    if err != nil || !inf.IsZero() {
+        t.Errorf("Assert failure: point at infinity should be decoded")
+    }
+}
+
+func TestCompressedEncodingInvalid(t *testing.T) {
+    b := new(P256).ScalarBaseMult(big.NewInt(7)).Bytes()
+    b[0] = 4
+    if _, err := new(P256).SetBytes(b); err != ErrInvalidEncoding {
+        t.Errorf("Assert failure: invalid prefix should be rejected")
+    }
+    if _, err := new(P256).SetBytes(b[:32]); err != ErrInvalidEncoding {
+        t.Errorf("Assert failure: short encoding should be rejected")
+    }
+    // x = 5 is not the abscissa of a point of secp256k1
+    b = make([]byte, COMPRESSED_SIZE)
+    b[0] = 2
+    b[32] = 5
+    if _, err := new(P256).SetBytes(b); err != ErrPointNotOnCurve {
+        t.Errorf("Assert failure: point not on the curve should be rejected")
+    }
+    copy(b[1:], CURVE.P.Bytes())
+    if _, err := new(P256).SetBytes(b); err != ErrInvalidEncoding {
+        t.Errorf("Assert failure: non canonical x should be rejected")
+    }
+}
@@ -0,0 +1,232 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the encodings of the commitments and the openings. The binary
+encoding starts with the identifier of the group, followed by the point and the
+generator h, in the encoding of its package, or by the value and the blinding
+factor, as 32 bytes in big-endian order. The JSON encoding contains the name of the group and the same
+elements, as hexadecimal strings.
+*/
+
+package pedersen
+
+import (
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+)
+
+// SCALAR_SIZE is the size in bytes of an encoded value or blinding factor.
+const SCALAR_SIZE = 32
+
+var (
+    ErrInvalidScalar = errors.New("scalar is not lower than the order of the group")
+    ErrInvalidLength = errors.New("encoded data does not have the expected length")
+    ErrUnknownGroup  = group.ErrUnknownGroup
+)
+
+type commitmentJSON struct {
+    Group string
+    Point string
+    H     string
+}
+
+type openingJSON struct {
+    Group    string
+    Value    string
+    Blinding string
+}
+
+/*
+MarshalBinary returns the binary encoding of the commitment.
+*/
+func (c Commitment) MarshalBinary() ([]byte, error) {
+    if c.group == nil || c.h == nil {
+        return nil, ErrUnknownGroup
+    }
+    result := append([]byte{c.group.ID()}, c.point.Bytes()...)
+    return append(result, c.h.Bytes()...), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of a commitment. It returns an error if
+the point does not belong to the group.
+*/
+func (c *Commitment) UnmarshalBinary(data []byte) error {
+    if len(data) == 0 {
+        return ErrInvalidLength
+    }
+    g, err := group.ByID(data[0])
+    if err != nil {
+        return err
+    }
+    return c.decode(g, data[1:])
+}
+
+/*
+MarshalJSON returns the JSON encoding of the commitment.
+*/
+func (c Commitment) MarshalJSON() ([]byte, error) {
+    if c.group == nil || c.h == nil {
+        return nil, ErrUnknownGroup
+    }
+    return json.Marshal(commitmentJSON{
+        c.group.Name(),
+        hex.EncodeToString(c.point.Bytes()),
+        hex.EncodeToString(c.h.Bytes()),
+    })
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of a commitment.
+*/
+func (c *Commitment) UnmarshalJSON(data []byte) error {
+    var decoded commitmentJSON
+    if err := json.Unmarshal(data, &decoded); err != nil {
+        return err
+    }
+    g, err := group.ByName(decoded.Group)
+    if err != nil {
+        return err
+    }
+    point, err := hex.DecodeString(decoded.Point)
+    if err != nil {
+        return err
+    }
+    h, err := hex.DecodeString(decoded.H)
+    if err != nil {
+        return err
+    }
+    if len(point) != len(h) {
+        return ErrInvalidLength
+    }
+    return c.decode(g, append(point, h...))
+}
+
+/*
+decode decodes the point and the generator h of a commitment of the group g.
+*/
+func (c *Commitment) decode(g Group, b []byte) error {
+    size := len(g.Generator().Bytes())
+    if len(b) != 2*size {
+        return ErrInvalidLength
+    }
+    p, err := g.Decode(b[:size])
+    if err != nil {
+        return err
+    }
+    h, err := g.Decode(b[size:])
+    if err != nil {
+        return err
+    }
+    if h.Equal(g.Identity()) {
+        return group.ErrInvalidPoint
+    }
+    c.group, c.h, c.point = g, h, p
+    return nil
+}
+
+/*
+MarshalBinary returns the binary encoding of the opening.
+*/
+func (o Opening) MarshalBinary() ([]byte, error) {
+    if o.Group == nil {
+        return nil, ErrUnknownGroup
+    }
+    result := []byte{o.Group.ID()}
+    result = append(result, o.scalarBytes(o.Value)...)
+    return append(result, o.scalarBytes(o.Blinding)...), nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of an opening.
+*/
+func (o *Opening) UnmarshalBinary(data []byte) error {
+    if len(data) != 1+2*SCALAR_SIZE {
+        return ErrInvalidLength
+    }
+    g, err := group.ByID(data[0])
+    if err != nil {
+        return err
+    }
+    return o.decode(g, data[1:1+SCALAR_SIZE], data[1+SCALAR_SIZE:])
+}
+
+/*
+MarshalJSON returns the JSON encoding of the opening.
+*/
+func (o Opening) MarshalJSON() ([]byte, error) {
+    if o.Group == nil {
+        return nil, ErrUnknownGroup
+    }
+    return json.Marshal(openingJSON{
+        o.Group.Name(),
+        hex.EncodeToString(o.scalarBytes(o.Value)),
+        hex.EncodeToString(o.scalarBytes(o.Blinding)),
+    })
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of an opening.
+*/
+func (o *Opening) UnmarshalJSON(data []byte) error {
+    var decoded openingJSON
+    if err := json.Unmarshal(data, &decoded); err != nil {
+        return err
+    }
+    g, err := group.ByName(decoded.Group)
+    if err != nil {
+        return err
+    }
+    value, err := hex.DecodeString(decoded.Value)
+    if err != nil {
+        return err
+    }
+    blinding, err := hex.DecodeString(decoded.Blinding)
+    if err != nil {
+        return err
+    }
+    return o.decode(g, value, blinding)
+}
+
+func (o *Opening) decode(g Group, value, blinding []byte) error {
+    if len(value) != SCALAR_SIZE || len(blinding) != SCALAR_SIZE {
+        return ErrInvalidLength
+    }
+    v := new(big.Int).SetBytes(value)
+    r := new(big.Int).SetBytes(blinding)
+    if v.Cmp(g.Order()) >= 0 || r.Cmp(g.Order()) >= 0 {
+        return ErrInvalidScalar
+    }
+    o.Group, o.Value, o.Blinding = g, v, r
+    return nil
+}
+
+/*
+scalarBytes returns x reduced modulo the order of the group, as SCALAR_SIZE bytes in
+big-endian order.
+*/
+func (o Opening) scalarBytes(x *big.Int) []byte {
+    b := new(big.Int).Mod(x, o.Group.Order()).Bytes()
+    result := make([]byte, SCALAR_SIZE)
+    copy(result[SCALAR_SIZE-len(b):], b)
+    return result
+}
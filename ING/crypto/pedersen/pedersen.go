@@ -0,0 +1,340 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+Package pedersen implements Pedersen commitments C = g^x.h^r, where g is the
+generator of the group and h a second generator whose discrete logarithm with
+respect to g is unknown. The commitments are computed in secp256k1, used by the
+Bulletproofs, or in G1 and G2 of bn256, used by ccs08.
+
+The commitments are additively homomorphic: the product of two commitments is a
+commitment to the sum of the values, with the sum of the blinding factors. The
+openings follow the same arithmetic modulo the order of the group, so that the
+opening of a combination of commitments is the same combination of their openings.
+*/
+package pedersen
+
+import (
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+// Group is a group in which commitments are computed, see the package group.
+type Group = group.Group
+
+// The groups of the commitments.
+var (
+    P256    = group.P256
+    BN256G1 = group.BN256G1
+    BN256G2 = group.BN256G2
+)
+
+var ErrGroupMismatch = group.ErrGroupMismatch
+
+/*
+Params contains the generators g and h of a group. The generator g is the one of
+the group, and h must be computed such that nobody knows its discrete logarithm.
+*/
+type Params struct {
+    group Group
+    g, h  group.Element
+}
+
+/*
+Commitment is a Pedersen commitment g^x.h^r to a value x with blinding factor r. It
+records the generator h, so that commitments of different parameters are never
+combined.
+*/
+type Commitment struct {
+    group Group
+    h     group.Element
+    point group.Element
+}
+
+/*
+Opening contains the value and the blinding factor of a commitment, both reduced
+modulo the order of the group.
+*/
+type Opening struct {
+    Group    Group
+    Value    *big.Int
+    Blinding *big.Int
+}
+
+/*
+NewP256Params returns the parameters of the commitments in secp256k1 with the
+generator h.
+*/
+func NewP256Params(h *p256.P256) Params {
+    return NewParams(group.NewP256(h))
+}
+
+/*
+NewG1Params returns the parameters of the commitments in G1 of bn256 with the
+generator h.
+*/
+func NewG1Params(h *bn256.G1) Params {
+    return NewParams(group.NewG1(h))
+}
+
+/*
+NewG2Params returns the parameters of the commitments in G2 of bn256 with the
+generator h.
+*/
+func NewG2Params(h *bn256.G2) Params {
+    return NewParams(group.NewG2(h))
+}
+
+/*
+NewParams returns the parameters of the commitments in the group of h, with the
+generator of the group and h.
+*/
+func NewParams(h group.Element) Params {
+    return Params{h.Group(), h.Group().Generator(), h}
+}
+
+/*
+Group returns the group of the commitments.
+*/
+func (p Params) Group() Group {
+    return p.group
+}
+
+/*
+G returns the generator g, that the values are committed with.
+*/
+func (p Params) G() group.Element {
+    return p.g
+}
+
+/*
+H returns the generator h, that the blinding factors are committed with.
+*/
+func (p Params) H() group.Element {
+    return p.h
+}
+
+/*
+Commit returns the commitment g^value.h^blinding and its opening.
+*/
+func (p Params) Commit(value, blinding *big.Int) (Commitment, Opening) {
+    o := Opening{p.group, p.reduce(value), p.reduce(blinding)}
+    return p.commit(o), o
+}
+
+/*
+CommitRandom returns a commitment to value, whose blinding factor is read from r,
+and its opening. If r is nil, crypto/rand is used.
+*/
+func (p Params) CommitRandom(value *big.Int, r io.Reader) (Commitment, Opening, error) {
+    blinding, err := group.RandomScalar(p.group, r)
+    if err != nil {
+        return Commitment{}, Opening{}, err
+    }
+    c, o := p.Commit(value, blinding)
+    return c, o, nil
+}
+
+/*
+Verify returns true if and only if o is an opening of c, and c was computed with the
+parameters p.
+*/
+func (p Params) Verify(c Commitment, o Opening) bool {
+    if !p.Owns(c) || o.Group != p.group || o.Value == nil || o.Blinding == nil {
+        return false
+    }
+    return p.commit(o).point.Equal(c.point)
+}
+
+/*
+NewCommitment returns the commitment of the parameters p represented by the element
+C. It returns an invalid commitment, which can not be combined nor encoded, if C
+does not belong to the group of p.
+*/
+func (p Params) NewCommitment(C group.Element) Commitment {
+    if C == nil || C.Group() != p.group {
+        return Commitment{}
+    }
+    return Commitment{p.group, p.h, C}
+}
+
+func (p Params) commit(o Opening) Commitment {
+    gx := p.g.ScalarMult(o.Value)
+    hr := p.h.ScalarMult(o.Blinding)
+    return Commitment{p.group, p.h, gx.Add(hr)}
+}
+
+/*
+Owns returns true if and only if c was computed with the parameters p, i.e. in the
+group of p with the generator h of p.
+*/
+func (p Params) Owns(c Commitment) bool {
+    return p.group != nil && c.point != nil && checkParams(c, Commitment{p.group, p.h, c.point}) == nil
+}
+
+func (p Params) reduce(x *big.Int) *big.Int {
+    return new(big.Int).Mod(x, p.group.Order())
+}
+
+/*
+Group returns the group of the commitment.
+*/
+func (c Commitment) Group() Group {
+    return c.group
+}
+
+/*
+H returns the generator h of the parameters of the commitment.
+*/
+func (c Commitment) H() group.Element {
+    return c.h
+}
+
+/*
+Element returns the element of the group that represents the commitment.
+*/
+func (c Commitment) Element() group.Element {
+    return c.point
+}
+
+/*
+P256 returns the point of secp256k1 of the commitment.
+*/
+func (c Commitment) P256() (*p256.P256, error) {
+    if c.point == nil {
+        return nil, ErrGroupMismatch
+    }
+    return group.ToP256(c.point)
+}
+
+/*
+G1 returns the point of G1 of the commitment.
+*/
+func (c Commitment) G1() (*bn256.G1, error) {
+    if c.point == nil {
+        return nil, ErrGroupMismatch
+    }
+    return group.ToG1(c.point)
+}
+
+/*
+G2 returns the point of G2 of the commitment.
+*/
+func (c Commitment) G2() (*bn256.G2, error) {
+    if c.point == nil {
+        return nil, ErrGroupMismatch
+    }
+    return group.ToG2(c.point)
+}
+
+/*
+Add returns c.d, a commitment to the sum of the values of c and d.
+*/
+func (c Commitment) Add(d Commitment) (Commitment, error) {
+    if err := checkParams(c, d); err != nil {
+        return Commitment{}, err
+    }
+    return Commitment{c.group, c.h, c.point.Add(d.point)}, nil
+}
+
+/*
+Sub returns c/d, a commitment to the difference of the values of c and d.
+*/
+func (c Commitment) Sub(d Commitment) (Commitment, error) {
+    if err := checkParams(c, d); err != nil {
+        return Commitment{}, err
+    }
+    return Commitment{c.group, c.h, c.point.Add(d.point.Neg())}, nil
+}
+
+/*
+ScalarMul returns c^k, a commitment to the value of c multiplied by k.
+*/
+func (c Commitment) ScalarMul(k *big.Int) Commitment {
+    if c.group == nil {
+        return c
+    }
+    return Commitment{c.group, c.h, c.point.ScalarMult(k)}
+}
+
+/*
+Equal returns true if and only if c and d are the same commitment, of the same
+parameters.
+*/
+func (c Commitment) Equal(d Commitment) bool {
+    return checkParams(c, d) == nil && c.point.Equal(d.point)
+}
+
+/*
+Add returns the opening of the sum of the commitments opened by o and q.
+*/
+func (o Opening) Add(q Opening) (Opening, error) {
+    if err := checkGroups(o.Group, q.Group); err != nil {
+        return Opening{}, err
+    }
+    return o.combine(new(big.Int).Add(o.Value, q.Value), new(big.Int).Add(o.Blinding, q.Blinding)), nil
+}
+
+/*
+Sub returns the opening of the difference of the commitments opened by o and q.
+*/
+func (o Opening) Sub(q Opening) (Opening, error) {
+    if err := checkGroups(o.Group, q.Group); err != nil {
+        return Opening{}, err
+    }
+    return o.combine(new(big.Int).Sub(o.Value, q.Value), new(big.Int).Sub(o.Blinding, q.Blinding)), nil
+}
+
+/*
+ScalarMul returns the opening of the commitment opened by o raised to the power k.
+*/
+func (o Opening) ScalarMul(k *big.Int) Opening {
+    if o.Group == nil {
+        return o
+    }
+    return o.combine(new(big.Int).Mul(o.Value, k), new(big.Int).Mul(o.Blinding, k))
+}
+
+func (o Opening) combine(value, blinding *big.Int) Opening {
+    order := o.Group.Order()
+    return Opening{o.Group, value.Mod(value, order), blinding.Mod(blinding, order)}
+}
+
+func checkGroups(a, b Group) error {
+    if a == nil || a != b {
+        return ErrGroupMismatch
+    }
+    return nil
+}
+
+/*
+checkParams returns ErrGroupMismatch unless c and d belong to the same group and
+were computed with the same generator h.
+*/
+func checkParams(c, d Commitment) error {
+    if err := checkGroups(c.group, d.group); err != nil {
+        return err
+    }
+    if c.h == nil || d.h == nil || !c.h.Equal(d.h) {
+        return ErrGroupMismatch
+    }
+    return nil
+}
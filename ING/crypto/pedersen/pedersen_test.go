@@ -0,0 +1,223 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package pedersen
+
+import (
+    "crypto/rand"
+    "encoding/json"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/p256"
+)
+
+func testParams(t *testing.T) []Params {
+    h, err := p256.MapToGroup("pedersen test")
+    if err != nil {
+        t.Fatal(err)
+    }
+    _, h1, _ := bn256.RandomG1(rand.Reader)
+    _, h2, _ := bn256.RandomG2(rand.Reader)
+    return []Params{NewP256Params(h), NewG1Params(h1), NewG2Params(h2)}
+}
+
+func TestCommitVerify(t *testing.T) {
+    for _, p := range testParams(t) {
+        c, o, err := p.CommitRandom(big.NewInt(42), nil)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !p.Verify(c, o) {
+            t.Errorf("Assert failure: opening should verify in %s", p.Group().Name())
+        }
+        wrong := o
+        wrong.Value = big.NewInt(43)
+        if p.Verify(c, wrong) {
+            t.Errorf("Assert failure: wrong value should not verify in %s", p.Group().Name())
+        }
+        wrong = o
+        wrong.Blinding = new(big.Int).Add(o.Blinding, big.NewInt(1))
+        if p.Verify(c, wrong) {
+            t.Errorf("Assert failure: wrong blinding factor should not verify in %s", p.Group().Name())
+        }
+    }
+}
+
+func TestHomomorphism(t *testing.T) {
+    for _, p := range testParams(t) {
+        c1, o1 := p.Commit(big.NewInt(30), big.NewInt(1234))
+        c2, o2 := p.Commit(big.NewInt(12), big.NewInt(5678))
+
+        sum, err := c1.Add(c2)
+        if err != nil {
+            t.Fatal(err)
+        }
+        osum, _ := o1.Add(o2)
+        if osum.Value.Int64() != 42 || !p.Verify(sum, osum) {
+            t.Errorf("Assert failure: sum should open to 42 in %s", p.Group().Name())
+        }
+
+        diff, _ := c2.Sub(c1)
+        odiff, _ := o2.Sub(o1)
+        if !p.Verify(diff, odiff) {
+            t.Errorf("Assert failure: difference should verify in %s", p.Group().Name())
+        }
+        expected := new(big.Int).Sub(p.Group().Order(), big.NewInt(18))
+        if odiff.Value.Cmp(expected) != 0 {
+            t.Errorf("Assert failure: difference should be reduced modulo the order in %s", p.Group().Name())
+        }
+
+        double, _ := c1.Add(c1)
+        if !double.Equal(c1.ScalarMul(big.NewInt(2))) {
+            t.Errorf("Assert failure: c.c should be equal to c^2 in %s", p.Group().Name())
+        }
+        scaled := c1.ScalarMul(big.NewInt(-3))
+        if !p.Verify(scaled, o1.ScalarMul(big.NewInt(-3))) {
+            t.Errorf("Assert failure: c^-3 should verify in %s", p.Group().Name())
+        }
+
+        zero, _ := c1.Sub(c1)
+        ozero, _ := o1.Sub(o1)
+        if !p.Verify(zero, ozero) {
+            t.Errorf("Assert failure: c/c should open to zero in %s", p.Group().Name())
+        }
+    }
+}
+
+func TestGroupMismatch(t *testing.T) {
+    params := testParams(t)
+    c1, o1 := params[0].Commit(big.NewInt(1), big.NewInt(2))
+    c2, o2 := params[2].Commit(big.NewInt(1), big.NewInt(2))
+    if _, err := c1.Add(c2); err != ErrGroupMismatch {
+        t.Errorf("Assert failure: expected ErrGroupMismatch, got %v", err)
+    }
+    if _, err := o1.Sub(o2); err != ErrGroupMismatch {
+        t.Errorf("Assert failure: expected ErrGroupMismatch, got %v", err)
+    }
+    if params[2].Verify(c1, o1) || c1.Equal(c2) || c1.Equal(Commitment{}) {
+        t.Errorf("Assert failure: elements of different groups should not match")
+    }
+    if _, err := c1.G2(); err != ErrGroupMismatch {
+        t.Errorf("Assert failure: expected ErrGroupMismatch, got %v", err)
+    }
+    if P, err := c1.P256(); err != nil || !c1.Equal(params[0].NewCommitment(group.NewP256(P))) {
+        t.Errorf("Assert failure: commitment should be a point of secp256k1")
+    }
+    if C, _ := c2.G2(); params[0].NewCommitment(group.NewG2(C)).Group() != nil {
+        t.Errorf("Assert failure: point of G2 should not be a commitment of secp256k1")
+    }
+}
+
+/*
+Tests that the commitments of the same group computed with different generators h
+are not combined.
+*/
+func TestGeneratorMismatch(t *testing.T) {
+    _, h, _ := bn256.RandomG2(rand.Reader)
+    p1 := testParams(t)[2]
+    p2 := NewG2Params(h)
+    c1, o1 := p1.Commit(big.NewInt(1), big.NewInt(2))
+    c2, _ := p2.Commit(big.NewInt(1), big.NewInt(2))
+    if _, err := c1.Add(c2); err != ErrGroupMismatch {
+        t.Errorf("Assert failure: expected ErrGroupMismatch, got %v", err)
+    }
+    if _, err := c1.Sub(c2); err != ErrGroupMismatch {
+        t.Errorf("Assert failure: expected ErrGroupMismatch, got %v", err)
+    }
+    if p2.Verify(c1, o1) || c1.Equal(c2) {
+        t.Errorf("Assert failure: commitments of different generators should not match")
+    }
+    C, _ := c1.G2()
+    if !p1.Verify(p1.NewCommitment(group.NewG2(C)), o1) || p2.Verify(p2.NewCommitment(group.NewG2(C)), o1) {
+        t.Errorf("Assert failure: the commitment should only open with the parameters of its generator")
+    }
+    data, _ := c1.MarshalBinary()
+    var decoded Commitment
+    if err := decoded.UnmarshalBinary(data); err != nil || !p1.Verify(decoded, o1) || p2.Verify(decoded, o1) {
+        t.Errorf("Assert failure: the decoded commitment should keep its generator: %v", err)
+    }
+}
+
+func TestEncoding(t *testing.T) {
+    for _, p := range testParams(t) {
+        commitments := make([]Commitment, 2)
+        openings := make([]Opening, 2)
+        commitments[0], openings[0], _ = p.CommitRandom(big.NewInt(7), rand.Reader)
+        commitments[1], openings[1] = p.Commit(big.NewInt(0), big.NewInt(0))
+
+        for i := range commitments {
+            data, err := commitments[i].MarshalBinary()
+            if err != nil {
+                t.Fatal(err)
+            }
+            var c Commitment
+            if err := c.UnmarshalBinary(data); err != nil || !c.Equal(commitments[i]) {
+                t.Errorf("Assert failure: binary commitment in %s: %v", p.Group().Name(), err)
+            }
+            data, _ = json.Marshal(commitments[i])
+            c = Commitment{}
+            if err := json.Unmarshal(data, &c); err != nil || !c.Equal(commitments[i]) {
+                t.Errorf("Assert failure: JSON commitment in %s: %v", p.Group().Name(), err)
+            }
+
+            data, _ = openings[i].MarshalBinary()
+            var o Opening
+            if err := o.UnmarshalBinary(data); err != nil || !p.Verify(commitments[i], o) {
+                t.Errorf("Assert failure: binary opening in %s: %v", p.Group().Name(), err)
+            }
+            data, _ = json.Marshal(openings[i])
+            o = Opening{}
+            if err := json.Unmarshal(data, &o); err != nil || !p.Verify(commitments[i], o) {
+                t.Errorf("Assert failure: JSON opening in %s: %v", p.Group().Name(), err)
+            }
+        }
+    }
+}
+
+func TestDecodingErrors(t *testing.T) {
+    var c Commitment
+    if c.UnmarshalBinary(nil) == nil || c.UnmarshalBinary([]byte{9, 0}) == nil {
+        t.Errorf("Assert failure: expected error")
+    }
+    if c.UnmarshalBinary(append([]byte{P256.ID()}, make([]byte, 5)...)) == nil {
+        t.Errorf("Assert failure: truncated point should be rejected")
+    }
+    notOnCurve := make([]byte, 1+2*64)
+    notOnCurve[0], notOnCurve[64] = BN256G1.ID(), 5
+    if c.UnmarshalBinary(notOnCurve) == nil {
+        t.Errorf("Assert failure: point not on the curve should be rejected")
+    }
+    identity := make([]byte, 1+2*64)
+    identity[0] = BN256G1.ID()
+    if c.UnmarshalBinary(identity) != group.ErrInvalidPoint {
+        t.Errorf("Assert failure: the identity should be rejected as generator h")
+    }
+
+    var o Opening
+    data := make([]byte, 1+2*SCALAR_SIZE)
+    data[0] = BN256G2.ID()
+    copy(data[1:], bn256.Order.Bytes())
+    if o.UnmarshalBinary(data) != ErrInvalidScalar {
+        t.Errorf("Assert failure: non-canonical value should be rejected")
+    }
+    if o.UnmarshalBinary(data[:10]) != ErrInvalidLength {
+        t.Errorf("Assert failure: truncated opening should be rejected")
+    }
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package transcript
+
+/*
+This file contains the transcript RNG of Merlin, which derives the nonces of a prover
+from the public transcript, the secret witness and some external randomness. The
+nonces are then unique to the statement and the witness, so that a failing random
+source, that repeats its output or returns zeros, can not leak the witness: at worst
+the same proof is computed twice.
+*/
+
+import (
+    "io"
+)
+
+// RNG_ENTROPY_SIZE is the number of bytes read from the external random source.
+const RNG_ENTROPY_SIZE = 32
+
+/*
+RngBuilder absorbs the witness into a copy of the transcript, before the RNG is
+created by Finalize.
+*/
+type RngBuilder struct {
+    t *Transcript
+}
+
+/*
+Rng is a deterministic random source, which implements io.Reader. It is only used by
+the prover, and never affects the transcript it was built from.
+*/
+type Rng struct {
+    t *Transcript
+}
+
+/*
+BuildRng returns a builder over a copy of the transcript, which is not modified.
+*/
+func (t *Transcript) BuildRng() *RngBuilder {
+    return &RngBuilder{t: t.Clone()}
+}
+
+/*
+RekeyWithWitness absorbs the secret witness into the RNG, under the given label.
+*/
+func (b *RngBuilder) RekeyWithWitness(label string, witness []byte) *RngBuilder {
+    b.t.update(opRekey, label, witness)
+    return b
+}
+
+/*
+Finalize absorbs RNG_ENTROPY_SIZE bytes read from r and returns the RNG. If r is
+nil, the output only depends on the transcript and the witness, which is useful for
+known-answer tests. Errors of r are returned.
+*/
+func (b *RngBuilder) Finalize(r io.Reader) (*Rng, error) {
+    entropy := make([]byte, RNG_ENTROPY_SIZE)
+    if r != nil {
+        if _, err := io.ReadFull(r, entropy); err != nil {
+            return nil, err
+        }
+    }
+    b.t.update(opRekey, "rng", entropy)
+    return &Rng{t: b.t}, nil
+}
+
+/*
+Read fills p with the output of the RNG. It never fails.
+*/
+func (rng *Rng) Read(p []byte) (int, error) {
+    copy(p, rng.t.ChallengeBytes("rng", len(p)))
+    return len(p), nil
+}
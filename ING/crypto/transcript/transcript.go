@@ -0,0 +1,138 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package transcript
+
+/*
+This file contains a transcript for the Fiat-Shamir heuristic, in the style of Merlin:
+https://merlin.cool
+The prover and the verifier absorb the same labelled messages, in the same order, and
+derive the challenges from everything absorbed so far. Every proof system starts its
+transcript with its own label, so that challenges of different protocols are
+domain-separated.
+Instead of STROBE, the state is a SHA-256 chaining value. Each operation hashes the
+previous state together with an operation code and the length-prefixed label and
+message, so that two different sequences of operations never hash the same input.
+*/
+
+import (
+    "crypto/sha256"
+    "encoding/binary"
+    "math/big"
+)
+
+// PROTOCOL_LABEL identifies this construction, and its version, in the initial state.
+const PROTOCOL_LABEL = "zkrp transcript v1"
+
+// Operation codes, absorbed before the label of each operation.
+const (
+    opAppend    = 1
+    opChallenge = 2
+    opOutput    = 3
+    opRekey     = 4
+)
+
+/*
+Transcript contains the state of the Fiat-Shamir heuristic.
+*/
+type Transcript struct {
+    state [sha256.Size]byte
+}
+
+/*
+New returns a transcript for the protocol identified by label.
+*/
+func New(label string) *Transcript {
+    t := &Transcript{state: sha256.Sum256([]byte(PROTOCOL_LABEL))}
+    t.AppendMessage("dom-sep", []byte(label))
+    return t
+}
+
+/*
+Clone returns an independent copy of the transcript.
+*/
+func (t *Transcript) Clone() *Transcript {
+    return &Transcript{state: t.state}
+}
+
+/*
+AppendMessage absorbs message into the transcript, under the given label.
+*/
+func (t *Transcript) AppendMessage(label string, message []byte) {
+    t.update(opAppend, label, message)
+}
+
+/*
+AppendUint64 absorbs the 8 bytes little-endian encoding of x.
+*/
+func (t *Transcript) AppendUint64(label string, x uint64) {
+    var b [8]byte
+    binary.LittleEndian.PutUint64(b[:], x)
+    t.AppendMessage(label, b[:])
+}
+
+/*
+ChallengeBytes derives n bytes from the transcript. The label and the length of the
+output are absorbed before, so the next challenges also depend on this one.
+*/
+func (t *Transcript) ChallengeBytes(label string, n int) []byte {
+    var length [8]byte
+    binary.LittleEndian.PutUint64(length[:], uint64(n))
+    t.update(opChallenge, label, length[:])
+
+    output := make([]byte, 0, n+sha256.Size)
+    for counter := uint32(0); len(output) < n; counter++ {
+        var input [sha256.Size + 5]byte
+        copy(input[:], t.state[:])
+        input[sha256.Size] = opOutput
+        binary.LittleEndian.PutUint32(input[sha256.Size+1:], counter)
+        block := sha256.Sum256(input[:])
+        output = append(output, block[:]...)
+    }
+    return output[:n]
+}
+
+/*
+ChallengeScalar derives a non-zero integer modulo order. It reduces 64 bytes of
+output, so the bias is negligible for orders of up to 256 bits.
+*/
+func (t *Transcript) ChallengeScalar(label string, order *big.Int) *big.Int {
+    for {
+        c := new(big.Int).SetBytes(t.ChallengeBytes(label, 64))
+        c.Mod(c, order)
+        if c.Sign() != 0 {
+            return c
+        }
+    }
+}
+
+/*
+update sets the state to SHA-256(state || op || len(label) || label || len(message) || message).
+*/
+func (t *Transcript) update(op byte, label string, message []byte) {
+    var length [4]byte
+    digest := sha256.New()
+    digest.Write(t.state[:])
+    digest.Write([]byte{op})
+    binary.LittleEndian.PutUint32(length[:], uint32(len(label)))
+    digest.Write(length[:])
+    digest.Write([]byte(label))
+    binary.LittleEndian.PutUint32(length[:], uint32(len(message)))
+    digest.Write(length[:])
+    digest.Write(message)
+    copy(t.state[:], digest.Sum(nil))
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package transcript
+
+import (
+    "encoding/hex"
+    "math/big"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestChallengeDeterministic(t *testing.T) {
+    t1 := New("test protocol")
+    t1.AppendMessage("a", []byte("message"))
+    t2 := New("test protocol")
+    t2.AppendMessage("a", []byte("message"))
+    assert.Equal(t, t1.ChallengeBytes("c", 32), t2.ChallengeBytes("c", 32))
+    assert.Equal(t, t1.ChallengeBytes("c", 32), t2.ChallengeBytes("c", 32))
+}
+
+func TestChallengeKnownAnswer(t *testing.T) {
+    tr := New("test protocol")
+    tr.AppendMessage("a", []byte("message"))
+    tr.AppendUint64("n", 64)
+    c := tr.ChallengeBytes("c", 32)
+    assert.Equal(t, "10639bd4d59face7fa9029ee2a7d671268eda0f90ee0a9982a54b970e0edb7dc", hex.EncodeToString(c))
+}
+
+func TestChallengeDomainSeparation(t *testing.T) {
+    base := New("test protocol")
+    base.AppendMessage("a", []byte("bc"))
+    expected := base.ChallengeBytes("c", 32)
+
+    others := []*Transcript{New("other protocol"), New("test protocol"), New("test protocol"), New("test protocol")}
+    others[0].AppendMessage("a", []byte("bc"))
+    // The label and the message are framed, so moving bytes between them matters.
+    others[1].AppendMessage("ab", []byte("c"))
+    others[2].AppendMessage("a", []byte("bd"))
+    others[3].AppendMessage("a", []byte("b"))
+    others[3].AppendMessage("", []byte("c"))
+    for i, other := range others {
+        assert.NotEqual(t, expected, other.ChallengeBytes("c", 32), "transcript %d", i)
+    }
+
+    // The label of the challenge is absorbed too.
+    base = New("test protocol")
+    base.AppendMessage("a", []byte("bc"))
+    assert.NotEqual(t, expected, base.ChallengeBytes("d", 32))
+}
+
+func TestChallengeChaining(t *testing.T) {
+    tr := New("test protocol")
+    c1 := tr.ChallengeBytes("c", 32)
+    c2 := tr.ChallengeBytes("c", 32)
+    assert.NotEqual(t, c1, c2, "consecutive challenges should differ")
+
+    long := New("test protocol").ChallengeBytes("c", 100)
+    assert.Equal(t, 100, len(long))
+    assert.Equal(t, c1, New("test protocol").ChallengeBytes("c", 32))
+}
+
+func TestClone(t *testing.T) {
+    tr := New("test protocol")
+    tr.AppendMessage("a", []byte("message"))
+    clone := tr.Clone()
+    clone.AppendMessage("b", []byte("more"))
+    assert.NotEqual(t, tr.Clone().ChallengeBytes("c", 32), clone.ChallengeBytes("c", 32))
+
+    clone = tr.Clone()
+    assert.Equal(t, tr.ChallengeBytes("c", 32), clone.ChallengeBytes("c", 32))
+}
+
+func TestChallengeScalar(t *testing.T) {
+    order := big.NewInt(7)
+    tr := New("test protocol")
+    for i := 0; i < 100; i++ {
+        c := tr.ChallengeScalar("x", order)
+        assert.True(t, c.Sign() > 0 && c.Cmp(order) < 0, "challenge should be in [1, order)")
+    }
+}
+
+func TestRngWitness(t *testing.T) {
+    tr := New("test protocol")
+    tr.AppendMessage("a", []byte("message"))
+    expected := tr.Clone().ChallengeBytes("c", 32)
+
+    read := func(tr *Transcript, witness string) []byte {
+        rng, err := tr.BuildRng().RekeyWithWitness("x", []byte(witness)).Finalize(nil)
+        assert.NoError(t, err)
+        b := make([]byte, 32)
+        rng.Read(b)
+        return b
+    }
+    assert.Equal(t, read(tr, "secret"), read(tr, "secret"), "output should be deterministic")
+    assert.NotEqual(t, read(tr, "secret"), read(tr, "other secret"))
+    assert.NotEqual(t, read(tr, "secret"), read(New("other protocol"), "secret"))
+
+    // Building the RNG does not modify the transcript.
+    assert.Equal(t, expected, tr.ChallengeBytes("c", 32))
+}
+
+func TestRngKnownAnswer(t *testing.T) {
+    tr := New("test protocol")
+    tr.AppendMessage("a", []byte("message"))
+    rng, _ := tr.BuildRng().RekeyWithWitness("x", []byte("secret")).Finalize(nil)
+    b := make([]byte, 32)
+    rng.Read(b)
+    assert.Equal(t, "992870833c709c81109d8f87f9fed8f8dcc7310f5588e6f8a75a017afa52f1e8", hex.EncodeToString(b))
+}
+
+func TestRngEntropy(t *testing.T) {
+    tr := New("test protocol")
+    b1, b2 := make([]byte, 32), make([]byte, 32)
+    rng, _ := tr.BuildRng().RekeyWithWitness("x", []byte("secret")).Finalize(strings.NewReader(strings.Repeat("a", 32)))
+    rng.Read(b1)
+    rng, _ = tr.BuildRng().RekeyWithWitness("x", []byte("secret")).Finalize(strings.NewReader(strings.Repeat("b", 32)))
+    rng.Read(b2)
+    assert.NotEqual(t, b1, b2, "output should depend on the external randomness")
+
+    // Consecutive reads differ.
+    rng.Read(b1)
+    assert.NotEqual(t, b1, b2)
+
+    _, err := tr.BuildRng().Finalize(strings.NewReader("short"))
+    assert.Error(t, err, "errors of the random source should be returned")
+}
@@ -17,7 +17,7 @@ package main
 import (
   "fmt"
   "os"
-  "strconv"
+  "strings"
   "github.com/ing-bank/zkrp/bulletproofs"
   "math/big"
   "encoding/json"
@@ -34,25 +34,46 @@ func checkErr(e error, s string) {
   }
 }
 
+// parseBigInt reads a decimal or, with a 0x prefix, hexadecimal integer.
+func parseBigInt(s string) (*big.Int, bool) {
+  sign := ""
+  if strings.HasPrefix(s, "-") {
+    sign = "-"
+    s = s[1:]
+  }
+  base := 10
+  if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+    base = 16
+    s = s[2:]
+  }
+  return new(big.Int).SetString(sign + s, base)
+}
+
 func generateProof(params map[string]string) {
-  x, err := strconv.ParseInt(params["-x"], 10, 64)
-  checkErr(err, "Secret value invalid.")
-  lower, err := strconv.ParseInt(params["-lower"], 10, 64)
-  checkErr(err, "Lower range invalid.")
-  upper, err := strconv.ParseInt(params["-upper"], 10, 64)
-  checkErr(err, "Upper range invalid.")
-  if lower >= upper {
+  x, ok := parseBigInt(params["-x"])
+  if !ok {
+    displayErr("Secret value invalid.")
+  }
+  lower, ok := parseBigInt(params["-lower"])
+  if !ok {
+    displayErr("Lower range invalid.")
+  }
+  upper, ok := parseBigInt(params["-upper"])
+  if !ok {
+    displayErr("Upper range invalid.")
+  }
+  if lower.Cmp(upper) >= 0 {
     displayErr("Range distance invalid.")
   }
-  if x < lower {
+  if x.Cmp(lower) < 0 {
     displayErr("Secret out of lower range.")
   }
-  if x >= upper {
+  if x.Cmp(upper) >= 0 {
     displayErr("Secret out of upper range.")
   }
-  bprp, err := bulletproofs.SetupGeneric(lower, upper)
+  bprp, err := bulletproofs.SetupGenericBig(lower, upper)
   checkErr(err, "Unable to setup proof.")
-  proof, err := bulletproofs.ProveGeneric(new(big.Int).SetInt64(x), bprp)
+  proof, err := bulletproofs.ProveGeneric(x, bprp)
   checkErr(err, "Unable to generate proof.")
   bytes, err := json.Marshal(proof)
   checkErr(err, "Unable to marshal json.")
@@ -62,37 +83,63 @@ func generateProof(params map[string]string) {
 }
 
 func verifyProof(params map[string]string) {
+  lower, ok := parseBigInt(params["-lower"])
+  if !ok {
+    displayErr("Lower range invalid.")
+  }
+  upper, ok := parseBigInt(params["-upper"])
+  if !ok {
+    displayErr("Upper range invalid.")
+  }
+  if lower.Cmp(upper) >= 0 {
+    displayErr("Range distance invalid.")
+  }
   bytes, err := ioutil.ReadFile(params["-proofIn"])
   checkErr(err, "Unable to read proof file.")
   var proof bulletproofs.ProofBPRP
   err = json.Unmarshal(bytes, &proof)
+  if err == bulletproofs.ErrUnsupportedVersion {
+    displayErr("Unsupported proof format, the proof must be generated again.")
+  }
   checkErr(err, "Unable to unmarshal bytes.")
-  res, err := proof.Verify()
+  // The generators are rebuilt here and never read from the proof file, and the
+  // interval is the one the verifier expects, not the one claimed by the prover.
+  bprp, err := bulletproofs.SetupGenericBig(lower, upper)
+  checkErr(err, "Unable to setup proof.")
+  res, err := proof.Verify(bprp)
   checkErr(err, "Unable to verify proof.")
   if res == true {
-    fmt.Printf("Proof successfully verified.")
+    fmt.Printf("Proof successfully verified for the interval [%s, %s).", lower, upper)
   } else {
-    fmt.Printf("Proof verification failed.")
+    fmt.Printf("Proof verification failed for the interval [%s, %s).", lower, upper)
   }
 }
 
-func main() {
+// parseArgs reads the arguments as pairs of a flag and its value. The token following
+// a flag is always its value, so that values may be negative numbers.
+func parseArgs(args []string) map[string]string {
   params := map[string]string{}
   param := ""
-  for _, arg := range os.Args[1:] {
-    if 45 == arg[0] {
+  for _, arg := range args {
+    if param == "" && strings.HasPrefix(arg, "-") {
       param = arg
     } else {
       params[param] = arg
+      param = ""
     }
   }
+  return params
+}
+
+func main() {
+  params := parseArgs(os.Args[1:])
   if "generate" == params["-action"] {
     if 5 != len(params) {
       displayErr("Invalid argument number.")
     }
     generateProof(params);
   } else if "verify" == params["-action"] {
-    if 2 != len(params) {
+    if 4 != len(params) {
       displayErr("Invalid argument number.")
     }
     verifyProof(params);
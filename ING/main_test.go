@@ -0,0 +1,71 @@
+/**
+ * Controller for ING-Bank Bulletproofs.
+ * license: MIT
+ * author: Christoph Leixnering
+ * adapted from: https://github.com/ing-bank/zkrp
+ * version: 1.0
+ * date: 2019-07-30
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+ * EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+ * MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY
+ * CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+ * TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+package main
+
+import (
+  "encoding/json"
+  "io/ioutil"
+  "math/big"
+  "os"
+  "path/filepath"
+  "testing"
+
+  "github.com/ing-bank/zkrp/bulletproofs"
+)
+
+func TestParseArgs(t *testing.T) {
+  params := parseArgs([]string{"-action", "generate", "-x", "-3", "-lower", "-5", "-upper", "10"})
+  expected := map[string]string{"-action": "generate", "-x": "-3", "-lower": "-5", "-upper": "10"}
+  if len(params) != len(expected) {
+    t.Fatalf("Assert failure: expected %v, actual: %v", expected, params)
+  }
+  for k, v := range expected {
+    if params[k] != v {
+      t.Errorf("Assert failure: expected %s for %s, actual: %s", v, k, params[k])
+    }
+  }
+}
+
+/*
+Tests a proof generated by the command line for a negative lower bound.
+*/
+func TestGenerateNegativeLower(t *testing.T) {
+  dir, err := ioutil.TempDir("", "zkrp")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+  proofOut := filepath.Join(dir, "proof.json")
+  params := parseArgs([]string{"-action", "generate", "-x", "-3", "-lower", "-5", "-upper", "10", "-proofOut", proofOut})
+  if len(params) != 5 {
+    t.Fatalf("Assert failure: expected 5 arguments, actual: %d", len(params))
+  }
+  generateProof(params)
+
+  data, err := ioutil.ReadFile(proofOut)
+  if err != nil {
+    t.Fatal(err)
+  }
+  var proof bulletproofs.ProofBPRP
+  if err = json.Unmarshal(data, &proof); err != nil {
+    t.Fatal(err)
+  }
+  bprp, _ := bulletproofs.SetupGenericBig(big.NewInt(-5), big.NewInt(10))
+  ok, _ := proof.Verify(bprp)
+  if !ok {
+    t.Errorf("Assert failure: the proof should verify for [-5, 10)")
+  }
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the AND and OR compositions of statements. The statements of an
+AND are proven with the same challenge, their responses are concatenated. The
+responses of an OR start with the challenges of all its statements but the last one,
+whose challenge is the challenge of the OR minus the others.
+*/
+
+package sigma
+
+import (
+    "errors"
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+)
+
+type andStatement struct {
+    statements []Statement
+}
+
+type orStatement struct {
+    statements []Statement
+}
+
+/*
+And returns the statement that all the statements hold. The witness is
+AndWitness(w_1, ..., w_n), where w_i is the witness of the statement i.
+*/
+func And(statements ...Statement) (Statement, error) {
+    if err := checkStatements(statements); err != nil {
+        return nil, err
+    }
+    return &andStatement{statements}, nil
+}
+
+/*
+Or returns the statement that at least one of the statements holds, without
+revealing which one. The witness is OrWitness(i, w), where w is the witness of the
+statement i.
+*/
+func Or(statements ...Statement) (Statement, error) {
+    if err := checkStatements(statements); err != nil {
+        return nil, err
+    }
+    return &orStatement{statements}, nil
+}
+
+/*
+checkStatements returns an error if there are no statements, or if they do not
+belong to the same group.
+*/
+func checkStatements(statements []Statement) error {
+    if len(statements) == 0 {
+        return errors.New("composition of no statements")
+    }
+    for _, s := range statements {
+        if s == nil || s.Group() != statements[0].Group() {
+            return group.ErrGroupMismatch
+        }
+    }
+    return nil
+}
+
+func (s *andStatement) Group() group.Group {
+    return s.statements[0].Group()
+}
+
+func (s *andStatement) appendTo(t *transcript.Transcript) {
+    t.AppendMessage("dom-sep", []byte("and v1"))
+    t.AppendUint64("n", uint64(len(s.statements)))
+    for _, statement := range s.statements {
+        statement.appendTo(t)
+    }
+}
+
+func (s *andStatement) size() int {
+    n := 0
+    for _, statement := range s.statements {
+        n += statement.size()
+    }
+    return n
+}
+
+func (s *andStatement) commit(r io.Reader, w Witness) ([]group.Element, *proverState, error) {
+    if len(w.parts) != len(s.statements) {
+        return nil, nil, ErrInvalidWitness
+    }
+    var commitments []group.Element
+    state := &proverState{parts: make([]*proverState, len(s.statements))}
+    for i, statement := range s.statements {
+        A, part, err := statement.commit(r, w.parts[i])
+        if err != nil {
+            return nil, nil, err
+        }
+        commitments = append(commitments, A...)
+        state.parts[i] = part
+    }
+    return commitments, state, nil
+}
+
+func (s *andStatement) respond(w Witness, state *proverState, c *big.Int) []*big.Int {
+    var responses []*big.Int
+    for i, statement := range s.statements {
+        responses = append(responses, statement.respond(w.parts[i], state.parts[i], c)...)
+    }
+    return responses
+}
+
+func (s *andStatement) recompute(c *big.Int, responses []*big.Int) []group.Element {
+    var commitments []group.Element
+    for _, statement := range s.statements {
+        n := statement.size()
+        commitments = append(commitments, statement.recompute(c, responses[:n])...)
+        responses = responses[n:]
+    }
+    return commitments
+}
+
+func (s *orStatement) Group() group.Group {
+    return s.statements[0].Group()
+}
+
+func (s *orStatement) appendTo(t *transcript.Transcript) {
+    t.AppendMessage("dom-sep", []byte("or v1"))
+    t.AppendUint64("n", uint64(len(s.statements)))
+    for _, statement := range s.statements {
+        statement.appendTo(t)
+    }
+}
+
+func (s *orStatement) size() int {
+    n := len(s.statements) - 1
+    for _, statement := range s.statements {
+        n += statement.size()
+    }
+    return n
+}
+
+/*
+commit computes the first messages of the statement known by the prover, and
+simulates the others with random challenges and responses.
+*/
+func (s *orStatement) commit(r io.Reader, w Witness) ([]group.Element, *proverState, error) {
+    if len(w.parts) != 1 || w.index < 0 || w.index >= len(s.statements) {
+        return nil, nil, ErrInvalidWitness
+    }
+    n := len(s.statements)
+    state := &proverState{
+        parts:      make([]*proverState, n),
+        challenges: make([]*big.Int, n),
+        responses:  make([][]*big.Int, n),
+    }
+    var commitments []group.Element
+    for i, statement := range s.statements {
+        if i == w.index {
+            A, part, err := statement.commit(r, w.parts[0])
+            if err != nil {
+                return nil, nil, err
+            }
+            commitments = append(commitments, A...)
+            state.parts[i] = part
+            continue
+        }
+        simulated, err := sampleScalars(r, s.Group(), 1+statement.size())
+        if err != nil {
+            return nil, nil, err
+        }
+        state.challenges[i], state.responses[i] = simulated[0], simulated[1:]
+        commitments = append(commitments, statement.recompute(state.challenges[i], state.responses[i])...)
+    }
+    return commitments, state, nil
+}
+
+func (s *orStatement) respond(w Witness, state *proverState, c *big.Int) []*big.Int {
+    order := s.Group().Order()
+    // The challenge of the known statement is c minus the simulated challenges.
+    ck := new(big.Int).Set(c)
+    for i, ci := range state.challenges {
+        if i != w.index {
+            ck.Sub(ck, ci)
+        }
+    }
+    state.challenges[w.index] = ck.Mod(ck, order)
+    state.responses[w.index] = s.statements[w.index].respond(w.parts[0], state.parts[w.index], ck)
+
+    responses := append([]*big.Int{}, state.challenges[:len(s.statements)-1]...)
+    for _, r := range state.responses {
+        responses = append(responses, r...)
+    }
+    return responses
+}
+
+func (s *orStatement) recompute(c *big.Int, responses []*big.Int) []group.Element {
+    n := len(s.statements)
+    challenges := append([]*big.Int{}, responses[:n-1]...)
+    last := new(big.Int).Set(c)
+    for _, ci := range challenges {
+        last.Sub(last, ci)
+    }
+    challenges = append(challenges, last.Mod(last, s.Group().Order()))
+    responses = responses[n-1:]
+
+    var commitments []group.Element
+    for i, statement := range s.statements {
+        size := statement.size()
+        commitments = append(commitments, statement.recompute(challenges[i], responses[:size])...)
+        responses = responses[size:]
+    }
+    return commitments
+}
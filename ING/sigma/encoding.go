@@ -0,0 +1,152 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the encodings of the proofs. The binary encoding starts with the
+version, followed by the challenge and the responses, as 32 bytes in big-endian
+order. The JSON encoding contains the same elements, as hexadecimal strings. The
+number of responses and their range are checked against the statement by Verify.
+*/
+
+package sigma
+
+import (
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "math/big"
+)
+
+// ENCODING_VERSION is the version of the binary and JSON encodings of the proofs.
+const ENCODING_VERSION = 1
+
+// SCALAR_SIZE is the size in bytes of an encoded scalar.
+const SCALAR_SIZE = 32
+
+var (
+    ErrUnsupportedVersion = errors.New("unsupported proof encoding version")
+    ErrInvalidEncoding    = errors.New("invalid encoding of the proof")
+)
+
+type proofJSON struct {
+    Version   int
+    Challenge string
+    Responses []string
+}
+
+/*
+MarshalBinary returns the binary encoding of the proof.
+*/
+func (proof Proof) MarshalBinary() ([]byte, error) {
+    if err := proof.check(); err != nil {
+        return nil, err
+    }
+    result := []byte{ENCODING_VERSION}
+    result = append(result, scalarBytes(proof.Challenge)...)
+    for _, s := range proof.Responses {
+        result = append(result, scalarBytes(s)...)
+    }
+    return result, nil
+}
+
+/*
+UnmarshalBinary decodes the binary encoding of a proof.
+*/
+func (proof *Proof) UnmarshalBinary(data []byte) error {
+    if len(data) == 0 {
+        return ErrInvalidEncoding
+    }
+    if data[0] != ENCODING_VERSION {
+        return ErrUnsupportedVersion
+    }
+    data = data[1:]
+    if len(data) < SCALAR_SIZE || len(data)%SCALAR_SIZE != 0 {
+        return ErrInvalidEncoding
+    }
+    proof.Challenge = new(big.Int).SetBytes(data[:SCALAR_SIZE])
+    proof.Responses = make([]*big.Int, len(data)/SCALAR_SIZE-1)
+    for i := range proof.Responses {
+        proof.Responses[i] = new(big.Int).SetBytes(data[(i+1)*SCALAR_SIZE : (i+2)*SCALAR_SIZE])
+    }
+    return nil
+}
+
+/*
+MarshalJSON returns the JSON encoding of the proof.
+*/
+func (proof Proof) MarshalJSON() ([]byte, error) {
+    if err := proof.check(); err != nil {
+        return nil, err
+    }
+    encoded := proofJSON{
+        Version:   ENCODING_VERSION,
+        Challenge: hex.EncodeToString(scalarBytes(proof.Challenge)),
+        Responses: make([]string, len(proof.Responses)),
+    }
+    for i, s := range proof.Responses {
+        encoded.Responses[i] = hex.EncodeToString(scalarBytes(s))
+    }
+    return json.Marshal(encoded)
+}
+
+/*
+UnmarshalJSON decodes the JSON encoding of a proof.
+*/
+func (proof *Proof) UnmarshalJSON(data []byte) error {
+    var decoded proofJSON
+    if err := json.Unmarshal(data, &decoded); err != nil {
+        return err
+    }
+    if decoded.Version != ENCODING_VERSION {
+        return ErrUnsupportedVersion
+    }
+    c, err := decodeScalar(decoded.Challenge)
+    if err != nil {
+        return err
+    }
+    responses := make([]*big.Int, len(decoded.Responses))
+    for i, s := range decoded.Responses {
+        if responses[i], err = decodeScalar(s); err != nil {
+            return err
+        }
+    }
+    proof.Challenge, proof.Responses = c, responses
+    return nil
+}
+
+/*
+check returns an error if a scalar of the proof can not be encoded.
+*/
+func (proof Proof) check() error {
+    for _, x := range append([]*big.Int{proof.Challenge}, proof.Responses...) {
+        if x == nil || x.Sign() < 0 || x.BitLen() > 8*SCALAR_SIZE {
+            return ErrInvalidEncoding
+        }
+    }
+    return nil
+}
+
+func decodeScalar(s string) (*big.Int, error) {
+    b, err := hex.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    if len(b) != SCALAR_SIZE {
+        return nil, ErrInvalidEncoding
+    }
+    return new(big.Int).SetBytes(b), nil
+}
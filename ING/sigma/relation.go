@@ -0,0 +1,252 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+This file contains the linear relations, the statements of the form
+Y_i = prod_j B_ij^x_j, where the elements Y_i and the bases B_ij are public and the
+scalars x_j are the witness. The prover sends A_i = prod_j B_ij^k_j for random
+nonces k_j, and answers the challenge c with s_j = k_j + c.x_j, which the verifier
+checks with A_i = prod_j B_ij^s_j . Y_i^-c.
+*/
+
+package sigma
+
+import (
+    "errors"
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+)
+
+/*
+Term is the base B raised to the variable Var, in an equation of a LinearRelation.
+*/
+type Term struct {
+    Var  int
+    Base group.Element
+}
+
+type equation struct {
+    image group.Element
+    terms []Term
+}
+
+/*
+LinearRelation is a system of equations Y_i = prod_j B_ij^x_j over the variables x_j.
+The variables can appear in several equations, which proves that they are equal.
+*/
+type LinearRelation struct {
+    group     group.Group
+    vars      int
+    equations []equation
+}
+
+/*
+NewLinearRelation returns a relation without equations over vars variables in the
+group g.
+*/
+func NewLinearRelation(g group.Group, vars int) *LinearRelation {
+    return &LinearRelation{group: g, vars: vars}
+}
+
+/*
+AddEquation adds the equation image = prod_i terms[i].Base^x_terms[i].Var.
+*/
+func (r *LinearRelation) AddEquation(image group.Element, terms ...Term) error {
+    if len(terms) == 0 {
+        return errors.New("equation must contain at least one term")
+    }
+    if err := group.SameGroup(r.group, image); err != nil {
+        return err
+    }
+    for _, term := range terms {
+        if term.Var < 0 || term.Var >= r.vars {
+            return errors.New("variable of the term does not belong to the relation")
+        }
+        if err := group.SameGroup(r.group, term.Base); err != nil {
+            return err
+        }
+    }
+    r.equations = append(r.equations, equation{image, terms})
+    return nil
+}
+
+/*
+Group returns the group of the relation.
+*/
+func (r *LinearRelation) Group() group.Group {
+    return r.group
+}
+
+func (r *LinearRelation) appendTo(t *transcript.Transcript) {
+    t.AppendMessage("dom-sep", []byte("linear relation v1"))
+    t.AppendMessage("group", []byte(r.group.Name()))
+    t.AppendUint64("vars", uint64(r.vars))
+    t.AppendUint64("equations", uint64(len(r.equations)))
+    for _, eq := range r.equations {
+        t.AppendMessage("Y", eq.image.Bytes())
+        t.AppendUint64("terms", uint64(len(eq.terms)))
+        for _, term := range eq.terms {
+            t.AppendUint64("var", uint64(term.Var))
+            t.AppendMessage("B", term.Base.Bytes())
+        }
+    }
+}
+
+func (r *LinearRelation) size() int {
+    return r.vars
+}
+
+func (r *LinearRelation) commit(rng io.Reader, w Witness) ([]group.Element, *proverState, error) {
+    if len(w.scalars) != r.vars || len(w.parts) != 0 {
+        return nil, nil, ErrInvalidWitness
+    }
+    nonces, err := sampleScalars(rng, r.group, r.vars)
+    if err != nil {
+        return nil, nil, err
+    }
+    return r.evaluate(nonces), &proverState{nonces: nonces}, nil
+}
+
+func (r *LinearRelation) respond(w Witness, state *proverState, c *big.Int) []*big.Int {
+    order := r.group.Order()
+    result := make([]*big.Int, r.vars)
+    for j := range result {
+        s := new(big.Int).Mul(c, w.scalars[j])
+        s.Add(s, state.nonces[j])
+        result[j] = s.Mod(s, order)
+    }
+    return result
+}
+
+func (r *LinearRelation) recompute(c *big.Int, responses []*big.Int) []group.Element {
+    result := r.evaluate(responses)
+    minusC := new(big.Int).Neg(c)
+    for i, eq := range r.equations {
+        result[i] = result[i].Add(eq.image.ScalarMult(minusC))
+    }
+    return result
+}
+
+/*
+evaluate returns prod_j B_ij^x_j for each equation.
+*/
+func (r *LinearRelation) evaluate(x []*big.Int) []group.Element {
+    result := make([]group.Element, len(r.equations))
+    for i, eq := range r.equations {
+        result[i] = r.group.Identity()
+        for _, term := range eq.terms {
+            result[i] = result[i].Add(term.Base.ScalarMult(x[term.Var]))
+        }
+    }
+    return result
+}
+
+/*
+Schnorr returns the statement Y = g^x, i.e. the knowledge of the private key x of the
+public key Y. The witness is Scalars(x).
+*/
+func Schnorr(g, Y group.Element) (*LinearRelation, error) {
+    r := NewLinearRelation(g.Group(), 1)
+    return r, r.AddEquation(Y, Term{0, g})
+}
+
+/*
+DLEQ returns the statement Y1 = g1^x and Y2 = g2^x, i.e. the equality of the discrete
+logarithms of Y1 and Y2 with respect to the bases g1 and g2. The witness is
+Scalars(x).
+*/
+func DLEQ(g1, Y1, g2, Y2 group.Element) (*LinearRelation, error) {
+    r := NewLinearRelation(g1.Group(), 1)
+    if err := r.AddEquation(Y1, Term{0, g1}); err != nil {
+        return nil, err
+    }
+    return r, r.AddEquation(Y2, Term{0, g2})
+}
+
+/*
+PedersenOpening returns the statement C = g^x.h^r, i.e. the knowledge of the opening
+of the commitment C. The witness is OpeningWitness(opening).
+*/
+func PedersenOpening(params pedersen.Params, C pedersen.Commitment) (*LinearRelation, error) {
+    r := NewLinearRelation(params.Group(), 2)
+    if !params.Owns(C) {
+        return nil, group.ErrGroupMismatch
+    }
+    return r, r.AddEquation(C.Element(), Term{0, params.G()}, Term{1, params.H()})
+}
+
+/*
+OpeningWitness returns the witness of PedersenOpening.
+*/
+func OpeningWitness(opening pedersen.Opening) Witness {
+    return Scalars(opening.Value, opening.Blinding)
+}
+
+/*
+CommittedLinearRelation returns the statement sum_i a_i.x_i = b, where x_i is the
+value committed in C_i. Since prod_i C_i^a_i . g^-b = h^(sum_i a_i.r_i) if and only if
+the values satisfy the relation, the prover shows that it knows the discrete
+logarithm of this element with respect to h. The witness is
+CommittedLinearWitness(a, openings).
+*/
+func CommittedLinearRelation(params pedersen.Params, a []*big.Int, C []pedersen.Commitment, b *big.Int) (*LinearRelation, error) {
+    if len(a) != len(C) || len(a) == 0 {
+        return nil, errors.New("number of coefficients is different from the number of commitments")
+    }
+    Y := params.G().ScalarMult(new(big.Int).Neg(b))
+    for i := range C {
+        if !params.Owns(C[i]) {
+            return nil, group.ErrGroupMismatch
+        }
+        Y = Y.Add(C[i].Element().ScalarMult(a[i]))
+    }
+    r := NewLinearRelation(params.Group(), 1)
+    return r, r.AddEquation(Y, Term{0, params.H()})
+}
+
+/*
+CommittedLinearWitness returns the witness of CommittedLinearRelation, the scalar
+sum_i a_i.r_i, where r_i is the blinding factor of the opening i.
+*/
+func CommittedLinearWitness(a []*big.Int, openings []pedersen.Opening) Witness {
+    r := new(big.Int)
+    for i := range a {
+        if i < len(openings) {
+            r.Add(r, new(big.Int).Mul(a[i], openings[i].Blinding))
+        }
+    }
+    return Scalars(r)
+}
+
+/*
+CommittedEquality returns the statement that C1 and C2 commit to the same value. The
+witness is CommittedEqualityWitness(o1, o2).
+*/
+func CommittedEquality(params pedersen.Params, C1, C2 pedersen.Commitment) (*LinearRelation, error) {
+    return CommittedLinearRelation(params, []*big.Int{big.NewInt(1), big.NewInt(-1)}, []pedersen.Commitment{C1, C2}, big.NewInt(0))
+}
+
+/*
+CommittedEqualityWitness returns the witness of CommittedEquality.
+*/
+func CommittedEqualityWitness(o1, o2 pedersen.Opening) Witness {
+    return CommittedLinearWitness([]*big.Int{big.NewInt(1), big.NewInt(-1)}, []pedersen.Opening{o1, o2})
+}
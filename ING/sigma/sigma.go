@@ -0,0 +1,259 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+/*
+Package sigma implements non-interactive sigma protocols for discrete logarithm
+relations, in the groups of the package group. A statement is a LinearRelation,
+where each equation states that a public element is a product of public bases raised
+to secret scalars, or an AND or an OR of statements. This covers the proofs of
+knowledge of a private key (Schnorr), of the opening of a Pedersen commitment, of
+the equality of discrete logarithms and of linear relations between committed
+values.
+
+The protocols are made non-interactive with the Fiat-Shamir heuristic on the
+transcript of the package transcript, which absorbs the statement and the first
+messages of the prover. A proof only contains the challenge and the responses, the
+first messages being recomputed by the verifier. The OR composition follows
+Cramer, Damgard and Schoenmakers: the prover simulates the statements it does not
+know a witness for, and the challenges of all the statements sum to the challenge
+of the proof.
+*/
+package sigma
+
+import (
+    "crypto/rand"
+    "errors"
+    "io"
+    "math/big"
+
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+)
+
+// SIGMA_PROOF_LABEL is the label of the transcripts of Prove and Verify.
+const SIGMA_PROOF_LABEL = "zkrp sigma protocol"
+
+var (
+    ErrInvalidWitness = errors.New("witness does not match the statement")
+    ErrInvalidProof   = errors.New("proof does not match the statement")
+)
+
+/*
+Statement is a relation that can be proven with a sigma protocol. The statements are
+built with NewLinearRelation, or one of its shortcuts, And and Or.
+*/
+type Statement interface {
+    // Group returns the group of the elements of the statement.
+    Group() group.Group
+    // appendTo absorbs the statement into the transcript.
+    appendTo(t *transcript.Transcript)
+    // size returns the number of scalars of the responses.
+    size() int
+    // commit returns the first messages of the prover, and the state needed to
+    // compute the responses.
+    commit(r io.Reader, w Witness) ([]group.Element, *proverState, error)
+    // respond returns the responses to the challenge c.
+    respond(w Witness, state *proverState, c *big.Int) []*big.Int
+    // recompute returns the first messages for which the responses answer the
+    // challenge c. The responses must contain size() scalars.
+    recompute(c *big.Int, responses []*big.Int) []group.Element
+}
+
+/*
+Witness contains the secret of a statement: the scalars of a linear relation, the
+witnesses of the statements of an AND, or the witness of one of the statements of
+an OR.
+*/
+type Witness struct {
+    scalars []*big.Int
+    parts   []Witness
+    index   int
+}
+
+/*
+Scalars returns the witness of a linear relation, with one scalar per variable.
+*/
+func Scalars(x ...*big.Int) Witness {
+    return Witness{scalars: x}
+}
+
+/*
+AndWitness returns the witness of an AND, with one witness per statement.
+*/
+func AndWitness(w ...Witness) Witness {
+    return Witness{parts: w}
+}
+
+/*
+OrWitness returns the witness of an OR, where w is the witness of the statement at
+position index.
+*/
+func OrWitness(index int, w Witness) Witness {
+    return Witness{parts: []Witness{w}, index: index}
+}
+
+/*
+secrets returns all the scalars of the witness, which key the RNG of the nonces.
+*/
+func (w Witness) secrets() []*big.Int {
+    result := append([]*big.Int{}, w.scalars...)
+    for _, part := range w.parts {
+        result = append(result, part.secrets()...)
+    }
+    return result
+}
+
+/*
+proverState contains the nonces of a linear relation, the states of the statements
+of an AND or OR, and the challenges and responses simulated for an OR.
+*/
+type proverState struct {
+    nonces     []*big.Int
+    parts      []*proverState
+    challenges []*big.Int
+    responses  [][]*big.Int
+}
+
+/*
+Proof is a non-interactive proof of a statement.
+*/
+type Proof struct {
+    Challenge *big.Int
+    Responses []*big.Int
+}
+
+/*
+Option configures the prover.
+*/
+type Option func(*config)
+
+type config struct {
+    rand io.Reader
+}
+
+/*
+WithRand sets the random source of the prover, crypto/rand by default. The nonces are
+derived from the transcript and the witness together with 32 bytes of the random
+source, so that a broken random source can not leak the witness. With nil, the
+proofs are deterministic.
+*/
+func WithRand(r io.Reader) Option {
+    return func(c *config) {
+        c.rand = r
+    }
+}
+
+/*
+Prove returns a proof that the prover knows a witness w of the statement s.
+*/
+func Prove(s Statement, w Witness, opts ...Option) (Proof, error) {
+    return ProveWithTranscript(transcript.New(SIGMA_PROOF_LABEL), s, w, opts...)
+}
+
+/*
+Verify returns true if and only if the proof is valid for the statement s.
+*/
+func Verify(s Statement, proof Proof) (bool, error) {
+    return VerifyWithTranscript(transcript.New(SIGMA_PROOF_LABEL), s, proof)
+}
+
+/*
+ProveWithTranscript proves the statement s, deriving the challenge from t, so that
+the proof is bound to the messages already absorbed by t, e.g. a message to sign or
+the statement of another proof.
+*/
+func ProveWithTranscript(t *transcript.Transcript, s Statement, w Witness, opts ...Option) (Proof, error) {
+    cfg := config{rand: rand.Reader}
+    for _, opt := range opts {
+        opt(&cfg)
+    }
+    s.appendTo(t)
+
+    builder := t.BuildRng()
+    for _, x := range w.secrets() {
+        if x == nil {
+            return Proof{}, ErrInvalidWitness
+        }
+        builder.RekeyWithWitness("witness", scalarBytes(new(big.Int).Mod(x, s.Group().Order())))
+    }
+    rng, err := builder.Finalize(cfg.rand)
+    if err != nil {
+        return Proof{}, err
+    }
+
+    commitments, state, err := s.commit(rng, w)
+    if err != nil {
+        return Proof{}, err
+    }
+    c := challenge(t, s.Group(), commitments)
+    return Proof{Challenge: c, Responses: s.respond(w, state, c)}, nil
+}
+
+/*
+VerifyWithTranscript verifies the proof of the statement s, deriving the challenge
+from t.
+*/
+func VerifyWithTranscript(t *transcript.Transcript, s Statement, proof Proof) (bool, error) {
+    order := s.Group().Order()
+    if len(proof.Responses) != s.size() {
+        return false, ErrInvalidProof
+    }
+    for _, x := range append([]*big.Int{proof.Challenge}, proof.Responses...) {
+        if x == nil || x.Sign() < 0 || x.Cmp(order) >= 0 {
+            return false, ErrInvalidProof
+        }
+    }
+    s.appendTo(t)
+    commitments := s.recompute(proof.Challenge, proof.Responses)
+    return challenge(t, s.Group(), commitments).Cmp(proof.Challenge) == 0, nil
+}
+
+/*
+challenge absorbs the first messages of the prover and returns the challenge.
+*/
+func challenge(t *transcript.Transcript, g group.Group, commitments []group.Element) *big.Int {
+    for _, A := range commitments {
+        t.AppendMessage("A", A.Bytes())
+    }
+    return t.ChallengeScalar("c", g.Order())
+}
+
+/*
+sampleScalars returns n scalars lower than the order of g, read from r.
+*/
+func sampleScalars(r io.Reader, g group.Group, n int) ([]*big.Int, error) {
+    var err error
+    result := make([]*big.Int, n)
+    for i := range result {
+        result[i], err = group.RandomScalar(g, r)
+        if err != nil {
+            return nil, err
+        }
+    }
+    return result, nil
+}
+
+/*
+scalarBytes returns the SCALAR_SIZE bytes big-endian encoding of a scalar lower than
+the order of the group.
+*/
+func scalarBytes(x *big.Int) []byte {
+    b := x.Bytes()
+    result := make([]byte, SCALAR_SIZE)
+    copy(result[SCALAR_SIZE-len(b):], b)
+    return result
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright (C) 2019 ING BANK N.V.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ *
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package sigma
+
+import (
+    "encoding/json"
+    "math/big"
+    "testing"
+
+    "github.com/ing-bank/zkrp/crypto/bn256"
+    "github.com/ing-bank/zkrp/crypto/group"
+    "github.com/ing-bank/zkrp/crypto/p256"
+    "github.com/ing-bank/zkrp/crypto/pedersen"
+    "github.com/ing-bank/zkrp/crypto/transcript"
+    "github.com/stretchr/testify/assert"
+)
+
+var groups = []group.Group{group.P256, group.BN256G1, group.BN256G2}
+
+func randomElement(t *testing.T, g group.Group) group.Element {
+    x, err := group.RandomScalar(g, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return g.Generator().ScalarMult(x)
+}
+
+func pedersenParams(t *testing.T, g group.Group) pedersen.Params {
+    return pedersen.NewParams(randomElement(t, g))
+}
+
+func proveAndVerify(t *testing.T, s Statement, w Witness) bool {
+    proof, err := Prove(s, w)
+    if err != nil {
+        t.Fatal(err)
+    }
+    ok, err := Verify(s, proof)
+    assert.NoError(t, err)
+    return ok
+}
+
+func TestSchnorr(t *testing.T) {
+    for _, g := range groups {
+        x := big.NewInt(123456789)
+        Y := g.Generator().ScalarMult(x)
+        s, err := Schnorr(g.Generator(), Y)
+        assert.NoError(t, err)
+        assert.True(t, proveAndVerify(t, s, Scalars(x)), "private key proof should verify in %s", g.Name())
+        assert.False(t, proveAndVerify(t, s, Scalars(big.NewInt(1))), "wrong private key should fail in %s", g.Name())
+
+        proof, _ := Prove(s, Scalars(x))
+        proof.Responses[0] = new(big.Int).Add(proof.Responses[0], big.NewInt(1))
+        ok, _ := Verify(s, proof)
+        assert.False(t, ok, "tampered response should fail in %s", g.Name())
+    }
+}
+
+func TestDLEQ(t *testing.T) {
+    g := group.P256
+    x := big.NewInt(42)
+    g1, g2 := randomElement(t, g), randomElement(t, g)
+    s, _ := DLEQ(g1, g1.ScalarMult(x), g2, g2.ScalarMult(x))
+    assert.True(t, proveAndVerify(t, s, Scalars(x)))
+
+    s, _ = DLEQ(g1, g1.ScalarMult(x), g2, g2.ScalarMult(big.NewInt(43)))
+    assert.False(t, proveAndVerify(t, s, Scalars(x)), "different discrete logarithms should fail")
+
+    _, err := DLEQ(g1, g1, group.BN256G1.Generator(), group.BN256G1.Generator())
+    assert.Equal(t, group.ErrGroupMismatch, err)
+}
+
+func TestPedersenOpening(t *testing.T) {
+    for _, g := range groups {
+        params := pedersenParams(t, g)
+        C, opening, _ := params.CommitRandom(big.NewInt(40), nil)
+        s, err := PedersenOpening(params, C)
+        assert.NoError(t, err)
+        assert.True(t, proveAndVerify(t, s, OpeningWitness(opening)), "opening proof should verify in %s", g.Name())
+
+        opening.Value = big.NewInt(41)
+        assert.False(t, proveAndVerify(t, s, OpeningWitness(opening)), "wrong opening should fail in %s", g.Name())
+    }
+}
+
+func TestCommittedLinearRelation(t *testing.T) {
+    params := pedersenParams(t, group.P256)
+    C1, o1, _ := params.CommitRandom(big.NewInt(5), nil)
+    C2, o2, _ := params.CommitRandom(big.NewInt(7), nil)
+    C3, o3, _ := params.CommitRandom(big.NewInt(19), nil)
+    C := []pedersen.Commitment{C1, C2, C3}
+    openings := []pedersen.Opening{o1, o2, o3}
+
+    // x1 + 2.x2 - x3 = 0
+    a := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(-1)}
+    s, err := CommittedLinearRelation(params, a, C, big.NewInt(0))
+    assert.NoError(t, err)
+    assert.True(t, proveAndVerify(t, s, CommittedLinearWitness(a, openings)))
+
+    // x1 + x2 = 13 does not hold
+    a = []*big.Int{big.NewInt(1), big.NewInt(1)}
+    s, _ = CommittedLinearRelation(params, a, C[:2], big.NewInt(13))
+    assert.False(t, proveAndVerify(t, s, CommittedLinearWitness(a, openings[:2])))
+
+    // Equality of committed values, with different blinding factors.
+    D, oD, _ := params.CommitRandom(big.NewInt(7), nil)
+    s, _ = CommittedEquality(params, C2, D)
+    assert.True(t, proveAndVerify(t, s, CommittedEqualityWitness(o2, oD)))
+    s, _ = CommittedEquality(params, C1, D)
+    assert.False(t, proveAndVerify(t, s, CommittedEqualityWitness(o1, oD)))
+}
+
+func TestAnd(t *testing.T) {
+    g := group.BN256G2
+    params := pedersenParams(t, g)
+    x := big.NewInt(99)
+    key, _ := Schnorr(g.Generator(), g.Generator().ScalarMult(x))
+    C, opening, _ := params.CommitRandom(big.NewInt(40), nil)
+    open, _ := PedersenOpening(params, C)
+
+    s, err := And(key, open)
+    assert.NoError(t, err)
+    assert.True(t, proveAndVerify(t, s, AndWitness(Scalars(x), OpeningWitness(opening))))
+    assert.False(t, proveAndVerify(t, s, AndWitness(Scalars(big.NewInt(98)), OpeningWitness(opening))))
+
+    _, err = Prove(s, Scalars(x))
+    assert.Equal(t, ErrInvalidWitness, err)
+
+    other, _ := Schnorr(group.P256.Generator(), group.P256.Generator())
+    _, err = And(key, other)
+    assert.Equal(t, group.ErrGroupMismatch, err)
+}
+
+func TestOr(t *testing.T) {
+    g := group.P256
+    keys := make([]Statement, 3)
+    for i := range keys {
+        keys[i], _ = Schnorr(g.Generator(), randomElement(t, g))
+    }
+    x := big.NewInt(7)
+    known, _ := Schnorr(g.Generator(), g.Generator().ScalarMult(x))
+
+    // The prover knows one of the private keys, in any position.
+    for i := 0; i <= len(keys); i++ {
+        statements := append(append(append([]Statement{}, keys[:i]...), known), keys[i:]...)
+        s, err := Or(statements...)
+        assert.NoError(t, err)
+        assert.True(t, proveAndVerify(t, s, OrWitness(i, Scalars(x))), "position %d", i)
+        // The witness of another position is not known.
+        assert.False(t, proveAndVerify(t, s, OrWitness((i+1)%len(statements), Scalars(x))))
+    }
+
+    // OR of ANDs, within an AND
+    params := pedersenParams(t, g)
+    C, opening, _ := params.CommitRandom(big.NewInt(1), nil)
+    open, _ := PedersenOpening(params, C)
+    left, _ := And(keys[0], keys[1])
+    right, _ := And(known, open)
+    or, _ := Or(left, right)
+    s, _ := And(or, known)
+    w := AndWitness(OrWitness(1, AndWitness(Scalars(x), OpeningWitness(opening))), Scalars(x))
+    assert.True(t, proveAndVerify(t, s, w))
+
+    _, err := Prove(or, OrWitness(2, Scalars(x)))
+    assert.Equal(t, ErrInvalidWitness, err)
+}
+
+func TestTranscriptBinding(t *testing.T) {
+    g := group.P256
+    x := big.NewInt(1234)
+    s, _ := Schnorr(g.Generator(), g.Generator().ScalarMult(x))
+
+    // A signature of the message: the proof is bound to the transcript.
+    sign := transcript.New("signature")
+    sign.AppendMessage("message", []byte("hello"))
+    proof, _ := ProveWithTranscript(sign, s, Scalars(x))
+
+    verify := transcript.New("signature")
+    verify.AppendMessage("message", []byte("hello"))
+    ok, _ := VerifyWithTranscript(verify, s, proof)
+    assert.True(t, ok)
+
+    verify = transcript.New("signature")
+    verify.AppendMessage("message", []byte("other"))
+    ok, _ = VerifyWithTranscript(verify, s, proof)
+    assert.False(t, ok, "proof should not verify for another message")
+
+    ok, _ = Verify(s, proof)
+    assert.False(t, ok, "proof should not verify with another transcript")
+}
+
+func TestDeterministic(t *testing.T) {
+    g := group.P256
+    x := big.NewInt(1234)
+    s, _ := Schnorr(g.Generator(), g.Generator().ScalarMult(x))
+    proof1, _ := Prove(s, Scalars(x), WithRand(nil))
+    proof2, _ := Prove(s, Scalars(x), WithRand(nil))
+    assert.Equal(t, proof1, proof2, "proofs without external randomness should be equal")
+    proof3, _ := Prove(s, Scalars(x))
+    assert.NotEqual(t, proof1, proof3)
+}
+
+func TestEncoding(t *testing.T) {
+    g := group.BN256G1
+    params := pedersenParams(t, g)
+    C, opening, _ := params.CommitRandom(big.NewInt(40), nil)
+    open, _ := PedersenOpening(params, C)
+    key, _ := Schnorr(g.Generator(), randomElement(t, g))
+    s, _ := Or(key, open)
+    proof, _ := Prove(s, OrWitness(1, OpeningWitness(opening)))
+
+    data, err := proof.MarshalBinary()
+    assert.NoError(t, err)
+    assert.Equal(t, 1+(1+s.size())*SCALAR_SIZE, len(data))
+    var decoded Proof
+    assert.NoError(t, decoded.UnmarshalBinary(data))
+    assert.Equal(t, proof, decoded)
+    ok, _ := Verify(s, decoded)
+    assert.True(t, ok)
+
+    data, _ = json.Marshal(proof)
+    decoded = Proof{}
+    assert.NoError(t, json.Unmarshal(data, &decoded))
+    assert.Equal(t, proof, decoded)
+
+    data, _ = proof.MarshalBinary()
+    data[0] = 2
+    assert.Equal(t, ErrUnsupportedVersion, decoded.UnmarshalBinary(data))
+    assert.Equal(t, ErrInvalidEncoding, decoded.UnmarshalBinary([]byte{ENCODING_VERSION, 1, 2}))
+
+    // Proofs with the wrong number of responses, or responses out of range, are
+    // rejected by the verifier.
+    decoded = Proof{Challenge: proof.Challenge, Responses: proof.Responses[1:]}
+    _, err = Verify(s, decoded)
+    assert.Equal(t, ErrInvalidProof, err)
+    decoded = Proof{Challenge: proof.Challenge, Responses: append([]*big.Int{bn256.Order}, proof.Responses[1:]...)}
+    _, err = Verify(s, decoded)
+    assert.Equal(t, ErrInvalidProof, err)
+}
+
+func TestP256Commitments(t *testing.T) {
+    // The commitments of Bulletproofs can be used in the statements.
+    h, _ := p256.MapToGroup("sigma test")
+    params := pedersen.NewP256Params(h)
+    C, opening := params.Commit(big.NewInt(40), big.NewInt(5))
+    s, _ := PedersenOpening(params, C)
+    assert.True(t, proveAndVerify(t, s, OpeningWitness(opening)))
+}
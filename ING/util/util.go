@@ -18,13 +18,11 @@
 package util
 
 import (
-    "crypto/sha256"
     "math/big"
 
     "github.com/ing-bank/zkrp/crypto/bn256"
     "github.com/ing-bank/zkrp/crypto/p256"
     "github.com/ing-bank/zkrp/util/bn"
-    "github.com/ing-bank/zkrp/util/byteconversion"
 )
 
 // Constants that are going to be used frequently, then we just need to compute them once.
@@ -55,7 +53,8 @@ func Decompose(x *big.Int, u int64, l int64) ([]int64, error) {
 
 /*
 Commit method corresponds to the Pedersen commitment scheme. Namely, given input
-message x, and randomness r, it outputs g^x.h^r.
+message x, and randomness r, it outputs g^x.h^r. The package crypto/pedersen contains
+the typed commitments and openings.
 */
 func Commit(x, r *big.Int, h *bn256.G2) (*bn256.G2, error) {
     var C = new(bn256.G2).ScalarBaseMult(x)
@@ -73,29 +72,3 @@ func CommitG1(x, r *big.Int, h *p256.P256) (*p256.P256, error) {
     C.Add(C, Hr)
     return C, nil
 }
-
-/*
-HashSet is responsible for the computing a Zp element given elements from GT and G2.
-*/
-func HashSet(a *bn256.GT, D *bn256.G2) (*big.Int, error) {
-    digest := sha256.New()
-    digest.Write([]byte(a.String()))
-    digest.Write([]byte(D.String()))
-    output := digest.Sum(nil)
-    tmp := output[0:]
-    return byteconversion.FromByteArray(tmp)
-}
-
-/*
-Hash is responsible for the computing a Zp element given elements from GT and G2.
-*/
-func Hash(a []*bn256.GT, D *bn256.G2) (*big.Int, error) {
-    digest := sha256.New()
-    for i := range a {
-        digest.Write([]byte(a[i].String()))
-    }
-    digest.Write([]byte(D.String()))
-    output := digest.Sum(nil)
-    tmp := output[0:]
-    return byteconversion.FromByteArray(tmp)
-}
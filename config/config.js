@@ -488,6 +488,8 @@ let config = {
         roles: [ 'prover', 'verifier', 'issuer' ],
         href: '/zkrp?fnc=verifyProof',
         input: {
+          lower: '18', // Lower bound of the range to check.
+          upper: '65', // Upper bound of the range to check.
           inPath: 'ING\\proof.json'
         }
       }
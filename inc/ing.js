@@ -55,7 +55,8 @@ module.exports.generateProof = function (user, input, ret) {
 module.exports.verifyProof = function (user, input, ret) {
   if (!tools.isEmpty(input)) input = JSON.parse(unescape(input));
   else { console.error('Missing input params.'); return; }
-  let cmd = ['-action', 'verify', '-proofIn', input.inPath];
+  let cmd = ['-action', 'verify', '-lower', input.lower, '-upper', input.upper,
+    '-proofIn', input.inPath];
   try {
     let res = execFileSync(global.config.ing.executablePath, cmd,
       { maxBuffer: global.config.node.exec.maxBuffer }).toString();
@@ -543,8 +543,11 @@ module.exports.verifyRangeProof = function (user, input) {
           };
           tools.writeToFile(Buffer.from(
                   document.message.proof, 'base64'), input.inPath);
-          res.verification = ing.verifyProof(
-                  user, JSON.stringify({inPath: input.inPath}), true);
+          res.verification = ing.verifyProof(user, JSON.stringify({
+            lower: document.message.lower,
+            upper: document.message.upper,
+            inPath: input.inPath
+          }), true);
           tools.displayTiming('verifyProof');
           tools.addHistory(control, res, {fnc: 'verifyRangeProof'});
           console.log(res);